@@ -88,12 +88,62 @@ func (i *Identifier) Display() string {
 	return fmt.Sprintf("Identifier{%s}", i.Value)
 }
 
+// TypeAnnotation is an optional type on a parameter, return value, var, or val ie. `int`,
+// `list[str]`, `map[str, int]`, or the union `int | none`
+type TypeAnnotation struct {
+	Token  token.Token       // Token is the type's name token or the first token of a union
+	Span   token.Span        // Span covers the whole annotation
+	Name   string            // Name is the name of the type, it is empty for a union
+	Params []*TypeAnnotation // Params are the element types of a generic ie. str and int for map[str, int]
+	Union  []*TypeAnnotation // Union are the alternatives of a union type
+}
+
+// TokenLiteral returns the first token of the annotation
+func (ta *TypeAnnotation) TokenLiteral() string { return ta.Token.Literal }
+
+// String returns the annotation as it would be written ie. `map[str, int] | none`
+func (ta *TypeAnnotation) String() string {
+	if len(ta.Union) > 0 {
+		alts := []string{}
+		for _, t := range ta.Union {
+			alts = append(alts, t.String())
+		}
+		return strings.Join(alts, " | ")
+	}
+	if len(ta.Params) == 0 {
+		return ta.Name
+	}
+	params := []string{}
+	for _, t := range ta.Params {
+		params = append(params, t.String())
+	}
+	return ta.Name + "[" + strings.Join(params, ", ") + "]"
+}
+
+func (ta *TypeAnnotation) Display() string {
+	return "TypeAnnotation{" + ta.String() + "}"
+}
+
+// annotatedParameters returns the parameters with their annotations ie. `a: int, b`
+func annotatedParameters(params []*Identifier, types []*TypeAnnotation) []string {
+	out := []string{}
+	for i, p := range params {
+		if i < len(types) && types[i] != nil {
+			out = append(out, p.String()+": "+types[i].String())
+		} else {
+			out = append(out, p.String())
+		}
+	}
+	return out
+}
+
 // VarStatement is the node for var statements
 type VarStatement struct {
-	Token           token.Token // Token == token.VAR
-	Name            *Identifier // Name is the identifier that Value is being binded to
-	Value           Expression  // Value is the expression node that is being assinged to
-	AssignmentToken token.Token // AssignmentToken is the token used for assignment
+	Token           token.Token     // Token == token.VAR
+	Name            *Identifier     // Name is the identifier that Value is being binded to
+	Type            *TypeAnnotation // Type is nil unless the var is annotated ie. `var n: int = 0`
+	Value           Expression      // Value is the expression node that is being assinged to
+	AssignmentToken token.Token     // AssignmentToken is the token used for assignment
 }
 
 // statementNode makes var a statement
@@ -108,6 +158,9 @@ func (vars *VarStatement) String() string {
 
 	out.WriteString(vars.TokenLiteral() + " ")
 	out.WriteString(vars.Name.String())
+	if vars.Type != nil {
+		out.WriteString(": " + vars.Type.String())
+	}
 	out.WriteString(" ")
 	out.WriteString(vars.AssignmentToken.Literal)
 	out.WriteString(" ")
@@ -127,9 +180,10 @@ func (vars *VarStatement) Display() string {
 
 // ValStatement is the node for val statements
 type ValStatement struct {
-	Token token.Token // Token == token.VAL
-	Name  *Identifier // Name is the identifier that Value is being binded to
-	Value Expression  // Value is the expression node that is being assinged to
+	Token token.Token     // Token == token.VAL
+	Name  *Identifier     // Name is the identifier that Value is being binded to
+	Type  *TypeAnnotation // Type is nil unless the val is annotated ie. `val name: str = "blue"`
+	Value Expression      // Value is the expression node that is being assinged to
 }
 
 // statementNode makes val a statement
@@ -144,6 +198,9 @@ func (vals *ValStatement) String() string {
 
 	out.WriteString(vals.TokenLiteral() + " ")
 	out.WriteString(vals.Name.String())
+	if vals.Type != nil {
+		out.WriteString(": " + vals.Type.String())
+	}
 	out.WriteString(" = ")
 
 	if vals.Value != nil {
@@ -169,6 +226,9 @@ type FunctionStatement struct {
 	Parameters           []*Identifier
 	ParameterExpressions []Expression // ParameterExpressions defines the expression to perform for identifier if
 	// if it is not nil the value will be used as the default parameter
+	Decorators     []Expression      // Decorators are the `@decorator` expressions above the function in source order
+	ParameterTypes []*TypeAnnotation // ParameterTypes is nil or the annotation of each parameter, nil if it has none
+	ReturnType     *TypeAnnotation   // ReturnType is nil unless the function is annotated ie. `fun f() -> int`
 }
 
 // statementNode satisfies the statement interface
@@ -181,14 +241,17 @@ func (fs *FunctionStatement) TokenLiteral() string { return fs.Token.Literal }
 func (fs *FunctionStatement) String() string {
 	var out bytes.Buffer
 
-	params := []string{}
-	for _, p := range fs.Parameters {
-		params = append(params, p.String())
-	}
+	params := annotatedParameters(fs.Parameters, fs.ParameterTypes)
 
+	for _, d := range fs.Decorators {
+		out.WriteString("@" + d.String() + "\n")
+	}
 	out.WriteString("fun ")
 	out.WriteString(fs.Name.String() + "(")
 	out.WriteString(strings.Join(params, ", ") + ")")
+	if fs.ReturnType != nil {
+		out.WriteString(" -> " + fs.ReturnType.String())
+	}
 	out.WriteString(" {\n\t")
 	out.WriteString(fs.Body.String())
 	out.WriteString("\n}\n")
@@ -201,15 +264,28 @@ func (fs *FunctionStatement) Display() string {
 	out.WriteString("FunctionStatement{Name: '")
 	out.WriteString(fs.Name.Value)
 	out.WriteString("', Parameters: [")
-	for i, name := range fs.Parameters {
+	for i, param := range annotatedParameters(fs.Parameters, fs.ParameterTypes) {
 		out.WriteString("'")
-		out.WriteString(name.Value)
+		out.WriteString(param)
 		out.WriteString("'")
 		if len(fs.Parameters) > i+1 {
 			out.WriteString(", ")
 		}
 	}
 	out.WriteString("], ")
+	if fs.ReturnType != nil {
+		out.WriteString("ReturnType: " + fs.ReturnType.Display() + ", ")
+	}
+	if len(fs.Decorators) > 0 {
+		out.WriteString("Decorators: [")
+		for i, d := range fs.Decorators {
+			out.WriteString(d.Display())
+			if len(fs.Decorators) > i+1 {
+				out.WriteString(", ")
+			}
+		}
+		out.WriteString("], ")
+	}
 	out.WriteString(fs.Body.Display())
 	out.WriteString("}")
 	return out.String()
@@ -292,6 +368,64 @@ func (bil *BigIntegerLiteral) Display() string {
 	return fmt.Sprintf("BigIntegerLiteral{%s}", bil.Value.String())
 }
 
+// RationalLiteral is the exact rational literal ast node ie. `1.5r`
+type RationalLiteral struct {
+	Token token.Token // token == token.RATIONAL
+	Value *big.Rat    // Value stores the exact rational value
+}
+
+// expressionNode satisfies the Expression interface
+func (rl *RationalLiteral) expressionNode() {}
+
+// TokenLiteral returns the string value of the rational
+func (rl *RationalLiteral) TokenLiteral() string { return rl.Token.Literal }
+
+// String returns the string value of the rational
+func (rl *RationalLiteral) String() string { return rl.Token.Literal }
+
+func (rl *RationalLiteral) Display() string {
+	return fmt.Sprintf("RationalLiteral{%s}", rl.Value.RatString())
+}
+
+// DecimalLiteral is the exact base 10 decimal literal ast node ie. `19.99d`
+type DecimalLiteral struct {
+	Token    token.Token // token == token.DECIMAL
+	Unscaled *big.Int    // Unscaled is the value with the decimal point removed ie. 1999
+	Scale    int         // Scale is the number of digits after the decimal point ie. 2
+}
+
+// expressionNode satisfies the Expression interface
+func (dl *DecimalLiteral) expressionNode() {}
+
+// TokenLiteral returns the string value of the decimal
+func (dl *DecimalLiteral) TokenLiteral() string { return dl.Token.Literal }
+
+// String returns the string value of the decimal
+func (dl *DecimalLiteral) String() string { return dl.Token.Literal }
+
+func (dl *DecimalLiteral) Display() string {
+	return fmt.Sprintf("DecimalLiteral{%se-%d}", dl.Unscaled.String(), dl.Scale)
+}
+
+// DurationLiteral is the duration literal ast node ie. `200ms` or `5s`
+type DurationLiteral struct {
+	Token token.Token // token == token.DURATION
+	Value int64       // Value is the duration in milliseconds
+}
+
+// expressionNode satisfies the Expression interface
+func (dl *DurationLiteral) expressionNode() {}
+
+// TokenLiteral returns the string value of the duration
+func (dl *DurationLiteral) TokenLiteral() string { return dl.Token.Literal }
+
+// String returns the string value of the duration
+func (dl *DurationLiteral) String() string { return dl.Token.Literal }
+
+func (dl *DurationLiteral) Display() string {
+	return fmt.Sprintf("DurationLiteral{%dms}", dl.Value)
+}
+
 // IntegerLiteral is the integer literal expression
 type IntegerLiteral struct {
 	Token token.Token // Token == token.INT
@@ -330,6 +464,25 @@ func (fl *FloatLiteral) Display() string {
 	return fmt.Sprintf("FloatLiteral{%f}", fl.Value)
 }
 
+// ImaginaryLiteral is the imaginary literal expression ie. `3i`
+type ImaginaryLiteral struct {
+	Token token.Token // Token == token.IMAGINARY
+	Value float64     // Value stores the imaginary part as a float64
+}
+
+// expressionNode satisfies the Expression interface
+func (il *ImaginaryLiteral) expressionNode() {}
+
+// TokenLiteral returns the string value of the imaginary number
+func (il *ImaginaryLiteral) TokenLiteral() string { return il.Token.Literal }
+
+// String returns the string value of the imaginary number
+func (il *ImaginaryLiteral) String() string { return il.Token.Literal }
+
+func (il *ImaginaryLiteral) Display() string {
+	return fmt.Sprintf("ImaginaryLiteral{%fi}", il.Value)
+}
+
 // HexLiteral is the hex literal expression
 type HexLiteral struct {
 	Token token.Token // Token == token.HEX
@@ -581,6 +734,7 @@ func (me *MatchExpression) Display() string {
 type BlockStatement struct {
 	Token      token.Token // Token == {
 	Statements []Statement // Statements is the list of statements in the block
+	Yields     bool        // Yields is true for a function body that contains a yield, calling it returns a generator
 }
 
 // statementNode satisifes the statement interface
@@ -616,7 +770,9 @@ type FunctionLiteral struct {
 	Parameters           []*Identifier
 	ParameterExpressions []Expression // ParameterExpressions defines the expression to perform for identifier if
 	// if it is not nil the value will be used as the default parameter
-	Body *BlockStatement
+	Body           *BlockStatement
+	ParameterTypes []*TypeAnnotation // ParameterTypes is nil or the annotation of each parameter, nil if it has none
+	ReturnType     *TypeAnnotation   // ReturnType is nil unless the function is annotated ie. `fun() -> int`
 }
 
 // expressionNode satisfies the expression interface
@@ -629,15 +785,16 @@ func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
-	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
-	}
+	params := annotatedParameters(fl.Parameters, fl.ParameterTypes)
 
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
-	out.WriteString(" ) {\n\t")
+	out.WriteString(" )")
+	if fl.ReturnType != nil {
+		out.WriteString(" -> " + fl.ReturnType.String())
+	}
+	out.WriteString(" {\n\t")
 	out.WriteString(fl.Body.String())
 	out.WriteString("\n}\n")
 
@@ -648,15 +805,18 @@ func (fl *FunctionLiteral) Display() string {
 	var out bytes.Buffer
 	out.WriteString("FunctionLiteral{")
 	out.WriteString("Parameters: [")
-	for i, name := range fl.Parameters {
+	for i, param := range annotatedParameters(fl.Parameters, fl.ParameterTypes) {
 		out.WriteString("'")
-		out.WriteString(name.Value)
+		out.WriteString(param)
 		out.WriteString("'")
 		if len(fl.Parameters) > i+1 {
 			out.WriteString(", ")
 		}
 	}
 	out.WriteString("]")
+	if fl.ReturnType != nil {
+		out.WriteString(", ReturnType: " + fl.ReturnType.Display())
+	}
 	out.WriteString(fl.Body.Display())
 	out.WriteString("}\n")
 	return out.String()
@@ -670,6 +830,7 @@ type CallExpression struct {
 	Arguments []Expression // Arguments is the list of expression to be passed as arguments
 
 	DefaultArguments map[string]Expression // DefaultArguments is the map of the identifer as a string to the expression to be used as the value
+	Optional         bool                  // Optional is true for `f?.()` which is null instead of calling a null function
 }
 
 // expressionNode satisfies the expression interface
@@ -687,6 +848,9 @@ func (ce *CallExpression) String() string {
 	}
 
 	out.WriteString(ce.Function.String())
+	if ce.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
 	// TODO: Put a \n here to make the ast print nicer.  This makes tests fail though
@@ -715,10 +879,32 @@ func (ce *CallExpression) Display() string {
 	return out.String()
 }
 
+// SymbolLiteral is the symbol literal ast node ie. `:name`
+type SymbolLiteral struct {
+	Token token.Token // Token == token.SYMBOL
+	Value string      // Value is the name of the symbol without the `:`
+}
+
+// expressionNode satisfies the expression interface
+func (sl *SymbolLiteral) expressionNode() {}
+
+// TokenLiteral returns the symbol's name
+func (sl *SymbolLiteral) TokenLiteral() string { return sl.Token.Literal }
+
+// String returns the symbol with its leading `:`
+func (sl *SymbolLiteral) String() string { return ":" + sl.Value }
+
+func (sl *SymbolLiteral) Display() string {
+	return fmt.Sprintf("SymbolLiteral{:%s}", sl.Value)
+}
+
 // ExecStringLiteral is the contents of a string within backticks ``
 type ExecStringLiteral struct {
-	Token token.Token
-	Value string
+	Token               token.Token  // Token == `
+	Value               string       // Value is the full command (with interpolation not removed)
+	InterpolationValues []Expression // InterpolationValues is the expressions that need to be evaluated and put back into the command
+
+	OriginalInterpolationString []string // OriginalInterpolationString is a slice of strings to use to replace with interpolation
 }
 
 // expressionNode satisfies the expression interface
@@ -863,9 +1049,10 @@ func (ml *MapLiteral) Display() string {
 
 // IndexExpression is the ast node of an index call expression
 type IndexExpression struct {
-	Token token.Token // Token == [
-	Left  Expression
-	Index Expression
+	Token    token.Token // Token == [
+	Left     Expression
+	Index    Expression
+	Optional bool // Optional is true for `m?[k]` and `m?.k` which are null when Left is null
 }
 
 // expressionNode satisfies the expression interface
@@ -880,6 +1067,9 @@ func (ie *IndexExpression) String() string {
 
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
@@ -897,6 +1087,97 @@ func (ie *IndexExpression) Display() string {
 	return out.String()
 }
 
+// YieldExpression is the ast node for `yield x` which hands x to the consumer
+// of the generator and waits until the next value is requested
+type YieldExpression struct {
+	Token token.Token // Token == yield
+	Value Expression  // Value is nil for a bare `yield` which yields null
+}
+
+// expressionNode satisfies the expression interface
+func (ye *YieldExpression) expressionNode() {}
+
+// TokenLiteral returns the yield token
+func (ye *YieldExpression) TokenLiteral() string { return ye.Token.Literal }
+
+// String returns the yield expression as a string
+func (ye *YieldExpression) String() string {
+	if ye.Value == nil {
+		return "yield"
+	}
+	return "yield " + ye.Value.String()
+}
+
+func (ye *YieldExpression) Display() string {
+	if ye.Value == nil {
+		return "YieldExpression{Value: nil}"
+	}
+	return "YieldExpression{Value: " + ye.Value.Display() + "}"
+}
+
+// SliceExpression is the ast node for slicing ie. `xs[1:3]`, `xs[:-1]`, or `xs[::2]`
+// Start, End, and Step are nil when they are left out
+type SliceExpression struct {
+	Token    token.Token // Token == [
+	Left     Expression
+	Start    Expression
+	End      Expression
+	Step     Expression
+	Optional bool // Optional is true for `xs?[1:]` which is null when Left is null
+}
+
+// expressionNode satisfies the expression interface
+func (se *SliceExpression) expressionNode() {}
+
+// TokenLiteral returns the [ token
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+
+// String returns a string representation of the slice expression
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	if se.Optional {
+		out.WriteString("?")
+	}
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.End != nil {
+		out.WriteString(se.End.String())
+	}
+	if se.Step != nil {
+		out.WriteString(":")
+		out.WriteString(se.Step.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
+func (se *SliceExpression) Display() string {
+	display := func(exp Expression) string {
+		if exp == nil {
+			return "nil"
+		}
+		return exp.Display()
+	}
+	var out bytes.Buffer
+	out.WriteString("SliceExpression{Left: ")
+	out.WriteString(se.Left.Display())
+	out.WriteString(", Start: ")
+	out.WriteString(display(se.Start))
+	out.WriteString(", End: ")
+	out.WriteString(display(se.End))
+	out.WriteString(", Step: ")
+	out.WriteString(display(se.Step))
+	out.WriteString("}")
+	return out.String()
+}
+
 // ForExpression is the for loop ast node
 type ForExpression struct {
 	Token       token.Token     // token == for
@@ -1000,10 +1281,452 @@ func (set *SetLiteral) Display() string {
 	return out.String()
 }
 
-// ImportStatement is the representation of the map literal ast node
+// EnumStatement is the enum declaration ast node ie. `enum Shape { Circle(r), Rect(w, h), Empty }`
+type EnumStatement struct {
+	Token    token.Token    // Token == token.ENUM
+	Name     *Identifier    // Name is the identifier the enum is bound to
+	Variants []*EnumVariant // Variants are the variants of the enum in order
+}
+
+// EnumVariant is a single variant of an enum, Fields is empty for variants without a payload
+type EnumVariant struct {
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+// String returns the variant as it was declared ie. `Rect(w, h)`
+func (ev *EnumVariant) String() string {
+	if len(ev.Fields) == 0 {
+		return ev.Name.Value
+	}
+	fields := []string{}
+	for _, f := range ev.Fields {
+		fields = append(fields, f.Value)
+	}
+	return ev.Name.Value + "(" + strings.Join(fields, ", ") + ")"
+}
+
+// statementNode satisfies the statement interface
+func (es *EnumStatement) statementNode() {}
+
+// TokenLiteral returns the enum token as a string
+func (es *EnumStatement) TokenLiteral() string { return es.Token.Literal }
+
+// String returns the enum declaration as a string
+func (es *EnumStatement) String() string {
+	variants := []string{}
+	for _, v := range es.Variants {
+		variants = append(variants, v.String())
+	}
+	return fmt.Sprintf("enum %s { %s }", es.Name.Value, strings.Join(variants, ", "))
+}
+
+func (es *EnumStatement) Display() string {
+	variants := []string{}
+	for _, v := range es.Variants {
+		variants = append(variants, "'"+v.String()+"'")
+	}
+	return fmt.Sprintf("EnumStatement{Name: '%s', Variants: [%s]}", es.Name.Value, strings.Join(variants, ", "))
+}
+
+// StructStatement is the struct declaration ast node ie.
+// `struct Point { x, y = 0, fun norm2(self) { self.x * self.x + self.y * self.y } }`
+type StructStatement struct {
+	Token    token.Token          // Token == token.STRUCT
+	Name     *Identifier          // Name is the identifier the struct is bound to
+	Fields   []*Identifier        // Fields are the names of the fields in order
+	Defaults []Expression         // Defaults is nil or the default expression for each field
+	Methods  []*FunctionStatement // Methods take the receiver as their first parameter
+}
+
+// statementNode satisfies the statement interface
+func (ss *StructStatement) statementNode() {}
+
+// TokenLiteral returns the struct token as a string
+func (ss *StructStatement) TokenLiteral() string { return ss.Token.Literal }
+
+// String returns the struct declaration as a string
+func (ss *StructStatement) String() string {
+	members := ss.fields()
+	for _, m := range ss.Methods {
+		members = append(members, strings.TrimSuffix(m.String(), "\n"))
+	}
+	return fmt.Sprintf("struct %s { %s }", ss.Name.Value, strings.Join(members, ", "))
+}
+
+// fields returns each field with its default ie. `y = 0`
+func (ss *StructStatement) fields() []string {
+	fields := []string{}
+	for i, f := range ss.Fields {
+		if ss.Defaults != nil && ss.Defaults[i] != nil {
+			fields = append(fields, f.Value+" = "+ss.Defaults[i].String())
+		} else {
+			fields = append(fields, f.Value)
+		}
+	}
+	return fields
+}
+
+func (ss *StructStatement) Display() string {
+	fields := []string{}
+	for _, f := range ss.fields() {
+		fields = append(fields, "'"+f+"'")
+	}
+	methods := []string{}
+	for _, m := range ss.Methods {
+		methods = append(methods, m.Display())
+	}
+	return fmt.Sprintf("StructStatement{Name: '%s', Fields: [%s], Methods: [%s]}", ss.Name.Value, strings.Join(fields, ", "), strings.Join(methods, ", "))
+}
+
+// TraitStatement is the trait declaration ast node, methods without a body are
+// required and methods with a body are defaults ie.
+// `trait Shape { fun area(self) fun describe(self) { "area #{self.area()}" } }`
+type TraitStatement struct {
+	Token   token.Token          // Token == token.TRAIT
+	Name    *Identifier          // Name is the identifier the trait is bound to
+	Methods []*FunctionStatement // Methods have a nil Body when they are required
+}
+
+// statementNode satisfies the statement interface
+func (ts *TraitStatement) statementNode() {}
+
+// TokenLiteral returns the trait token as a string
+func (ts *TraitStatement) TokenLiteral() string { return ts.Token.Literal }
+
+// String returns the trait declaration as a string
+func (ts *TraitStatement) String() string {
+	methods := []string{}
+	for _, m := range ts.Methods {
+		methods = append(methods, methodSignature(m))
+	}
+	return fmt.Sprintf("trait %s { %s }", ts.Name.Value, strings.Join(methods, ", "))
+}
+
+func (ts *TraitStatement) Display() string {
+	methods := []string{}
+	for _, m := range ts.Methods {
+		methods = append(methods, "'"+m.Name.Value+"'")
+	}
+	return fmt.Sprintf("TraitStatement{Name: '%s', Methods: [%s]}", ts.Name.Value, strings.Join(methods, ", "))
+}
+
+// ImplStatement adds methods to a struct, optionally implementing a trait ie.
+// `impl Shape for Circle { fun area(self) { 3 * self.r * self.r } }` or `impl Circle { ... }`
+type ImplStatement struct {
+	Token   token.Token          // Token == token.IMPL
+	Trait   *Identifier          // Trait is nil when the methods are added without a trait
+	Target  *Identifier          // Target is the struct the methods are added to
+	Methods []*FunctionStatement // Methods take the receiver as their first parameter
+}
+
+// statementNode satisfies the statement interface
+func (is *ImplStatement) statementNode() {}
+
+// TokenLiteral returns the impl token as a string
+func (is *ImplStatement) TokenLiteral() string { return is.Token.Literal }
+
+// String returns the impl block as a string
+func (is *ImplStatement) String() string {
+	methods := []string{}
+	for _, m := range is.Methods {
+		methods = append(methods, methodSignature(m))
+	}
+	if is.Trait == nil {
+		return fmt.Sprintf("impl %s { %s }", is.Target.Value, strings.Join(methods, ", "))
+	}
+	return fmt.Sprintf("impl %s for %s { %s }", is.Trait.Value, is.Target.Value, strings.Join(methods, ", "))
+}
+
+func (is *ImplStatement) Display() string {
+	trait := ""
+	if is.Trait != nil {
+		trait = is.Trait.Value
+	}
+	methods := []string{}
+	for _, m := range is.Methods {
+		methods = append(methods, "'"+m.Name.Value+"'")
+	}
+	return fmt.Sprintf("ImplStatement{Trait: '%s', Target: '%s', Methods: [%s]}", trait, is.Target.Value, strings.Join(methods, ", "))
+}
+
+// methodSignature returns the method's name and parameters ie. `fun area(self)`
+func methodSignature(fs *FunctionStatement) string {
+	params := annotatedParameters(fs.Parameters, fs.ParameterTypes)
+	sig := "fun " + fs.Name.Value + "(" + strings.Join(params, ", ") + ")"
+	if fs.ReturnType != nil {
+		sig += " -> " + fs.ReturnType.String()
+	}
+	return sig
+}
+
+// QuoteExpression is the ast node for `quote { ... }` which evaluates to the code in
+// its body instead of running it, `unquote(x)` inside the body is replaced with the value of x
+type QuoteExpression struct {
+	Token token.Token     // Token == token.QUOTE
+	Body  *BlockStatement // Body is the quoted code
+}
+
+// expressionNode satisfies the expression interface
+func (qe *QuoteExpression) expressionNode() {}
+
+// TokenLiteral returns the quote token
+func (qe *QuoteExpression) TokenLiteral() string { return qe.Token.Literal }
+
+// String returns the quote expression as a string
+func (qe *QuoteExpression) String() string {
+	return "quote { " + qe.Body.String() + " }"
+}
+
+func (qe *QuoteExpression) Display() string {
+	return "QuoteExpression{Body: " + qe.Body.Display() + "}"
+}
+
+// MacroStatement is the ast node for a macro definition ie. `macro unless(cond, body) { ... }`
+// calls to the macro are replaced with the quote it returns before the program is evaluated
+type MacroStatement struct {
+	Token      token.Token     // Token == token.MACRO
+	Name       *Identifier     // Name is the name the macro is called with
+	Parameters []*Identifier   // Parameters are bound to the quoted arguments of a call
+	Body       *BlockStatement // Body must return a quote
+}
+
+// statementNode satisfies the statement interface
+func (ms *MacroStatement) statementNode() {}
+
+// TokenLiteral returns the macro token
+func (ms *MacroStatement) TokenLiteral() string { return ms.Token.Literal }
+
+// String returns the macro definition as a string
+func (ms *MacroStatement) String() string {
+	params := []string{}
+	for _, p := range ms.Parameters {
+		params = append(params, p.String())
+	}
+	return "macro " + ms.Name.Value + "(" + strings.Join(params, ", ") + ") { " + ms.Body.String() + " }"
+}
+
+func (ms *MacroStatement) Display() string {
+	params := []string{}
+	for _, p := range ms.Parameters {
+		params = append(params, "'"+p.Value+"'")
+	}
+	return fmt.Sprintf("MacroStatement{Name: '%s', Parameters: [%s], %s}", ms.Name.Value, strings.Join(params, ", "), ms.Body.Display())
+}
+
+// BlockExpression is a block of statements used as an expression, it is created when a
+// macro expands to more than one statement and evaluates to the last statement's value
+type BlockExpression struct {
+	Token token.Token // Token == {
+	Block *BlockStatement
+}
+
+// expressionNode satisfies the expression interface
+func (be *BlockExpression) expressionNode() {}
+
+// TokenLiteral returns the { token
+func (be *BlockExpression) TokenLiteral() string { return be.Token.Literal }
+
+// String returns the block as a string
+func (be *BlockExpression) String() string {
+	return "{ " + be.Block.String() + " }"
+}
+
+func (be *BlockExpression) Display() string {
+	return "BlockExpression{" + be.Block.Display() + "}"
+}
+
+// SpawnExpression is the ast node for `spawn f(x)` which calls f on its own goroutine
+type SpawnExpression struct {
+	Token token.Token // Token == spawn
+	Value Expression  // Value is the call to run or a function that is called without arguments
+}
+
+// expressionNode satisfies the expression interface
+func (se *SpawnExpression) expressionNode() {}
+
+// TokenLiteral returns the spawn token
+func (se *SpawnExpression) TokenLiteral() string { return se.Token.Literal }
+
+// String returns the spawn expression as a string
+func (se *SpawnExpression) String() string { return "spawn " + se.Value.String() }
+
+func (se *SpawnExpression) Display() string {
+	return "SpawnExpression{Value: " + se.Value.Display() + "}"
+}
+
+// AwaitExpression is the ast node for `await task` which waits for the task's result
+type AwaitExpression struct {
+	Token token.Token // Token == await
+	Value Expression  // Value is the task or list of tasks to wait for
+}
+
+// expressionNode satisfies the expression interface
+func (ae *AwaitExpression) expressionNode() {}
+
+// TokenLiteral returns the await token
+func (ae *AwaitExpression) TokenLiteral() string { return ae.Token.Literal }
+
+// String returns the await expression as a string
+func (ae *AwaitExpression) String() string { return "await " + ae.Value.String() }
+
+func (ae *AwaitExpression) Display() string {
+	return "AwaitExpression{Value: " + ae.Value.Display() + "}"
+}
+
+// SelectExpression is the ast node for `select { msg = recv(ch) => { ... }, }` which waits
+// until one of its cases can run, each case is `recv(ch)`, `name = recv(ch)`, `send(ch, v)`,
+// `timeout(ms)`, or `_` which runs when no other case is ready
+type SelectExpression struct {
+	Token       token.Token       // Token == select
+	Cases       []Expression      // Cases are the channel operations to wait for
+	Consequence []*BlockStatement // Consequence is the block to run for the case in the same position
+}
+
+// expressionNode satisfies the expression interface
+func (se *SelectExpression) expressionNode() {}
+
+// TokenLiteral returns the select token
+func (se *SelectExpression) TokenLiteral() string { return se.Token.Literal }
+
+// String returns the select expression as a string
+func (se *SelectExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("select {\n")
+	for i, c := range se.Cases {
+		out.WriteString("\t")
+		out.WriteString(c.String())
+		out.WriteString(" => {")
+		out.WriteString(se.Consequence[i].String())
+		out.WriteString("},\n")
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+func (se *SelectExpression) Display() string {
+	var out bytes.Buffer
+	out.WriteString("SelectExpression{Cases: [")
+	for _, c := range se.Cases {
+		out.WriteString(c.Display())
+		out.WriteString(", ")
+	}
+	out.WriteString("], Consequences: [")
+	for _, c := range se.Consequence {
+		out.WriteString(c.Display())
+		out.WriteString(", ")
+	}
+	out.WriteString("]}")
+	return out.String()
+}
+
+// ReceiveExpression is the ast node for `receive { [:add, n] => { ... }, }` which waits
+// for the first message in the mailbox of the actor that matches one of its patterns,
+// a `timeout(ms)` arm runs when no message matches in time
+type ReceiveExpression struct {
+	Token       token.Token       // Token == receive
+	Patterns    []Expression      // Patterns are matched against each message in order
+	Consequence []*BlockStatement // Consequence is the block to run for the pattern in the same position
+}
+
+// expressionNode satisfies the expression interface
+func (re *ReceiveExpression) expressionNode() {}
+
+// TokenLiteral returns the receive token
+func (re *ReceiveExpression) TokenLiteral() string { return re.Token.Literal }
+
+// String returns the receive expression as a string
+func (re *ReceiveExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("receive {\n")
+	for i, p := range re.Patterns {
+		out.WriteString("\t")
+		out.WriteString(p.String())
+		out.WriteString(" => {")
+		out.WriteString(re.Consequence[i].String())
+		out.WriteString("},\n")
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+func (re *ReceiveExpression) Display() string {
+	var out bytes.Buffer
+	out.WriteString("ReceiveExpression{Patterns: [")
+	for _, p := range re.Patterns {
+		out.WriteString(p.Display())
+		out.WriteString(", ")
+	}
+	out.WriteString("], Consequences: [")
+	for _, c := range re.Consequence {
+		out.WriteString(c.Display())
+		out.WriteString(", ")
+	}
+	out.WriteString("]}")
+	return out.String()
+}
+
+// ScopeExpression is the ast node for `scope { ... }` which waits for every task spawned
+// in its body and cancels the rest when one of them fails
+type ScopeExpression struct {
+	Token token.Token     // Token == scope
+	Body  *BlockStatement // Body is the block the tasks are spawned in
+}
+
+// expressionNode satisfies the expression interface
+func (se *ScopeExpression) expressionNode() {}
+
+// TokenLiteral returns the scope token
+func (se *ScopeExpression) TokenLiteral() string { return se.Token.Literal }
+
+// String returns the scope expression as a string
+func (se *ScopeExpression) String() string {
+	return "scope { " + se.Body.String() + " }"
+}
+
+func (se *ScopeExpression) Display() string {
+	return "ScopeExpression{Body: " + se.Body.Display() + "}"
+}
+
+// WithExpression is the ast node for `with lock { ... }` which holds the lock while
+// its body runs
+type WithExpression struct {
+	Token token.Token     // Token == with
+	Lock  Expression      // Lock is the mutex, rwmutex, or read lock to hold
+	Body  *BlockStatement // Body is the block to run while the lock is held
+}
+
+// expressionNode satisfies the expression interface
+func (we *WithExpression) expressionNode() {}
+
+// TokenLiteral returns the with token
+func (we *WithExpression) TokenLiteral() string { return we.Token.Literal }
+
+// String returns the with expression as a string
+func (we *WithExpression) String() string {
+	return "with " + we.Lock.String() + " { " + we.Body.String() + " }"
+}
+
+func (we *WithExpression) Display() string {
+	return "WithExpression{Lock: " + we.Lock.Display() + ", Body: " + we.Body.Display() + "}"
+}
+
+// ImportStatement is the import of a module ie. `import path/filepath`
 type ImportStatement struct {
 	Token token.Token // Token == import
-	Path  *Identifier // Path is the import's path which refers to a file
+	Path  *Identifier // Path is the import path of the module
+}
+
+// Name returns the name the module is bound to, the last element of its path without
+// a version or a prefix before a dash ie. yaml for gopkg.in/yaml.v3 and sqlite3 for
+// github.com/mattn/go-sqlite3
+func (is *ImportStatement) Name() string {
+	path := is.Path.Value
+	name := path[strings.LastIndex(path, "/")+1:]
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[:i]
+	}
+	return name[strings.LastIndex(name, "-")+1:]
 }
 
 // statementNode satisfies the statement interface
@@ -1012,7 +1735,7 @@ func (is *ImportStatement) statementNode() {}
 // TokenLiteral returns the import token as a string
 func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
 
-// String returns the string representation of the map literal ast node
+// String returns the import ie. `import path/filepath`
 func (is *ImportStatement) String() string {
 	return fmt.Sprintf("%s %s", is.Token.Literal, is.Path)
 }
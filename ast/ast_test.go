@@ -50,3 +50,39 @@ func TestString2(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "1"}, Value: 1} }
+	two := func() Expression { return &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "2"}, Value: 2} }
+	turnOneIntoTwo := func(node Node) Node {
+		if il, ok := node.(*IntegerLiteral); ok && il.Value == 1 {
+			return two()
+		}
+		return node
+	}
+
+	tests := []struct {
+		input    Node
+		expected string
+	}{
+		{one(), "2"},
+		{&InfixExpression{Operator: "+", Left: one(), Right: two()}, "(2 + 2)"},
+		{&PrefixExpression{Operator: "-", Right: one()}, "(-2)"},
+		{&ListLiteral{Elements: []Expression{one(), two(), one()}}, "[2, 2, 2]"},
+		{&IndexExpression{Left: one(), Index: one()}, "(2[2])"},
+		{&Program{Statements: []Statement{&ExpressionStatement{Expression: one()}}}, "2"},
+		{&ReturnStatement{Token: token.Token{Type: token.RETURN, Literal: "return"}, ReturnValue: one()}, "return 2;"},
+		{&QuoteExpression{Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}}}, "quote { 1 }"},
+	}
+
+	for _, tt := range tests {
+		before := tt.input.String()
+		modified := Modify(tt.input, turnOneIntoTwo)
+		if modified.String() != tt.expected {
+			t.Errorf("wrong modified node. got=%q, want=%q", modified.String(), tt.expected)
+		}
+		if tt.input.String() != before {
+			t.Errorf("the original node was changed. got=%q, want=%q", tt.input.String(), before)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package ast
+
+// ModifierFunc is called with every node by Modify and returns the node to replace it with
+type ModifierFunc func(Node) Node
+
+// Modify walks the tree depth first and calls the modifier with a copy of each node whose
+// children were already modified, the tree that was passed in is never changed so a node
+// can be modified any number of times. Quoted code is data so the body of a quote is not walked
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	case *Program:
+		cp := *node
+		cp.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&cp)
+	case *BlockStatement:
+		cp := *node
+		cp.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&cp)
+	case *BlockExpression:
+		cp := *node
+		cp.Block = modifyBlock(node.Block, modifier)
+		return modifier(&cp)
+	case *ExpressionStatement:
+		cp := *node
+		cp.Expression = modifyExpression(node.Expression, modifier)
+		return modifier(&cp)
+	case *ReturnStatement:
+		cp := *node
+		cp.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+		return modifier(&cp)
+	case *ValStatement:
+		cp := *node
+		cp.Name = modifyIdentifier(node.Name, modifier)
+		cp.Value = modifyExpression(node.Value, modifier)
+		return modifier(&cp)
+	case *VarStatement:
+		cp := *node
+		cp.Name = modifyIdentifier(node.Name, modifier)
+		cp.Value = modifyExpression(node.Value, modifier)
+		return modifier(&cp)
+	case *FunctionStatement:
+		return modifier(modifyFunctionStatement(node, modifier))
+	case *FunctionLiteral:
+		cp := *node
+		cp.Parameters = modifyIdentifiers(node.Parameters, modifier)
+		cp.ParameterExpressions = modifyExpressions(node.ParameterExpressions, modifier)
+		cp.Body = modifyBlock(node.Body, modifier)
+		return modifier(&cp)
+	case *PrefixExpression:
+		cp := *node
+		cp.Right = modifyExpression(node.Right, modifier)
+		return modifier(&cp)
+	case *InfixExpression:
+		cp := *node
+		cp.Left = modifyExpression(node.Left, modifier)
+		cp.Right = modifyExpression(node.Right, modifier)
+		return modifier(&cp)
+	case *AssignmentExpression:
+		cp := *node
+		cp.Left = modifyExpression(node.Left, modifier)
+		cp.Value = modifyExpression(node.Value, modifier)
+		return modifier(&cp)
+	case *IfExpression:
+		cp := *node
+		cp.Condition = modifyExpression(node.Condition, modifier)
+		cp.Consequence = modifyBlock(node.Consequence, modifier)
+		cp.Alternative = modifyBlock(node.Alternative, modifier)
+		return modifier(&cp)
+	case *ForExpression:
+		cp := *node
+		cp.Condition = modifyExpression(node.Condition, modifier)
+		cp.Consequence = modifyBlock(node.Consequence, modifier)
+		return modifier(&cp)
+	case *MatchExpression:
+		cp := *node
+		cp.OptionalValue = modifyExpression(node.OptionalValue, modifier)
+		cp.Condition = modifyExpressions(node.Condition, modifier)
+		cp.Consequence = make([]*BlockStatement, len(node.Consequence))
+		for i, block := range node.Consequence {
+			cp.Consequence[i] = modifyBlock(block, modifier)
+		}
+		return modifier(&cp)
+	case *CallExpression:
+		cp := *node
+		cp.Function = modifyExpression(node.Function, modifier)
+		cp.Arguments = modifyExpressions(node.Arguments, modifier)
+		if node.DefaultArguments != nil {
+			cp.DefaultArguments = make(map[string]Expression, len(node.DefaultArguments))
+			for name, arg := range node.DefaultArguments {
+				cp.DefaultArguments[name] = modifyExpression(arg, modifier)
+			}
+		}
+		return modifier(&cp)
+	case *IndexExpression:
+		cp := *node
+		cp.Left = modifyExpression(node.Left, modifier)
+		cp.Index = modifyExpression(node.Index, modifier)
+		return modifier(&cp)
+	case *SliceExpression:
+		cp := *node
+		cp.Left = modifyExpression(node.Left, modifier)
+		cp.Start = modifyExpression(node.Start, modifier)
+		cp.End = modifyExpression(node.End, modifier)
+		cp.Step = modifyExpression(node.Step, modifier)
+		return modifier(&cp)
+	case *YieldExpression:
+		cp := *node
+		cp.Value = modifyExpression(node.Value, modifier)
+		return modifier(&cp)
+	case *SpawnExpression:
+		cp := *node
+		cp.Value = modifyExpression(node.Value, modifier)
+		return modifier(&cp)
+	case *AwaitExpression:
+		cp := *node
+		cp.Value = modifyExpression(node.Value, modifier)
+		return modifier(&cp)
+	case *SelectExpression:
+		cp := *node
+		cp.Cases = modifyExpressions(node.Cases, modifier)
+		cp.Consequence = make([]*BlockStatement, len(node.Consequence))
+		for i, block := range node.Consequence {
+			cp.Consequence[i] = modifyBlock(block, modifier)
+		}
+		return modifier(&cp)
+	case *WithExpression:
+		cp := *node
+		cp.Lock = modifyExpression(node.Lock, modifier)
+		cp.Body = modifyBlock(node.Body, modifier)
+		return modifier(&cp)
+	case *ScopeExpression:
+		cp := *node
+		cp.Body = modifyBlock(node.Body, modifier)
+		return modifier(&cp)
+	case *ReceiveExpression:
+		cp := *node
+		cp.Patterns = modifyExpressions(node.Patterns, modifier)
+		cp.Consequence = make([]*BlockStatement, len(node.Consequence))
+		for i, block := range node.Consequence {
+			cp.Consequence[i] = modifyBlock(block, modifier)
+		}
+		return modifier(&cp)
+	case *ListLiteral:
+		cp := *node
+		cp.Elements = modifyExpressions(node.Elements, modifier)
+		return modifier(&cp)
+	case *SetLiteral:
+		cp := *node
+		cp.Elements = modifyExpressions(node.Elements, modifier)
+		return modifier(&cp)
+	case *MapLiteral:
+		cp := *node
+		cp.Pairs = make(map[Expression]Expression, len(node.Pairs))
+		for k, v := range node.Pairs {
+			cp.Pairs[modifyExpression(k, modifier)] = modifyExpression(v, modifier)
+		}
+		return modifier(&cp)
+	case *StringLiteral:
+		cp := *node
+		cp.InterpolationValues = modifyExpressions(node.InterpolationValues, modifier)
+		return modifier(&cp)
+	case *ExecStringLiteral:
+		cp := *node
+		cp.InterpolationValues = modifyExpressions(node.InterpolationValues, modifier)
+		return modifier(&cp)
+	case *StructStatement:
+		cp := *node
+		cp.Defaults = modifyExpressions(node.Defaults, modifier)
+		cp.Methods = modifyMethods(node.Methods, modifier)
+		return modifier(&cp)
+	case *TraitStatement:
+		cp := *node
+		cp.Methods = modifyMethods(node.Methods, modifier)
+		return modifier(&cp)
+	case *ImplStatement:
+		cp := *node
+		cp.Methods = modifyMethods(node.Methods, modifier)
+		return modifier(&cp)
+	}
+	return modifier(node)
+}
+
+// modifyFunctionStatement returns a copy of the function statement with its children modified
+func modifyFunctionStatement(node *FunctionStatement, modifier ModifierFunc) *FunctionStatement {
+	cp := *node
+	cp.Name = modifyIdentifier(node.Name, modifier)
+	cp.Parameters = modifyIdentifiers(node.Parameters, modifier)
+	cp.ParameterExpressions = modifyExpressions(node.ParameterExpressions, modifier)
+	cp.Body = modifyBlock(node.Body, modifier)
+	cp.Decorators = modifyExpressions(node.Decorators, modifier)
+	return &cp
+}
+
+// modifyMethods modifies the children of each method, methods are not passed to the modifier
+// themselves as they must stay function statements
+func modifyMethods(methods []*FunctionStatement, modifier ModifierFunc) []*FunctionStatement {
+	if methods == nil {
+		return nil
+	}
+	out := make([]*FunctionStatement, len(methods))
+	for i, m := range methods {
+		out[i] = modifyFunctionStatement(m, modifier)
+	}
+	return out
+}
+
+// modifyStatements modifies each statement, a statement the modifier replaced with nil is removed
+func modifyStatements(stmts []Statement, modifier ModifierFunc) []Statement {
+	out := make([]Statement, 0, len(stmts))
+	for _, stmt := range stmts {
+		if modified, ok := Modify(stmt, modifier).(Statement); ok && modified != nil {
+			out = append(out, modified)
+		}
+	}
+	return out
+}
+
+// modifyExpression modifies the expression, nil expressions are left as nil
+func modifyExpression(exp Expression, modifier ModifierFunc) Expression {
+	if exp == nil {
+		return nil
+	}
+	modified, _ := Modify(exp, modifier).(Expression)
+	return modified
+}
+
+// modifyExpressions returns the modified expressions
+func modifyExpressions(exps []Expression, modifier ModifierFunc) []Expression {
+	if exps == nil {
+		return nil
+	}
+	out := make([]Expression, len(exps))
+	for i, exp := range exps {
+		out[i] = modifyExpression(exp, modifier)
+	}
+	return out
+}
+
+// modifyBlock modifies the block, a nil block is left as nil
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	modified, _ := Modify(block, modifier).(*BlockStatement)
+	return modified
+}
+
+// modifyIdentifier modifies the identifier, a nil identifier is left as nil
+func modifyIdentifier(ident *Identifier, modifier ModifierFunc) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	modified, _ := Modify(ident, modifier).(*Identifier)
+	return modified
+}
+
+// modifyIdentifiers returns the modified identifiers
+func modifyIdentifiers(idents []*Identifier, modifier ModifierFunc) []*Identifier {
+	if idents == nil {
+		return nil
+	}
+	out := make([]*Identifier, len(idents))
+	for i, ident := range idents {
+		out[i] = modifyIdentifier(ident, modifier)
+	}
+	return out
+}
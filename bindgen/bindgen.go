@@ -0,0 +1,221 @@
+// bindgen generates the Go source that registers a Go package as a blue module, the
+// generated file registers the exported funcs, constants, and struct types of the package
+// with interp.RegisterModule so that blue programs can `import` it
+//
+//	src, err := bindgen.Generate("path/filepath", "stdlib", "go1.17")
+package bindgen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/build"
+	"go/constant"
+	"go/format"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Generate loads the package from its source and returns the bindings for it as a
+// file of the package pkgName. The standard library symbols added after goVersion
+// ie. "go1.17" are left out so that the bindings build with that version, every
+// symbol is kept when goVersion is empty
+func Generate(importPath, pkgName, goVersion string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkg, err := importer.ForCompiler(fset, "source", nil).Import(importPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load %s: %w", importPath, err)
+	}
+	newer, err := newerSymbols(importPath, goVersion)
+	if err != nil {
+		return nil, err
+	}
+	return generate(pkg, pkgName, newer)
+}
+
+// newerSymbols returns the names of the package that the API files of the Go
+// installation list as added after goVersion
+func newerSymbols(importPath, goVersion string) (map[string]bool, error) {
+	newer := map[string]bool{}
+	if goVersion == "" {
+		return newer, nil
+	}
+	// the patch version of ie. go1.21.0 does not add symbols
+	minor, err := strconv.Atoi(strings.SplitN(strings.TrimPrefix(goVersion, "go1."), ".", 2)[0])
+	if !strings.HasPrefix(goVersion, "go1.") || err != nil {
+		return nil, fmt.Errorf("invalid Go version %q, want ie. go1.17", goVersion)
+	}
+	prefix := "pkg " + importPath + ", "
+	for v := minor + 1; ; v++ {
+		f, err := os.Open(filepath.Join(build.Default.GOROOT, "api", "go1."+strconv.Itoa(v)+".txt"))
+		if os.IsNotExist(err) {
+			return newer, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			// ie. `pkg strings, func Cut(string, string) (string, string, bool)`, the
+			// lines of a single platform and of deprecations are not additions
+			line := scanner.Text()
+			if !strings.HasPrefix(line, prefix) || strings.HasSuffix(line, "//deprecated") {
+				continue
+			}
+			if name, ok := addedName(strings.TrimPrefix(line, prefix)); ok {
+				newer[name] = true
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// addedName returns the name of a top level func, const, var, or type declared by the
+// line of an API file, ok is false for methods and for fields added to existing types
+func addedName(decl string) (string, bool) {
+	fields := strings.Fields(decl)
+	if len(fields) < 2 {
+		return "", false
+	}
+	switch fields[0] {
+	case "func":
+		return strings.SplitN(fields[1], "(", 2)[0], true
+	case "const", "var":
+		return fields[1], true
+	case "type":
+		if len(fields) > 2 && (fields[2] == "struct," || fields[2] == "interface,") {
+			return "", false
+		}
+		return fields[1], true
+	}
+	return "", false
+}
+
+// usesIter returns true if the signature takes or returns an iterator of the iter package,
+// blue cannot range over them
+func usesIter(sig *types.Signature) bool {
+	for _, tuple := range []*types.Tuple{sig.Params(), sig.Results()} {
+		for i := 0; i < tuple.Len(); i++ {
+			if named, ok := tuple.At(i).Type().(*types.Named); ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "iter" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isGeneric returns true for a func or type with type parameters, they are found in
+// its type string ie. `func[T any](t T) T` or `example.com/pkg.Pair[T any]` as the
+// TypeParams methods of go/types are not available in go1.17
+func isGeneric(obj types.Object) bool {
+	typ := obj.Type().String()
+	if _, ok := obj.(*types.Func); ok {
+		return strings.HasPrefix(typ, "func[")
+	}
+	return strings.HasSuffix(typ, "]")
+}
+
+// generate returns the bindings for the loaded package without the names in skip
+func generate(pkg *types.Package, pkgName string, skip map[string]bool) ([]byte, error) {
+	// the interp package is renamed when it would collide with the bound package
+	interpName := "interp"
+	if pkg.Name() == interpName {
+		interpName = "blueinterp"
+	}
+	var funcs, values, typs []string
+	names := pkg.Scope().Names()
+	sort.Strings(names)
+	for _, name := range names {
+		obj := pkg.Scope().Lookup(name)
+		if !obj.Exported() || skip[name] {
+			continue
+		}
+		qualified := pkg.Name() + "." + name
+		switch obj := obj.(type) {
+		case *types.Func:
+			sig := obj.Type().(*types.Signature)
+			if isGeneric(obj) || usesIter(sig) {
+				continue
+			}
+			funcs = append(funcs, fmt.Sprintf("%q: %s,", name, qualified))
+		case *types.Const:
+			if expr, ok := constExpr(obj, qualified); ok {
+				values = append(values, fmt.Sprintf("%q: %s,", name, expr))
+			}
+		case *types.TypeName:
+			named, ok := obj.Type().(*types.Named)
+			if !ok || obj.IsAlias() || isGeneric(obj) {
+				continue
+			}
+			if _, ok := named.Underlying().(*types.Struct); ok {
+				typs = append(typs, fmt.Sprintf("%q: (*%s)(nil),", name, qualified))
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by blue bindgen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkgName)
+	fmt.Fprintf(&buf, "import (\n")
+	if interpName == "interp" {
+		fmt.Fprintf(&buf, "%q\n", "blue/interp")
+	} else {
+		fmt.Fprintf(&buf, "%s %q\n", interpName, "blue/interp")
+	}
+	fmt.Fprintf(&buf, "%q\n)\n\n", pkg.Path())
+	fmt.Fprintf(&buf, "func init() {\n")
+	fmt.Fprintf(&buf, "%s.RegisterModule(%q, %s.Module{\n", interpName, pkg.Path(), interpName)
+	writeMap(&buf, "Funcs", funcs)
+	writeMap(&buf, "Values", values)
+	writeMap(&buf, "Types", typs)
+	fmt.Fprintf(&buf, "})\n}\n")
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("cannot format the bindings for %s: %w", pkg.Path(), err)
+	}
+	return src, nil
+}
+
+// writeMap writes the field of interp.Module, it is left out when there are no entries
+func writeMap(buf *bytes.Buffer, field string, entries []string) {
+	if len(entries) == 0 {
+		return
+	}
+	fmt.Fprintf(buf, "%s: map[string]interface{}{\n%s\n},\n", field, strings.Join(entries, "\n"))
+}
+
+// constExpr returns the expression for the constant in the bindings, untyped constants
+// are converted to the type they are stored as in an interface{} and ok is false for
+// integers that do not fit in 64 bits
+func constExpr(c *types.Const, qualified string) (string, bool) {
+	basic, ok := c.Type().(*types.Basic)
+	if !ok || basic.Info()&types.IsUntyped == 0 {
+		return qualified, true
+	}
+	switch basic.Kind() {
+	case types.UntypedInt:
+		if _, exact := constant.Int64Val(c.Val()); exact {
+			return "int64(" + qualified + ")", true
+		}
+		if _, exact := constant.Uint64Val(c.Val()); exact {
+			return "uint64(" + qualified + ")", true
+		}
+		return "", false
+	case types.UntypedRune:
+		return "rune(" + qualified + ")", true
+	case types.UntypedFloat:
+		return "float64(" + qualified + ")", true
+	case types.UntypedComplex:
+		return "complex128(" + qualified + ")", true
+	}
+	return qualified, true
+}
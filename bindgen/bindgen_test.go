@@ -0,0 +1,186 @@
+package bindgen
+
+import (
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+const src = `package interp
+
+import "errors"
+
+const (
+	Small      = 1
+	Huge       = 1 << 64
+	Large      = 1 << 63
+	Ratio      = 0.5
+	Letter     = 'a'
+	Name       = "x"
+	Typed  int = 2
+	hidden     = 3
+)
+
+var Default = errors.New("x")
+
+type Point struct{ X, Y float64 }
+type Grid struct{ Cells [2][2]int }
+type Alias = Point
+type Kind int
+
+func New(x, y float64) *Point { return &Point{x, y} }
+func helper()                {}
+func Recent()                {}
+`
+
+// genericSrc is the package with generics and iterators, it needs go1.23 to be checked
+const genericSrc = `package interp
+
+import "iter"
+
+type Pair[T any] struct{ A, B T }
+type Triple[A, B, C any] struct{ A A }
+type Grid struct{ Cells [2][2]int }
+
+func Map[T any](t T) T       { return t }
+func Values() iter.Seq[int]  { return nil }
+func Each(seq iter.Seq[int]) {}
+func Keep()                  {}
+`
+
+// hasRelease returns true if the Go toolchain running the tests is at least the version
+func hasRelease(version string) bool {
+	for _, tag := range build.Default.ReleaseTags {
+		if tag == version {
+			return true
+		}
+	}
+	return false
+}
+
+// check parses and type checks the source of the package
+func check(t *testing.T, src string) *types.Package {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "interp.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.Default()}
+	pkg, err := conf.Check("example.com/interp", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+func TestGenerate(t *testing.T) {
+	out, err := generate(check(t, src), "bindings", map[string]bool{"Recent": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(out)
+	expected := []string{
+		"// Code generated by blue bindgen; DO NOT EDIT.\n\npackage bindings\n",
+		`blueinterp "blue/interp"`,
+		`"example.com/interp"`,
+		`blueinterp.RegisterModule("example.com/interp", blueinterp.Module{`,
+		`"New": interp.New,`,
+		`"Small":  int64(interp.Small),`,
+		`"Large":  uint64(interp.Large),`,
+		`"Ratio":  float64(interp.Ratio),`,
+		`"Letter": rune(interp.Letter),`,
+		`"Name":   interp.Name,`,
+		`"Typed":  interp.Typed,`,
+		`"Point": (*interp.Point)(nil),`,
+		`"Grid":  (*interp.Grid)(nil),`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(got, e) {
+			t.Errorf("the bindings do not contain %q. got=\n%s", e, got)
+		}
+	}
+	for _, name := range []string{"Huge", "hidden", "Default", "Pair", "Alias", "Kind", "helper", "Recent"} {
+		if strings.Contains(got, `"`+name+`"`) {
+			t.Errorf("the bindings should not contain %s. got=\n%s", name, got)
+		}
+	}
+}
+
+func TestGenerateGenerics(t *testing.T) {
+	if !hasRelease("go1.23") {
+		t.Skip("generics and iterators need go1.23")
+	}
+	out, err := generate(check(t, genericSrc), "bindings", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(out)
+	for _, e := range []string{`"Keep": interp.Keep,`, `"Grid": (*interp.Grid)(nil),`} {
+		if !strings.Contains(got, e) {
+			t.Errorf("the bindings do not contain %q. got=\n%s", e, got)
+		}
+	}
+	for _, name := range []string{"Pair", "Triple", "Map", "Values", "Each"} {
+		if strings.Contains(got, `"`+name+`"`) {
+			t.Errorf("the bindings should not contain %s. got=\n%s", name, got)
+		}
+	}
+}
+
+func TestGenerateImport(t *testing.T) {
+	out, err := Generate("path/filepath", "stdlib", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []string{`interp.RegisterModule("path/filepath", interp.Module{`, `"Join":`, `"Separator":`} {
+		if !strings.Contains(string(out), e) {
+			t.Errorf("the bindings do not contain %q. got=\n%s", e, out)
+		}
+	}
+	if _, err := Generate("no/such/package", "stdlib", ""); err == nil || !strings.HasPrefix(err.Error(), "cannot load no/such/package") {
+		t.Errorf("wrong error for a missing package. got=%v", err)
+	}
+}
+
+func TestGenerateGoVersion(t *testing.T) {
+	// the symbols that are included only exist if the toolchain running the tests has
+	// the release that added them
+	tests := []struct {
+		goVersion string
+		release   string
+		included  []string
+		excluded  []string
+	}{
+		{"go1.17", "go1.17", []string{`"Split":`, `"Builder":`}, []string{`"Cut":`, `"Clone":`, `"CutPrefix":`, `"Lines":`}},
+		{"go1.19", "go1.18", []string{`"Cut":`, `"Clone":`}, []string{`"CutPrefix":`, `"CutLast":`}},
+		{"go1.20.3", "go1.20", []string{`"CutPrefix":`}, []string{`"ContainsFunc":`}},
+		{"", "go1.21", []string{`"CutPrefix":`, `"ContainsFunc":`}, []string{`"Lines":`, `"SplitSeq":`}},
+	}
+	for _, tt := range tests {
+		if !hasRelease(tt.release) {
+			continue
+		}
+		out, err := Generate("strings", "stdlib", tt.goVersion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range tt.included {
+			if !strings.Contains(string(out), e) {
+				t.Errorf("%s: the bindings do not contain %s", tt.goVersion, e)
+			}
+		}
+		for _, e := range tt.excluded {
+			if strings.Contains(string(out), e) {
+				t.Errorf("%s: the bindings should not contain %s", tt.goVersion, e)
+			}
+		}
+	}
+	if _, err := Generate("strings", "stdlib", "1.17"); err == nil || err.Error() != `invalid Go version "1.17", want ie. go1.17` {
+		t.Errorf("wrong error for an invalid version. got=%v", err)
+	}
+}
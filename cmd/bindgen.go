@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"blue/bindgen"
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// bindgenPackages writes the bindings for each Go package to <name>_bindings.go so that
+// blue programs can import it once the generated files are compiled in
+//
+//	blue bindgen -o stdlib -pkg stdlib strings path/filepath math
+func bindgenPackages(args []string) {
+	fs := flag.NewFlagSet("bindgen", flag.ExitOnError)
+	oFlag := fs.String("o", ".", "The directory the bindings are written to")
+	pkgFlag := fs.String("pkg", "", "The package of the bindings, it defaults to the name of the directory")
+	goFlag := fs.String("go", "", "The Go version the bindings must build with ie. go1.17, it defaults to the go directive of the go.mod of the directory")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: blue bindgen [-o dir] [-pkg name] [-go version] packages...")
+		os.Exit(2)
+	}
+	goVersion := *goFlag
+	if goVersion == "" {
+		goVersion = moduleGoVersion(*oFlag)
+	}
+	pkgName := *pkgFlag
+	if pkgName == "" {
+		dir, err := filepath.Abs(*oFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "bindgen: "+err.Error())
+			os.Exit(1)
+		}
+		pkgName = filepath.Base(dir)
+	}
+	for _, importPath := range fs.Args() {
+		src, err := bindgen.Generate(importPath, pkgName, goVersion)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "bindgen: "+err.Error())
+			os.Exit(1)
+		}
+		filename := filepath.Join(*oFlag, importPath[strings.LastIndex(importPath, "/")+1:]+"_bindings.go")
+		if err := os.WriteFile(filename, src, 0644); err != nil {
+			fmt.Fprintln(os.Stderr, "bindgen: "+err.Error())
+			os.Exit(1)
+		}
+	}
+}
+
+// moduleGoVersion returns the go directive of the go.mod in dir or its closest parent
+// as ie. go1.17, it is empty when there is none
+func moduleGoVersion(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		if f, err := os.Open(filepath.Join(dir, "go.mod")); err == nil {
+			defer f.Close()
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				if fields := strings.Fields(scanner.Text()); len(fields) == 2 && fields[0] == "go" {
+					return "go" + fields[1]
+				}
+			}
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
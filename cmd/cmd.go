@@ -1,12 +1,18 @@
 package cmd
 
 import (
+	"blue/evaluator"
 	"blue/lexer"
+	"blue/object"
 	"blue/parser"
+	_ "blue/stdlib" // the bound Go packages can be imported by the programs that are run
 	"blue/token"
+	"blue/types"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 )
 
 const VERSION = "v0.0.1"
@@ -37,6 +43,17 @@ func Run(args []string) {
 	if aFlag != nil && *aFlag != "" {
 		parseFile(*aFlag)
 	}
+	if flag.NArg() > 0 && flag.Arg(0) == "bindgen" {
+		bindgenPackages(flag.Args()[1:])
+		return
+	}
+	if flag.NArg() > 0 && flag.Arg(0) == "check" {
+		checkFiles(flag.Args()[1:])
+		return
+	}
+	if flag.NArg() > 0 {
+		evalFile(flag.Arg(0))
+	}
 }
 
 func lexFile(filename string) {
@@ -65,3 +82,74 @@ func parseFile(filename string) {
 	ast := p.ParseProgram()
 	fmt.Println(ast.Display())
 }
+
+func evalFile(filename string) {
+	input := readAll(filename)
+	l := lexer.New(input, filename)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintln(os.Stderr, "ParserError: "+msg)
+		}
+		os.Exit(1)
+	}
+	e := evaluator.New()
+	result := e.Eval(program)
+	if errObj, ok := result.(*object.Error); ok {
+		msg := errObj.Inspect()
+		if errObj.Span != nil {
+			if printable := l.GetSpanPrintable(*errObj.Span, msg); printable != "" {
+				msg = strings.TrimSuffix(printable, "\n")
+			}
+		}
+		fmt.Fprintln(os.Stderr, msg)
+		os.Exit(1)
+	}
+}
+
+// checkFiles type checks each file without running it and exits with 1 if any has errors
+func checkFiles(filenames []string) {
+	if len(filenames) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: blue check files...")
+		os.Exit(2)
+	}
+	if !check(filenames, os.Stderr) {
+		os.Exit(1)
+	}
+}
+
+// check writes the errors of each file to w, a file that cannot be read is an error,
+// and returns false if any file has errors
+func check(filenames []string, w io.Writer) bool {
+	ok := true
+	for _, filename := range filenames {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintln(w, "blue check: "+err.Error())
+			ok = false
+			continue
+		}
+		l := lexer.New(string(data), filename)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			for _, msg := range p.Errors() {
+				fmt.Fprintln(w, "ParserError: "+msg)
+			}
+			ok = false
+			continue
+		}
+		for _, err := range types.Check(program) {
+			msg := "TypeError: " + err.Message
+			if printable := l.GetSpanPrintable(err.Span, msg); err.Span != (token.Span{}) && printable != "" {
+				msg = strings.TrimSuffix(printable, "\n")
+			} else {
+				msg = filename + ": " + msg
+			}
+			fmt.Fprintln(w, msg)
+			ok = false
+		}
+	}
+	return ok
+}
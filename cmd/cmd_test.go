@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	good := write("good.b", "val x: int = 1\nx + 2\n")
+	bad := write("bad.b", "val x: int = \"a\"\n")
+	missing := filepath.Join(dir, "missing.b")
+
+	tests := []struct {
+		filenames []string
+		ok        bool
+		output    string
+	}{
+		{[]string{good}, true, ""},
+		{[]string{bad}, false, "TypeError"},
+		{[]string{missing}, false, "blue check: open " + missing + ": no such file or directory"},
+		{[]string{missing, good}, false, "no such file or directory"},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		if ok := check(tt.filenames, &out); ok != tt.ok {
+			t.Errorf("%v: wrong result. want=%t, got=%t, output=%s", tt.filenames, tt.ok, ok, out.String())
+		}
+		if !strings.Contains(out.String(), tt.output) || (tt.output == "" && out.Len() != 0) {
+			t.Errorf("%v: wrong output. want=%q, got=%q", tt.filenames, tt.output, out.String())
+		}
+	}
+}
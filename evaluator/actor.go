@@ -0,0 +1,545 @@
+package evaluator
+
+import (
+	"blue/ast"
+	"blue/object"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Actors are lightweight processes with a mailbox. `spawn_actor(f, args...)` calls f on
+// its own goroutine and returns its pid, `send(pid, msg)` puts a message in the mailbox
+// and `receive` takes the first message that matches one of its patterns, the messages
+// that do not match stay in the mailbox for a later receive:
+//
+//	fun counter(n) {
+//	    receive {
+//	        [:add, x] => { counter(n + x) },
+//	        [:get, from] => { send(from, n); counter(n) },
+//	        timeout(5000) => { n },
+//	    }
+//	}
+//
+// A pattern is `_`, a name that binds the message, a list of patterns, an enum variant
+// pattern ie. `Msg.Add(x)`, or any other expression that is compared with ==.
+// `link(pid)` kills each actor when the other crashes, `trap_exits()` turns that into a
+// `[:exit, pid, reason]` message, and `monitor(pid)` sends `[:down, pid, reason]` once
+// pid exits. A killed actor is cancelled like the tasks of a failed scope.
+//
+// `supervisor([f, g], {"strategy": :one_for_all, "max_restarts": 3, "within": 5000})` starts
+// each function as an actor and restarts them when one crashes, :one_for_one restarts
+// only the crashed actor and :one_for_all restarts all of them. When there are more than
+// max_restarts restarts within the last `within` milliseconds the supervisor gives up,
+// stops its children and exits itself.
+
+// supervisorLog is where supervisors log the crashes of their children
+var supervisorLog io.Writer = os.Stderr
+
+// startActor calls fn with the args on a new goroutine as the actor pid
+func (e *Evaluator) startActor(pid *object.Pid, fn object.Object, args []object.Object) {
+	ae := e.withEnv(e.env)
+	// an actor never yields to the generator it was started in
+	ae.gen = nil
+	ae.actor = pid
+	// an actor outlives the scope it was started in and is only cancelled by killing it
+	ctx, cancel := context.WithCancel(context.Background())
+	ae.ctx, ae.group = ctx, nil
+	go func() {
+		select {
+		case <-pid.Killed():
+		case <-pid.Done():
+		}
+		cancel()
+	}()
+	go func() {
+		var result object.Object
+		defer func() {
+			if r := recover(); r != nil {
+				result = newError("actor panicked: %v", r)
+			}
+			pid.Exit(exitReason(pid, result))
+		}()
+		result = ae.applyFunction(fn, args, nil)
+	}()
+}
+
+// exitReason returns why the actor stopped with the result, it is the reason it was
+// killed with, the message of the error it returned, or :normal
+func exitReason(pid *object.Pid, result object.Object) object.Object {
+	if reason := pid.KillReason(); reason != nil {
+		return reason
+	}
+	if errObj, ok := result.(*object.Error); ok {
+		return &object.String{Value: errObj.Message}
+	}
+	return object.Intern("normal")
+}
+
+// evalReceiveExpression waits for the first message in the mailbox that matches one of
+// the patterns and evaluates its block with the names the pattern bound
+func (e *Evaluator) evalReceiveExpression(node *ast.ReceiveExpression) object.Object {
+	var timeout <-chan time.Time
+	timeoutArm := -1
+	for i, pattern := range node.Patterns {
+		call, ok := pattern.(*ast.CallExpression)
+		if !ok {
+			continue
+		}
+		if ident, ok := call.Function.(*ast.Identifier); !ok || ident.Value != "timeout" {
+			continue
+		}
+		if timeoutArm != -1 {
+			return newError("receive can only have one timeout")
+		}
+		args, _, errVal := e.evalArguments(call)
+		if errVal != nil {
+			return errVal
+		}
+		d, errObj := durationArg("timeout", args)
+		if errObj != nil {
+			return errObj
+		}
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout, timeoutArm = timer.C, i
+	}
+
+	pid := e.actor
+	checked := 0
+	for {
+		select {
+		case <-pid.Killed():
+			return newError("%s was killed: %s", pid.Inspect(), pid.KillReason().Inspect())
+		default:
+		}
+		if errObj := e.checkCancelled(); errObj != nil {
+			return errObj
+		}
+		for _, msg := range pid.Messages(checked) {
+			for i, pattern := range node.Patterns {
+				if i == timeoutArm {
+					continue
+				}
+				env := object.NewEnclosedEnvironment(e.env)
+				matched, errObj := e.matchPattern(pattern, msg, env)
+				if errObj != nil {
+					return errObj
+				}
+				if matched {
+					pid.Take(checked)
+					e.claimOwner(msg)
+					return e.withEnv(env).Eval(node.Consequence[i])
+				}
+			}
+			checked++
+		}
+		select {
+		case <-pid.Signal():
+		case <-pid.Killed():
+		case <-e.ctx.Done():
+		case <-timeout:
+			return e.Eval(node.Consequence[timeoutArm])
+		}
+	}
+}
+
+// matchPattern returns true if the value matches the pattern of a receive and sets the
+// names the pattern binds in env
+func (e *Evaluator) matchPattern(pattern ast.Expression, val object.Object, env *object.Environment) (bool, *object.Error) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			env.Set(pattern.Value, val)
+		}
+		return true, nil
+	case *ast.ListLiteral:
+		list, ok := val.(*object.List)
+		if !ok {
+			return false, nil
+		}
+		elems := list.Elements()
+		if len(elems) != len(pattern.Elements) {
+			return false, nil
+		}
+		for i, elem := range pattern.Elements {
+			if matched, errObj := e.matchPattern(elem, elems[i], env); !matched || errObj != nil {
+				return false, errObj
+			}
+		}
+		return true, nil
+	case *ast.CallExpression:
+		fn := e.Eval(pattern.Function)
+		if isError(fn) {
+			return false, fn.(*object.Error)
+		}
+		variant, ok := fn.(*object.EnumVariant)
+		if !ok {
+			break
+		}
+		if len(pattern.Arguments) != len(variant.Fields) {
+			return false, newError("pattern %s.%s must have %d fields, got=%d", variant.Enum.Name, variant.Name, len(variant.Fields), len(pattern.Arguments))
+		}
+		ev, ok := val.(*object.EnumValue)
+		if !ok || ev.Variant != variant {
+			return false, nil
+		}
+		for i, arg := range pattern.Arguments {
+			if matched, errObj := e.matchPattern(arg, ev.Payload[i], env); !matched || errObj != nil {
+				return false, errObj
+			}
+		}
+		return true, nil
+	}
+	want := e.Eval(pattern)
+	if isError(want) {
+		return false, want.(*object.Error)
+	}
+	return objectsEqual(want, val), nil
+}
+
+// pidArg returns the first argument as a pid after checking the number of arguments
+func pidArg(name string, args []object.Object, want int) (*object.Pid, *object.Error) {
+	if len(args) != want {
+		return nil, newError("wrong number of arguments to `%s`. got=%d, want=%d", name, len(args), want)
+	}
+	pid, ok := args[0].(*object.Pid)
+	if !ok {
+		return nil, newError("first argument to `%s` must be PID, got %s", name, args[0].Type())
+	}
+	return pid, nil
+}
+
+// builtinSpawnActor starts the function as a new actor and returns its pid
+func (e *Evaluator) builtinSpawnActor(args ...object.Object) object.Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments to `spawn_actor`. got=0, want at least 1")
+	}
+	if !IsCallable(args[0]) {
+		return newError("first argument to `spawn_actor` must be a function, got %s", args[0].Type())
+	}
+	pid := object.NewPid()
+	e.startActor(pid, args[0], args[1:])
+	return pid
+}
+
+// builtinSelfPid returns the pid of the running actor
+func (e *Evaluator) builtinSelfPid(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments to `self_pid`. got=%d, want=0", len(args))
+	}
+	return e.actor
+}
+
+// builtinLink links the running actor to the pid
+func (e *Evaluator) builtinLink(args ...object.Object) object.Object {
+	pid, errObj := pidArg("link", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	e.actor.Link(pid)
+	return NULL
+}
+
+// builtinMonitor sends `[:down, pid, reason]` to the running actor once pid exits
+func (e *Evaluator) builtinMonitor(args ...object.Object) object.Object {
+	pid, errObj := pidArg("monitor", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	pid.Monitor(e.actor)
+	return NULL
+}
+
+// builtinTrapExits makes the running actor receive the crashes of its links as messages
+func (e *Evaluator) builtinTrapExits(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments to `trap_exits`. got=%d, want=0", len(args))
+	}
+	e.actor.TrapExits()
+	return NULL
+}
+
+// builtinKill asks the actor to stop with the reason :killed
+func builtinKill(args ...object.Object) object.Object {
+	pid, errObj := pidArg("kill", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	pid.Kill(object.Intern("killed"))
+	return NULL
+}
+
+// builtinAlive returns true until the actor has exited
+func builtinAlive(args ...object.Object) object.Object {
+	pid, errObj := pidArg("alive", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	return nativeToBooleanObject(pid.Alive())
+}
+
+// registry maps the names given with `register` to their actors
+var registry = struct {
+	sync.Mutex
+	names map[string]*object.Pid
+}{names: map[string]*object.Pid{}}
+
+// whereis returns the living actor registered with the name
+func whereis(name string) (*object.Pid, bool) {
+	registry.Lock()
+	defer registry.Unlock()
+	pid, ok := registry.names[name]
+	if !ok || !pid.Alive() {
+		return nil, false
+	}
+	return pid, true
+}
+
+// builtinRegister names the actor so messages can be sent to it with `send(:name, msg)`
+// which keeps working after a supervisor restarts it under the same name
+func builtinRegister(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments to `register`. got=%d, want=2", len(args))
+	}
+	name, ok := args[0].(*object.Symbol)
+	if !ok {
+		return newError("first argument to `register` must be SYMBOL, got %s", args[0].Type())
+	}
+	pid, ok := args[1].(*object.Pid)
+	if !ok {
+		return newError("second argument to `register` must be PID, got %s", args[1].Type())
+	}
+	if other, ok := whereis(name.Name); ok && other != pid {
+		return newError("%s is already registered as %s", name.Inspect(), other.Inspect())
+	}
+	registry.Lock()
+	registry.names[name.Name] = pid
+	registry.Unlock()
+	return NULL
+}
+
+// builtinWhereis returns the actor registered with the name or null
+func builtinWhereis(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments to `whereis`. got=%d, want=1", len(args))
+	}
+	name, ok := args[0].(*object.Symbol)
+	if !ok {
+		return newError("argument to `whereis` must be SYMBOL, got %s", args[0].Type())
+	}
+	if pid, ok := whereis(name.Name); ok {
+		return pid
+	}
+	return NULL
+}
+
+// sendToActor puts the message in the mailbox of the pid or of the actor registered
+// with the symbol, it returns false if dest is neither
+func sendToActor(dest, msg object.Object) (object.Object, bool) {
+	switch dest := dest.(type) {
+	case *object.Pid:
+		dest.Send(msg)
+		return NULL, true
+	case *object.Symbol:
+		pid, ok := whereis(dest.Name)
+		if !ok {
+			return newError("no actor is registered as %s", dest.Inspect()), true
+		}
+		pid.Send(msg)
+		return NULL, true
+	}
+	return nil, false
+}
+
+// supervisor is the state of a running supervisor
+type supervisor struct {
+	pid         *object.Pid
+	specs       []object.Object // specs are the functions each child runs
+	oneForAll   bool
+	maxRestarts int
+	within      time.Duration
+
+	mu       sync.Mutex
+	children []*object.Pid
+}
+
+// childExit is sent to the supervisor when one of its children exits
+type childExit struct {
+	index  int
+	pid    *object.Pid
+	reason object.Object
+}
+
+// supervisors are the running supervisors by their pid
+var supervisors sync.Map
+
+// builtinSupervisor starts each function as an actor and returns the pid of the
+// supervisor that restarts them when they crash
+func (e *Evaluator) builtinSupervisor(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments to `supervisor`. got=%d, want 1 or 2", len(args))
+	}
+	specs, ok := args[0].(*object.List)
+	if !ok {
+		return newError("first argument to `supervisor` must be LIST, got %s", args[0].Type())
+	}
+	children := specs.Elements()
+	for _, spec := range children {
+		if !IsCallable(spec) {
+			return newError("children of `supervisor` must be functions, got %s", spec.Type())
+		}
+	}
+	s := &supervisor{
+		pid:         object.NewPid(),
+		specs:       children,
+		maxRestarts: 3,
+		within:      5 * time.Second,
+		children:    make([]*object.Pid, len(children)),
+	}
+	if len(args) == 2 {
+		if errObj := s.setOptions(args[1]); errObj != nil {
+			return errObj
+		}
+	}
+	supervisors.Store(s.pid, s)
+	go e.supervise(s)
+	return s.pid
+}
+
+// setOptions sets the strategy and restart intensity from the options map
+func (s *supervisor) setOptions(opts object.Object) *object.Error {
+	m, ok := opts.(*object.Map)
+	if !ok {
+		return newError("second argument to `supervisor` must be MAP, got %s", opts.Type())
+	}
+	for _, pair := range m.Pairs() {
+		switch pair.Key.Inspect() {
+		case "strategy":
+			switch pair.Value {
+			case object.Intern("one_for_one"):
+				s.oneForAll = false
+			case object.Intern("one_for_all"):
+				s.oneForAll = true
+			default:
+				return newError("strategy of `supervisor` must be :one_for_one or :one_for_all, got %s", pair.Value.Inspect())
+			}
+		case "max_restarts":
+			n, ok := pair.Value.(*object.Integer)
+			if !ok || n.Value < 0 {
+				return newError("max_restarts of `supervisor` must be a positive INTEGER, got %s", pair.Value.Inspect())
+			}
+			s.maxRestarts = int(n.Value)
+		case "within":
+			d, errObj := durationArg("within", []object.Object{pair.Value})
+			if errObj != nil {
+				return errObj
+			}
+			s.within = d
+		default:
+			return newError("unknown option %q to `supervisor`", pair.Key.Inspect())
+		}
+	}
+	return nil
+}
+
+// supervise starts the children and restarts them until the supervisor is killed or
+// its restart intensity is reached
+func (e *Evaluator) supervise(s *supervisor) {
+	se := e.withEnv(e.env)
+	se.actor = s.pid
+	exits := make(chan childExit)
+	start := func(i int) {
+		pid := object.NewPid()
+		s.mu.Lock()
+		s.children[i] = pid
+		s.mu.Unlock()
+		pid.Watch(func(reason object.Object) {
+			go func() {
+				select {
+				case exits <- childExit{index: i, pid: pid, reason: reason}:
+				case <-s.pid.Done():
+				}
+			}()
+		})
+		se.startActor(pid, s.specs[i], nil)
+	}
+	stop := func(reason object.Object) {
+		for _, child := range s.current() {
+			child.Kill(object.Intern("shutdown"))
+		}
+		supervisors.Delete(s.pid)
+		s.pid.Exit(reason)
+	}
+
+	for i := range s.specs {
+		start(i)
+	}
+	var restarts []time.Time
+	for {
+		select {
+		case <-s.pid.Killed():
+			stop(s.pid.KillReason())
+			return
+		case ex := <-exits:
+			if s.current()[ex.index] != ex.pid || ex.reason == object.Intern("normal") {
+				// the child was replaced already or finished its work
+				continue
+			}
+			fmt.Fprintf(supervisorLog, "supervisor %s: child %s crashed: %s, restarting\n", s.pid.Inspect(), ex.pid.Inspect(), ex.reason.Inspect())
+			now := time.Now()
+			kept := restarts[:0]
+			for _, t := range restarts {
+				if now.Sub(t) < s.within {
+					kept = append(kept, t)
+				}
+			}
+			restarts = append(kept, now)
+			if len(restarts) > s.maxRestarts {
+				fmt.Fprintf(supervisorLog, "supervisor %s: more than %d restarts in %s, shutting down\n", s.pid.Inspect(), s.maxRestarts, s.within)
+				stop(&object.String{Value: "supervisor reached its maximum restart intensity"})
+				return
+			}
+			if !s.oneForAll {
+				start(ex.index)
+				continue
+			}
+			for i, child := range s.current() {
+				if i != ex.index {
+					child.Kill(object.Intern("shutdown"))
+				}
+			}
+			for i := range s.specs {
+				start(i)
+			}
+		}
+	}
+}
+
+// current returns the running children of the supervisor
+func (s *supervisor) current() []*object.Pid {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*object.Pid{}, s.children...)
+}
+
+// builtinChildren returns the pids of the children of a supervisor
+func builtinChildren(args ...object.Object) object.Object {
+	pid, errObj := pidArg("children", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	s, ok := supervisors.Load(pid)
+	if !ok {
+		return newError("%s is not a running supervisor", pid.Inspect())
+	}
+	children := s.(*supervisor).current()
+	elems := make([]object.Object, len(children))
+	for i, child := range children {
+		elems[i] = child
+	}
+	return object.NewList(elems)
+}
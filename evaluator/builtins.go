@@ -0,0 +1,355 @@
+package evaluator
+
+import (
+	"blue/object"
+	"fmt"
+	"math"
+	"math/big"
+	"math/cmplx"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// builtins is the map of builtin function names to the builtin function objects
+var builtins = map[string]*object.Builtin{
+	"len": {
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments to `len`. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.String:
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			case *object.List:
+				return &object.Integer{Value: int64(arg.Len())}
+			case *object.Map:
+				return &object.Integer{Value: int64(arg.Len())}
+			case *object.Set:
+				return &object.Integer{Value: int64(arg.Len())}
+			case *object.ConcurrentMap:
+				return &object.Integer{Value: int64(arg.Len())}
+			case *object.ConcurrentQueue:
+				return &object.Integer{Value: int64(arg.Len())}
+			case *object.StructInstance:
+				if res, ok := arg.CallMethod("len"); ok {
+					return res
+				}
+			}
+			return newError("argument to `len` not supported, got %s", args[0].Type())
+		},
+	},
+	"append": {
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) < 1 {
+				return newError("wrong number of arguments to `append`. got=%d, want at least 1", len(args))
+			}
+			list, ok := args[0].(*object.List)
+			if !ok {
+				return newError("argument to `append` must be LIST, got %s", args[0].Type())
+			}
+			return object.NewList(append(list.Elements(), args[1:]...))
+		},
+	},
+	"type": {
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments to `type`. got=%d, want=1", len(args))
+			}
+			return &object.String{Value: string(args[0].Type())}
+		},
+	},
+	"decimal": {
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments to `decimal`. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Integer, *object.BigInteger, *object.Decimal:
+				return toDecimal(arg)
+			case *object.Rational:
+				if d, ok := ratToDecimal(arg.Value); ok {
+					return d
+				}
+				return newError("%s cannot be converted to a DECIMAL exactly, use round(x, places)", arg.Inspect())
+			case *object.Float:
+				if d, ok := floatToDecimal(arg.Value); ok {
+					return d
+				}
+			case *object.String:
+				if d, ok := parseDecimal(arg.Value); ok {
+					return d
+				}
+			}
+			return newError("cannot convert %s %q to a DECIMAL", args[0].Type(), args[0].Inspect())
+		},
+	},
+	"int": {
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments to `int`. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Integer, *object.BigInteger:
+				return arg
+			case *object.Rational, *object.Decimal:
+				return normalizeBigInt(roundRat(toRat(arg), 0, "down"))
+			case *object.Float:
+				if !math.IsNaN(arg.Value) && !math.IsInf(arg.Value, 0) {
+					i, _ := big.NewFloat(arg.Value).Int(nil)
+					return normalizeBigInt(i)
+				}
+			case *object.String:
+				if i, ok := new(big.Int).SetString(strings.TrimSpace(arg.Value), 0); ok {
+					return normalizeBigInt(i)
+				}
+			}
+			return newError("cannot convert %s %q to an INTEGER", args[0].Type(), args[0].Inspect())
+		},
+	},
+	"float": {
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments to `float`. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.String:
+				if f, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64); err == nil {
+					return &object.Float{Value: f}
+				}
+			case *object.Complex:
+				return newError("cannot convert COMPLEX %s to a FLOAT, use real() or imag()", arg.Inspect())
+			default:
+				if isNumber(arg) {
+					return &object.Float{Value: toFloat(arg)}
+				}
+			}
+			return newError("cannot convert %s %q to a FLOAT", args[0].Type(), args[0].Inspect())
+		},
+	},
+	"complex": {
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 2 {
+				return newError("wrong number of arguments to `complex`. got=%d, want 1 or 2", len(args))
+			}
+			parts := []float64{0, 0}
+			for i, arg := range args {
+				if !isNumber(arg) || arg.Type() == object.COMPLEX_OBJ {
+					return newError("arguments to `complex` must be real numbers, got %s", arg.Type())
+				}
+				parts[i] = toFloat(arg)
+			}
+			return &object.Complex{Value: complex(parts[0], parts[1])}
+		},
+	},
+	"real": {
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 || !isNumber(args[0]) {
+				return newError("`real` takes 1 number argument")
+			}
+			return &object.Float{Value: real(toComplex(args[0]))}
+		},
+	},
+	"imag": {
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 || !isNumber(args[0]) {
+				return newError("`imag` takes 1 number argument")
+			}
+			return &object.Float{Value: imag(toComplex(args[0]))}
+		},
+	},
+	"conj": {
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 || !isNumber(args[0]) {
+				return newError("`conj` takes 1 number argument")
+			}
+			return &object.Complex{Value: cmplx.Conj(toComplex(args[0]))}
+		},
+	},
+	"symbol": {
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments to `symbol`. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Symbol:
+				return arg
+			case *object.String:
+				if arg.Value == "" {
+					return newError("cannot make a SYMBOL from an empty STRING")
+				}
+				return object.Intern(arg.Value)
+			}
+			return newError("argument to `symbol` must be STRING, got %s", args[0].Type())
+		},
+	},
+	"str": {
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments to `str`. got=%d, want=1", len(args))
+			}
+			if res, ok := callMethod(args[0], "to_string"); ok {
+				if isError(res) || res.Type() == object.STRING_OBJ {
+					return res
+				}
+				return &object.String{Value: res.Inspect()}
+			}
+			return &object.String{Value: args[0].Inspect()}
+		},
+	},
+	// round(x, places = 0, mode = "half_even") rounds a number to places digits after
+	// the decimal point, decimals and rationals return a DECIMAL and floats a FLOAT
+	"round": {
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 3 {
+				return newError("wrong number of arguments to `round`. got=%d, want 1 to 3", len(args))
+			}
+			places, mode := int64(0), defaultDecimalOpts.rounding
+			if len(args) > 1 {
+				p, ok := args[1].(*object.Integer)
+				if !ok || p.Value < 0 || p.Value > math.MaxInt32 {
+					return newError("places argument to `round` must be a positive INTEGER, got %s", args[1].Inspect())
+				}
+				places = p.Value
+			}
+			if len(args) > 2 {
+				m, ok := args[2].(*object.String)
+				if !ok || !isRoundingMode(m.Value) {
+					return newError("mode argument to `round` must be one of %s, got %s", strings.Join(roundingModes, ", "), args[2].Inspect())
+				}
+				mode = m.Value
+			}
+			switch arg := args[0].(type) {
+			case *object.Integer, *object.BigInteger:
+				return arg
+			case *object.Rational, *object.Decimal:
+				return &object.Decimal{Unscaled: roundRat(toRat(arg), int(places), mode), Scale: int(places)}
+			case *object.Float:
+				d, ok := floatToDecimal(arg.Value)
+				if !ok {
+					return arg
+				}
+				rounded := &object.Decimal{Unscaled: roundRat(decimalToRat(d), int(places), mode), Scale: int(places)}
+				return &object.Float{Value: toFloat(rounded)}
+			}
+			return newError("argument to `round` must be a number, got %s", args[0].Type())
+		},
+	},
+	"print": {
+		Fun: func(args ...object.Object) object.Object {
+			fmt.Print(joinInspect(args))
+			return NULL
+		},
+	},
+	"println": {
+		Fun: func(args ...object.Object) object.Object {
+			fmt.Println(joinInspect(args))
+			return NULL
+		},
+	},
+	// list(iterable) returns a list of the elements, a generator is run until it is exhausted
+	"list": {
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments to `list`. got=%d, want=1", len(args))
+			}
+			elems, errObj := iterableToElements(args[0])
+			if errObj != nil {
+				return errObj
+			}
+			return object.NewList(append([]object.Object{}, elems...))
+		},
+	},
+	"next":     {Fun: builtinNext},
+	"chan":     {Fun: builtinChan},
+	"close":    {Fun: builtinClose},
+	"kill":     {Fun: builtinKill},
+	"alive":    {Fun: builtinAlive},
+	"register": {Fun: builtinRegister},
+	"whereis":  {Fun: builtinWhereis},
+	"children": {Fun: builtinChildren},
+
+	// locks, atomic cells, and concurrent collections shared between tasks
+	"mutex":            {Fun: builtinMutex},
+	"rwmutex":          {Fun: builtinRWMutex},
+	"read_lock":        {Fun: builtinReadLock},
+	"lock":             {Fun: builtinLock},
+	"unlock":           {Fun: builtinUnlock},
+	"atomic_int":       {Fun: builtinAtomicInt},
+	"atomic_ref":       {Fun: builtinAtomicRef},
+	"load":             {Fun: builtinLoad},
+	"store":            {Fun: builtinStore},
+	"swap":             {Fun: builtinSwap},
+	"cas":              {Fun: builtinCas},
+	"add":              {Fun: builtinAdd},
+	"concurrent_map":   {Fun: builtinConcurrentMap},
+	"delete":           {Fun: builtinDelete},
+	"concurrent_queue": {Fun: builtinConcurrentQueue},
+	"push":             {Fun: builtinPush},
+	"pop":              {Fun: builtinPop},
+
+	// durations and timers
+	"duration": {Fun: builtinDuration},
+	"cancel":   {Fun: builtinCancel},
+
+	// implements(value, Trait) returns true if the struct or instance implements the trait
+	"implements": {
+		Fun: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments to `implements`. got=%d, want=2", len(args))
+			}
+			trait, ok := args[1].(*object.Trait)
+			if !ok {
+				return newError("second argument to `implements` must be TRAIT, got %s", args[1].Type())
+			}
+			return nativeToBooleanObject(implements(args[0], trait))
+		},
+	},
+}
+
+// evaluatorBuiltins are the builtins that need the evaluator that calls them, ie. to
+// know which actor is running or to stop when it is cancelled
+var evaluatorBuiltins map[string]func(e *Evaluator, args ...object.Object) object.Object
+
+func init() {
+	evaluatorBuiltins = map[string]func(e *Evaluator, args ...object.Object) object.Object{
+		"send":        (*Evaluator).builtinSend,
+		"recv":        (*Evaluator).builtinRecv,
+		"sleep":       (*Evaluator).builtinSleep,
+		"spawn_actor": (*Evaluator).builtinSpawnActor,
+		"self_pid":    (*Evaluator).builtinSelfPid,
+		"link":        (*Evaluator).builtinLink,
+		"monitor":     (*Evaluator).builtinMonitor,
+		"trap_exits":  (*Evaluator).builtinTrapExits,
+		"supervisor":  (*Evaluator).builtinSupervisor,
+		"update":      (*Evaluator).builtinUpdate,
+		"take":        (*Evaluator).builtinTake,
+		"skip":        (*Evaluator).builtinSkip,
+		"zip":         (*Evaluator).builtinZip,
+		"enumerate":   (*Evaluator).builtinEnumerate,
+		"chunk":       (*Evaluator).builtinChunk,
+		"window":      (*Evaluator).builtinWindow,
+
+		"after":        (*Evaluator).builtinAfter,
+		"every":        (*Evaluator).builtinEvery,
+		"with_timeout": (*Evaluator).builtinWithTimeout,
+	}
+}
+
+// evaluatorBuiltin returns the named evaluator builtin bound to the evaluator
+func (e *Evaluator) evaluatorBuiltin(name string) (*object.Builtin, bool) {
+	fn, ok := evaluatorBuiltins[name]
+	if !ok {
+		return nil, false
+	}
+	return &object.Builtin{Fun: func(args ...object.Object) object.Object { return fn(e, args...) }}, true
+}
+
+// joinInspect joins the Inspect of all the objects with a space
+func joinInspect(args []object.Object) string {
+	strs := make([]string, 0, len(args))
+	for _, arg := range args {
+		strs = append(strs, arg.Inspect())
+	}
+	return strings.Join(strs, " ")
+}
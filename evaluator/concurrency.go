@@ -0,0 +1,421 @@
+package evaluator
+
+import (
+	"blue/ast"
+	"blue/object"
+	"context"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// `spawn f(x)` evaluates f and its arguments and then calls f on its own goroutine,
+// it returns a task whose result is returned by `await task`. Tasks talk over
+// channels made with `chan()` or the buffered `chan(n)`: `send(ch, v)` blocks until
+// the value is received (or buffered), `recv(ch)` blocks until a value is sent and is
+// null once the channel is closed with `close(ch)`, and `for (msg in ch)` receives
+// until the channel is closed. `select` waits for the first of several channel
+// operations that can run:
+//
+//	select {
+//	    msg = recv(inbox) => { handle(msg) },
+//	    send(out, job) => { sent += 1 },
+//	    timeout(100) => { println("idle for 100ms") },
+//	    _ => { println("nothing is ready") },
+//	}
+//
+// `scope { ... }` waits for every task spawned in its block, including the tasks they
+// spawn. When one of them fails the others are cancelled and the scope returns the
+// error. Cancelled code stops at its next loop iteration, function call, or blocking
+// operation ie. sleep, channel operations, select, receive, await, and exec strings.
+
+// evalSpawnExpression starts the call on a new goroutine and returns its task
+func (e *Evaluator) evalSpawnExpression(node *ast.SpawnExpression) object.Object {
+	var fn object.Object
+	var args []object.Object
+	var namedArgs map[string]object.Object
+	if call, ok := node.Value.(*ast.CallExpression); ok {
+		fn = e.Eval(call.Function)
+		if isError(fn) {
+			return fn
+		}
+		var errObj object.Object
+		args, namedArgs, errObj = e.evalArguments(call)
+		if errObj != nil {
+			return errObj
+		}
+	} else {
+		fn = e.Eval(node.Value)
+		if isError(fn) {
+			return fn
+		}
+	}
+	if !IsCallable(fn) {
+		return newError("cannot spawn %s, it is not a function", fn.Type())
+	}
+
+	task := object.NewTask()
+	te := e.withEnv(e.env)
+	// a task never yields to the generator the spawn was in
+	te.gen = nil
+	te.actor = object.NewPid()
+	if e.group != nil {
+		e.group.wg.Add(1)
+	}
+	go func() {
+		var result object.Object
+		defer func() {
+			if r := recover(); r != nil {
+				result = newError("task panicked: %v", r)
+			}
+			te.actor.Exit(exitReason(te.actor, result))
+			task.Finish(result)
+			if te.group != nil {
+				if isError(result) {
+					te.group.fail(result)
+				}
+				te.group.wg.Done()
+			}
+		}()
+		result = te.applyFunction(fn, args, namedArgs)
+	}()
+	return task
+}
+
+// IsCallable returns true for the objects that applyFunction can call
+func IsCallable(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Function, *object.Builtin, *object.BoundMethod, *object.Struct, *object.EnumVariant:
+		return true
+	}
+	return false
+}
+
+// evalAwaitExpression waits for the task or timer, or each task in a list, and returns
+// its result, an error in a task is returned as the error of the await
+func (e *Evaluator) evalAwaitExpression(node *ast.AwaitExpression) object.Object {
+	val := e.Eval(node.Value)
+	if isError(val) {
+		return val
+	}
+	switch val := val.(type) {
+	case *object.Task:
+		return e.wait(val)
+	case *object.Timer:
+		return e.wait(val.Task)
+	case *object.List:
+		elems := val.Elements()
+		results := make([]object.Object, len(elems))
+		for i, elem := range elems {
+			task, ok := elem.(*object.Task)
+			if !ok {
+				return newError("cannot await %s in a list of tasks", elem.Type())
+			}
+			results[i] = e.wait(task)
+			if isError(results[i]) {
+				return results[i]
+			}
+		}
+		return object.NewList(results)
+	}
+	return newError("cannot await %s", val.Type())
+}
+
+// wait returns the result of the task, now owned by the running task, unless the
+// evaluator is cancelled first
+func (e *Evaluator) wait(task *object.Task) object.Object {
+	result, err := task.Wait(e.ctx)
+	if err != nil {
+		return newCancelledError()
+	}
+	e.claimOwner(result)
+	return result
+}
+
+// taskGroup is the tasks spawned in a scope, the first one that fails cancels the rest
+type taskGroup struct {
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+
+	mu  sync.Mutex
+	err object.Object // err is the first error
+}
+
+// fail records the error and cancels the group unless another error came first
+func (g *taskGroup) fail(err object.Object) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.err == nil {
+		g.err = err
+		g.cancel()
+	}
+}
+
+// evalScopeExpression evaluates the block and then waits for the tasks spawned in it,
+// the result is the first error of the block or a task, or the value of the block
+func (e *Evaluator) evalScopeExpression(node *ast.ScopeExpression) object.Object {
+	ctx, cancel := context.WithCancel(e.ctx)
+	defer cancel()
+	group := &taskGroup{cancel: cancel}
+	se := e.withEnv(object.NewEnclosedEnvironment(e.env))
+	se.ctx, se.group = ctx, group
+
+	result := se.Eval(node.Body)
+	if isError(result) {
+		group.fail(result)
+	}
+	group.wg.Wait()
+	if group.err != nil {
+		return group.err
+	}
+	return result
+}
+
+// checkCancelled returns an error once the context of the evaluator is done
+func (e *Evaluator) checkCancelled() *object.Error {
+	if e.ctx.Err() != nil {
+		return newCancelledError()
+	}
+	return nil
+}
+
+// newCancelledError returns the error of code that stopped because it was cancelled
+func newCancelledError() *object.Error {
+	return newError("cancelled")
+}
+
+// selectCase is a case of a select that is ready to be passed to reflect.Select
+type selectCase struct {
+	reflect.SelectCase
+	bind *ast.Identifier // bind is the name the received value is bound to or nil
+}
+
+// evalSelectExpression waits until one of the cases can run and then evaluates its
+// block, if more than one case is ready one of them is chosen at random
+func (e *Evaluator) evalSelectExpression(node *ast.SelectExpression) object.Object {
+	if len(node.Cases) == 0 {
+		return newError("select must have at least one case")
+	}
+	cases := make([]reflect.SelectCase, len(node.Cases))
+	binds := make([]*ast.Identifier, len(node.Cases))
+	var timers []*time.Timer
+	defer func() {
+		for _, t := range timers {
+			t.Stop()
+		}
+	}()
+	for i, exp := range node.Cases {
+		sc, timer, errObj := e.evalSelectCase(exp)
+		if errObj != nil {
+			return errObj
+		}
+		if timer != nil {
+			timers = append(timers, timer)
+		}
+		cases[i], binds[i] = sc.SelectCase, sc.bind
+	}
+
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(e.ctx.Done())})
+	chosen, recv, recvOK, errObj := doSelect(cases)
+	if errObj != nil {
+		return errObj
+	}
+	if chosen == len(node.Cases) {
+		return newCancelledError()
+	}
+	env := object.NewEnclosedEnvironment(e.env)
+	if binds[chosen] != nil {
+		var val object.Object = NULL
+		if recvOK {
+			val = recv.Interface().(object.Object)
+			e.claimOwner(val)
+		}
+		env.Set(binds[chosen].Value, val)
+	}
+	return e.withEnv(env).Eval(node.Consequence[chosen])
+}
+
+// doSelect runs the select, sending on a closed channel is an error
+func doSelect(cases []reflect.SelectCase) (chosen int, recv reflect.Value, recvOK bool, errObj *object.Error) {
+	defer func() {
+		if recover() != nil {
+			errObj = newError("send on closed channel")
+		}
+	}()
+	chosen, recv, recvOK = reflect.Select(cases)
+	return chosen, recv, recvOK, nil
+}
+
+// evalSelectCase evaluates the channel and values of a case of a select, the timer of a
+// timeout case is returned so it can be stopped
+func (e *Evaluator) evalSelectCase(exp ast.Expression) (selectCase, *time.Timer, *object.Error) {
+	sc := selectCase{}
+	if ident, ok := exp.(*ast.Identifier); ok && ident.Value == "_" {
+		sc.Dir = reflect.SelectDefault
+		return sc, nil, nil
+	}
+	if assign, ok := exp.(*ast.AssignmentExpression); ok && assign.Token.Literal == "=" {
+		ident, ok := assign.Left.(*ast.Identifier)
+		if !ok {
+			return sc, nil, newError("select can only bind a received value to a name, got %s", assign.Left.String())
+		}
+		sc.bind = ident
+		exp = assign.Value
+	}
+	call, ok := exp.(*ast.CallExpression)
+	name := ""
+	if ok {
+		if ident, ok := call.Function.(*ast.Identifier); ok {
+			name = ident.Value
+		}
+	}
+	if name != "recv" && name != "send" && name != "timeout" {
+		return sc, nil, newError("select case must be recv, send, timeout, or _, got %s", exp.String())
+	}
+	if sc.bind != nil && name != "recv" {
+		return sc, nil, newError("select can only bind the value of recv, got %s", name)
+	}
+	args, _, errVal := e.evalArguments(call)
+	if errVal != nil {
+		return sc, nil, errVal.(*object.Error)
+	}
+
+	switch name {
+	case "recv", "send":
+		want := map[string]int{"recv": 1, "send": 2}[name]
+		if len(args) != want {
+			return sc, nil, newError("wrong number of arguments to `%s` in select. got=%d, want=%d", name, len(args), want)
+		}
+		ch, ok := args[0].(*object.Channel)
+		if !ok {
+			return sc, nil, newError("first argument to `%s` must be CHANNEL, got %s", name, args[0].Type())
+		}
+		sc.Chan = reflect.ValueOf(ch.Chan())
+		sc.Dir = reflect.SelectRecv
+		if name == "send" {
+			sc.Dir = reflect.SelectSend
+			sc.Send = reflect.ValueOf(&args[1]).Elem()
+		}
+		return sc, nil, nil
+	}
+	d, errObj := durationArg("timeout", args)
+	if errObj != nil {
+		return sc, nil, errObj
+	}
+	timer := time.NewTimer(d)
+	sc.Chan = reflect.ValueOf(timer.C)
+	sc.Dir = reflect.SelectRecv
+	return sc, timer, nil
+}
+
+// durationArg returns the single argument of the builtin as a number of milliseconds
+func durationArg(name string, args []object.Object) (time.Duration, *object.Error) {
+	if len(args) != 1 {
+		return 0, newError("wrong number of arguments to `%s`. got=%d, want=1", name, len(args))
+	}
+	ms, ok := args[0].(*object.Integer)
+	if !ok || ms.Value < 0 {
+		return 0, newError("argument to `%s` must be a positive INTEGER of milliseconds, got %s", name, args[0].Inspect())
+	}
+	return time.Duration(ms.Value) * time.Millisecond, nil
+}
+
+// builtinChan returns a new channel, it is unbuffered unless a buffer size is given
+func builtinChan(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments to `chan`. got=%d, want 0 or 1", len(args))
+	}
+	size := int64(0)
+	if len(args) == 1 {
+		n, ok := args[0].(*object.Integer)
+		if !ok || n.Value < 0 {
+			return newError("argument to `chan` must be a positive INTEGER, got %s", args[0].Inspect())
+		}
+		size = n.Value
+	}
+	return object.NewChannel(int(size))
+}
+
+// channelArg returns the first argument as a channel after checking the number of arguments
+func channelArg(name string, args []object.Object, want int) (*object.Channel, *object.Error) {
+	if len(args) != want {
+		return nil, newError("wrong number of arguments to `%s`. got=%d, want=%d", name, len(args), want)
+	}
+	ch, ok := args[0].(*object.Channel)
+	if !ok {
+		return nil, newError("first argument to `%s` must be CHANNEL, got %s", name, args[0].Type())
+	}
+	return ch, nil
+}
+
+// builtinSend sends the value on the channel, blocking until it is received or buffered,
+// or puts it in the mailbox of an actor
+func (e *Evaluator) builtinSend(args ...object.Object) object.Object {
+	if len(args) == 2 {
+		if res, ok := sendToActor(args[0], args[1]); ok {
+			return res
+		}
+	}
+	ch, errObj := channelArg("send", args, 2)
+	if errObj != nil {
+		return errObj
+	}
+	if err := ch.Send(e.ctx, args[1]); err == object.ErrClosedChannel {
+		return newError("send on closed channel")
+	} else if err != nil {
+		return newCancelledError()
+	}
+	return NULL
+}
+
+// builtinRecv receives a value from the channel, it is null once the channel is closed and empty
+func (e *Evaluator) builtinRecv(args ...object.Object) object.Object {
+	ch, errObj := channelArg("recv", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	val, ok, err := ch.Recv(e.ctx)
+	if err != nil {
+		return newCancelledError()
+	}
+	if ok {
+		e.claimOwner(val)
+		return val
+	}
+	return NULL
+}
+
+// builtinSleep blocks for the number of milliseconds unless it is cancelled first
+func (e *Evaluator) builtinSleep(args ...object.Object) object.Object {
+	d, errObj := durationArg("sleep", args)
+	if errObj != nil {
+		return errObj
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return NULL
+	case <-e.ctx.Done():
+		return newCancelledError()
+	}
+}
+
+// builtinClose closes the channel so receivers stop once it is empty, or stops the
+// generator so that it releases what it holds and yields nothing more
+func builtinClose(args ...object.Object) object.Object {
+	if len(args) == 1 {
+		if gen, ok := args[0].(*object.Generator); ok {
+			stopGenerator(gen)
+			return NULL
+		}
+	}
+	ch, errObj := channelArg("close", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	if !ch.Close() {
+		return newError("close of closed channel")
+	}
+	return NULL
+}
@@ -0,0 +1,307 @@
+package evaluator
+
+import (
+	"blue/object"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DECIMAL_OPTS_NAME is the name of the map in scope that configures decimal arithmetic
+// ie. `val decimal_opts = {"precision": 2, "rounding": "half_up"}`
+const DECIMAL_OPTS_NAME = "decimal_opts"
+
+// Decimal arithmetic is exact for `+`, `-`, `*`, `//`, and `%`. Only results that
+// cannot be represented exactly (`/` and `**` with a negative exponent) are rounded
+// to `precision` digits after the decimal point using the `rounding` mode.
+//
+// Mixed arithmetic promotes as follows:
+//   INTEGER/BIG_INTEGER with DECIMAL -> DECIMAL
+//   DECIMAL with RATIONAL            -> RATIONAL (every decimal is an exact rational)
+//   DECIMAL with FLOAT or COMPLEX    -> error, convert with decimal() or float() first
+// Comparisons between any two numbers are always allowed
+
+// decimalOpts are the options used when a decimal result has to be rounded
+type decimalOpts struct {
+	precision int
+	rounding  string
+}
+
+// defaultDecimalOpts are used when `decimal_opts` is not bound
+var defaultDecimalOpts = decimalOpts{precision: 28, rounding: "half_even"}
+
+// roundingModes are all of the supported rounding modes
+var roundingModes = []string{"half_even", "half_up", "half_down", "up", "down", "ceiling", "floor"}
+
+// isRoundingMode returns true if mode is one of the supported rounding modes
+func isRoundingMode(mode string) bool {
+	for _, m := range roundingModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// getDecimalOpts returns the options bound to `decimal_opts` in the current scope
+// if it is not bound the default options are used
+func (e *Evaluator) getDecimalOpts() (decimalOpts, *object.Error) {
+	opts := defaultDecimalOpts
+	obj, ok := e.env.Get(DECIMAL_OPTS_NAME)
+	if !ok || obj == NULL {
+		return opts, nil
+	}
+	m, ok := obj.(*object.Map)
+	if !ok {
+		return opts, newError("%s must be a MAP, got %s", DECIMAL_OPTS_NAME, obj.Type())
+	}
+	for _, pair := range m.Pairs() {
+		key, ok := pair.Key.(*object.String)
+		if !ok {
+			return opts, newError("%s keys must be STRING, got %s", DECIMAL_OPTS_NAME, pair.Key.Type())
+		}
+		switch key.Value {
+		case "precision":
+			precision, ok := pair.Value.(*object.Integer)
+			if !ok || precision.Value < 0 || precision.Value > math.MaxInt32 {
+				return opts, newError("%s.precision must be a positive INTEGER, got %s", DECIMAL_OPTS_NAME, pair.Value.Inspect())
+			}
+			opts.precision = int(precision.Value)
+		case "rounding":
+			rounding, ok := pair.Value.(*object.String)
+			if !ok || !isRoundingMode(rounding.Value) {
+				return opts, newError("%s.rounding must be one of %s, got %s", DECIMAL_OPTS_NAME, strings.Join(roundingModes, ", "), pair.Value.Inspect())
+			}
+			opts.rounding = rounding.Value
+		default:
+			return opts, newError("unknown %s key %q, expected precision or rounding", DECIMAL_OPTS_NAME, key.Value)
+		}
+	}
+	return opts, nil
+}
+
+// evalDecimalInfixExpression applies the operator when at least one side is a decimal
+func evalDecimalInfixExpression(operator string, left, right object.Object, opts decimalOpts) object.Object {
+	switch operator {
+	case "<", ">", "<=", ">=", "==", "!=":
+		cmp, ok := compareNumbers(left, right)
+		if !ok {
+			return nativeToBooleanObject(operator == "!=")
+		}
+		return compareResult(operator, cmp)
+	}
+	switch {
+	case left.Type() == object.FLOAT_OBJ || right.Type() == object.FLOAT_OBJ,
+		left.Type() == object.COMPLEX_OBJ || right.Type() == object.COMPLEX_OBJ:
+		return newError("cannot mix %s and %s in arithmetic, convert with decimal() or float()", left.Type(), right.Type())
+	case left.Type() == object.RATIONAL_OBJ || right.Type() == object.RATIONAL_OBJ:
+		return evalRationalInfixExpression(operator, toRat(left), toRat(right))
+	}
+	l, r := toDecimal(left), toDecimal(right)
+	switch operator {
+	case "+", "-":
+		scale := maxInt(l.Scale, r.Scale)
+		lu, ru := rescale(l, scale), rescale(r, scale)
+		if operator == "+" {
+			return &object.Decimal{Unscaled: lu.Add(lu, ru), Scale: scale}
+		}
+		return &object.Decimal{Unscaled: lu.Sub(lu, ru), Scale: scale}
+	case "*":
+		return &object.Decimal{Unscaled: new(big.Int).Mul(l.Unscaled, r.Unscaled), Scale: l.Scale + r.Scale}
+	case "/":
+		if r.Unscaled.Sign() == 0 {
+			return newError("division by zero")
+		}
+		quo := new(big.Rat).Quo(decimalToRat(l), decimalToRat(r))
+		return trimDecimal(roundRat(quo, opts.precision, opts.rounding), opts.precision, maxInt(l.Scale, r.Scale))
+	case "//", "%":
+		if r.Unscaled.Sign() == 0 {
+			return newError("division by zero")
+		}
+		scale := maxInt(l.Scale, r.Scale)
+		floor, mod := bigFloorDivMod(rescale(l, scale), rescale(r, scale))
+		if operator == "//" {
+			return &object.Decimal{Unscaled: floor, Scale: 0}
+		}
+		return &object.Decimal{Unscaled: mod, Scale: scale}
+	case "**":
+		exp, ok := decimalToInt64(r)
+		if !ok {
+			return newError("DECIMAL exponent must be a whole number that fits in an INTEGER, got %s", r.Inspect())
+		}
+		if powTooLarge(l.Unscaled, big.NewInt(exp)) {
+			return newError("exponent too large: %d", exp)
+		}
+		if exp >= 0 {
+			if int64(l.Scale)*exp > math.MaxInt32 {
+				return newError("DECIMAL exponent too large: %d", exp)
+			}
+			unscaled := new(big.Int).Exp(l.Unscaled, big.NewInt(exp), nil)
+			return &object.Decimal{Unscaled: unscaled, Scale: l.Scale * int(exp)}
+		}
+		if l.Unscaled.Sign() == 0 {
+			return newError("division by zero")
+		}
+		denom := new(big.Rat).SetFrac(
+			new(big.Int).Exp(l.Unscaled, big.NewInt(-exp), nil),
+			new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(l.Scale)*-exp), nil),
+		)
+		quo := new(big.Rat).Inv(denom)
+		return trimDecimal(roundRat(quo, opts.precision, opts.rounding), opts.precision, l.Scale)
+	}
+	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+}
+
+// roundRat returns r * 10^scale rounded to a whole number with the rounding mode
+func roundRat(r *big.Rat, scale int, mode string) *big.Int {
+	num := new(big.Int).Mul(r.Num(), pow10(scale))
+	q, m := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	if m.Sign() == 0 {
+		return q
+	}
+	neg := r.Sign() < 0
+	half := new(big.Int).Lsh(new(big.Int).Abs(m), 1).Cmp(r.Denom())
+	var away bool
+	switch mode {
+	case "up":
+		away = true
+	case "down":
+		away = false
+	case "ceiling":
+		away = !neg
+	case "floor":
+		away = neg
+	case "half_up":
+		away = half >= 0
+	case "half_down":
+		away = half > 0
+	default:
+		away = half > 0 || (half == 0 && q.Bit(0) == 1)
+	}
+	if away {
+		if neg {
+			return q.Sub(q, big.NewInt(1))
+		}
+		return q.Add(q, big.NewInt(1))
+	}
+	return q
+}
+
+// trimDecimal returns the decimal for unscaled at scale with trailing zeros removed
+// but never with fewer than minScale digits after the decimal point
+func trimDecimal(unscaled *big.Int, scale, minScale int) *object.Decimal {
+	ten, m := big.NewInt(10), new(big.Int)
+	for scale > minScale {
+		q, r := new(big.Int).QuoRem(unscaled, ten, m)
+		if r.Sign() != 0 {
+			break
+		}
+		unscaled, scale = q, scale-1
+	}
+	return &object.Decimal{Unscaled: unscaled, Scale: scale}
+}
+
+// rescale returns the unscaled value of d at the given scale, scale must be >= d.Scale
+func rescale(d *object.Decimal, scale int) *big.Int {
+	return new(big.Int).Mul(d.Unscaled, pow10(scale-d.Scale))
+}
+
+// pow10 returns 10^n
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// maxInt returns the larger of a and b
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// toDecimal converts an integer, big integer, or decimal object to a decimal
+func toDecimal(obj object.Object) *object.Decimal {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return &object.Decimal{Unscaled: big.NewInt(obj.Value), Scale: 0}
+	case *object.BigInteger:
+		return &object.Decimal{Unscaled: obj.Value, Scale: 0}
+	case *object.Decimal:
+		return obj
+	}
+	return nil
+}
+
+// decimalToRat returns the exact rational value of the decimal
+func decimalToRat(d *object.Decimal) *big.Rat {
+	return d.Rat()
+}
+
+// decimalToInt64 returns the decimal as an int64 if it is a whole number that fits
+func decimalToInt64(d *object.Decimal) (int64, bool) {
+	r := decimalToRat(d)
+	if !r.IsInt() || !r.Num().IsInt64() {
+		return 0, false
+	}
+	return r.Num().Int64(), true
+}
+
+// ratToDecimal returns the decimal for r if it has a finite number of digits
+// after the decimal point, ie. 1/4 is 0.25 but 1/3 cannot be converted
+func ratToDecimal(r *big.Rat) (*object.Decimal, bool) {
+	denom := new(big.Int).Set(r.Denom())
+	twos, fives := 0, 0
+	five, m := big.NewInt(5), new(big.Int)
+	for denom.Bit(0) == 0 {
+		denom.Rsh(denom, 1)
+		twos++
+	}
+	for {
+		q, rem := new(big.Int).QuoRem(denom, five, m)
+		if rem.Sign() != 0 {
+			break
+		}
+		denom = q
+		fives++
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return nil, false
+	}
+	scale := maxInt(twos, fives)
+	return &object.Decimal{Unscaled: roundRat(r, scale, "down"), Scale: scale}, true
+}
+
+// parseDecimal parses a string such as `-19.99` into a decimal
+func parseDecimal(s string) (*object.Decimal, bool) {
+	s = strings.Replace(strings.TrimSpace(s), "_", "", -1)
+	digits := strings.TrimLeft(s, "+-")
+	if len(s)-len(digits) > 1 {
+		return nil, false
+	}
+	scale := 0
+	if dot := strings.Index(digits, "."); dot != -1 {
+		scale = len(digits) - dot - 1
+		digits = digits[:dot] + digits[dot+1:]
+	}
+	if digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return nil, false
+	}
+	unscaled, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, false
+	}
+	if strings.HasPrefix(s, "-") {
+		unscaled.Neg(unscaled)
+	}
+	return &object.Decimal{Unscaled: unscaled, Scale: scale}, true
+}
+
+// floatToDecimal converts the float to the decimal of its shortest string
+// representation, so 0.1 becomes 0.1 rather than its exact binary value
+func floatToDecimal(f float64) (*object.Decimal, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, false
+	}
+	return parseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+}
@@ -0,0 +1,231 @@
+package evaluator
+
+import (
+	"blue/ast"
+	"blue/object"
+	"strings"
+)
+
+// evalEnumStatement creates the enum object and binds it to the enum's name
+func (e *Evaluator) evalEnumStatement(node *ast.EnumStatement) object.Object {
+	enum := &object.Enum{Name: node.Name.Value}
+	for _, v := range node.Variants {
+		if enum.Variant(v.Name.Value) != nil {
+			return newError("duplicate variant %s in enum %s", v.Name.Value, enum.Name)
+		}
+		variant := &object.EnumVariant{Enum: enum, Name: v.Name.Value}
+		seen := map[string]bool{}
+		for _, f := range v.Fields {
+			if seen[f.Value] {
+				return newError("duplicate field %s in %s.%s", f.Value, enum.Name, variant.Name)
+			}
+			seen[f.Value] = true
+			variant.Fields = append(variant.Fields, f.Value)
+		}
+		if len(variant.Fields) == 0 {
+			variant.Value = &object.EnumValue{Variant: variant}
+		}
+		enum.Variants = append(enum.Variants, variant)
+	}
+	e.env.SetImmutable(enum.Name, enum)
+	return NULL
+}
+
+// evalEnumMember returns the variant for `Shape.Circle` or one of the helpers
+// `values()` and `from_name()` which are only available without payloads
+func evalEnumMember(enum *object.Enum, name string) object.Object {
+	if variant := enum.Variant(name); variant != nil {
+		if variant.Value != nil {
+			return variant.Value
+		}
+		return variant
+	}
+	switch name {
+	case "values":
+		return &object.Builtin{Fun: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return newError("wrong number of arguments to `values`. got=%d, want=0", len(args))
+			}
+			values := make([]object.Object, 0, len(enum.Variants))
+			for _, v := range enum.Variants {
+				if v.Value == nil {
+					return newError("`values` is only available on enums without payloads, %s.%s has fields", enum.Name, v.Name)
+				}
+				values = append(values, v.Value)
+			}
+			return object.NewList(values)
+		}}
+	case "from_name":
+		return &object.Builtin{Fun: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments to `from_name`. got=%d, want=1", len(args))
+			}
+			var name string
+			switch arg := args[0].(type) {
+			case *object.String:
+				name = arg.Value
+			case *object.Symbol:
+				name = arg.Name
+			default:
+				return newError("argument to `from_name` must be STRING or SYMBOL, got %s", args[0].Type())
+			}
+			variant := enum.Variant(name)
+			if variant == nil {
+				return newError("enum %s has no variant %q", enum.Name, name)
+			}
+			if variant.Value == nil {
+				return newError("`from_name` is only available for variants without payloads, %s.%s has fields", enum.Name, name)
+			}
+			return variant.Value
+		}}
+	}
+	return newError("enum %s has no variant %q", enum.Name, name)
+}
+
+// evalEnumValueMember returns the payload field of the enum value, or its variant's name for `.name`
+func evalEnumValueMember(value *object.EnumValue, name string) object.Object {
+	for i, f := range value.Variant.Fields {
+		if f == name {
+			return value.Payload[i]
+		}
+	}
+	if name == "name" {
+		return &object.String{Value: value.Variant.Name}
+	}
+	return newError("%s.%s has no field %q", value.Variant.Enum.Name, value.Variant.Name, name)
+}
+
+// newEnumValue constructs an enum value from positional or named arguments
+func newEnumValue(variant *object.EnumVariant, args []object.Object, namedArgs map[string]object.Object) object.Object {
+	if len(args) > len(variant.Fields) {
+		return newError("wrong number of arguments to %s.%s. want=%d, got=%d", variant.Enum.Name, variant.Name, len(variant.Fields), len(args))
+	}
+	payload := make([]object.Object, len(variant.Fields))
+	copy(payload, args)
+	for name, val := range namedArgs {
+		idx := -1
+		for i, f := range variant.Fields {
+			if f == name {
+				idx = i
+			}
+		}
+		if idx == -1 {
+			return newError("%s.%s has no field %q", variant.Enum.Name, variant.Name, name)
+		}
+		if payload[idx] != nil {
+			return newError("field %q of %s.%s given more than once", name, variant.Enum.Name, variant.Name)
+		}
+		payload[idx] = val
+	}
+	for i, p := range payload {
+		if p == nil {
+			return newError("missing field %q for %s.%s", variant.Fields[i], variant.Enum.Name, variant.Name)
+		}
+	}
+	return &object.EnumValue{Variant: variant, Payload: payload}
+}
+
+// enumArm is a match arm resolved against an enum value
+type enumArm struct {
+	wildcard bool
+	variant  *object.EnumVariant // variant is set for destructuring patterns ie. `Shape.Circle(r)`
+	args     []ast.Expression
+	pattern  object.Object // pattern is the evaluated arm for every other pattern
+}
+
+// evalEnumMatch matches an enum value against the arms of a match, patterns can destructure
+// the payload ie. `Shape.Rect(w, h) => { w * h }`. Unless there is a `_` arm every
+// variant of the enum must be covered or an error is returned
+func (e *Evaluator) evalEnumMatch(node *ast.MatchExpression, value *object.EnumValue) object.Object {
+	arms := make([]enumArm, len(node.Condition))
+	covered := map[*object.EnumVariant]bool{}
+	hasWildcard := false
+	for i, cond := range node.Condition {
+		if ident, ok := cond.(*ast.Identifier); ok && ident.Value == "_" {
+			arms[i].wildcard, hasWildcard = true, true
+			continue
+		}
+		if call, ok := cond.(*ast.CallExpression); ok {
+			fn := e.Eval(call.Function)
+			if isError(fn) {
+				return fn
+			}
+			if variant, ok := fn.(*object.EnumVariant); ok {
+				if len(call.Arguments) != len(variant.Fields) {
+					return newError("pattern %s.%s must have %d fields, got=%d", variant.Enum.Name, variant.Name, len(variant.Fields), len(call.Arguments))
+				}
+				arms[i].variant, arms[i].args = variant, call.Arguments
+				if allIdentifiers(call.Arguments) {
+					covered[variant] = true
+				}
+				continue
+			}
+		}
+		pattern := e.Eval(cond)
+		if isError(pattern) {
+			return pattern
+		}
+		arms[i].pattern = pattern
+		if ev, ok := pattern.(*object.EnumValue); ok && ev.Variant.Value == ev {
+			covered[ev.Variant] = true
+		}
+	}
+
+	if !hasWildcard {
+		missing := []string{}
+		for _, v := range value.Variant.Enum.Variants {
+			if !covered[v] {
+				missing = append(missing, v.Name)
+			}
+		}
+		if len(missing) > 0 {
+			return newError("non-exhaustive match on %s, missing %s", value.Variant.Enum.Name, strings.Join(missing, ", "))
+		}
+	}
+
+	for i, arm := range arms {
+		switch {
+		case arm.wildcard:
+			return e.Eval(node.Consequence[i])
+		case arm.variant != nil:
+			if arm.variant != value.Variant {
+				continue
+			}
+			env := object.NewEnclosedEnvironment(e.env)
+			matched := true
+			for j, arg := range arm.args {
+				if ident, ok := arg.(*ast.Identifier); ok {
+					if ident.Value != "_" {
+						env.Set(ident.Value, value.Payload[j])
+					}
+					continue
+				}
+				want := e.Eval(arg)
+				if isError(want) {
+					return want
+				}
+				if !objectsEqual(want, value.Payload[j]) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				return e.withEnv(env).Eval(node.Consequence[i])
+			}
+		case objectsEqual(value, arm.pattern):
+			return e.Eval(node.Consequence[i])
+		}
+	}
+	return NULL
+}
+
+// allIdentifiers returns true if every expression is an identifier, ie. the
+// pattern binds every field and matches any payload
+func allIdentifiers(exps []ast.Expression) bool {
+	for _, exp := range exps {
+		if _, ok := exp.(*ast.Identifier); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,967 @@
+// evaluator walks the ast of the blue programming language
+// and evaluates it into objects
+package evaluator
+
+import (
+	"blue/ast"
+	"blue/lexer"
+	"blue/object"
+	"blue/parser"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var (
+	// TRUE is the single true boolean object
+	TRUE = &object.Boolean{Value: true}
+	// FALSE is the single false boolean object
+	FALSE = &object.Boolean{Value: false}
+	// NULL is the single null object
+	NULL = &object.Null{}
+)
+
+// Evaluator is the struct containing the environment that nodes are evaluated in
+type Evaluator struct {
+	env    *object.Environment
+	gen    *generatorState          // gen is set while evaluating the body of a generator
+	macros map[string]*object.Macro // macros are the macros defined by the programs expanded so far
+	actor  *object.Pid              // actor is the actor the code runs as, receive reads its mailbox
+	ctx    context.Context          // ctx is done once the code is cancelled ie. a sibling in its scope failed
+	group  *taskGroup               // group is set in a scope, the tasks spawned in it join the group
+}
+
+// New returns a new Evaluator with an empty top level environment
+func New() *Evaluator {
+	return &Evaluator{
+		env:    object.NewEnvironment(),
+		macros: map[string]*object.Macro{},
+		actor:  object.NewPid(),
+		ctx:    context.Background(),
+	}
+}
+
+// Get returns the object bound to name at the top level
+func (e *Evaluator) Get(name string) (object.Object, bool) {
+	return e.env.Get(name)
+}
+
+// Set binds name to val at the top level as a var so that programs can use it
+func (e *Evaluator) Set(name string, val object.Object) {
+	e.env.Set(name, val)
+}
+
+// Call calls the function, builtin, method, or struct with the arguments and returns
+// its result, it returns an error object if the call fails
+func (e *Evaluator) Call(fn object.Object, args ...object.Object) object.Object {
+	if !IsCallable(fn) {
+		return newError("cannot call %s, it is not a function", fn.Type())
+	}
+	return e.applyFunction(fn, args, nil)
+}
+
+// withEnv returns a copy of the evaluator that evaluates in env
+func (e *Evaluator) withEnv(env *object.Environment) *Evaluator {
+	newE := *e
+	newE.env = env
+	return &newE
+}
+
+// Eval evaluates the node and returns the resulting object
+func (e *Evaluator) Eval(node ast.Node) object.Object {
+	switch node := node.(type) {
+	// Statements
+	case *ast.Program:
+		return e.evalProgram(node)
+	case *ast.ExpressionStatement:
+		return e.Eval(node.Expression)
+	case *ast.BlockStatement:
+		return e.evalBlockStatement(node)
+	case *ast.ReturnStatement:
+		val := e.Eval(node.ReturnValue)
+		if isError(val) {
+			return val
+		}
+		return &object.ReturnValue{Value: val}
+	case *ast.VarStatement:
+		return e.evalVarStatement(node)
+	case *ast.ValStatement:
+		val := e.Eval(node.Value)
+		if isError(val) {
+			return val
+		}
+		if node.Type != nil {
+			if errObj := e.checkType(node.Type, val, fmt.Sprintf("%q", node.Name.Value), node.Type.Span); errObj != nil {
+				return errObj
+			}
+		}
+		e.setOwner(val)
+		e.env.SetImmutable(node.Name.Value, val)
+		return NULL
+	case *ast.FunctionStatement:
+		return e.evalFunctionStatement(node)
+	case *ast.EnumStatement:
+		return e.evalEnumStatement(node)
+	case *ast.StructStatement:
+		return e.evalStructStatement(node)
+	case *ast.TraitStatement:
+		return e.evalTraitStatement(node)
+	case *ast.ImplStatement:
+		return e.evalImplStatement(node)
+	case *ast.MacroStatement:
+		return evalMacroStatement(node)
+	case *ast.ImportStatement:
+		return e.evalImportStatement(node)
+
+	// Expressions
+	case *ast.IntegerLiteral:
+		return &object.Integer{Value: node.Value}
+	case *ast.BigIntegerLiteral:
+		return normalizeBigInt(node.Value)
+	case *ast.RationalLiteral:
+		return normalizeRat(node.Value)
+	case *ast.DecimalLiteral:
+		return &object.Decimal{Unscaled: node.Unscaled, Scale: node.Scale}
+	case *ast.DurationLiteral:
+		return &object.Integer{Value: node.Value}
+	case *ast.SymbolLiteral:
+		return object.Intern(node.Value)
+	case *ast.ImaginaryLiteral:
+		return &object.Complex{Value: complex(0, node.Value)}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+	case *ast.HexLiteral:
+		return normalizeBigInt(new(big.Int).SetUint64(node.Value))
+	case *ast.OctalLiteral:
+		return normalizeBigInt(new(big.Int).SetUint64(node.Value))
+	case *ast.BinaryLiteral:
+		return normalizeBigInt(new(big.Int).SetUint64(node.Value))
+	case *ast.Boolean:
+		return nativeToBooleanObject(node.Value)
+	case *ast.Null:
+		return NULL
+	case *ast.StringLiteral:
+		return e.evalStringLiteral(node)
+	case *ast.ExecStringLiteral:
+		return e.evalExecStringLiteral(node)
+	case *ast.Identifier:
+		return e.evalIdentifier(node)
+	case *ast.PrefixExpression:
+		right := e.Eval(node.Right)
+		if isError(right) {
+			return right
+		}
+		return evalPrefixExpression(node.Operator, right)
+	case *ast.InfixExpression:
+		return e.evalInfixExpression(node)
+	case *ast.IfExpression:
+		return e.evalIfExpression(node)
+	case *ast.MatchExpression:
+		return e.evalMatchExpression(node)
+	case *ast.ForExpression:
+		return e.evalForExpression(node)
+	case *ast.FunctionLiteral:
+		return &object.Function{Parameters: node.Parameters, DefaultParameters: node.ParameterExpressions, Body: node.Body, Env: e.env, ParameterTypes: node.ParameterTypes, ReturnType: node.ReturnType}
+	case *ast.CallExpression:
+		val, _ := e.evalCallExpression(node)
+		return val
+	case *ast.ListLiteral:
+		return e.evalListLiteral(node)
+	case *ast.ListCompLiteral:
+		return e.evalListCompLiteral(node)
+	case *ast.MapLiteral:
+		return e.evalMapLiteral(node)
+	case *ast.SetLiteral:
+		return e.evalSetLiteral(node)
+	case *ast.IndexExpression:
+		val, _ := e.evalIndexExpression(node)
+		return val
+	case *ast.SliceExpression:
+		val, _ := e.evalSliceExpression(node)
+		return val
+	case *ast.YieldExpression:
+		return e.evalYieldExpression(node)
+	case *ast.QuoteExpression:
+		return e.evalQuoteExpression(node)
+	case *ast.BlockExpression:
+		return e.evalBlockStatement(node.Block)
+	case *ast.AssignmentExpression:
+		return e.evalAssignmentExpression(node)
+	case *ast.SpawnExpression:
+		return e.evalSpawnExpression(node)
+	case *ast.AwaitExpression:
+		return e.evalAwaitExpression(node)
+	case *ast.SelectExpression:
+		return e.evalSelectExpression(node)
+	case *ast.ReceiveExpression:
+		return e.evalReceiveExpression(node)
+	case *ast.ScopeExpression:
+		return e.evalScopeExpression(node)
+	case *ast.WithExpression:
+		return e.evalWithExpression(node)
+	}
+	if node == nil {
+		return newError("cannot evaluate a nil node")
+	}
+	return newError("evaluating %T is not supported", node)
+}
+
+// evalProgram expands the macros of the program then evaluates all of its statements
+// and returns the last result
+func (e *Evaluator) evalProgram(program *ast.Program) object.Object {
+	program, errObj := e.ExpandMacros(program)
+	if errObj != nil {
+		return errObj
+	}
+	var result object.Object = NULL
+
+	for _, stmt := range program.Statements {
+		result = e.Eval(stmt)
+
+		switch result := result.(type) {
+		case *object.ReturnValue:
+			return result.Value
+		case *object.Error:
+			return result
+		}
+	}
+
+	return result
+}
+
+// evalFunctionStatement binds the function to its name, the decorators are evaluated top
+// to bottom and then the function is replaced by calling each decorator with it starting
+// with the one closest to the function, so `@a @b fun f()` binds f to a(b(f))
+func (e *Evaluator) evalFunctionStatement(node *ast.FunctionStatement) object.Object {
+	var fun object.Object = &object.Function{Parameters: node.Parameters, DefaultParameters: node.ParameterExpressions, Body: node.Body, Env: e.env, ParameterTypes: node.ParameterTypes, ReturnType: node.ReturnType}
+	decorators := e.evalExpressions(node.Decorators)
+	if len(decorators) == 1 && isError(decorators[0]) {
+		return decorators[0]
+	}
+	for i := len(decorators) - 1; i >= 0; i-- {
+		fun = e.applyFunction(decorators[i], []object.Object{fun}, nil)
+		if isError(fun) {
+			return fun
+		}
+	}
+	e.env.SetImmutable(node.Name.Value, fun)
+	return NULL
+}
+
+// evalBlockStatement evaluates the statements in the block and stops early
+// on return values and errors so they can be passed up
+func (e *Evaluator) evalBlockStatement(block *ast.BlockStatement) object.Object {
+	var result object.Object = NULL
+
+	for _, stmt := range block.Statements {
+		result = e.Eval(stmt)
+
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+				return result
+			}
+		}
+	}
+
+	return result
+}
+
+// evalVarStatement binds the value to the var's name as a mutable binding
+func (e *Evaluator) evalVarStatement(node *ast.VarStatement) object.Object {
+	if node.AssignmentToken.Literal != "=" {
+		return newError("var statement for %q must use =, got %s", node.Name.Value, node.AssignmentToken.Literal)
+	}
+	val := e.Eval(node.Value)
+	if isError(val) {
+		return val
+	}
+	if node.Type != nil {
+		if errObj := e.checkType(node.Type, val, fmt.Sprintf("%q", node.Name.Value), node.Type.Span); errObj != nil {
+			return errObj
+		}
+	}
+	e.env.Set(node.Name.Value, val)
+	if node.Type != nil {
+		e.env.SetType(node.Name.Value, node.Type)
+	}
+	return NULL
+}
+
+// evalIdentifier looks up the identifier in the environment and then the builtins
+func (e *Evaluator) evalIdentifier(node *ast.Identifier) object.Object {
+	if val, ok := e.env.Get(node.Value); ok {
+		return val
+	}
+	if builtin, ok := builtins[node.Value]; ok {
+		return builtin
+	}
+	if builtin, ok := e.evaluatorBuiltin(node.Value); ok {
+		return builtin
+	}
+	return newError("identifier not found: %s", node.Value)
+}
+
+// evalStringLiteral returns the string object with all of the interpolation values evaluated
+func (e *Evaluator) evalStringLiteral(node *ast.StringLiteral) object.Object {
+	if len(node.InterpolationValues) == 0 {
+		return &object.String{Value: node.Value}
+	}
+	str, errObj := e.interpolate(node.Value, node.InterpolationValues, node.OriginalInterpolationString, func(obj object.Object) string {
+		return obj.Inspect()
+	})
+	if errObj != nil {
+		return errObj
+	}
+	return &object.String{Value: str}
+}
+
+// interpolate replaces every original `#{...}` string in value with the converted
+// result of evaluating its expression
+func (e *Evaluator) interpolate(value string, exps []ast.Expression, origs []string, convert func(object.Object) string) (string, *object.Error) {
+	var out strings.Builder
+	rest := value
+	for i, exp := range exps {
+		if exp == nil || i >= len(origs) {
+			return "", newError("invalid string interpolation in %q", value)
+		}
+		val := e.Eval(exp)
+		if isError(val) {
+			return "", val.(*object.Error)
+		}
+		idx := strings.Index(rest, origs[i])
+		if idx == -1 {
+			return "", newError("invalid string interpolation in %q", value)
+		}
+		out.WriteString(rest[:idx])
+		out.WriteString(convert(val))
+		rest = rest[idx+len(origs[i]):]
+	}
+	out.WriteString(rest)
+	return out.String(), nil
+}
+
+// evalIfExpression evaluates the consequence if the condition is truthy, otherwise the alternative
+func (e *Evaluator) evalIfExpression(node *ast.IfExpression) object.Object {
+	condition := e.Eval(node.Condition)
+	if isError(condition) {
+		return condition
+	}
+
+	if isTruthy(condition) {
+		return e.Eval(node.Consequence)
+	} else if node.Alternative != nil {
+		return e.Eval(node.Alternative)
+	}
+	return NULL
+}
+
+// evalMatchExpression evaluates the first consequence whose condition matches
+// when there is an optional value the conditions are compared to it, otherwise
+// the conditions are checked for truthiness. `_` matches anything
+func (e *Evaluator) evalMatchExpression(node *ast.MatchExpression) object.Object {
+	var value object.Object
+	if node.OptionalValue != nil {
+		value = e.Eval(node.OptionalValue)
+		if isError(value) {
+			return value
+		}
+		if enumValue, ok := value.(*object.EnumValue); ok {
+			return e.evalEnumMatch(node, enumValue)
+		}
+	}
+
+	for i, cond := range node.Condition {
+		if ident, ok := cond.(*ast.Identifier); ok && ident.Value == "_" {
+			return e.Eval(node.Consequence[i])
+		}
+		c := e.Eval(cond)
+		if isError(c) {
+			return c
+		}
+		if (value != nil && objectsEqual(value, c)) || (value == nil && isTruthy(c)) {
+			return e.Eval(node.Consequence[i])
+		}
+	}
+	return NULL
+}
+
+// evalForExpression evaluates either a `for (x in iterable)` loop or
+// a `for (condition)` loop, each iteration gets its own scope
+func (e *Evaluator) evalForExpression(node *ast.ForExpression) object.Object {
+	if infix, ok := node.Condition.(*ast.InfixExpression); ok && infix.Operator == "in" {
+		if ident, ok := infix.Left.(*ast.Identifier); ok {
+			return e.evalForInExpression(ident, infix.Right, node.Consequence)
+		}
+	}
+
+	for {
+		if errObj := e.checkCancelled(); errObj != nil {
+			return errObj
+		}
+		cond := e.Eval(node.Condition)
+		if isError(cond) {
+			return cond
+		}
+		if !isTruthy(cond) {
+			break
+		}
+		result := e.withEnv(object.NewEnclosedEnvironment(e.env)).Eval(node.Consequence)
+		if isError(result) || isReturnValue(result) {
+			return result
+		}
+	}
+	return NULL
+}
+
+// evalForInExpression binds ident to each element of the iterable and evaluates the body
+// a generator is consumed lazily and stopped if the loop returns early
+func (e *Evaluator) evalForInExpression(ident *ast.Identifier, iterableExp ast.Expression, body *ast.BlockStatement) object.Object {
+	iterable := e.Eval(iterableExp)
+	if isError(iterable) {
+		return iterable
+	}
+	_, fromChannel := iterable.(*object.Channel)
+	gen, errObj := e.iterate(iterable)
+	if errObj != nil {
+		return errObj
+	}
+	for {
+		if errObj := e.checkCancelled(); errObj != nil {
+			stopGenerator(gen)
+			return errObj
+		}
+		elem, ok := gen.Next()
+		if !ok {
+			return NULL
+		}
+		if isError(elem) {
+			return elem
+		}
+		if fromChannel {
+			e.claimOwner(elem)
+		}
+		env := object.NewEnclosedEnvironment(e.env)
+		env.Set(ident.Value, elem)
+		result := e.withEnv(env).Eval(body)
+		if isError(result) || isReturnValue(result) {
+			stopGenerator(gen)
+			return result
+		}
+	}
+}
+
+// iterableToElements returns all of the elements of the iterable, generators are drained
+func iterableToElements(iterable object.Object) ([]object.Object, *object.Error) {
+	switch iterable := iterable.(type) {
+	case *object.Generator:
+		return collect(iterable)
+	case *object.List:
+		return iterable.Elements(), nil
+	case *object.String:
+		elems := []object.Object{}
+		for _, r := range iterable.Value {
+			elems = append(elems, &object.String{Value: string(r)})
+		}
+		return elems, nil
+	case *object.Map:
+		elems := []object.Object{}
+		for _, pair := range iterable.Pairs() {
+			elems = append(elems, pair.Key)
+		}
+		return elems, nil
+	case *object.ConcurrentMap:
+		return iterableToElements(iterable.Snapshot())
+	case *object.Set:
+		return iterable.Elements(), nil
+	case *object.StructInstance:
+		if res, ok := iterable.CallMethod("iter"); ok {
+			if errObj, ok := res.(*object.Error); ok {
+				return nil, errObj
+			}
+			return iterableToElements(res)
+		}
+	}
+	return nil, newError("cannot iterate over %s", iterable.Type())
+}
+
+// evalChainLeft evaluates the left side of a member access, index, slice, or call, short
+// is true once a `?.` or `?[` link of the chain was null so that the rest of the chain
+// is skipped and is null too ie. `u?.a.b` and `u?.f()` are null when u is null
+func (e *Evaluator) evalChainLeft(left ast.Expression, optional bool) (val object.Object, short bool) {
+	switch left := left.(type) {
+	case *ast.IndexExpression:
+		val, short = e.evalIndexExpression(left)
+	case *ast.SliceExpression:
+		val, short = e.evalSliceExpression(left)
+	case *ast.CallExpression:
+		val, short = e.evalCallExpression(left)
+	default:
+		val = e.Eval(left)
+	}
+	return val, short || (optional && val == NULL)
+}
+
+// evalCallExpression evaluates the function and its arguments and then applies it
+func (e *Evaluator) evalCallExpression(node *ast.CallExpression) (object.Object, bool) {
+	function, short := e.evalChainLeft(node.Function, node.Optional)
+	if short || isError(function) {
+		return function, short
+	}
+
+	args, namedArgs, errObj := e.evalArguments(node)
+	if errObj != nil {
+		return errObj, false
+	}
+	return e.applyFunction(function, args, namedArgs), false
+}
+
+// evalArguments evaluates the positional and then the named arguments of the call
+func (e *Evaluator) evalArguments(node *ast.CallExpression) ([]object.Object, map[string]object.Object, object.Object) {
+	args := e.evalExpressions(node.Arguments)
+	if len(args) == 1 && isError(args[0]) {
+		return nil, nil, args[0]
+	}
+
+	namedArgs := make(map[string]object.Object, len(node.DefaultArguments))
+	for name, exp := range node.DefaultArguments {
+		val := e.Eval(exp)
+		if isError(val) {
+			return nil, nil, val
+		}
+		namedArgs[name] = val
+	}
+	return args, namedArgs, nil
+}
+
+// evalExpressions evaluates the expressions in order, if any is an error
+// it will be returned as the only element
+func (e *Evaluator) evalExpressions(exps []ast.Expression) []object.Object {
+	result := []object.Object{}
+
+	for _, exp := range exps {
+		evaluated := e.Eval(exp)
+		if isError(evaluated) {
+			return []object.Object{evaluated}
+		}
+		result = append(result, evaluated)
+	}
+
+	return result
+}
+
+// applyFunction calls the function or builtin with the given arguments
+func (e *Evaluator) applyFunction(fn object.Object, args []object.Object, namedArgs map[string]object.Object) object.Object {
+	switch fn := fn.(type) {
+	case *object.Function:
+		if errObj := e.checkCancelled(); errObj != nil {
+			return errObj
+		}
+		env, errObj := e.extendFunctionEnv(fn, args, namedArgs)
+		if errObj != nil {
+			return errObj
+		}
+		var result object.Object
+		if fn.Body.Yields {
+			result = e.newFunctionGenerator(fn, env)
+		} else {
+			result = unwrapReturnValue(e.withEnv(env).Eval(fn.Body))
+		}
+		if fn.ReturnType != nil && !isError(result) {
+			if errObj := e.withEnv(env).checkType(fn.ReturnType, result, "return value", fn.ReturnType.Span); errObj != nil {
+				return errObj
+			}
+		}
+		return result
+	case *object.Builtin:
+		if len(namedArgs) > 0 {
+			return newError("builtin functions do not take named arguments")
+		}
+		return fn.Fun(args...)
+	case *object.EnumVariant:
+		return newEnumValue(fn, args, namedArgs)
+	case *object.Struct:
+		return e.newStructInstance(fn, args, namedArgs)
+	case *object.BoundMethod:
+		return e.applyFunction(fn.Method, append([]object.Object{fn.Receiver}, args...), namedArgs)
+	default:
+		return newError("not a function: %s", fn.Type())
+	}
+}
+
+// extendFunctionEnv binds the arguments to the function parameters in a new scope
+// positional arguments are bound first, then named arguments, then default parameters
+func (e *Evaluator) extendFunctionEnv(fn *object.Function, args []object.Object, namedArgs map[string]object.Object) (*object.Environment, *object.Error) {
+	if len(args) > len(fn.Parameters) {
+		return nil, newError("wrong number of arguments. want at most %d, got=%d", len(fn.Parameters), len(args))
+	}
+	for name := range namedArgs {
+		idx := parameterIndex(fn, name)
+		if idx == -1 {
+			return nil, newError("unexpected named argument %q", name)
+		}
+		if idx < len(args) {
+			return nil, newError("argument %q was already passed positionally", name)
+		}
+	}
+	env := object.NewEnclosedEnvironment(fn.Env)
+	fnE := e.withEnv(env)
+
+	for i, param := range fn.Parameters {
+		if i < len(args) {
+			env.Set(param.Value, args[i])
+			continue
+		}
+		if arg, ok := namedArgs[param.Value]; ok {
+			env.Set(param.Value, arg)
+			continue
+		}
+		if i < len(fn.DefaultParameters) && fn.DefaultParameters[i] != nil {
+			val := fnE.Eval(fn.DefaultParameters[i])
+			if isError(val) {
+				return nil, val.(*object.Error)
+			}
+			env.Set(param.Value, val)
+			continue
+		}
+		return nil, newError("missing argument for parameter %q", param.Value)
+	}
+	if errObj := e.checkParameterTypes(fn, env); errObj != nil {
+		return nil, errObj
+	}
+	return env, nil
+}
+
+// parameterIndex returns the position of the named parameter or -1 if it does not exist
+func parameterIndex(fn *object.Function, name string) int {
+	for i, p := range fn.Parameters {
+		if p.Value == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// unwrapReturnValue returns the value inside of a return value object
+func unwrapReturnValue(obj object.Object) object.Object {
+	if returnValue, ok := obj.(*object.ReturnValue); ok {
+		return returnValue.Value
+	}
+	return obj
+}
+
+// evalListLiteral returns the list object, a list comprehension is evaluated as its own program
+func (e *Evaluator) evalListLiteral(node *ast.ListLiteral) object.Object {
+	if len(node.Elements) == 1 {
+		if lc, ok := node.Elements[0].(*ast.ListCompLiteral); ok {
+			return e.evalListCompLiteral(lc)
+		}
+	}
+	elements := e.evalExpressions(node.Elements)
+	if len(elements) == 1 && isError(elements[0]) {
+		return elements[0]
+	}
+	return object.NewList(elements)
+}
+
+// evalListCompLiteral parses and evaluates the program of the list comprehension
+// in a new scope and returns the list it built
+func (e *Evaluator) evalListCompLiteral(node *ast.ListCompLiteral) object.Object {
+	l := lexer.New(node.NonEvaluatedProgram, "<list comprehension>")
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return newError("failed to parse list comprehension: %s", strings.Join(p.Errors(), ", "))
+	}
+	newE := e.withEnv(object.NewEnclosedEnvironment(e.env))
+	result := newE.Eval(program)
+	if isError(result) {
+		return result
+	}
+	list, _ := newE.env.Get("__internal__")
+	return list
+}
+
+// evalMapLiteral returns the map object
+func (e *Evaluator) evalMapLiteral(node *ast.MapLiteral) object.Object {
+	m := object.NewMap()
+
+	for keyNode, valueNode := range node.Pairs {
+		key := e.Eval(keyNode)
+		if isError(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as a map key: %s", key.Type())
+		}
+
+		value := e.Eval(valueNode)
+		if isError(value) {
+			return value
+		}
+
+		m.Set(hashKey, value)
+	}
+
+	return m
+}
+
+// evalSetLiteral returns the set object
+func (e *Evaluator) evalSetLiteral(node *ast.SetLiteral) object.Object {
+	elements := e.evalExpressions(node.Elements)
+	if len(elements) == 1 && isError(elements[0]) {
+		return elements[0]
+	}
+	set := object.NewSet()
+	for _, elem := range elements {
+		hashKey, ok := elem.(object.Hashable)
+		if !ok {
+			return newError("unusable as a set element: %s", elem.Type())
+		}
+		set.Add(hashKey)
+	}
+	return set
+}
+
+// evalIndexExpression evaluates indexing into lists, strings, and maps
+func (e *Evaluator) evalIndexExpression(node *ast.IndexExpression) (object.Object, bool) {
+	left, short := e.evalChainLeft(node.Left, node.Optional)
+	if short || isError(left) {
+		return left, short
+	}
+	return e.evalIndex(node, left), false
+}
+
+// evalIndex indexes the already evaluated left side of the index expression
+func (e *Evaluator) evalIndex(node *ast.IndexExpression, left object.Object) object.Object {
+	if slice, ok := symbolSlice(node, left); ok {
+		return e.evalSlice(left, slice)
+	}
+	index := e.Eval(node.Index)
+	if isError(index) {
+		return index
+	}
+
+	switch {
+	case left.Type() == object.LIST_OBJ && index.Type() == object.INTEGER_OBJ:
+		list := left.(*object.List)
+		idx, ok := normalizeIndex(index.(*object.Integer).Value, int64(list.Len()))
+		if !ok {
+			return newError("index out of range: %d", index.(*object.Integer).Value)
+		}
+		elem, ok := list.Get(idx)
+		if !ok {
+			// another task shortened the list
+			return newError("index out of range: %d", index.(*object.Integer).Value)
+		}
+		return elem
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		runes := []rune(left.(*object.String).Value)
+		idx, ok := normalizeIndex(index.(*object.Integer).Value, int64(len(runes)))
+		if !ok {
+			return newError("index out of range: %d", index.(*object.Integer).Value)
+		}
+		return &object.String{Value: string(runes[idx])}
+	case left.Type() == object.MAP_OBJ:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as a map key: %s", index.Type())
+		}
+		if val, ok := left.(*object.Map).Get(key); ok {
+			return val
+		}
+		return NULL
+	case left.Type() == object.CONCURRENT_MAP_OBJ:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as a map key: %s", index.Type())
+		}
+		if val, ok := left.(*object.ConcurrentMap).Get(key); ok {
+			return val
+		}
+		return NULL
+	}
+	if name, ok := index.(*object.String); ok {
+		switch left := left.(type) {
+		case *object.Enum:
+			return evalEnumMember(left, name.Value)
+		case *object.EnumValue:
+			return evalEnumValueMember(left, name.Value)
+		case *object.Struct:
+			return evalStructTypeMember(left, name.Value)
+		case *object.StructInstance:
+			return evalStructMember(left, name.Value)
+		case object.Members:
+			if val, ok := left.Member(name.Value); ok {
+				return val
+			}
+			return newError("%s has no field or method %q", left.Type(), name.Value)
+		}
+	}
+	if res, ok := callMethod(left, "index", index); ok {
+		return res
+	}
+	return newError("index operator not supported: %s[%s]", left.Type(), index.Type())
+}
+
+// evalAssignmentExpression rebinds identifiers or sets list and map elements
+// compound assignments like += apply their operator to the current value first
+func (e *Evaluator) evalAssignmentExpression(node *ast.AssignmentExpression) object.Object {
+	val := e.Eval(node.Value)
+	if isError(val) {
+		return val
+	}
+	op := strings.TrimSuffix(node.Token.Literal, "=")
+
+	switch left := node.Left.(type) {
+	case *ast.Identifier:
+		cur, ok := e.env.Get(left.Value)
+		if !ok {
+			return newError("identifier not found: %s", left.Value)
+		}
+		if e.env.IsImmutable(left.Value) {
+			return newError("%q is immutable and cannot be reassigned", left.Value)
+		}
+		if op != "" {
+			val = e.evalInfix(op, cur, val)
+			if isError(val) {
+				return val
+			}
+		}
+		if typ := e.env.TypeOf(left.Value); typ != nil {
+			if errObj := e.checkType(typ, val, fmt.Sprintf("%q", left.Value), left.Token.Span); errObj != nil {
+				return errObj
+			}
+		}
+		e.env.Assign(left.Value, val)
+		return NULL
+	case *ast.IndexExpression:
+		obj := e.Eval(left.Left)
+		if isError(obj) {
+			return obj
+		}
+		if errObj := e.checkOwner(obj); errObj != nil {
+			return errObj
+		}
+		giveOwner(val, ownerOf(obj))
+		if slice, ok := symbolSlice(left, obj); ok {
+			return e.evalSliceAssignment(obj, slice, op, val)
+		}
+		index := e.Eval(left.Index)
+		if isError(index) {
+			return index
+		}
+		if op != "" {
+			cur := e.Eval(left)
+			if isError(cur) {
+				return cur
+			}
+			val = e.evalInfix(op, cur, val)
+			if isError(val) {
+				return val
+			}
+		}
+		return setIndex(obj, index, val)
+	case *ast.SliceExpression:
+		obj := e.Eval(left.Left)
+		if isError(obj) {
+			return obj
+		}
+		if errObj := e.checkOwner(obj); errObj != nil {
+			return errObj
+		}
+		giveOwner(val, ownerOf(obj))
+		return e.evalSliceAssignment(obj, left, op, val)
+	}
+	return newError("cannot assign to %T", node.Left)
+}
+
+// setIndex sets the element of a list or map at index to val
+func setIndex(obj, index, val object.Object) object.Object {
+	switch obj := obj.(type) {
+	case *object.List:
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return newError("list index must be an INTEGER, got %s", index.Type())
+		}
+		var result object.Object = NULL
+		obj.Update(func(elements []object.Object) []object.Object {
+			i, ok := normalizeIndex(idx.Value, int64(len(elements)))
+			if !ok {
+				result = newError("index out of range: %d", idx.Value)
+				return elements
+			}
+			elements[i] = val
+			return elements
+		})
+		return result
+	case *object.Map:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as a map key: %s", index.Type())
+		}
+		obj.Set(key, val)
+		return NULL
+	case *object.ConcurrentMap:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as a map key: %s", index.Type())
+		}
+		obj.Set(key, val)
+		return NULL
+	case *object.StructInstance:
+		return setStructField(obj, index, val)
+	case object.Members:
+		name, ok := index.(*object.String)
+		if !ok {
+			return newError("field name must be a STRING, got %s", index.Type())
+		}
+		if err := obj.SetMember(name.Value, val); err != nil {
+			return newError("%s", err)
+		}
+		return NULL
+	}
+	return newError("index assignment not supported: %s[%s]", obj.Type(), index.Type())
+}
+
+// newError returns a new error object with the formatted message
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
+
+// isError returns true if the object is an error object
+func isError(obj object.Object) bool {
+	if obj != nil {
+		return obj.Type() == object.ERROR_OBJ
+	}
+	return false
+}
+
+// isReturnValue returns true if the object is a return value object
+func isReturnValue(obj object.Object) bool {
+	if obj != nil {
+		return obj.Type() == object.RETURN_VALUE_OBJ
+	}
+	return false
+}
+
+// isTruthy returns false for null and false, everything else is true
+func isTruthy(obj object.Object) bool {
+	switch obj {
+	case NULL, FALSE:
+		return false
+	default:
+		return true
+	}
+}
+
+// nativeToBooleanObject returns the TRUE or FALSE object for the given bool
+func nativeToBooleanObject(input bool) *object.Boolean {
+	if input {
+		return TRUE
+	}
+	return FALSE
+}
@@ -0,0 +1,1271 @@
+package evaluator
+
+import (
+	"blue/lexer"
+	"blue/object"
+	"blue/parser"
+	"blue/token"
+	"bytes"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEval parses and evaluates the input and returns the resulting object
+func testEval(t *testing.T, input string) object.Object {
+	l := lexer.New(input, "<string>")
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser had errors for %q: %v", input, p.Errors())
+	}
+	e := New()
+	return e.Eval(program)
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf("object is not Integer. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%d, want=%d", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.String)
+	if !ok {
+		t.Errorf("object is not String. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%q, want=%q", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
+	result, ok := obj.(*object.Boolean)
+	if !ok {
+		t.Errorf("object is not Boolean. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%t, want=%t", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func testErrorObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("object is not Error. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Message != expected {
+		t.Errorf("wrong error message. got=%q, want=%q", result.Message, expected)
+		return false
+	}
+	return true
+}
+
+func TestEvalIntegerExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"5", 5},
+		{"-5", -5},
+		{"5 + 5 + 5 + 5 - 10", 10},
+		{"2 * (5 + 10)", 30},
+		{"7 // 2", 3},
+		{"-7 // 2", -4},
+		{"-7 % 3", 2},
+		{"2 ** 10", 1024},
+		{"1 << 4", 16},
+		{"0xff & 0b1010", 10},
+		{"~0", -1},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestRangeMembershipEvaluatesBoundsOnce(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2 in lo()..3", "true"},
+		{"5 not in lo()..<5", "true"},
+		{"2.0 in lo()..3", "true"},
+		{"2 in lo()..3.5", "EvaluatorError: unknown operator: FLOAT .. FLOAT"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New("var calls = 0\nfun lo() { calls += 1; 1 }\n"+tt.input, "<string>"))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser had errors for %q: %v", tt.input, p.Errors())
+		}
+		e := New()
+		if result := e.Eval(program); result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+		if calls, _ := e.Get("calls"); calls.Inspect() != "1" {
+			t.Errorf("%s: the bounds were evaluated %s times", tt.input, calls.Inspect())
+		}
+	}
+}
+
+func TestEvalStoredRanges(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"val r = 1..1000000000000; [5 in r, 0 in r, 1000000000000 in r, 1000000000001 in r]", "[true, false, true, false]"},
+		{"val r = 1..<1000000000000; [1000000000000 in r, 999999999999 not in r, (2 ** 70) in r]", "[false, false, false]"},
+		{"val r = 10..1; [10 in r, 1 in r, 0 in r, 11 in r]", "[true, true, false, false]"},
+		{"val r = 5..<1; [5 in r, 2 in r, 1 in r]", "[true, true, false]"},
+		{"val r = 1..1000000000000; [len(r), r[0], r[-1], r[999]]", "[1000000000000, 1, 1000000000000, 1000]"},
+		{"fun upto(r) { var n = 0; for (x in r) { if (x > 3) { return n }; n += x }; n }\nval r = 1..1000000000000; upto(r)", "6"},
+		{"val r = 1..3; [2.0 in r, 2.5 in r, \"2\" in r]", "[true, false, false]"},
+		{"var r = 1..3; r[0] = 7; [r, 7 in r, 1 in r]", "[[7, 2, 3], true, false]"},
+		{"var r = 1..3; r[1:] = [9]; [r, 9 in r, 3 in r]", "[[1, 9], true, false]"},
+	}
+
+	for _, tt := range tests {
+		start := time.Now()
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s: took %s", tt.input, elapsed)
+		}
+	}
+}
+
+func TestEvalNumericTower(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedType object.Type
+		expected     string
+	}{
+		{"9223372036854775807 + 1", object.BIG_INTEGER_OBJ, "9223372036854775808"},
+		{"(9223372036854775807 + 1) - 1", object.INTEGER_OBJ, "9223372036854775807"},
+		{"-9223372036854775807 - 2", object.BIG_INTEGER_OBJ, "-9223372036854775809"},
+		{"4611686018427387904 * 2", object.BIG_INTEGER_OBJ, "9223372036854775808"},
+		{"2 ** 100", object.BIG_INTEGER_OBJ, "1267650600228229401496703205376"},
+		{"(2 ** 100) // (2 ** 98)", object.INTEGER_OBJ, "4"},
+		{"-(2 ** 100) // 3", object.BIG_INTEGER_OBJ, "-422550200076076467165567735126"},
+		{"(2 ** 100) % 7", object.INTEGER_OBJ, "2"},
+		{"1 << 64", object.BIG_INTEGER_OBJ, "18446744073709551616"},
+		{"(1 << 64) >> 60", object.INTEGER_OBJ, "16"},
+		{"0xffff_ffff_ffff_ffff", object.BIG_INTEGER_OBJ, "18446744073709551615"},
+		{"0x1_0000_0000_0000_0000 - 1", object.BIG_INTEGER_OBJ, "18446744073709551615"},
+		{"1 / 3", object.RATIONAL_OBJ, "1/3"},
+		{"6 / 3", object.INTEGER_OBJ, "2"},
+		{"1 / 3 + 1 / 6", object.RATIONAL_OBJ, "1/2"},
+		{"(1 / 3) * 3", object.INTEGER_OBJ, "1"},
+		{"0.75r", object.RATIONAL_OBJ, "3/4"},
+		{"3r", object.INTEGER_OBJ, "3"},
+		{"2 ** -2", object.RATIONAL_OBJ, "1/4"},
+		{"1 ** 100000000000", object.INTEGER_OBJ, "1"},
+		{"(-1) ** 100000000001", object.INTEGER_OBJ, "-1"},
+		{"len(str(2 ** 100000))", object.INTEGER_OBJ, "30103"},
+		{"(2 / 3) ** 2", object.RATIONAL_OBJ, "4/9"},
+		{"7r / 2 // 1", object.INTEGER_OBJ, "3"},
+		{"7r / 2 % 1", object.RATIONAL_OBJ, "1/2"},
+		{"1 / 4 + 0.5", object.FLOAT_OBJ, "0.75"},
+		{"-(9223372036854775807 + 1) - 1 + 1", object.INTEGER_OBJ, "-9223372036854775808"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Type() != tt.expectedType {
+			t.Errorf("%s: wrong type. got=%s, want=%s", tt.input, result.Type(), tt.expectedType)
+			continue
+		}
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEvalDecimals(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedType object.Type
+		expected     string
+	}{
+		{"0.1d + 0.2d", object.DECIMAL_OBJ, "0.3"},
+		{"19.99d * 3", object.DECIMAL_OBJ, "59.97"},
+		{"10.00d / 4", object.DECIMAL_OBJ, "2.50"},
+		{"1d / 3", object.DECIMAL_OBJ, "0.3333333333333333333333333333"},
+		{"val decimal_opts = {\"precision\": 2}; 2d / 3", object.DECIMAL_OBJ, "0.67"},
+		{"val decimal_opts = {\"precision\": 0, \"rounding\": \"half_even\"}; 5d / 2", object.DECIMAL_OBJ, "2"},
+		{"val decimal_opts = {\"precision\": 0, \"rounding\": \"half_up\"}; 5d / 2", object.DECIMAL_OBJ, "3"},
+		{"val decimal_opts = {\"precision\": 1, \"rounding\": \"floor\"}; -1d / 3", object.DECIMAL_OBJ, "-0.4"},
+		{"7.5d // 2", object.DECIMAL_OBJ, "3"},
+		{"-7.5d % 2", object.DECIMAL_OBJ, "0.5"},
+		{"1.1d ** 2", object.DECIMAL_OBJ, "1.21"},
+		{"2d ** -2", object.DECIMAL_OBJ, "0.25"},
+		{"-1.50d", object.DECIMAL_OBJ, "-1.50"},
+		{"0.5d + 1 / 3", object.RATIONAL_OBJ, "5/6"},
+		{"decimal(\"-19.99\") + 1", object.DECIMAL_OBJ, "-18.99"},
+		{"decimal(0.1)", object.DECIMAL_OBJ, "0.1"},
+		{"decimal(1 / 8)", object.DECIMAL_OBJ, "0.125"},
+		{"int(-19.99d)", object.INTEGER_OBJ, "-19"},
+		{"float(19.99d)", object.FLOAT_OBJ, "19.99"},
+		{"str(0.50d)", object.STRING_OBJ, "0.50"},
+		{"round(2.675d, 2)", object.DECIMAL_OBJ, "2.68"},
+		{"round(2.665d, 2)", object.DECIMAL_OBJ, "2.66"},
+		{"round(2.665d, 2, \"half_up\")", object.DECIMAL_OBJ, "2.67"},
+		{"round(1 / 3, 4)", object.DECIMAL_OBJ, "0.3333"},
+		{"round(2.675, 2, \"half_up\")", object.FLOAT_OBJ, "2.68"},
+		{"{1.50d: 1}[1.5d]", object.INTEGER_OBJ, "1"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Type() != tt.expectedType {
+			t.Errorf("%s: wrong type. got=%s, want=%s", tt.input, result.Type(), tt.expectedType)
+			continue
+		}
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEvalComplexNumbers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"3i", "(0+3i)"},
+		{"1 + 2i", "(1+2i)"},
+		{"(1 + 2i) * (3 - 1i)", "(5+5i)"},
+		{"1i ** 2", "(-1+0i)"},
+		{"(1 + 1i) / 1i", "(1-1i)"},
+		{"-3i", "(0-3i)"},
+		{"complex(1, 2)", "(1+2i)"},
+		{"conj(1 + 2i)", "(1-2i)"},
+		{"real(1 + 2i) + imag(1 + 2i)", "3"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEvalNumericKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1d in [1, 2]", "true"},
+		{"1d in {1, 2}", "true"},
+		{"1.0 in {1, 2}", "true"},
+		{"(1 + 0i) in {1, 2}", "true"},
+		{"0.5d in {1 / 2, 1}", "true"},
+		{"(2 ** 64) in {18446744073709551616.0, 1.5}", "true"},
+		{"(2 ** 60 + 1) in {2 ** 60, 1}", "false"},
+		{"(1 + 1i) in {1, 2}", "false"},
+		{"len({1, 1.0, 1d, 1.00d})", "1"},
+		{"{1: \"a\"}[1d]", "a"},
+		{"{1: \"a\"}[1.0]", "a"},
+		{"{0: \"a\"}[-0.0]", "a"},
+		{"{1.5: \"a\"}[3 / 2]", "a"},
+		{"{1d: 1} == {1: 1}", "true"},
+		{"{1.0, 2} == {1d, 2}", "true"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEvalBooleanExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1 < 2", true},
+		{"1 == 1.0", true},
+		{"\"a\" == \"a\"", true},
+		{"[1, 2] == [1, 2]", true},
+		{"not true", false},
+		{"true and false", false},
+		{"false or true", true},
+		{"2 in [1, 2, 3]", true},
+		{"\"ell\" in \"hello\"", true},
+		{"\"a\" in {\"a\": 1}", true},
+		{"4 not in [1, 2, 3]", true},
+		{"2 not in [1, 2, 3]", false},
+		{"2 in {1, 2, 3}", true},
+		{"[1] in {1, 2}", false},
+		{"\"b\" not in {\"a\": 1}", true},
+		{"\"xyz\" not in \"hello\"", true},
+		{"5 in 1..5", true},
+		{"5 in 1..<5", false},
+		{"0 not in 1..5", true},
+		{"3 in 5..1", true},
+		{"1 in 5..<1", false},
+		{"2.0 in 1..3", true},
+		{"1000000 in 0..100000000000", true},
+		{"1 / 3 == 2 / 6", true},
+		{"1 / 3 < 0.34", true},
+		{"2 ** 64 > 2 ** 63", true},
+		{"2 ** 64 == 18446744073709551616.0", true},
+		{"0.1d + 0.2d == 0.3d", true},
+		{"1.50d == 1.5d", true},
+		{"2.00d == 2", true},
+		{"0.5d < 1 / 3", false},
+		{"0.1d == 0.1", true},
+		{"1 + 2i == 1 + 2i", true},
+		{"1 + 0i == 1", true},
+		{"1i == 1", false},
+		{"1e3 == 1000", true},
+		{":a == :a", true},
+		{":a == \"a\"", false},
+		{":a != :b", true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"val x = 5; x", 5},
+		{"var x = 5; x += 2; x", 7},
+		{"fun add(x, y) { x + y }\nadd(1, 2)", 3},
+		{"fun add(x, y = 10) { return x + y; }\nadd(1)", 11},
+		{"fun add(x, y = 10) { x + y }\nadd(1, y = 2)", 3},
+		{"val f = |x| => { x * 2 }; f(4)", 8},
+		{"fun adder(x) { fun(y) { x + y } }\nadder(2)(3)", 5},
+		{"if (1 > 2) { 1 } else { 2 }", 2},
+		{"var total = 0; for (x in 1..4) { total += x; }; total", 10},
+		{"var i = 0; for (i < 5) { i += 1; }; i", 5},
+		{"val x = match 2 { 1 => { 10 }, 2 => { 20 }, _ => { 30 }, }; x", 20},
+		{"val x = match 2 { 2 => { 20 }, _ => { 30 }, }\nx", 20},
+		{"var xs = [1, 2, 3]; xs[1] = 5; xs[1]", 5},
+		{"val name = \"n\"; val m = {name: 1}; m.n", 1},
+		{"len([x for (x in 1..10) if (x % 2 == 0)])", 5},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalSymbols(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{":idle", ":idle"},
+		{"type(:idle)", "SYMBOL"},
+		{"val m = {:idle: \"waiting\"}; m[:idle]", "waiting"},
+		{"var s = :running\nmatch s { :idle => { \"a\" }, :running => { \"b\" }, _ => { \"c\" }, }", "b"},
+		{"[:a, :b]", "[:a, :b]"},
+		{"{:a, :b}", "{:a, :b}"},
+		{"val v = 1; val m = {\"k\" :v, \"j\": :w}; [m.k, m.j]", "[1, :w]"},
+		{"fun f(x) {\n  if (x) { return :a }\n  :b\n}\n[f(true), f(false)]", "[:a, :b]"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+
+	if testEval(t, ":idle") != testEval(t, "symbol(\"idle\")") {
+		t.Errorf("symbols are not interned")
+	}
+}
+
+func TestEvalEnums(t *testing.T) {
+	shape := "enum Shape { Circle(r), Rect(w, h), Empty }\n" +
+		"fun area(s) { match s { Shape.Circle(r) => { 3 * r * r }, Shape.Rect(w, h) => { w * h }, Shape.Empty => { 0 }, } }\n"
+	color := "enum Color { Red, Green, Blue }\n"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{shape + "area(Shape.Circle(2))", "12"},
+		{shape + "area(Shape.Rect(h = 5, w = 2))", "10"},
+		{shape + "area(Shape.Empty)", "0"},
+		{shape + "Shape.Rect(1, \"a\")", "Shape.Rect(1, \"a\")"},
+		{shape + "Shape.Rect(1, 2).h", "2"},
+		{shape + "type(Shape.Empty)", "Shape"},
+		{shape + "Shape", "enum Shape { Circle(r), Rect(w, h), Empty }"},
+		{shape + "Shape.Circle(1) == Shape.Circle(1)", "true"},
+		{shape + "Shape.Circle(1) == Shape.Circle(2)", "false"},
+		{shape + "match Shape.Circle(0) { Shape.Circle(0) => { \"zero\" }, _ => { \"other\" }, }", "zero"},
+		{color + "Color.values()", "[Color.Red, Color.Green, Color.Blue]"},
+		{color + "Color.from_name(\"Green\") == Color.Green", "true"},
+		{color + "Color.Blue.name", "Blue"},
+		{color + "{Color.Red: 1}[Color.Red]", "1"},
+		{color + "match Color.Blue { Color.Red => { 1 }, _ => { 2 }, }", "2"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEvalStructs(t *testing.T) {
+	point := "struct Point {\n" +
+		"  x,\n" +
+		"  y = 0,\n" +
+		"  fun norm_sq(self) { self.x * self.x + self.y * self.y }\n" +
+		"  fun move(self, dx, dy = 0) { self.x += dx; self.y += dy; self }\n" +
+		"}\n"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{point + "Point(3, 4)", "Point{x: 3, y: 4}"},
+		{point + "Point(x = 1)", "Point{x: 1, y: 0}"},
+		{point + "Point(3, 4).norm_sq()", "25"},
+		{point + "Point.norm_sq(Point(1, 1))", "2"},
+		{point + "val p = Point(1); p.y = 5; p.y", "5"},
+		{point + "Point(1).move(2, dy = 3)", "Point{x: 3, y: 3}"},
+		{point + "type(Point(1))", "Point"},
+		{point + "Point(1) == Point(1, 0)", "true"},
+		{point + "Point(1) == Point(2)", "false"},
+		{point + "{Point(1, 2): \"a\"}[Point(1, 2)]", "a"},
+		{point + "Point", "struct Point { x, y }"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEvalTraits(t *testing.T) {
+	shape := "trait Shape {\n" +
+		"  fun area(self)\n" +
+		"  fun describe(self) { \"area #{self.area()}\" }\n" +
+		"}\n" +
+		"struct Square { side }\n" +
+		"struct Circle { r }\n" +
+		"impl Shape for Square {\n" +
+		"  fun area(self) { self.side * self.side }\n" +
+		"}\n"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{shape + "Square(3).area()", "9"},
+		{shape + "Square(3).describe()", "area 9"},
+		{shape + "implements(Square(3), Shape)", "true"},
+		{shape + "implements(Circle(1), Shape)", "false"},
+		{shape + "impl Square { fun double(self) { Square(self.side * 2) } }\nSquare(2).double()", "Square{side: 4}"},
+		{shape + "impl Shape for Circle {\n  fun area(self) { 3 * self.r * self.r }\n  fun describe(self) { \"circle\" }\n}\nCircle(1).describe()", "circle"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEvalOperatorOverloading(t *testing.T) {
+	vec := "struct Vec {\n" +
+		"  x,\n" +
+		"  y,\n" +
+		"  fun add(self, o) { Vec(self.x + o.x, self.y + o.y) }\n" +
+		"  fun sub(self, o) { Vec(self.x - o.x, self.y - o.y) }\n" +
+		"  fun mul(self, k) { Vec(self.x * k, self.y * k) }\n" +
+		"  fun rmul(self, k) { Vec(self.x * k, self.y * k) }\n" +
+		"  fun neg(self) { Vec(-self.x, -self.y) }\n" +
+		"  fun equals(self, o) { self.x == o.x }\n" +
+		"  fun compare(self, o) { self.x - o }\n" +
+		"  fun to_string(self) { \"<#{self.x}, #{self.y}>\" }\n" +
+		"}\n"
+	bag := "struct Bag {\n" +
+		"  items,\n" +
+		"  fun len(self) { len(self.items) }\n" +
+		"  fun iter(self) { self.items }\n" +
+		"  fun contains(self, x) { x in self.items }\n" +
+		"  fun index(self, i) { self.items[i] }\n" +
+		"  fun set_index(self, i, v) { self.items[i] = v }\n" +
+		"}\n"
+	// every key has the same hash so maps and sets must tell them apart with equals
+	key := "struct Key {\n" +
+		"  v,\n" +
+		"  fun hash(self) { 0 }\n" +
+		"  fun equals(self, o) { self.v == o.v }\n" +
+		"}\n"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{vec + "Vec(1, 2) + Vec(3, 4)", "<4, 6>"},
+		{vec + "Vec(1, 2) - Vec(3, 4)", "<-2, -2>"},
+		{vec + "Vec(1, 2) * 3", "<3, 6>"},
+		{vec + "3 * Vec(1, 2)", "<3, 6>"},
+		{vec + "-Vec(1, 2)", "<-1, -2>"},
+		{vec + "Vec(1, 2) == Vec(1, 5)", "true"},
+		{vec + "Vec(1, 2) != Vec(1, 5)", "false"},
+		{vec + "Vec(1, 2) < 2", "true"},
+		{vec + "2 < Vec(1, 2)", "false"},
+		{vec + "Vec(3, 2) >= 3", "true"},
+		{vec + "str(Vec(1, 2))", "<1, 2>"},
+		{vec + "[Vec(1, 2)]", "[<1, 2>]"},
+		{vec + "Vec(1, 0) in [Vec(1, 9)]", "true"},
+		{vec + "var v = Vec(1, 1); v += Vec(1, 1); v", "<2, 2>"},
+		{bag + "len(Bag([1, 2, 3]))", "3"},
+		{bag + "2 in Bag([1, 2, 3])", "true"},
+		{bag + "4 in Bag([1, 2, 3])", "false"},
+		{bag + "4 not in Bag([1, 2, 3])", "true"},
+		{bag + "Bag([1, 2, 3])[1]", "2"},
+		{bag + "val b = Bag([1, 2, 3]); b[0] = 5; b.items", "[5, 2, 3]"},
+		{bag + "var total = 0; for (x in Bag([1, 2, 3])) { total += x }; total", "6"},
+		{key + "var m = {}; m[Key(1)] = \"one\"; m[Key(2)] = \"two\"; [m[Key(1)], m[Key(2)], len(m)]", "[\"one\", \"two\", 2]"},
+		{key + "var m = {}; m[Key(1)] = 1; m[Key(2)] = 2; m[Key(1)] = 3; [m[Key(1)], m[Key(2)], len(m)]", "[3, 2, 2]"},
+		{key + "val s = {Key(1), Key(2), Key(1)}; [len(s), Key(2) in s, Key(3) in s]", "[2, true, false]"},
+		{key + "{Key(1): 1, Key(2): 2} == {Key(2): 2, Key(1): 1}", "true"},
+		{"{1.5d: 1} == {1.50d: 1}", "true"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEvalSlices(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[0, 1, 2, 3, 4][1:3]", "[1, 2]"},
+		{"[0, 1, 2, 3, 4][:-1]", "[0, 1, 2, 3]"},
+		{"[0, 1, 2, 3, 4][::2]", "[0, 2, 4]"},
+		{"[0, 1, 2, 3, 4][::-1]", "[4, 3, 2, 1, 0]"},
+		{"[0, 1, 2, 3, 4][-2:]", "[3, 4]"},
+		{"[0, 1, 2, 3, 4][10:]", "[]"},
+		{"[0, 1, 2, 3, 4][-10:2]", "[0, 1]"},
+		{"[0, 1, 2, 3, 4][3:1]", "[]"},
+		{"[0, 1, 2, 3, 4][3:1:-1]", "[3, 2]"},
+		{"val n = 2; [0, 1, 2, 3, 4][:n]", "[0, 1]"},
+		{"val n = 2; [0, 1, 2, 3, 4][:n+1]", "[0, 1, 2]"},
+		{"val xs = [0, 1, 2, 3]; xs[:len(xs)-1]", "[0, 1, 2]"},
+		{"val n = 1; \"hello\"[n:n*3]", "el"},
+		{"val n = 2; [0, 1, 2, 3, 4][::n-1]", "[0, 1, 2, 3, 4]"},
+		{"val n = 2; [0, 1, 2, 3, 4][::n]", "[0, 2, 4]"},
+		{"[0, 1, 2][-1]", "2"},
+		{"\"héllo\"[1:3]", "él"},
+		{"\"hello\"[::-1]", "olleh"},
+		{"\"hello\"[-1]", "o"},
+		{"(1..10)[2:5]", "[3, 4, 5]"},
+		{"var xs = [0, 1, 2, 3]; xs[1:3] = [9]; xs", "[0, 9, 3]"},
+		{"var xs = [0, 1, 2, 3]; xs[::2] = [7, 8]; xs", "[7, 1, 8, 3]"},
+		{"var xs = [0, 1, 2]; xs[:0] += [5]; xs", "[5, 0, 1, 2]"},
+		{"var xs = [0, 1, 2]; xs[-1] = 9; xs", "[0, 1, 9]"},
+		{"var xs = [0, 1, 2]; val n = 2; xs[:n] = [7]; xs", "[7, 2]"},
+		{"var xs = [0, 1, 2]; val n = 1; xs[:n] += [5]; xs", "[0, 5, 1, 2]"},
+		{"val m = {:n: 1}; m[:n] = 2; m[:n]", "2"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEvalOptionalChaining(t *testing.T) {
+	cfg := "val cfg = {\"user\": {\"address\": {\"city\": \"Paris\"}}, \"f\": null}\n"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{cfg + "cfg?.user?.address?.city", "Paris"},
+		{cfg + "cfg.missing?.address?.city", "null"},
+		{cfg + "cfg?[\"user\"]?[\"name\"] ?? \"anon\"", "anon"},
+		{cfg + "cfg.f?.(1)", "null"},
+		{cfg + "cfg.f?[1:]", "null"},
+		{"val f = fun(x) { x + 1 }; f?.(1)", "2"},
+		{"null?.foo(1)", "null"},
+		{"val u = null; u?.f()", "null"},
+		{"val u = null; u?.a.b", "null"},
+		{"val u = null; u?.a.b(1).c", "null"},
+		{"val u = null; u?.a[0][1:]", "null"},
+		{"val u = null; u?[\"a\"].b", "null"},
+		{"val u = null; u?.a.b ?? \"none\"", "none"},
+		{cfg + "cfg?.user.address.city", "Paris"},
+		{cfg + "len(cfg?.user.address.city)", "5"},
+		{"struct P { x, fun double(self) { self.x * 2 } }\nval p = P(2); val q = null; [p?.double(), q?.double()]", "[4, null]"},
+		{"null ?? 1 + 2", "3"},
+		{"false ?? 1", "false"},
+		{"null ?? null ?? 3", "3"},
+		{"1 ?? missing", "1"},
+		{"val empty? = fun(xs) { len(xs) == 0 }; empty?([])", "true"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEvalPipeline(t *testing.T) {
+	helpers := "fun filter(xs, f) { var out = []; for (x in xs) { if (f(x)) { out = append(out, x) } }; out }\n" +
+		"fun map(xs, f) { var out = []; for (x in xs) { out = append(out, f(x)) }; out }\n" +
+		"fun sum(xs) { var t = 0; for (x in xs) { t += x }; t }\n" +
+		"fun is_even(x) { x % 2 == 0 }\n" +
+		"fun double(x) { x * 2 }\n"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{helpers + "[1, 2, 3, 4] |> filter(is_even) |> map(double) |> sum()", "12"},
+		{helpers + "([1, 2, 3] |> len()) == 3", "true"},
+		{helpers + "2 == 2 |> double", "EvaluatorError: type mismatch: BOOLEAN * INTEGER"},
+		{helpers + "1 + 2 |> double", "6"},
+		{helpers + "[1, 2] |> map(|x| => { x + 1 })", "[2, 3]"},
+		{helpers + "[1, 2] |> fun(xs) { len(xs) }", "2"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEvalGenerators(t *testing.T) {
+	count := "fun count(start) {\n" +
+		"  var i = start\n" +
+		"  for (true) { yield i; i += 1 }\n" +
+		"}\n"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{count + "val g = count(1); [next(g), next(g), next(g)]", "[1, 2, 3]"},
+		{count + "type(count(1))", "GENERATOR"},
+		{count + "list(take(count(10), 3))", "[10, 11, 12]"},
+		{count + "list(skip(take(count(0), 6), 4))", "[4, 5]"},
+		{count + "list(zip(count(0), \"abc\"))", "[[0, \"a\"], [1, \"b\"], [2, \"c\"]]"},
+		{count + "list(take(enumerate(count(5), 1), 2))", "[[1, 5], [2, 6]]"},
+		{count + "[x * 2 for (x in take(count(1), 3))]", "[2, 4, 6]"},
+		{count + "fun first_over(n) { for (x in count(0)) { if (x > n) { return x } } }\nfirst_over(5)", "6"},
+		{"list(chunk(1..7, 3))", "[[1, 2, 3], [4, 5, 6], [7]]"},
+		{"list(window(1..5, 3))", "[[1, 2, 3], [2, 3, 4], [3, 4, 5]]"},
+		{"list(window([1], 3))", "[]"},
+		{"list(enumerate([\"a\", \"b\"]))", "[[0, \"a\"], [1, \"b\"]]"},
+		{"fun ab() { yield \"a\"; yield \"b\" }\nvar s = \"\"; for (x in ab()) { s += x }; s", "ab"},
+		{"fun ab() { yield \"a\"; yield \"b\" }\nval g = ab(); [next(g), next(g), next(g), next(g, 0)]", "[\"a\", \"b\", null, 0]"},
+		{"fun early() { yield 1; return 5; yield 2 }\nlist(early())", "[1]"},
+		{"fun bare() { yield }\nlist(bare())", "[null]"},
+		{"struct Nums { n, fun iter(self) { take(enumerate([1, 2, 3]), self.n) } }\nlist(Nums(2))", "[[0, 1], [1, 2]]"},
+		{count + "val g = count(1); next(g); close(g); [next(g), next(g, 0)]", "[null, 0]"},
+		{count + "val g = take(count(1), 5); next(g); close(g); next(g)", "null"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestGeneratorGoroutines(t *testing.T) {
+	count := "fun count(start) {\n" +
+		"  var i = start\n" +
+		"  for (true) { yield i; i += 1 }\n" +
+		"}\n"
+	tests := []string{
+		count + "val g = count(1); next(g); close(g)",
+		count + "val g = count(1); close(g)",
+		count + "scope { val g = count(1); next(g) }",
+		count + "scope { val g = zip(count(1), count(2)); next(g) }",
+	}
+
+	for _, input := range tests {
+		before := runtime.NumGoroutine()
+		testEval(t, input)
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if n := runtime.NumGoroutine(); n > before {
+			t.Errorf("%s: generator goroutines were not released. before=%d, after=%d", input, before, n)
+		}
+	}
+}
+
+func TestEvalMacros(t *testing.T) {
+	unless := "macro unless(cond, body) {\n" +
+		"  quote { if (not unquote(cond)) { unquote(body) } }\n" +
+		"}\n"
+	twice := "macro twice(x) {\n" +
+		"  quote { val result = unquote(x); result + result }\n" +
+		"}\n"
+	repeat := "macro repeat(n, body) {\n" +
+		"  quote { for (i in 1..unquote(n)) { unquote(body) } }\n" +
+		"}\n"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"quote { 1 + 2 }", "quote((1 + 2))"},
+		{"val x = 5; quote { unquote(x) * 2 }", "quote((5 * 2))"},
+		{"val q = quote { a + b }; quote { unquote(q) - c }", "quote(((a + b) - c))"},
+		{"quote { unquote([1, \"a\", :b, true, null]) }", "quote([1, \"a\", :b, true, null])"},
+		{"type(quote { 1 })", "QUOTE"},
+		{unless + "unless(1 > 2, \"ran\")", "ran"},
+		{unless + "unless(2 > 1, \"ran\")", "null"},
+		{twice + "val result = 10; [twice(result + 1), result]", "[22, 10]"},
+		{repeat + "var i = 5; var total = 0\nfun add(n) { total += n }\nrepeat(3, add(i)); [i, total]", "[5, 15]"},
+		{"macro ignore(x) { quote { 1 } }\nignore(not_defined)", "1"},
+		{"macro double(x) { quote { unquote(x) * 2 } }\nmacro quadruple(x) { quote { double(double(unquote(x))) } }\nquadruple(3)", "12"},
+		{"macro double(x) { quote { unquote(x) * 2 } }\nfun f(n) { double(n) + 1 }\nf(4)", "9"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEvalDecorators(t *testing.T) {
+	memo := "fun memo(f) {\n" +
+		"  val cache = {}\n" +
+		"  return |n| => { if (n in cache) { return cache[n] }; val v = f(n); cache[n] = v; v }\n" +
+		"}\n"
+	retry := "fun retry(times=1) {\n" +
+		"  return |f| => { |x| => {\n" +
+		"    var result = null; var attempt = 0\n" +
+		"    for (result == null and attempt < times) { attempt += 1; result = f(x) }\n" +
+		"    result\n" +
+		"  } }\n" +
+		"}\n"
+	tag := "fun tag(t) { |f| => { |x| => { \"<\" + t + \">\" + f(x) } } }\n"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{memo + "var calls = 0\n@memo\nfun fib(n) { calls += 1; if (n < 2) { return n }; fib(n - 1) + fib(n - 2) }\nval r = fib(30); [r, calls]", "[832040, 31]"},
+		{retry + "var tries = 0\n@retry(times=3)\nfun flaky(x) { tries += 1; if (tries < 3) { return null }; x * 2 }\nval r = flaky(21); [r, tries]", "[42, 3]"},
+		{retry + "var tries = 0\n@retry()\nfun flaky(x) { tries += 1; null }\nval r = flaky(21); [r, tries]", "[null, 1]"},
+		{"val routes = {}\nfun route(path) { |f| => { routes[path] = f; f } }\n@route(\"/hello\")\nfun hello() { \"hi\" }\nroutes[\"/hello\"]()", "hi"},
+		{tag + "@tag(\"a\")\n@tag(\"b\")\nfun f(x) { x }\nf(\"x\")", "<a><b>x"},
+		{tag + "@tag(\"a\") fun f(x) { x }\nf(\"x\")", "<a>x"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEvalTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fun add(a: int, b: int) -> int { a + b }\nadd(1, 2)", "3"},
+		{"fun add(a : int, b :int) -> int { a + b }\nadd(1, 2)", "3"},
+		{"fun f(x: int | none = null) -> str { \"#{x}\" }\n[f(), f(1)]", "[\"null\", \"1\"]"},
+		{"val name: str = \"blue\"; name", "blue"},
+		{"var n: num = 1; n = 2.5; n += 1; n", "3.5"},
+		{"val xs: list[str] = [\"a\", \"b\"]; len(xs)", "2"},
+		{"val m: map[str, list[int]] = {\"a\": [1], \"b\": []}; len(m)", "2"},
+		{"val s: set[int] = {1, 2}; len(s)", "2"},
+		{"val f: fun = len; f([1])", "1"},
+		{"val x: any = :sym; x", ":sym"},
+		{"struct P { x }\nfun getx(p: P) -> int { p.x }\ngetx(P(4))", "4"},
+		{"enum Color { Red, Green }\nval c: Color = Color.Red; c", "Color.Red"},
+		{"trait Named { fun name(self) }\nstruct Dog { n, fun name(self) { self.n } }\nimpl Named for Dog {}\nfun greet(x: Named) -> str { x.name() }\ngreet(Dog(\"rex\"))", "rex"},
+		{"fun count() -> generator { yield 1 }\nlist(count())", "[1]"},
+		{"var n: int = 1; var n = \"a\"; n", "a"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestTypeErrorSpans(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		span     token.Span
+	}{
+		{"fun add(a: int, b: int) { a + b }\nadd(1, \"x\")", "type error: parameter \"b\" must be int, got STRING", token.Span{Start: 19, End: 22}},
+		{"fun f() -> int { \"x\" }\nf()", "type error: return value must be int, got STRING", token.Span{Start: 11, End: 14}},
+		{"val xs: list[int] = [1, \"a\"]", "type error: \"xs\" must be list[int], got LIST", token.Span{Start: 8, End: 17}},
+		{"var n: int | none = 1; n = 1.5", "type error: \"n\" must be int | none, got FLOAT", token.Span{Start: 23, End: 24}},
+		{"fun f(x: int) { x = \"a\" }\nf(1)", "type error: \"x\" must be int, got STRING", token.Span{Start: 16, End: 17}},
+		{"val x: Missing = 1", "type error: unknown type Missing", token.Span{Start: 7, End: 14}},
+		{"val x: list[int, int] = []", "type error: list takes 1 element types, got 2", token.Span{Start: 7, End: 21}},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		errObj, ok := result.(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned. got=%T(%+v)", tt.input, result, result)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("%s: wrong error message. got=%q, want=%q", tt.input, errObj.Message, tt.expected)
+		}
+		if errObj.Span == nil || *errObj.Span != tt.span {
+			t.Errorf("%s: wrong error span. got=%v, want=%s", tt.input, errObj.Span, tt.span)
+		}
+	}
+}
+
+func TestEvalConcurrency(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fun sq(n) { n * n }\nawait spawn sq(4)", "16"},
+		{"fun sq(n) { n * n }\nawait [spawn sq(i) for (i in 1..4)]", "[1, 4, 9, 16]"},
+		{"fun add(a, b=1) { a + b }\nawait spawn add(1, b=10)", "11"},
+		{"await spawn fun() { \"lambda\" }", "lambda"},
+		{"type(spawn fun() { 1 })", "TASK"},
+		{"val t = spawn fun() { 1 }; await t; t", "<task done>"},
+		{"val ch = chan()\nspawn fun() { for (i in 1..3) { send(ch, i) }; close(ch) }\nvar total = 0; for (m in ch) { total += m }; total", "6"},
+		{"val ch = chan(2); send(ch, 1); send(ch, 2); close(ch); [recv(ch), recv(ch), recv(ch)]", "[1, 2, null]"},
+		{"val ch = chan(2); send(ch, :a); ch", "<channel 1/2>"},
+		{"val ch = chan()\nspawn fun() { send(ch, \"hi\") }\nselect { msg = recv(ch) => { msg + \"!\" }, }", "hi!"},
+		{"val ch = chan()\nselect { recv(ch) => { 1 }, timeout(10) => { \"timeout\" }, }", "timeout"},
+		{"val ch = chan()\nselect { recv(ch) => { 1 }, _ => { \"default\" }, }", "default"},
+		{"val ch = chan(1)\nselect { send(ch, 5) => { recv(ch) }, _ => { 0 }, }", "5"},
+		{"val ch = chan(); close(ch)\nselect { x = recv(ch) => { x }, }", "null"},
+		{"var n = 0\nval ts = [spawn fun() { n } for (i in 1..10)]\nlen(await ts)", "10"},
+		{"scope { spawn fun() { 1 }; spawn fun() { 2 }; 3 }", "3"},
+		{"val ch = chan(10)\nscope { for (i in 1..3) { spawn fun() { sleep(10); send(ch, i) } } }\nclose(ch); var n = 0; for (m in ch) { n += m }; n", "6"},
+		{"val ch = chan(10)\nscope { spawn fun() { spawn fun() { sleep(10); send(ch, 1) } } }\nclose(ch); recv(ch)", "1"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEvalScopeCancellation(t *testing.T) {
+	blocking := []string{
+		"sleep(10000)",
+		"recv(chan())",
+		"send(chan(), 1)",
+		"select { recv(chan()) => { 1 }, }",
+		"receive { :never => { 1 }, }",
+		"await spawn sleep(10000)",
+		"for (true) { 1 }",
+		"for (x in chan()) { x }",
+		"`sleep 10`",
+	}
+
+	for _, b := range blocking {
+		input := "scope { spawn fun() { " + b + " }; spawn fun() { sleep(10); 1 + true } }"
+		start := time.Now()
+		result := testEval(t, input)
+		errObj, ok := result.(*object.Error)
+		if !ok || errObj.Message != "type mismatch: INTEGER + BOOLEAN" {
+			t.Errorf("%s: expected the error of the failed task, got=%s", input, result.Inspect())
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("%s: siblings were not cancelled, the scope took %s", input, elapsed)
+		}
+	}
+
+	start := time.Now()
+	result := testEval(t, "scope { spawn sleep(10000); 1 + true }")
+	if errObj, ok := result.(*object.Error); !ok || errObj.Message != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("expected the error of the scope body, got=%s", result.Inspect())
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("tasks were not cancelled when the scope body failed, the scope took %s", elapsed)
+	}
+}
+
+func TestEvalSharedState(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"val m = mutex(); var total = 0\nscope { for (i in 1..100) { spawn fun() { with m { total += i } } } }\ntotal", "5050"},
+		{"val m = mutex()\nfun f() { with m { return 1 }; 2 }\nf(); m", "<mutex unlocked>"},
+		{"val m = mutex(); lock(m); val s = str(m); unlock(m); [s, m]", "[\"<mutex locked>\", <mutex unlocked>]"},
+		{"val rw = rwmutex(); var x = 0\nscope { spawn fun() { with rw { x = 1 } }; spawn fun() { with read_lock(rw) { x } } }; [x, rw]", "[1, <rwmutex 0 readers>]"},
+		{"val n = atomic_int()\nscope { for (i in 1..100) { spawn fun() { add(n, 1) } } }\nload(n)", "100"},
+		{"val n = atomic_int()\nscope { for (i in 1..50) { spawn fun() { update(n, |x| => { x + 2 }) } } }\nn", "<atomic_int 100>"},
+		{"val n = atomic_int(5); [swap(n, 7), cas(n, 7, 8), cas(n, 7, 9), load(n)]", "[5, true, false, 8]"},
+		{"val r = atomic_ref([1]); [cas(r, [1], [2]), load(r), update(r, |xs| => { xs + [3] }), r]", "[true, [2], [2, 3], <atomic_ref [2, 3]>]"},
+		{"val cache = concurrent_map()\nscope { for (i in 1..20) { spawn fun() { cache[i % 5] = i; update(cache, \"count\", |c| => { (c ?? 0) + 1 }) } } }; [len(cache), cache[\"count\"], 3 in cache, delete(cache, 3), 3 in cache]", "[6, 20, true, true, false]"},
+		{"val m = concurrent_map(); m.a = 1; [m.a, m[\"b\"], [k for (k in m)]]", "[1, null, [\"a\"]]"},
+		{"val q = concurrent_queue(); push(q, 1); push(q, 2); [pop(q), len(q), pop(q), pop(q)]", "[1, 1, 2, null]"},
+		{"val xs = [1]; xs[0] = 2; xs", "[2]"},
+		{"var xs = [1]\nawait spawn fun() { xs[0] = 5 }\nxs", "[5]"},
+		{"fun mk() { val local = [1]; return local }\nvar got = await spawn mk(); got[0] = 5; got", "[5]"},
+		{"fun mk() { val local = {\"a\": [1]}; return [local] }\nval got = await spawn mk(); got[0].a[0] = 5; got", "[{\"a\": [5]}]"},
+		{"val ch = chan(1)\nawait spawn fun() { val xs = [1]; send(ch, xs) }\nval got = recv(ch); got[0] = 2; got", "[2]"},
+		{"val ch = chan(1)\nawait spawn fun() { val xs = [1]; send(ch, xs); close(ch) }\nvar got = []; for (xs in ch) { xs[0] = 3; got = xs }; got", "[3]"},
+		{"val pid = self_pid()\nawait spawn fun() { val m = {\"a\": 1}; send(pid, m) }\nreceive { m => { m.a = 2; m }, }", "{\"a\": 2}"},
+		{"var m = {}\nscope { for (i in 1..8) { spawn fun() { for (j in 1..500) { m[i * 1000 + j] = j } } } }\nlen(m)", "4000"},
+		{"var xs = [0, 0]\nscope { for (i in 1..8) { spawn fun() { for (j in 1..500) { xs[0] = j; xs[:1] = [j] } } } }\nlen(xs)", "2"},
+		{"fun count() { for (i in 1..1000) { yield i } }\nval g = count(); val ch = chan(1000)\nscope { for (i in 1..8) { spawn fun() { for (x in g) { send(ch, x) } } } }\nclose(ch); var total = 0; for (x in ch) { total += x }; total", "500500"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestEvalTimers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[200ms, 5s, 1.5m, 1h, 2s + 500ms, duration(\"1m30s\")]", "[200, 5000, 90000, 3600000, 2500, 90000]"},
+		{"val t = after(10ms, fun() { 42 }); await t", "42"},
+		{"val t = after(10ms, fun() { 42 }); await t; [t, cancel(t)]", "[<timer done, ran 1 times>, false]"},
+		{"var ran = false; val t = after(1h, fun() { ran = true }); [cancel(t), cancel(t), await t, ran, t]", "[true, false, null, false, <timer cancelled, ran 0 times>]"},
+		{"val n = atomic_int(); val t = every(5ms, fun() { add(n, 1) })\nfor (load(n) < 3) { sleep(1ms) }\ncancel(t); await t; load(n) >= 3", "true"},
+		{"var n = 0; val t = every(1ms, fun() { n += 1; if (n == 3) { 1 + true } }); await t", "EvaluatorError: type mismatch: INTEGER + BOOLEAN"},
+		{"var hits = []; scope { after(20ms, fun() { hits += [2] }); after(10ms, fun() { hits += [1] }) }; hits", "[1, 2]"},
+		{"with_timeout(1s, fun() { sleep(1ms); :done })", ":done"},
+		{"with_timeout(10ms, fun() { sleep(10s) })", "EvaluatorError: timed out after 10ms"},
+		{"with_timeout(10ms, fun() { for (true) { 1 } })", "EvaluatorError: timed out after 10ms"},
+		{"with_timeout(10ms, fun() { recv(chan()) })", "EvaluatorError: timed out after 10ms"},
+	}
+
+	for _, tt := range tests {
+		start := time.Now()
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("%s: took %s", tt.input, elapsed)
+		}
+	}
+}
+
+func TestEvalActors(t *testing.T) {
+	var log bytes.Buffer
+	supervisorLog = &log
+	defer func() { supervisorLog = os.Stderr }()
+	registry.Lock()
+	registry.names = map[string]*object.Pid{}
+	registry.Unlock()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`fun counter(n) {
+    receive {
+        [:add, x] => { counter(n + x) },
+        [:get, from] => { send(from, n); counter(n) },
+    }
+}
+val pid = spawn_actor(counter, 0)
+send(pid, [:add, 2]); send(pid, [:add, 3]); send(pid, [:get, self_pid()])
+receive { n => { n }, }`, "5"},
+		{"send(self_pid(), :b); send(self_pid(), :a)\nval first = receive { :a => { 1 }, }; [first, receive { x => { x }, }]", "[1, :b]"},
+		{"receive { _ => { 1 }, timeout(10) => { 2 }, }", "2"},
+		{"enum Msg { Add(n), Stop }\nsend(self_pid(), Msg.Stop); send(self_pid(), Msg.Add(4))\nreceive { Msg.Add(n) => { n * 2 }, }", "8"},
+		{"val pid = spawn_actor(fun() { 1 + true })\nmonitor(pid)\nreceive { [:down, p, reason] => { [p == pid, reason] }, }", "[true, \"type mismatch: INTEGER + BOOLEAN\"]"},
+		{"val pid = spawn_actor(fun() { 1 })\nmonitor(pid)\nreceive { [:down, _, reason] => { reason }, }", ":normal"},
+		{"trap_exits()\nval pid = spawn_actor(fun() { receive { :crash => { 1 + true }, } })\nlink(pid); send(pid, :crash)\nreceive { [:exit, _, reason] => { reason }, }", "type mismatch: INTEGER + BOOLEAN"},
+		{"val a = spawn_actor(fun() { receive { :never => { 1 }, } })\nmonitor(a)\nspawn_actor(fun(other) { link(other); 1 + true }, a)\nreceive { [:down, _, reason] => { reason }, }", "type mismatch: INTEGER + BOOLEAN"},
+		{"val a = spawn_actor(fun() { receive { :never => { 1 }, } })\nmonitor(a); kill(a)\nval reason = receive { [:down, _, r] => { r }, }; [reason, alive(a)]", "[:killed, false]"},
+		{"val a = spawn_actor(fun() { receive { [:ping, from] => { send(from, :pong) }, } })\nregister(:ponger, a); val found = whereis(:ponger) == a\nsend(:ponger, [:ping, self_pid()])\nreceive { r => { [r, found] }, }", "[:pong, true]"},
+		{`fun worker() {
+    register(:one_for_one, self_pid())
+    receive {
+        :crash => { 1 + true },
+        [:ping, from] => { send(from, :pong); worker() },
+    }
+}
+fun wait_for(old) { for (whereis(:one_for_one) == null or whereis(:one_for_one) == old) { receive { timeout(1) => { null }, } } }
+val sup = supervisor([worker])
+wait_for(null); val first = whereis(:one_for_one)
+send(:one_for_one, :crash)
+wait_for(first); send(:one_for_one, [:ping, self_pid()])
+receive { r => { [r, len(children(sup))] }, }`, "[:pong, 1]"},
+		{`val main = self_pid()
+fun a() { send(main, :a); receive { :crash => { 1 + true }, } }
+fun b() { send(main, :b); receive { :never => { 1 }, } }
+val sup = supervisor([a, b], {"strategy": :one_for_all})
+receive { :a => { 1 }, }; receive { :b => { 1 }, }
+send(children(sup)[0], :crash); [receive { :a => { :a }, timeout(1000) => { :none }, }, receive { :b => { :b }, timeout(1000) => { :none }, }]`, "[:a, :b]"},
+		{"val sup = supervisor([fun() { 1 + true }], {\"max_restarts\": 2, \"within\": 1000})\nmonitor(sup)\nreceive { [:down, _, reason] => { reason }, }", "supervisor reached its maximum restart intensity"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)
+		}
+	}
+	if !strings.Contains(log.String(), "crashed: type mismatch: INTEGER + BOOLEAN, restarting") {
+		t.Errorf("supervisor did not log the crash. got=%q", log.String())
+	}
+	if !strings.Contains(log.String(), "more than 2 restarts in 1s, shutting down") {
+		t.Errorf("supervisor did not log shutting down. got=%q", log.String())
+	}
+}
+
+func TestEvalStringInterpolation(t *testing.T) {
+	input := `val name = "blue"; val xs = [1, 2]; "hello #{name} #{xs}"`
+	testStringObject(t, testEval(t, input), "hello blue [1, 2]")
+}
+
+func TestErrorHandling(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"foobar", "identifier not found: foobar"},
+		{"5 + true", "type mismatch: INTEGER + BOOLEAN"},
+		{"val x = 1; x = 2", "\"x\" is immutable and cannot be reassigned"},
+		{"1 // 0", "division by zero"},
+		{"1d / 0", "division by zero"},
+		{"2 ** 100000000000", "exponent too large: 100000000000"},
+		{"(2 ** 100) ** 1000000", "exponent too large: 1000000"},
+		{"3 ** -100000000000", "exponent too large: -100000000000"},
+		{"(2 / 3) ** 100000000000", "exponent too large: 100000000000"},
+		{"1.5d ** 100000000000", "exponent too large: 100000000000"},
+		{"import nope", "no module is registered as \"nope\""},
+		{"1i < 2i", "unknown operator: COMPLEX < COMPLEX"},
+		{"enum Color { Red, Green, Blue }\nmatch Color.Red { Color.Red => { 1 }, Color.Green => { 2 }, }", "non-exhaustive match on Color, missing Blue"},
+		{"enum Shape { Circle(r), Empty }\nmatch Shape.Empty { Shape.Circle(1) => { 1 }, Shape.Empty => { 2 }, }", "non-exhaustive match on Shape, missing Circle"},
+		{"enum Shape { Circle(r), Empty }\nShape.values()", "`values` is only available on enums without payloads, Shape.Circle has fields"},
+		{"enum Shape { Circle(r), Empty }\nShape.Circle()", "missing field \"r\" for Shape.Circle"},
+		{"enum Color { Red }\nColor.from_name(\"Blue\")", "enum Color has no variant \"Blue\""},
+		{"struct P { x }\nP(1).y", "P has no field \"y\""},
+		{"struct P { x }\nval p = P(1); p.y = 2", "P has no field \"y\""},
+		{"struct P { x }\nP()", "missing field \"x\" for P"},
+		{"struct P { x }\nP(1, 2)", "wrong number of arguments to P. want at most 1, got=2"},
+		{"fun bad() { yield 1; 1 + true }\nlist(bad())", "type mismatch: INTEGER + BOOLEAN"},
+		{"fun bad() { yield 1; 1 + true }\nfor (x in bad()) { x }", "type mismatch: INTEGER + BOOLEAN"},
+		{"val u = {\"a\": null}; u?.a.b", "index operator not supported: NULL[STRING]"},
+		{"next([1])", "argument to `next` must be GENERATOR, got LIST"},
+		{"take([1], -1)", "second argument to `take` must be a positive INTEGER, got -1"},
+		{"chunk([1], 0)", "second argument to `chunk` must be greater than 0"},
+		{"val ch = chan()\nscope { spawn fun() { 1 + true }; list(take(ch, 5)) }", "type mismatch: INTEGER + BOOLEAN"},
+		{"[1, 2][::0]", "slice step cannot be zero"},
+		{"receive { timeout(1) => { 1 }, timeout(2) => { 2 }, }", "receive can only have one timeout"},
+		{"val xs = [1, 2]\nawait spawn fun() { xs[0] = 5 }", "cannot mutate a LIST bound with val in another task, bind it with var and guard it with a mutex or use a concurrent collection"},
+		{"val m = {\"a\": 1}\nawait spawn fun() { m.a = 2 }", "cannot mutate a MAP bound with val in another task, bind it with var and guard it with a mutex or use a concurrent collection"},
+		{"val xs = [1, 2]\nawait spawn fun() { xs[:1] = [3] }", "cannot mutate a LIST bound with val in another task, bind it with var and guard it with a mutex or use a concurrent collection"},
+		{"val m = {\"a\": {\"b\": 1}}\nawait spawn fun() { m.a.b = 2 }", "cannot mutate a MAP bound with val in another task, bind it with var and guard it with a mutex or use a concurrent collection"},
+		{"val xs = [[1], {\"a\": [2]}]\nawait spawn fun() { xs[1].a[0] = 3 }", "cannot mutate a LIST bound with val in another task, bind it with var and guard it with a mutex or use a concurrent collection"},
+		{"val m = {\"a\": 1}; m.b = [1]\nawait spawn fun() { m.b[0] = 2 }", "cannot mutate a LIST bound with val in another task, bind it with var and guard it with a mutex or use a concurrent collection"},
+		{"val ch = chan(1)\nval xs = [1]; send(ch, xs); recv(ch)\nawait spawn fun() { xs[0] = 2 }", "cannot mutate a LIST bound with val in another task, bind it with var and guard it with a mutex or use a concurrent collection"},
+		{"with 1 { 2 }", "cannot lock INTEGER, `with` needs a MUTEX, RWMUTEX, or READ_LOCK"},
+		{"unlock(mutex())", "unlock of unlocked MUTEX"},
+		{"unlock(read_lock(rwmutex()))", "unlock of unlocked READ_LOCK"},
+		{"add(atomic_ref(1), 1)", "first argument to `add` must be ATOMIC_INT, got ATOMIC_REF"},
+		{"store(atomic_int(), \"a\")", "arguments to `store` on an ATOMIC_INT must be INTEGER, got STRING"},
+		{"pop([1])", "first argument to `pop` must be CONCURRENT_QUEUE, got LIST"},
+		{"after(10ms, 1)", "second argument to `after` must be a function, got INTEGER"},
+		{"every(0, fun() { 1 })", "argument to `every` must be a duration greater than 0"},
+		{"cancel(chan())", "argument to `cancel` must be TIMER, got CHANNEL"},
+		{"duration(\"soon\")", "could not parse \"soon\" as a duration"},
+		{"sleep(-5ms)", "argument to `sleep` must be a positive INTEGER of milliseconds, got -5"},
+		{"spawn_actor(1)", "first argument to `spawn_actor` must be a function, got INTEGER"},
+		{"kill(1)", "first argument to `kill` must be PID, got INTEGER"},
+		{"send(:nobody, 1)", "no actor is registered as :nobody"},
+		{"supervisor([1])", "children of `supervisor` must be functions, got INTEGER"},
+		{"supervisor([], {\"strategy\": :rest_for_one})", "strategy of `supervisor` must be :one_for_one or :one_for_all, got :rest_for_one"},
+		{"[1, 2][\"a\":]", "slice indices must be INTEGER, got STRING"},
+		{"{1: 2}[1:]", "slice operator not supported: MAP"},
+		{"var xs = [1, 2, 3]; xs[::2] = [1]", "cannot assign 1 elements to a slice of 2 elements with step 2"},
+		{"[1, 2][-3]", "index out of range: -3"},
+		{"struct P { x, fun f() { 1 } }", "method f of struct P must take the receiver as its first parameter"},
+		{"trait T { fun f(self) }\nstruct P { x }\nimpl T for P { fun g(self) { 1 } }", "P does not implement f from trait T"},
+		{"struct P { x, fun f(self) { 1 } }\nimpl P { fun f(self) { 2 } }", "duplicate method f in struct P"},
+		{"trait T { fun f(self) }\nimpl T for T { fun f(self) { 1 } }", "impl is only supported for structs, got TRAIT"},
+		{"struct P { x, fun compare(self, o) { true } }\nP(1) < P(2)", "compare must return an INTEGER, got BOOLEAN"},
+		{"1.5d * 2i", "cannot mix DECIMAL and COMPLEX in arithmetic, convert with decimal() or float()"},
+		{"1.5d + 1.0", "cannot mix DECIMAL and FLOAT in arithmetic, convert with decimal() or float()"},
+		{"decimal(1 / 3)", "1/3 cannot be converted to a DECIMAL exactly, use round(x, places)"},
+		{"decimal(\"abc\")", "cannot convert STRING \"abc\" to a DECIMAL"},
+		{"val decimal_opts = {\"rounding\": \"bankers\"}; 1d / 3", "decimal_opts.rounding must be one of half_even, half_up, half_down, up, down, ceiling, floor, got bankers"},
+		{"macro m() { 1 }\nm()", "macro m must return a QUOTE, got INTEGER"},
+		{"macro m(a) { quote { unquote(a) } }\nm()", "wrong number of arguments to macro m. got=0, want=1"},
+		{"macro m() { quote { m() } }\nm()", "expansion of macro m is nested more than 100 levels deep"},
+		{"macro m() { quote { 1 + true } }\nm()", "type mismatch: INTEGER + BOOLEAN"},
+		{"macro m() { 1 + true }\nm()", "error expanding macro m: type mismatch: INTEGER + BOOLEAN"},
+		{"fun f() { macro m() { quote { 1 } } }\nf()", "macro m must be defined at the top level of a program"},
+		{"quote { unquote(len) }", "cannot unquote BUILTIN"},
+		{"@1\nfun f() { 1 }", "not a function: INTEGER"},
+		{"@missing\nfun f() { 1 }", "identifier not found: missing"},
+		{"[1][3]", "index out of range: 3"},
+		{"fun f(x) { x }\nf()", "missing argument for parameter \"x\""},
+		{"fun f(x) { x }\nf(1, 2)", "wrong number of arguments. want at most 1, got=2"},
+		{"await spawn fun() { 1 + true }", "type mismatch: INTEGER + BOOLEAN"},
+		{"spawn 1", "cannot spawn INTEGER, it is not a function"},
+		{"await 1", "cannot await INTEGER"},
+		{"await [1]", "cannot await INTEGER in a list of tasks"},
+		{"val ch = chan(); close(ch); close(ch)", "close of closed channel"},
+		{"val ch = chan(1); close(ch); send(ch, 1)", "send on closed channel"},
+		{"val ch = chan(1); close(ch)\nselect { send(ch, 1) => { 1 }, }", "send on closed channel"},
+		{"chan(-1)", "argument to `chan` must be a positive INTEGER, got -1"},
+		{"recv([1])", "first argument to `recv` must be CHANNEL, got LIST"},
+		{"select { 1 => { 1 }, }", "select case must be recv, send, timeout, or _, got 1"},
+		{"val ch = chan()\nselect { x = send(ch, 1) => { 1 }, }", "select can only bind the value of recv, got send"},
+		{"select { timeout(\"a\") => { 1 }, }", "argument to `timeout` must be a positive INTEGER of milliseconds, got a"},
+		{"select { }", "select must have at least one case"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestExecStringLiteral(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("exec string tests use a posix shell")
+	}
+
+	result, ok := testEval(t, "`echo hello; echo oops 1>&2; exit 3`").(*object.Map)
+	if !ok {
+		t.Fatalf("exec string result is not a Map")
+	}
+	stdout, _ := result.Get(&object.String{Value: "stdout"})
+	testStringObject(t, stdout, "hello\n")
+	stderr, _ := result.Get(&object.String{Value: "stderr"})
+	testStringObject(t, stderr, "oops\n")
+	exitCode, _ := result.Get(&object.String{Value: "exit_code"})
+	testIntegerObject(t, exitCode, 3)
+	ok2, _ := result.Get(&object.String{Value: "ok"})
+	testBooleanObject(t, ok2, false)
+}
+
+func TestExecStringInterpolationIsQuoted(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("exec string tests use a posix shell")
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"val x = \"it's; echo injected\"; `printf '%s' #{x}`.stdout", "it's; echo injected"},
+		{"val xs = [\"a b\", \"$HOME\"]; `printf '%s|' #{xs}`.stdout", "a b|$HOME|"},
+	}
+
+	for _, tt := range tests {
+		testStringObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestExecStringShOpts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("exec string tests use a posix shell")
+	}
+
+	tests := []struct {
+		input    string
+		expected object.Object
+	}{
+		{"val sh_opts = {\"cwd\": \"/\"}; `pwd`.stdout", &object.String{Value: "/\n"}},
+		{"val sh_opts = {\"env\": {\"BLUE_TEST\": \"yes\"}}; `printf '%s' \"$BLUE_TEST\"`.stdout", &object.String{Value: "yes"}},
+		{"val sh_opts = {\"timeout\": 50}; `sleep 5`.exit_code", &object.Integer{Value: -1}},
+		{"val sh_opts = {\"cdw\": \"/\"}; `pwd`", &object.Error{Message: "unknown sh_opts key \"cdw\", expected cwd, env, or timeout"}},
+		{"val sh_opts = {\"timeout\": 0}; `pwd`", &object.Error{Message: "sh_opts.timeout must be a positive INTEGER of milliseconds, got 0"}},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case *object.String:
+			testStringObject(t, result, expected.Value)
+		case *object.Integer:
+			testIntegerObject(t, result, expected.Value)
+		case *object.Error:
+			testErrorObject(t, result, expected.Message)
+		}
+	}
+}
+
+func TestCmdQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a b", `"a b"`},
+		{`say "hi"`, `"say ""hi"""`},
+		{"%PATH%", `""^%"PATH"^%""`},
+		{"50% & more", `"50"^%" & more"`},
+	}
+
+	for _, tt := range tests {
+		if got := cmdQuote(tt.input); got != tt.expected {
+			t.Errorf("cmdQuote(%q): wrong value. got=%s, want=%s", tt.input, got, tt.expected)
+		}
+	}
+}
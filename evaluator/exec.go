@@ -0,0 +1,185 @@
+package evaluator
+
+import (
+	"blue/ast"
+	"blue/object"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// SH_OPTS_NAME is the identifier that exec strings look up for their options
+// ie. `val sh_opts = {"cwd": "/tmp", "env": {"KEY": "value"}, "timeout": 1000}`
+const SH_OPTS_NAME = "sh_opts"
+
+// shOpts are the options used when running an exec string
+type shOpts struct {
+	cwd     string
+	env     []string
+	timeout time.Duration
+}
+
+// evalExecStringLiteral runs the exec string as a shell command and returns
+// a map with the keys stdout, stderr, exit_code, and ok
+func (e *Evaluator) evalExecStringLiteral(node *ast.ExecStringLiteral) object.Object {
+	cmdStr, errObj := e.interpolate(node.Value, node.InterpolationValues, node.OriginalInterpolationString, shellQuoteObject)
+	if errObj != nil {
+		return errObj
+	}
+	opts, errObj := e.getShOpts()
+	if errObj != nil {
+		return errObj
+	}
+	return runShellCommand(e.ctx, cmdStr, opts)
+}
+
+// getShOpts returns the options bound to `sh_opts` in the current scope
+// if it is not bound the command runs with the default options
+func (e *Evaluator) getShOpts() (shOpts, *object.Error) {
+	opts := shOpts{}
+	obj, ok := e.env.Get(SH_OPTS_NAME)
+	if !ok || obj == NULL {
+		return opts, nil
+	}
+	m, ok := obj.(*object.Map)
+	if !ok {
+		return opts, newError("%s must be a MAP, got %s", SH_OPTS_NAME, obj.Type())
+	}
+	for _, pair := range m.Pairs() {
+		key, ok := pair.Key.(*object.String)
+		if !ok {
+			return opts, newError("%s keys must be STRING, got %s", SH_OPTS_NAME, pair.Key.Type())
+		}
+		switch key.Value {
+		case "cwd":
+			cwd, ok := pair.Value.(*object.String)
+			if !ok {
+				return opts, newError("%s.cwd must be a STRING, got %s", SH_OPTS_NAME, pair.Value.Type())
+			}
+			opts.cwd = cwd.Value
+		case "env":
+			env, ok := pair.Value.(*object.Map)
+			if !ok {
+				return opts, newError("%s.env must be a MAP, got %s", SH_OPTS_NAME, pair.Value.Type())
+			}
+			for _, envPair := range env.Pairs() {
+				opts.env = append(opts.env, envPair.Key.Inspect()+"="+envPair.Value.Inspect())
+			}
+		case "timeout":
+			timeout, ok := pair.Value.(*object.Integer)
+			if !ok || timeout.Value <= 0 {
+				return opts, newError("%s.timeout must be a positive INTEGER of milliseconds, got %s", SH_OPTS_NAME, pair.Value.Inspect())
+			}
+			opts.timeout = time.Duration(timeout.Value) * time.Millisecond
+		default:
+			return opts, newError("unknown %s key %q, expected cwd, env, or timeout", SH_OPTS_NAME, key.Value)
+		}
+	}
+	return opts, nil
+}
+
+// runShellCommand runs cmdStr with the platform's shell and returns the result map
+// a non zero exit code is not an error, it is reported with ok set to false. The
+// command is killed once the context is done
+func runShellCommand(ctx context.Context, cmdStr string, opts shOpts) object.Object {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", cmdStr)
+	} else {
+		cmd = exec.Command("sh", "-c", cmdStr)
+	}
+	cmd.Dir = opts.cwd
+	if opts.env != nil {
+		cmd.Env = append(os.Environ(), opts.env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// The shell gets its own process group so that a timeout also kills
+	// anything it started, otherwise they would keep stdout and stderr open
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return newError("failed to run `%s`: %s", cmdStr, err.Error())
+	}
+	var timedOut int32
+	if opts.timeout > 0 {
+		timer := time.AfterFunc(opts.timeout, func() {
+			atomic.StoreInt32(&timedOut, 1)
+			killProcessGroup(cmd)
+		})
+		defer timer.Stop()
+	}
+	var cancelled int32
+	waited := make(chan struct{})
+	defer close(waited)
+	go func() {
+		select {
+		case <-ctx.Done():
+			atomic.StoreInt32(&cancelled, 1)
+			killProcessGroup(cmd)
+		case <-waited:
+		}
+	}()
+
+	exitCode := 0
+	err := cmd.Wait()
+	if atomic.LoadInt32(&cancelled) == 1 {
+		return newCancelledError()
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if atomic.LoadInt32(&timedOut) == 1 {
+			exitCode = -1
+			stderr.WriteString(fmt.Sprintf("command timed out after %s", opts.timeout))
+		} else if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		} else {
+			return newError("failed to run `%s`: %s", cmdStr, err.Error())
+		}
+	}
+
+	result := object.NewMap()
+	result.Set(&object.String{Value: "stdout"}, &object.String{Value: stdout.String()})
+	result.Set(&object.String{Value: "stderr"}, &object.String{Value: stderr.String()})
+	result.Set(&object.String{Value: "exit_code"}, &object.Integer{Value: int64(exitCode)})
+	result.Set(&object.String{Value: "ok"}, nativeToBooleanObject(exitCode == 0))
+	return result
+}
+
+// shellQuoteObject converts an interpolated object to a quoted shell argument
+// so that it can never be interpreted as more than one argument, lists become
+// one quoted argument per element
+func shellQuoteObject(obj object.Object) string {
+	if list, ok := obj.(*object.List); ok {
+		elems := list.Elements()
+		args := make([]string, 0, len(elems))
+		for _, elem := range elems {
+			args = append(args, shellQuote(elem.Inspect()))
+		}
+		return strings.Join(args, " ")
+	}
+	return shellQuote(obj.Inspect())
+}
+
+// shellQuote quotes s for the platform's shell
+func shellQuote(s string) string {
+	if runtime.GOOS == "windows" {
+		return cmdQuote(s)
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// cmdQuote quotes s for cmd.exe, cmd expands %VAR% even inside of quotes so each %
+// is closed out of the quotes and escaped with ^ ie. `a%b` is `"a"^%"b"`
+func cmdQuote(s string) string {
+	s = strings.ReplaceAll(s, `"`, `""`)
+	return `"` + strings.ReplaceAll(s, "%", `"^%"`) + `"`
+}
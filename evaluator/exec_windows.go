@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+package evaluator
+
+import "os/exec"
+
+// setProcessGroup is a no-op on windows
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the command
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,399 @@
+package evaluator
+
+import (
+	"blue/ast"
+	"blue/object"
+	"context"
+	"unicode/utf8"
+)
+
+// Calling a function whose body contains a `yield` returns a generator instead of
+// running the body. The body runs in its own goroutine which hands each yielded
+// value to the consumer and then waits until the next value is requested, so only
+// one side ever runs at a time. Generators and the lazy helpers (take, skip, zip,
+// enumerate, chunk, window) never build the whole sequence in memory. A generator
+// that is not exhausted is released by `close` or once the scope it was made in ends
+
+// generatorState connects a running generator body to its consumer
+type generatorState struct {
+	values  chan object.Object // values receives each yielded value and is closed when the body returns
+	resume  chan bool          // resume is sent true to continue the body or false to stop it
+	stopped bool               // stopped is set once the consumer stopped the generator
+}
+
+// errGeneratorStopped unwinds the body of a generator that was stopped early
+var errGeneratorStopped = &object.Error{Message: "generator stopped"}
+
+// evalYieldExpression hands the value to the consumer and waits to be resumed
+func (e *Evaluator) evalYieldExpression(node *ast.YieldExpression) object.Object {
+	if e.gen == nil {
+		return newError("yield outside of a generator")
+	}
+	var val object.Object = NULL
+	if node.Value != nil {
+		val = e.Eval(node.Value)
+		if isError(val) {
+			return val
+		}
+	}
+	if e.gen.stopped {
+		return errGeneratorStopped
+	}
+	select {
+	case e.gen.values <- val:
+	case <-e.ctx.Done():
+		e.gen.stopped = true
+		return errGeneratorStopped
+	}
+	if !e.waitResume() {
+		e.gen.stopped = true
+		return errGeneratorStopped
+	}
+	return NULL
+}
+
+// waitResume waits until the consumer of the generator asks for the next value, it
+// returns false if the generator was stopped or its context is done
+func (e *Evaluator) waitResume() bool {
+	select {
+	case resume := <-e.gen.resume:
+		return resume
+	case <-e.ctx.Done():
+		return false
+	}
+}
+
+// newFunctionGenerator returns the generator for a call of a function that yields
+// env is the function's environment with the arguments already bound, the body stops
+// once the context of e is done
+func (e *Evaluator) newFunctionGenerator(fn *object.Function, env *object.Environment) *object.Generator {
+	state := &generatorState{values: make(chan object.Object), resume: make(chan bool)}
+	body := e.withEnv(env)
+	body.gen = state
+	started, done := false, false
+
+	next := func() (object.Object, bool) {
+		if done {
+			return nil, false
+		}
+		if !started {
+			started = true
+			go func() {
+				defer close(state.values)
+				if !body.waitResume() {
+					return
+				}
+				result := body.Eval(fn.Body)
+				if isError(result) && result != errGeneratorStopped {
+					state.values <- result
+				}
+			}()
+		}
+		select {
+		case state.resume <- true:
+		case <-body.ctx.Done():
+			done = true
+			return newCancelledError(), true
+		}
+		val, ok := <-state.values
+		if !ok || isError(val) {
+			done = true
+		}
+		return val, ok
+	}
+	stop := func() {
+		if done || !started {
+			done = true
+			return
+		}
+		done = true
+		select {
+		case state.resume <- false:
+		case <-body.ctx.Done():
+		}
+		for range state.values {
+		}
+	}
+	return object.NewGenerator("fun", next, stop)
+}
+
+// stopGenerator releases the generator if it can be stopped
+func stopGenerator(gen *object.Generator) {
+	if gen.Stop != nil {
+		gen.Stop()
+	}
+}
+
+// sliceGenerator returns a generator over the elements
+func sliceGenerator(name string, elems []object.Object) *object.Generator {
+	i := 0
+	return object.NewGenerator(name, func() (object.Object, bool) {
+		if i >= len(elems) {
+			return nil, false
+		}
+		i++
+		return elems[i-1], true
+	}, nil)
+}
+
+// channelGenerator returns a generator that receives from the channel until it is
+// closed, once the context is done it returns the cancelled error
+func channelGenerator(ctx context.Context, ch *object.Channel) *object.Generator {
+	return object.NewGenerator("channel", func() (object.Object, bool) {
+		val, ok, err := ch.Recv(ctx)
+		if err != nil {
+			return newCancelledError(), true
+		}
+		return val, ok
+	}, nil)
+}
+
+// iterate returns a generator over any iterable, a generator is returned as is and
+// a channel is received from until it is closed or the context of e is done
+func (e *Evaluator) iterate(iterable object.Object) (*object.Generator, *object.Error) {
+	switch iterable := iterable.(type) {
+	case *object.Generator:
+		return iterable, nil
+	case *object.List:
+		if _, _, n, ok := iterable.Range(); ok {
+			// the elements of a range are made one at a time
+			i := int64(0)
+			return object.NewGenerator("list", func() (object.Object, bool) {
+				if i >= n {
+					return nil, false
+				}
+				i++
+				return iterable.Get(i - 1)
+			}, nil), nil
+		}
+		return sliceGenerator("list", iterable.Elements()), nil
+	case *object.String:
+		s := iterable.Value
+		return object.NewGenerator("string", func() (object.Object, bool) {
+			if s == "" {
+				return nil, false
+			}
+			r, size := utf8.DecodeRuneInString(s)
+			s = s[size:]
+			return &object.String{Value: string(r)}, true
+		}, nil), nil
+	case *object.Channel:
+		return channelGenerator(e.ctx, iterable), nil
+	case *object.StructInstance:
+		if res, ok := iterable.CallMethod("iter"); ok {
+			if errObj, ok := res.(*object.Error); ok {
+				return nil, errObj
+			}
+			return e.iterate(res)
+		}
+	}
+	elems, errObj := iterableToElements(iterable)
+	if errObj != nil {
+		return nil, errObj
+	}
+	return sliceGenerator(string(iterable.Type()), elems), nil
+}
+
+// collect drains the generator into a list of its values
+func collect(gen *object.Generator) ([]object.Object, *object.Error) {
+	elems := []object.Object{}
+	for {
+		val, ok := gen.Next()
+		if !ok {
+			return elems, nil
+		}
+		if errObj, ok := val.(*object.Error); ok {
+			return nil, errObj
+		}
+		elems = append(elems, val)
+	}
+}
+
+// generatorArgs converts the first argument of a lazy helper to a generator and
+// checks that the second argument is a positive INTEGER
+func (e *Evaluator) generatorArgs(name string, args []object.Object) (*object.Generator, int64, *object.Error) {
+	if len(args) != 2 {
+		return nil, 0, newError("wrong number of arguments to `%s`. got=%d, want=2", name, len(args))
+	}
+	n, ok := args[1].(*object.Integer)
+	if !ok || n.Value < 0 {
+		return nil, 0, newError("second argument to `%s` must be a positive INTEGER, got %s", name, args[1].Inspect())
+	}
+	gen, errObj := e.iterate(args[0])
+	if errObj != nil {
+		return nil, 0, errObj
+	}
+	return gen, n.Value, nil
+}
+
+// builtinNext returns the next value of a generator, or the default (null if not
+// given) once it is exhausted
+func builtinNext(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments to `next`. got=%d, want 1 or 2", len(args))
+	}
+	gen, ok := args[0].(*object.Generator)
+	if !ok {
+		return newError("argument to `next` must be GENERATOR, got %s", args[0].Type())
+	}
+	if val, ok := gen.Next(); ok {
+		return val
+	}
+	if len(args) == 2 {
+		return args[1]
+	}
+	return NULL
+}
+
+// builtinTake lazily yields the first n values
+func (e *Evaluator) builtinTake(args ...object.Object) object.Object {
+	gen, n, errObj := e.generatorArgs("take", args)
+	if errObj != nil {
+		return errObj
+	}
+	taken := int64(0)
+	return object.NewGenerator("take", func() (object.Object, bool) {
+		if taken >= n {
+			stopGenerator(gen)
+			return nil, false
+		}
+		taken++
+		return gen.Next()
+	}, gen.Stop)
+}
+
+// builtinSkip lazily yields every value after the first n
+func (e *Evaluator) builtinSkip(args ...object.Object) object.Object {
+	gen, n, errObj := e.generatorArgs("skip", args)
+	if errObj != nil {
+		return errObj
+	}
+	skipped := false
+	return object.NewGenerator("skip", func() (object.Object, bool) {
+		for ; !skipped && n > 0; n-- {
+			if val, ok := gen.Next(); !ok || isError(val) {
+				return val, ok
+			}
+		}
+		skipped = true
+		return gen.Next()
+	}, gen.Stop)
+}
+
+// builtinZip lazily yields lists of one value from each iterable until the shortest is exhausted
+func (e *Evaluator) builtinZip(args ...object.Object) object.Object {
+	if len(args) < 2 {
+		return newError("wrong number of arguments to `zip`. got=%d, want at least 2", len(args))
+	}
+	gens := make([]*object.Generator, len(args))
+	for i, arg := range args {
+		gen, errObj := e.iterate(arg)
+		if errObj != nil {
+			return errObj
+		}
+		gens[i] = gen
+	}
+	stop := func() {
+		for _, gen := range gens {
+			stopGenerator(gen)
+		}
+	}
+	return object.NewGenerator("zip", func() (object.Object, bool) {
+		row := make([]object.Object, 0, len(gens))
+		for _, gen := range gens {
+			val, ok := gen.Next()
+			if !ok {
+				stop()
+				return nil, false
+			}
+			if isError(val) {
+				return val, true
+			}
+			row = append(row, val)
+		}
+		return object.NewList(row), true
+	}, stop)
+}
+
+// builtinEnumerate lazily yields [index, value] pairs, the index starts at 0 or the given start
+func (e *Evaluator) builtinEnumerate(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments to `enumerate`. got=%d, want 1 or 2", len(args))
+	}
+	i := int64(0)
+	if len(args) == 2 {
+		start, ok := args[1].(*object.Integer)
+		if !ok {
+			return newError("second argument to `enumerate` must be INTEGER, got %s", args[1].Type())
+		}
+		i = start.Value
+	}
+	gen, errObj := e.iterate(args[0])
+	if errObj != nil {
+		return errObj
+	}
+	return object.NewGenerator("enumerate", func() (object.Object, bool) {
+		val, ok := gen.Next()
+		if !ok || isError(val) {
+			return val, ok
+		}
+		i++
+		return object.NewList([]object.Object{&object.Integer{Value: i - 1}, val}), true
+	}, gen.Stop)
+}
+
+// builtinChunk lazily yields lists of n values, the last list may be shorter
+func (e *Evaluator) builtinChunk(args ...object.Object) object.Object {
+	gen, n, errObj := e.generatorArgs("chunk", args)
+	if errObj != nil {
+		return errObj
+	}
+	if n == 0 {
+		return newError("second argument to `chunk` must be greater than 0")
+	}
+	return object.NewGenerator("chunk", func() (object.Object, bool) {
+		chunk := make([]object.Object, 0, n)
+		for int64(len(chunk)) < n {
+			val, ok := gen.Next()
+			if !ok {
+				break
+			}
+			if isError(val) {
+				return val, true
+			}
+			chunk = append(chunk, val)
+		}
+		if len(chunk) == 0 {
+			return nil, false
+		}
+		return object.NewList(chunk), true
+	}, gen.Stop)
+}
+
+// builtinWindow lazily yields every run of n consecutive values as a list
+func (e *Evaluator) builtinWindow(args ...object.Object) object.Object {
+	gen, n, errObj := e.generatorArgs("window", args)
+	if errObj != nil {
+		return errObj
+	}
+	if n == 0 {
+		return newError("second argument to `window` must be greater than 0")
+	}
+	var window []object.Object
+	return object.NewGenerator("window", func() (object.Object, bool) {
+		if len(window) > 0 {
+			window = window[1:]
+		}
+		for int64(len(window)) < n {
+			val, ok := gen.Next()
+			if !ok {
+				return nil, false
+			}
+			if isError(val) {
+				return val, true
+			}
+			window = append(window, val)
+		}
+		return object.NewList(append([]object.Object{}, window...)), true
+	}, gen.Stop)
+}
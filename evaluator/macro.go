@@ -0,0 +1,267 @@
+package evaluator
+
+import (
+	"blue/ast"
+	"blue/object"
+	"blue/token"
+	"strconv"
+	"sync/atomic"
+)
+
+// Macros are expanded after parsing and before the program is evaluated. Every top
+// level `macro name(params) { ... }` is removed from the program and each call to it
+// is replaced with the code of the quote its body returns, the arguments of the call
+// are passed to the body as quotes instead of being evaluated.
+//
+// Expansion is hygienic: names bound inside of a quote (val, var, fun, parameters,
+// and for loop variables) are renamed to fresh names that cannot be written in source
+// code so they never capture or shadow the caller's variables. Code spliced in with
+// `unquote` is left as is so it still refers to the caller's names
+
+// maxMacroDepth is how deep macros can expand into calls of other macros
+const maxMacroDepth = 100
+
+// gensymCounter makes each generated name unique
+var gensymCounter int64
+
+// gensym returns a fresh name based on name, identifiers cannot contain digits so
+// the name cannot be written by the user
+func gensym(name string) string {
+	return name + "_" + strconv.FormatInt(atomic.AddInt64(&gensymCounter, 1), 10)
+}
+
+// splicedNode marks code inserted by unquote so hygiene leaves it alone
+type splicedNode struct {
+	ast.Expression
+}
+
+// ExpandMacros defines the program's top level macros and replaces every call to a macro
+// with its expansion, macros stay defined for the programs evaluated after this one
+func (e *Evaluator) ExpandMacros(program *ast.Program) (*ast.Program, *object.Error) {
+	stmts := make([]ast.Statement, 0, len(program.Statements))
+	for _, stmt := range program.Statements {
+		ms, ok := stmt.(*ast.MacroStatement)
+		if !ok {
+			stmts = append(stmts, stmt)
+			continue
+		}
+		e.macros[ms.Name.Value] = &object.Macro{Parameters: ms.Parameters, Body: ms.Body, Env: e.env}
+	}
+	if len(e.macros) == 0 {
+		return program, nil
+	}
+
+	var errObj *object.Error
+	depth := 0
+	var expand ast.ModifierFunc
+	expand = func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || errObj != nil {
+			return node
+		}
+		ident, ok := call.Function.(*ast.Identifier)
+		if !ok {
+			return node
+		}
+		macro, ok := e.macros[ident.Value]
+		if !ok {
+			return node
+		}
+		if depth >= maxMacroDepth {
+			errObj = newError("expansion of macro %s is nested more than %d levels deep", ident.Value, maxMacroDepth)
+			return node
+		}
+		expanded, err := e.expandMacroCall(ident.Value, macro, call)
+		if err != nil {
+			errObj = err
+			return node
+		}
+		depth++
+		defer func() { depth-- }()
+		return ast.Modify(expanded, expand)
+	}
+	expanded := ast.Modify(&ast.Program{Statements: stmts}, expand)
+	if errObj != nil {
+		return nil, errObj
+	}
+	return expanded.(*ast.Program), nil
+}
+
+// expandMacroCall evaluates the macro's body with the quoted arguments of the call
+// and returns the code of the quote it returns
+func (e *Evaluator) expandMacroCall(name string, macro *object.Macro, call *ast.CallExpression) (ast.Expression, *object.Error) {
+	if len(call.DefaultArguments) > 0 {
+		return nil, newError("macro %s does not take named arguments", name)
+	}
+	if len(call.Arguments) != len(macro.Parameters) {
+		return nil, newError("wrong number of arguments to macro %s. got=%d, want=%d", name, len(call.Arguments), len(macro.Parameters))
+	}
+	env := object.NewEnclosedEnvironment(macro.Env)
+	for i, param := range macro.Parameters {
+		env.Set(param.Value, &object.Quote{Node: call.Arguments[i]})
+	}
+	result := unwrapReturnValue(e.withEnv(env).Eval(macro.Body))
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, newError("error expanding macro %s: %s", name, errObj.Message)
+	}
+	quote, ok := result.(*object.Quote)
+	if !ok {
+		return nil, newError("macro %s must return a QUOTE, got %s", name, result.Type())
+	}
+	return quoteToExpression(quote.Node), nil
+}
+
+// quoteToExpression returns the quoted code as an expression, quoted statements become a block
+func quoteToExpression(node ast.Node) ast.Expression {
+	if exp, ok := node.(ast.Expression); ok {
+		return exp
+	}
+	block, ok := node.(*ast.BlockStatement)
+	if !ok {
+		block = &ast.BlockStatement{Token: token.Token{Type: token.LBRACE, Literal: "{"}, Statements: []ast.Statement{node.(ast.Statement)}}
+	}
+	return &ast.BlockExpression{Token: block.Token, Block: block}
+}
+
+// evalQuoteExpression returns the quoted code with every `unquote(x)` replaced by the
+// value of x and the names bound in the quote renamed for hygiene
+func (e *Evaluator) evalQuoteExpression(node *ast.QuoteExpression) object.Object {
+	var body ast.Node = node.Body
+	if len(node.Body.Statements) == 1 {
+		if es, ok := node.Body.Statements[0].(*ast.ExpressionStatement); ok {
+			body = es.Expression
+		}
+	}
+
+	var errObj *object.Error
+	body = ast.Modify(body, func(n ast.Node) ast.Node {
+		call, ok := n.(*ast.CallExpression)
+		if !ok || errObj != nil {
+			return n
+		}
+		if ident, ok := call.Function.(*ast.Identifier); !ok || ident.Value != "unquote" {
+			return n
+		}
+		if len(call.Arguments) != 1 || len(call.DefaultArguments) > 0 {
+			errObj = newError("wrong number of arguments to `unquote`. got=%d, want=1", len(call.Arguments)+len(call.DefaultArguments))
+			return n
+		}
+		val := e.Eval(call.Arguments[0])
+		if isError(val) {
+			errObj = val.(*object.Error)
+			return n
+		}
+		exp, err := objectToExpression(val, call.Token)
+		if err != nil {
+			errObj = err
+			return n
+		}
+		return &splicedNode{exp}
+	})
+	if errObj != nil {
+		return errObj
+	}
+
+	body = renameBindings(body)
+	body = ast.Modify(body, func(n ast.Node) ast.Node {
+		if spliced, ok := n.(*splicedNode); ok {
+			return spliced.Expression
+		}
+		return n
+	})
+	return &object.Quote{Node: body}
+}
+
+// objectToExpression converts the value of an unquote to code
+func objectToExpression(obj object.Object, tok token.Token) (ast.Expression, *object.Error) {
+	switch obj := obj.(type) {
+	case *object.Quote:
+		return quoteToExpression(obj.Node), nil
+	case *object.Integer:
+		lit := strconv.FormatInt(obj.Value, 10)
+		return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: lit, Span: tok.Span}, Value: obj.Value}, nil
+	case *object.Float:
+		lit := strconv.FormatFloat(obj.Value, 'f', -1, 64)
+		return &ast.FloatLiteral{Token: token.Token{Type: token.FLOAT, Literal: lit, Span: tok.Span}, Value: obj.Value}, nil
+	case *object.Boolean:
+		if obj.Value {
+			return &ast.Boolean{Token: token.Token{Type: token.TRUE, Literal: "true", Span: tok.Span}, Value: true}, nil
+		}
+		return &ast.Boolean{Token: token.Token{Type: token.FALSE, Literal: "false", Span: tok.Span}, Value: false}, nil
+	case *object.String:
+		return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: obj.Value, Span: tok.Span}, Value: obj.Value}, nil
+	case *object.Symbol:
+		return &ast.SymbolLiteral{Token: token.Token{Type: token.SYMBOL, Literal: obj.Name, Span: tok.Span}, Value: obj.Name}, nil
+	case *object.Null:
+		return &ast.Null{Token: token.Token{Type: token.NULL_KW, Literal: "null", Span: tok.Span}}, nil
+	case *object.List:
+		list := &ast.ListLiteral{Token: token.Token{Type: token.LBRACKET, Literal: "[", Span: tok.Span}}
+		for _, elem := range obj.Elements() {
+			exp, errObj := objectToExpression(elem, tok)
+			if errObj != nil {
+				return nil, errObj
+			}
+			list.Elements = append(list.Elements, exp)
+		}
+		return list, nil
+	}
+	return nil, newError("cannot unquote %s", obj.Type())
+}
+
+// renameBindings renames every name bound in the quoted code to a fresh name
+func renameBindings(node ast.Node) ast.Node {
+	renames := map[string]string{}
+	bind := func(ident *ast.Identifier) {
+		if ident == nil {
+			return
+		}
+		if _, ok := renames[ident.Value]; !ok {
+			renames[ident.Value] = gensym(ident.Value)
+		}
+	}
+	ast.Modify(node, func(n ast.Node) ast.Node {
+		switch n := n.(type) {
+		case *ast.ValStatement:
+			bind(n.Name)
+		case *ast.VarStatement:
+			bind(n.Name)
+		case *ast.FunctionStatement:
+			bind(n.Name)
+			for _, p := range n.Parameters {
+				bind(p)
+			}
+		case *ast.FunctionLiteral:
+			for _, p := range n.Parameters {
+				bind(p)
+			}
+		case *ast.ForExpression:
+			if infix, ok := n.Condition.(*ast.InfixExpression); ok && infix.Operator == "in" {
+				if ident, ok := infix.Left.(*ast.Identifier); ok {
+					bind(ident)
+				}
+			}
+		}
+		return n
+	})
+	if len(renames) == 0 {
+		return node
+	}
+	return ast.Modify(node, func(n ast.Node) ast.Node {
+		ident, ok := n.(*ast.Identifier)
+		if !ok {
+			return n
+		}
+		name, ok := renames[ident.Value]
+		if !ok {
+			return n
+		}
+		tok := ident.Token
+		tok.Literal = name
+		return &ast.Identifier{Token: tok, Value: name}
+	})
+}
+
+// evalMacroStatement is only reached for macros that are not at the top level of a program
+func evalMacroStatement(node *ast.MacroStatement) object.Object {
+	return newError("macro %s must be defined at the top level of a program", node.Name.Value)
+}
@@ -0,0 +1,54 @@
+package evaluator
+
+import (
+	"blue/ast"
+	"blue/object"
+	"sort"
+	"sync"
+)
+
+// `import path/filepath` binds the module registered with the import path to the last
+// element of the path, its members are read with member access ie. filepath.Join("a", "b").
+// Modules are registered by Go code, the bindings generated by `blue bindgen` register
+// a module for each Go package.
+
+var (
+	modulesMu sync.RWMutex
+	modules   = map[string]func(e *Evaluator) object.Object{}
+)
+
+// RegisterModule makes the module importable with `import path`, load is called each time
+// the module is imported and returns the module or an error object
+func RegisterModule(path string, load func(e *Evaluator) object.Object) {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+	modules[path] = load
+}
+
+// Modules returns the import paths of the registered modules in order
+func Modules() []string {
+	modulesMu.RLock()
+	defer modulesMu.RUnlock()
+	paths := make([]string, 0, len(modules))
+	for path := range modules {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// evalImportStatement loads the module and binds it as a val
+func (e *Evaluator) evalImportStatement(node *ast.ImportStatement) object.Object {
+	modulesMu.RLock()
+	load, ok := modules[node.Path.Value]
+	modulesMu.RUnlock()
+	if !ok {
+		return newError("no module is registered as %q", node.Path.Value)
+	}
+	mod := load(e)
+	if isError(mod) {
+		return mod
+	}
+	e.env.SetImmutable(node.Name(), mod)
+	return NULL
+}
@@ -0,0 +1,451 @@
+package evaluator
+
+import (
+	"blue/object"
+	"math"
+	"math/big"
+	"math/cmplx"
+)
+
+// The numeric tower is INTEGER/BIG_INTEGER < RATIONAL < FLOAT < COMPLEX. Mixed arithmetic is
+// done in the highest type of the two operands. Results are always normalized so
+// an integer that fits in an int64 is an INTEGER and a rational that is a whole
+// number is an integer, this keeps equality and hashing consistent. DECIMAL is
+// handled separately in decimal.go and keeps its scale so 2.50d stays 2.50
+
+// evalNumberInfixExpression applies the operator to two numbers of any type
+func evalNumberInfixExpression(operator string, left, right object.Object) object.Object {
+	switch {
+	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
+		return evalIntegerInfixExpression(operator, left.(*object.Integer).Value, right.(*object.Integer).Value)
+	case left.Type() == object.COMPLEX_OBJ || right.Type() == object.COMPLEX_OBJ:
+		return evalComplexInfixExpression(operator, toComplex(left), toComplex(right))
+	case left.Type() == object.FLOAT_OBJ || right.Type() == object.FLOAT_OBJ:
+		return evalFloatInfixExpression(operator, toFloat(left), toFloat(right))
+	case left.Type() == object.RATIONAL_OBJ || right.Type() == object.RATIONAL_OBJ:
+		return evalRationalInfixExpression(operator, toRat(left), toRat(right))
+	}
+	return evalBigIntegerInfixExpression(operator, toBigInt(left), toBigInt(right))
+}
+
+// evalIntegerInfixExpression applies the operator to two int64s, anything that
+// would overflow is done with big integers instead
+func evalIntegerInfixExpression(operator string, l, r int64) object.Object {
+	switch operator {
+	case "+":
+		result := l + r
+		if (l > 0 && r > 0 && result < 0) || (l < 0 && r < 0 && result >= 0) {
+			break
+		}
+		return &object.Integer{Value: result}
+	case "-":
+		result := l - r
+		if (l >= 0 && r < 0 && result < 0) || (l < 0 && r > 0 && result >= 0) {
+			break
+		}
+		return &object.Integer{Value: result}
+	case "*":
+		if l == 0 || r == 0 {
+			return &object.Integer{Value: 0}
+		}
+		result := l * r
+		if result/r != l || (l == -1 && r == math.MinInt64) || (r == -1 && l == math.MinInt64) {
+			break
+		}
+		return &object.Integer{Value: result}
+	case "/":
+		if r == 0 {
+			return newError("division by zero")
+		}
+		if l%r == 0 && !(l == math.MinInt64 && r == -1) {
+			return &object.Integer{Value: l / r}
+		}
+	case "//", "%":
+		if r == 0 {
+			return newError("division by zero")
+		}
+		if l == math.MinInt64 && r == -1 {
+			break
+		}
+		q := floorDiv(l, r)
+		if operator == "//" {
+			return &object.Integer{Value: q}
+		}
+		return &object.Integer{Value: l - r*q}
+	case "&":
+		return &object.Integer{Value: l & r}
+	case "|":
+		return &object.Integer{Value: l | r}
+	case "^":
+		return &object.Integer{Value: l ^ r}
+	case ">>":
+		if r < 0 {
+			return newError("negative shift count: %d", r)
+		}
+		if r > 63 {
+			r = 63
+		}
+		return &object.Integer{Value: l >> uint64(r)}
+	case "<":
+		return nativeToBooleanObject(l < r)
+	case ">":
+		return nativeToBooleanObject(l > r)
+	case "<=":
+		return nativeToBooleanObject(l <= r)
+	case ">=":
+		return nativeToBooleanObject(l >= r)
+	case "==":
+		return nativeToBooleanObject(l == r)
+	case "!=":
+		return nativeToBooleanObject(l != r)
+	case "..", "..<":
+		return object.NewRange(l, r, operator == "..")
+	}
+	return evalBigIntegerInfixExpression(operator, big.NewInt(l), big.NewInt(r))
+}
+
+// floorDiv returns l divided by r rounded towards negative infinity
+func floorDiv(l, r int64) int64 {
+	q := l / r
+	if (l%r != 0) && ((l < 0) != (r < 0)) {
+		q--
+	}
+	return q
+}
+
+// maxPowBits is the size in bits of the largest power that ** computes, a bigger one
+// would hang the interpreter ie. `2 ** 100000000000`
+const maxPowBits = 1 << 24
+
+// powTooLarge returns true if base ** exp would have more than maxPowBits bits
+func powTooLarge(base, exp *big.Int) bool {
+	if base.CmpAbs(big.NewInt(1)) <= 0 {
+		return false
+	}
+	exp = new(big.Int).Abs(exp)
+	return !exp.IsInt64() || exp.Int64() > maxPowBits/int64(base.BitLen()-1)
+}
+
+// evalBigIntegerInfixExpression applies the operator to two big integers
+func evalBigIntegerInfixExpression(operator string, l, r *big.Int) object.Object {
+	switch operator {
+	case "+":
+		return normalizeBigInt(new(big.Int).Add(l, r))
+	case "-":
+		return normalizeBigInt(new(big.Int).Sub(l, r))
+	case "*":
+		return normalizeBigInt(new(big.Int).Mul(l, r))
+	case "/":
+		if r.Sign() == 0 {
+			return newError("division by zero")
+		}
+		return normalizeRat(new(big.Rat).SetFrac(l, r))
+	case "//", "%":
+		if r.Sign() == 0 {
+			return newError("division by zero")
+		}
+		q, m := bigFloorDivMod(l, r)
+		if operator == "//" {
+			return normalizeBigInt(q)
+		}
+		return normalizeBigInt(m)
+	case "**":
+		if powTooLarge(l, r) {
+			return newError("exponent too large: %s", r.String())
+		}
+		if r.Sign() < 0 {
+			if l.Sign() == 0 {
+				return newError("division by zero")
+			}
+			denom := new(big.Int).Exp(l, new(big.Int).Neg(r), nil)
+			return normalizeRat(new(big.Rat).SetFrac(big.NewInt(1), denom))
+		}
+		return normalizeBigInt(new(big.Int).Exp(l, r, nil))
+	case "&":
+		return normalizeBigInt(new(big.Int).And(l, r))
+	case "|":
+		return normalizeBigInt(new(big.Int).Or(l, r))
+	case "^":
+		return normalizeBigInt(new(big.Int).Xor(l, r))
+	case "<<", ">>":
+		if r.Sign() < 0 {
+			return newError("negative shift count: %s", r.String())
+		}
+		if !r.IsInt64() || r.Int64() > math.MaxInt32 {
+			return newError("shift count too large: %s", r.String())
+		}
+		if operator == "<<" {
+			return normalizeBigInt(new(big.Int).Lsh(l, uint(r.Int64())))
+		}
+		return normalizeBigInt(new(big.Int).Rsh(l, uint(r.Int64())))
+	case "<", ">", "<=", ">=", "==", "!=":
+		return compareResult(operator, l.Cmp(r))
+	case "..", "..<":
+		return newError("range bounds must fit in an INTEGER")
+	}
+	return newError("unknown operator: %s %s %s", object.BIG_INTEGER_OBJ, operator, object.BIG_INTEGER_OBJ)
+}
+
+// bigFloorDivMod returns l divided by r rounded towards negative infinity and its modulus
+func bigFloorDivMod(l, r *big.Int) (*big.Int, *big.Int) {
+	q, m := new(big.Int).QuoRem(l, r, new(big.Int))
+	if m.Sign() != 0 && m.Sign() != r.Sign() {
+		q.Sub(q, big.NewInt(1))
+		m.Add(m, r)
+	}
+	return q, m
+}
+
+// evalRationalInfixExpression applies the operator to two rationals
+func evalRationalInfixExpression(operator string, l, r *big.Rat) object.Object {
+	switch operator {
+	case "+":
+		return normalizeRat(new(big.Rat).Add(l, r))
+	case "-":
+		return normalizeRat(new(big.Rat).Sub(l, r))
+	case "*":
+		return normalizeRat(new(big.Rat).Mul(l, r))
+	case "/", "//", "%":
+		if r.Sign() == 0 {
+			return newError("division by zero")
+		}
+		quo := new(big.Rat).Quo(l, r)
+		if operator == "/" {
+			return normalizeRat(quo)
+		}
+		floor, _ := bigFloorDivMod(quo.Num(), quo.Denom())
+		if operator == "//" {
+			return normalizeBigInt(floor)
+		}
+		return normalizeRat(new(big.Rat).Sub(l, new(big.Rat).Mul(r, new(big.Rat).SetInt(floor))))
+	case "**":
+		if !r.IsInt() {
+			return evalFloatInfixExpression(operator, ratToFloat(l), ratToFloat(r))
+		}
+		exp := r.Num()
+		if powTooLarge(l.Num(), exp) || powTooLarge(l.Denom(), exp) {
+			return newError("exponent too large: %s", exp.String())
+		}
+		num := new(big.Int).Exp(l.Num(), new(big.Int).Abs(exp), nil)
+		denom := new(big.Int).Exp(l.Denom(), new(big.Int).Abs(exp), nil)
+		if exp.Sign() < 0 {
+			num, denom = denom, num
+		}
+		if denom.Sign() == 0 {
+			return newError("division by zero")
+		}
+		return normalizeRat(new(big.Rat).SetFrac(num, denom))
+	case "<", ">", "<=", ">=", "==", "!=":
+		return compareResult(operator, l.Cmp(r))
+	}
+	return newError("unknown operator: %s %s %s", object.RATIONAL_OBJ, operator, object.RATIONAL_OBJ)
+}
+
+// evalFloatInfixExpression applies the operator to two floats
+func evalFloatInfixExpression(operator string, l, r float64) object.Object {
+	switch operator {
+	case "+":
+		return &object.Float{Value: l + r}
+	case "-":
+		return &object.Float{Value: l - r}
+	case "*":
+		return &object.Float{Value: l * r}
+	case "/":
+		if r == 0 {
+			return newError("division by zero")
+		}
+		return &object.Float{Value: l / r}
+	case "//":
+		if r == 0 {
+			return newError("division by zero")
+		}
+		return &object.Float{Value: math.Floor(l / r)}
+	case "%":
+		if r == 0 {
+			return newError("division by zero")
+		}
+		return &object.Float{Value: l - r*math.Floor(l/r)}
+	case "**":
+		return &object.Float{Value: math.Pow(l, r)}
+	case "<":
+		return nativeToBooleanObject(l < r)
+	case ">":
+		return nativeToBooleanObject(l > r)
+	case "<=":
+		return nativeToBooleanObject(l <= r)
+	case ">=":
+		return nativeToBooleanObject(l >= r)
+	case "==":
+		return nativeToBooleanObject(l == r)
+	case "!=":
+		return nativeToBooleanObject(l != r)
+	}
+	return newError("unknown operator: %s %s %s", object.FLOAT_OBJ, operator, object.FLOAT_OBJ)
+}
+
+// evalComplexInfixExpression applies the operator to two complex numbers
+// complex numbers are not ordered so only equality can be compared
+func evalComplexInfixExpression(operator string, l, r complex128) object.Object {
+	switch operator {
+	case "+":
+		return &object.Complex{Value: l + r}
+	case "-":
+		return &object.Complex{Value: l - r}
+	case "*":
+		return &object.Complex{Value: l * r}
+	case "/":
+		if r == 0 {
+			return newError("division by zero")
+		}
+		return &object.Complex{Value: l / r}
+	case "**":
+		// small whole number powers are multiplied out so 1i ** 2 is exactly -1
+		if n := real(r); imag(r) == 0 && n == math.Trunc(n) && math.Abs(n) <= 64 {
+			result := complex(1, 0)
+			for i := 0; i < int(math.Abs(n)); i++ {
+				result *= l
+			}
+			if n < 0 {
+				if result == 0 {
+					return newError("division by zero")
+				}
+				result = 1 / result
+			}
+			return &object.Complex{Value: result}
+		}
+		return &object.Complex{Value: cmplx.Pow(l, r)}
+	case "==":
+		return nativeToBooleanObject(l == r)
+	case "!=":
+		return nativeToBooleanObject(l != r)
+	}
+	return newError("unknown operator: %s %s %s", object.COMPLEX_OBJ, operator, object.COMPLEX_OBJ)
+}
+
+// compareResult converts the result of a Cmp into the boolean for the comparison operator
+func compareResult(operator string, cmp int) object.Object {
+	switch operator {
+	case "<":
+		return nativeToBooleanObject(cmp < 0)
+	case ">":
+		return nativeToBooleanObject(cmp > 0)
+	case "<=":
+		return nativeToBooleanObject(cmp <= 0)
+	case ">=":
+		return nativeToBooleanObject(cmp >= 0)
+	case "==":
+		return nativeToBooleanObject(cmp == 0)
+	}
+	return nativeToBooleanObject(cmp != 0)
+}
+
+// compareNumbers compares two numbers of any type, ok is false if they
+// cannot be ordered (ie. one of them is NaN)
+func compareNumbers(left, right object.Object) (int, bool) {
+	if left.Type() == object.COMPLEX_OBJ || right.Type() == object.COMPLEX_OBJ {
+		l, r := toComplex(left), toComplex(right)
+		if l == r {
+			return 0, true
+		}
+		if imag(l) != 0 || imag(r) != 0 {
+			return 0, false
+		}
+		left, right = &object.Float{Value: real(l)}, &object.Float{Value: real(r)}
+	}
+	if left.Type() == object.FLOAT_OBJ || right.Type() == object.FLOAT_OBJ {
+		l, r := toFloat(left), toFloat(right)
+		switch {
+		case l < r:
+			return -1, true
+		case l > r:
+			return 1, true
+		case l == r:
+			return 0, true
+		}
+		return 0, false
+	}
+	return toRat(left).Cmp(toRat(right)), true
+}
+
+// normalizeBigInt returns an INTEGER if the value fits in an int64, otherwise a BIG_INTEGER
+func normalizeBigInt(value *big.Int) object.Object {
+	if value.IsInt64() {
+		return &object.Integer{Value: value.Int64()}
+	}
+	return &object.BigInteger{Value: value}
+}
+
+// normalizeRat returns an integer if the value is a whole number, otherwise a RATIONAL
+func normalizeRat(value *big.Rat) object.Object {
+	if value.IsInt() {
+		return normalizeBigInt(new(big.Int).Set(value.Num()))
+	}
+	return &object.Rational{Value: value}
+}
+
+// isNumber returns true if the object is any kind of number
+func isNumber(obj object.Object) bool {
+	switch obj.Type() {
+	case object.INTEGER_OBJ, object.BIG_INTEGER_OBJ, object.RATIONAL_OBJ, object.DECIMAL_OBJ, object.FLOAT_OBJ, object.COMPLEX_OBJ:
+		return true
+	}
+	return false
+}
+
+// toBigInt converts an integer or big integer object to a *big.Int
+func toBigInt(obj object.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return big.NewInt(obj.Value)
+	case *object.BigInteger:
+		return obj.Value
+	}
+	return nil
+}
+
+// toRat converts an integer, big integer, rational, or decimal object to a *big.Rat
+func toRat(obj object.Object) *big.Rat {
+	switch obj := obj.(type) {
+	case *object.Decimal:
+		return decimalToRat(obj)
+	case *object.Integer:
+		return new(big.Rat).SetInt64(obj.Value)
+	case *object.BigInteger:
+		return new(big.Rat).SetInt(obj.Value)
+	case *object.Rational:
+		return obj.Value
+	}
+	return nil
+}
+
+// toFloat converts any number object to a float64
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInteger:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f
+	case *object.Rational:
+		return ratToFloat(obj.Value)
+	case *object.Decimal:
+		return ratToFloat(decimalToRat(obj))
+	case *object.Float:
+		return obj.Value
+	}
+	return math.NaN()
+}
+
+// toComplex converts any number object to a complex128
+func toComplex(obj object.Object) complex128 {
+	if c, ok := obj.(*object.Complex); ok {
+		return c.Value
+	}
+	return complex(toFloat(obj), 0)
+}
+
+// ratToFloat returns the closest float64 to the rational
+func ratToFloat(r *big.Rat) float64 {
+	f, _ := r.Float64()
+	return f
+}
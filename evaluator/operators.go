@@ -0,0 +1,282 @@
+package evaluator
+
+import (
+	"blue/ast"
+	"blue/object"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// evalPrefixExpression evaluates the prefix operators `not`, `-`, and `~`
+func evalPrefixExpression(operator string, right object.Object) object.Object {
+	switch operator {
+	case "not":
+		return nativeToBooleanObject(!isTruthy(right))
+	case "-":
+		switch right := right.(type) {
+		case *object.Integer:
+			if right.Value == math.MinInt64 {
+				return normalizeBigInt(new(big.Int).Neg(big.NewInt(right.Value)))
+			}
+			return &object.Integer{Value: -right.Value}
+		case *object.BigInteger:
+			return normalizeBigInt(new(big.Int).Neg(right.Value))
+		case *object.Rational:
+			return normalizeRat(new(big.Rat).Neg(right.Value))
+		case *object.Decimal:
+			return &object.Decimal{Unscaled: new(big.Int).Neg(right.Unscaled), Scale: right.Scale}
+		case *object.Float:
+			return &object.Float{Value: -right.Value}
+		case *object.Complex:
+			return &object.Complex{Value: 0 - right.Value}
+		case *object.StructInstance:
+			if res, ok := right.CallMethod("neg"); ok {
+				return res
+			}
+		}
+	case "~":
+		switch right := right.(type) {
+		case *object.Integer:
+			return &object.Integer{Value: ^right.Value}
+		case *object.BigInteger:
+			return normalizeBigInt(new(big.Int).Not(right.Value))
+		}
+	}
+	return newError("unknown operator: %s%s", operator, right.Type())
+}
+
+// evalInfixExpression evaluates both sides of the infix expression and applies the operator
+// `and`, `or`, and `??` short circuit so the right side is only evaluated when needed
+func (e *Evaluator) evalInfixExpression(node *ast.InfixExpression) object.Object {
+	left := e.Eval(node.Left)
+	if isError(left) {
+		return left
+	}
+	switch node.Operator {
+	case "and":
+		if !isTruthy(left) {
+			return left
+		}
+		return e.Eval(node.Right)
+	case "or":
+		if isTruthy(left) {
+			return left
+		}
+		return e.Eval(node.Right)
+	case "??":
+		if left != NULL {
+			return left
+		}
+		return e.Eval(node.Right)
+	}
+	right := e.Eval(node.Right)
+	if isError(right) {
+		return right
+	}
+	return e.evalInfix(node.Operator, left, right)
+}
+
+// evalInfix applies the infix operator to the already evaluated left and right objects
+func (e *Evaluator) evalInfix(operator string, left, right object.Object) object.Object {
+	switch operator {
+	case "in":
+		return evalInExpression(left, right)
+	case "not in":
+		result := evalInExpression(left, right)
+		if isError(result) {
+			return result
+		}
+		return nativeToBooleanObject(result == FALSE)
+	}
+	if res, ok := evalOverloadedInfix(operator, left, right); ok {
+		return res
+	}
+
+	switch {
+	case isNumber(left) && isNumber(right):
+		if left.Type() == object.DECIMAL_OBJ || right.Type() == object.DECIMAL_OBJ {
+			opts, errObj := e.getDecimalOpts()
+			if errObj != nil {
+				return errObj
+			}
+			return evalDecimalInfixExpression(operator, left, right, opts)
+		}
+		return evalNumberInfixExpression(operator, left, right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left.(*object.String).Value, right.(*object.String).Value)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.INTEGER_OBJ && operator == "*":
+		count := right.(*object.Integer).Value
+		if count < 0 {
+			return newError("cannot repeat a string a negative number of times")
+		}
+		return &object.String{Value: strings.Repeat(left.(*object.String).Value, int(count))}
+	case left.Type() == object.LIST_OBJ && right.Type() == object.LIST_OBJ && operator == "+":
+		return object.NewList(append(left.(*object.List).Elements(), right.(*object.List).Elements()...))
+	case operator == "==":
+		return nativeToBooleanObject(objectsEqual(left, right))
+	case operator == "!=":
+		return nativeToBooleanObject(!objectsEqual(left, right))
+	case left.Type() != right.Type():
+		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	}
+	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+}
+
+// evalStringInfixExpression applies the operator to two strings
+func evalStringInfixExpression(operator string, l, r string) object.Object {
+	switch operator {
+	case "+":
+		return &object.String{Value: l + r}
+	case "<":
+		return nativeToBooleanObject(l < r)
+	case ">":
+		return nativeToBooleanObject(l > r)
+	case "<=":
+		return nativeToBooleanObject(l <= r)
+	case ">=":
+		return nativeToBooleanObject(l >= r)
+	case "==":
+		return nativeToBooleanObject(l == r)
+	case "!=":
+		return nativeToBooleanObject(l != r)
+	}
+	return newError("unknown operator: %s %s %s", object.STRING_OBJ, operator, object.STRING_OBJ)
+}
+
+// evalInExpression returns TRUE if left is an element of a list or set, a key
+// of a map, a substring of a string, or contained by a user type's `contains`
+func evalInExpression(left, right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.StructInstance:
+		if res, ok := right.CallMethod("contains", left); ok {
+			if isError(res) {
+				return res
+			}
+			return nativeToBooleanObject(isTruthy(res))
+		}
+	case *object.List:
+		if start, step, n, ok := right.Range(); ok {
+			// a range is searched from its bounds, only integers can be in it
+			switch left := left.(type) {
+			case *object.Integer:
+				i := (left.Value - start) * step
+				return nativeToBooleanObject(i >= 0 && i < n)
+			case *object.BigInteger:
+				return FALSE
+			}
+		}
+		for _, elem := range right.Elements() {
+			if objectsEqual(left, elem) {
+				return TRUE
+			}
+		}
+		return FALSE
+	case *object.Set:
+		key, ok := left.(object.Hashable)
+		if !ok {
+			return FALSE
+		}
+		return nativeToBooleanObject(right.Contains(key))
+	case *object.Map:
+		key, ok := left.(object.Hashable)
+		if !ok {
+			return FALSE
+		}
+		_, ok = right.Get(key)
+		return nativeToBooleanObject(ok)
+	case *object.ConcurrentMap:
+		key, ok := left.(object.Hashable)
+		if !ok {
+			return FALSE
+		}
+		_, ok = right.Get(key)
+		return nativeToBooleanObject(ok)
+	case *object.String:
+		l, ok := left.(*object.String)
+		if !ok {
+			return newError("left side of `in` must be a STRING when checking a STRING, got %s", left.Type())
+		}
+		return nativeToBooleanObject(strings.Contains(right.Value, l.Value))
+	}
+	return newError("`in` is not supported for %s", right.Type())
+}
+
+// objectsEqual compares the objects by value, collections are compared element by element
+func objectsEqual(left, right object.Object) bool {
+	if res, ok := evalOverloadedInfix("==", left, right); ok {
+		return res == TRUE
+	}
+	if isNumber(left) && isNumber(right) {
+		cmp, ok := compareNumbers(left, right)
+		return ok && cmp == 0
+	}
+	if left.Type() != right.Type() {
+		return false
+	}
+	switch l := left.(type) {
+	case *object.String:
+		return l.Value == right.(*object.String).Value
+	case *object.Boolean:
+		return l.Value == right.(*object.Boolean).Value
+	case *object.Null:
+		return true
+	case *object.List:
+		le, re := l.Elements(), right.(*object.List).Elements()
+		if len(le) != len(re) {
+			return false
+		}
+		for i := range le {
+			if !objectsEqual(le[i], re[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.Map:
+		r := right.(*object.Map)
+		if l.Len() != r.Len() {
+			return false
+		}
+		for _, lp := range l.Pairs() {
+			rv, ok := r.Get(lp.Key.(object.Hashable))
+			if !ok || !objectsEqual(lp.Value, rv) {
+				return false
+			}
+		}
+		return true
+	case *object.Set:
+		r := right.(*object.Set)
+		if l.Len() != r.Len() {
+			return false
+		}
+		for _, elem := range l.Elements() {
+			if !r.Contains(elem.(object.Hashable)) {
+				return false
+			}
+		}
+		return true
+	case *object.StructInstance:
+		r := right.(*object.StructInstance)
+		if l.Struct != r.Struct {
+			return false
+		}
+		for i := range l.Fields {
+			if !objectsEqual(l.Fields[i], r.Fields[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.EnumValue:
+		r := right.(*object.EnumValue)
+		if l.Variant != r.Variant {
+			return false
+		}
+		for i := range l.Payload {
+			if !objectsEqual(l.Payload[i], r.Payload[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return left == right
+}
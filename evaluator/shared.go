@@ -0,0 +1,416 @@
+package evaluator
+
+import (
+	"blue/ast"
+	"blue/object"
+)
+
+// Tasks share state through locks, atomic cells, and concurrent collections:
+//
+//	val m = mutex()
+//	var total = 0
+//	scope { for (i in 1..10) { spawn fun() { with m { total += i } } } }
+//
+// `rwmutex()` is held by one writer with `with rw { }` or by many readers with
+// `with read_lock(rw) { }`. `atomic_int(n)` and `atomic_ref(v)` are cells read with
+// load and changed with store, swap, cas, add (atomic_int only), and update which
+// applies a function to the current value. `concurrent_map()` is indexed like a map
+// and `concurrent_queue()` is a first in first out queue used with push and pop.
+//
+// A list or map bound with val belongs to the task that bound it, together with the
+// lists and maps nested in it, mutating it from another task is an error so that
+// sharing it can never race. A task that receives it with await, recv, or receive
+// becomes its owner.
+
+// evalWithExpression holds the lock while the body runs, it is released even when
+// the body returns or fails
+func (e *Evaluator) evalWithExpression(node *ast.WithExpression) object.Object {
+	obj := e.Eval(node.Lock)
+	if isError(obj) {
+		return obj
+	}
+	lock, ok := obj.(object.Locker)
+	if !ok {
+		return newError("cannot lock %s, `with` needs a MUTEX, RWMUTEX, or READ_LOCK", obj.Type())
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	return e.withEnv(object.NewEnclosedEnvironment(e.env)).Eval(node.Body)
+}
+
+// ownable is a list or map, it can be owned by the task that bound it with val
+type ownable interface {
+	object.Object
+	Owner() *object.Pid
+	SetOwner(owner *object.Pid)
+}
+
+// eachOwnable calls fn once for the list or map and for each list and map nested in it
+func eachOwnable(val object.Object, fn func(c ownable), seen map[object.Object]bool) {
+	c, ok := val.(ownable)
+	if !ok || seen[val] {
+		return
+	}
+	seen[val] = true
+	fn(c)
+	switch val := val.(type) {
+	case *object.List:
+		if _, _, _, ok := val.Range(); ok {
+			// a range only holds integers
+			return
+		}
+		for _, elem := range val.Elements() {
+			eachOwnable(elem, fn, seen)
+		}
+	case *object.Map:
+		for _, pair := range val.Pairs() {
+			eachOwnable(pair.Value, fn, seen)
+		}
+	}
+}
+
+// ownerOf returns the owner of a list or map, it is nil for any other value
+func ownerOf(obj object.Object) *object.Pid {
+	if c, ok := obj.(ownable); ok {
+		return c.Owner()
+	}
+	return nil
+}
+
+// giveOwner makes owner the owner of the lists and maps in val that have no owner yet
+func giveOwner(val object.Object, owner *object.Pid) {
+	if owner == nil {
+		return
+	}
+	eachOwnable(val, func(c ownable) {
+		if c.Owner() == nil {
+			c.SetOwner(owner)
+		}
+	}, map[object.Object]bool{})
+}
+
+// setOwner makes the task the owner of a list or map it binds with val, and of the
+// lists and maps nested in it, unless they already have an owner
+func (e *Evaluator) setOwner(val object.Object) {
+	giveOwner(val, e.actor)
+}
+
+// claimOwner moves the lists and maps in val that another task owns to the running
+// task, it is called on the values a task receives with await, recv, or receive
+func (e *Evaluator) claimOwner(val object.Object) {
+	eachOwnable(val, func(c ownable) {
+		if owner := c.Owner(); owner != nil && owner != e.actor {
+			c.SetOwner(e.actor)
+		}
+	}, map[object.Object]bool{})
+}
+
+// checkOwner returns an error if the list or map belongs to another task
+func (e *Evaluator) checkOwner(obj object.Object) *object.Error {
+	if owner := ownerOf(obj); owner != nil && owner != e.actor {
+		return newError("cannot mutate a %s bound with val in another task, bind it with var and guard it with a mutex or use a concurrent collection", obj.Type())
+	}
+	return nil
+}
+
+// lockerArg returns the single argument as a lock
+func lockerArg(name string, args []object.Object) (object.Locker, *object.Error) {
+	if len(args) != 1 {
+		return nil, newError("wrong number of arguments to `%s`. got=%d, want=1", name, len(args))
+	}
+	lock, ok := args[0].(object.Locker)
+	if !ok {
+		return nil, newError("argument to `%s` must be MUTEX, RWMUTEX, or READ_LOCK, got %s", name, args[0].Type())
+	}
+	return lock, nil
+}
+
+// builtinMutex returns a new unlocked mutex
+func builtinMutex(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments to `mutex`. got=%d, want=0", len(args))
+	}
+	return &object.Mutex{}
+}
+
+// builtinRWMutex returns a new unlocked rwmutex
+func builtinRWMutex(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments to `rwmutex`. got=%d, want=0", len(args))
+	}
+	return &object.RWMutex{}
+}
+
+// builtinReadLock returns the read side of a rwmutex
+func builtinReadLock(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments to `read_lock`. got=%d, want=1", len(args))
+	}
+	rw, ok := args[0].(*object.RWMutex)
+	if !ok {
+		return newError("argument to `read_lock` must be RWMUTEX, got %s", args[0].Type())
+	}
+	return &object.ReadLock{RW: rw}
+}
+
+// builtinLock blocks until the lock is held
+func builtinLock(args ...object.Object) object.Object {
+	lock, errObj := lockerArg("lock", args)
+	if errObj != nil {
+		return errObj
+	}
+	lock.Lock()
+	return NULL
+}
+
+// builtinUnlock releases the lock
+func builtinUnlock(args ...object.Object) object.Object {
+	lock, errObj := lockerArg("unlock", args)
+	if errObj != nil {
+		return errObj
+	}
+	if !lock.Unlock() {
+		return newError("unlock of unlocked %s", lock.Type())
+	}
+	return NULL
+}
+
+// builtinAtomicInt returns an atomic integer holding its argument or 0
+func builtinAtomicInt(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments to `atomic_int`. got=%d, want 0 or 1", len(args))
+	}
+	if len(args) == 0 {
+		return object.NewAtomicInt(0)
+	}
+	n, ok := args[0].(*object.Integer)
+	if !ok {
+		return newError("argument to `atomic_int` must be INTEGER, got %s", args[0].Type())
+	}
+	return object.NewAtomicInt(n.Value)
+}
+
+// builtinAtomicRef returns an atomic reference holding its argument or null
+func builtinAtomicRef(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments to `atomic_ref`. got=%d, want 0 or 1", len(args))
+	}
+	if len(args) == 0 {
+		return object.NewAtomicRef(NULL)
+	}
+	return object.NewAtomicRef(args[0])
+}
+
+// atomicArgs checks the number of arguments of an atomic builtin and returns the
+// values to store in an atomic_int as integers
+func atomicArgs(name string, args []object.Object, want int) ([]int64, *object.Error) {
+	if len(args) != want {
+		return nil, newError("wrong number of arguments to `%s`. got=%d, want=%d", name, len(args), want)
+	}
+	switch args[0].(type) {
+	case *object.AtomicInt:
+	case *object.AtomicRef:
+		return nil, nil
+	default:
+		return nil, newError("first argument to `%s` must be ATOMIC_INT or ATOMIC_REF, got %s", name, args[0].Type())
+	}
+	ns := make([]int64, len(args)-1)
+	for i, arg := range args[1:] {
+		n, ok := arg.(*object.Integer)
+		if !ok {
+			return nil, newError("arguments to `%s` on an ATOMIC_INT must be INTEGER, got %s", name, arg.Type())
+		}
+		ns[i] = n.Value
+	}
+	return ns, nil
+}
+
+// builtinLoad returns the value of an atomic cell
+func builtinLoad(args ...object.Object) object.Object {
+	if _, errObj := atomicArgs("load", args, 1); errObj != nil {
+		return errObj
+	}
+	if a, ok := args[0].(*object.AtomicInt); ok {
+		return &object.Integer{Value: a.Load()}
+	}
+	return args[0].(*object.AtomicRef).Load()
+}
+
+// builtinStore sets the value of an atomic cell
+func builtinStore(args ...object.Object) object.Object {
+	ns, errObj := atomicArgs("store", args, 2)
+	if errObj != nil {
+		return errObj
+	}
+	if a, ok := args[0].(*object.AtomicInt); ok {
+		a.Store(ns[0])
+	} else {
+		args[0].(*object.AtomicRef).Store(args[1])
+	}
+	return NULL
+}
+
+// builtinSwap sets the value of an atomic cell and returns the old value
+func builtinSwap(args ...object.Object) object.Object {
+	ns, errObj := atomicArgs("swap", args, 2)
+	if errObj != nil {
+		return errObj
+	}
+	if a, ok := args[0].(*object.AtomicInt); ok {
+		return &object.Integer{Value: a.Swap(ns[0])}
+	}
+	return args[0].(*object.AtomicRef).Swap(args[1])
+}
+
+// builtinCas sets the value of an atomic cell to new if it equals old and returns
+// true if it did
+func builtinCas(args ...object.Object) object.Object {
+	ns, errObj := atomicArgs("cas", args, 3)
+	if errObj != nil {
+		return errObj
+	}
+	if a, ok := args[0].(*object.AtomicInt); ok {
+		return nativeToBooleanObject(a.CompareAndSwap(ns[0], ns[1]))
+	}
+	return nativeToBooleanObject(args[0].(*object.AtomicRef).CompareAndSwap(args[1], args[2], objectsEqual))
+}
+
+// builtinAdd adds to an atomic integer and returns the new value
+func builtinAdd(args ...object.Object) object.Object {
+	ns, errObj := atomicArgs("add", args, 2)
+	if errObj != nil {
+		return errObj
+	}
+	a, ok := args[0].(*object.AtomicInt)
+	if !ok {
+		return newError("first argument to `add` must be ATOMIC_INT, got %s", args[0].Type())
+	}
+	return &object.Integer{Value: a.Add(ns[0])}
+}
+
+// builtinUpdate replaces the value of an atomic cell, or of a key of a concurrent map,
+// with the result of calling the function on the current value and returns it. Other
+// tasks never see a value between the read and the write, the function must not use
+// the map it updates
+func (e *Evaluator) builtinUpdate(args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments to `update`. got=%d, want 2 or 3", len(args))
+	}
+	switch cell := args[0].(type) {
+	case *object.ConcurrentMap:
+		if len(args) != 3 {
+			return newError("wrong number of arguments to `update` on a CONCURRENT_MAP. got=%d, want=3", len(args))
+		}
+		key, ok := args[1].(object.Hashable)
+		if !ok {
+			return newError("unusable as a map key: %s", args[1].Type())
+		}
+		return cell.Update(key, func(cur object.Object) (object.Object, bool) {
+			if cur == nil {
+				cur = NULL
+			}
+			val := e.applyFunction(args[2], []object.Object{cur}, nil)
+			return val, !isError(val)
+		})
+	case *object.AtomicInt:
+		if len(args) != 2 {
+			return newError("wrong number of arguments to `update` on an ATOMIC_INT. got=%d, want=2", len(args))
+		}
+		for {
+			old := cell.Load()
+			val := e.applyFunction(args[1], []object.Object{&object.Integer{Value: old}}, nil)
+			if isError(val) {
+				return val
+			}
+			n, ok := val.(*object.Integer)
+			if !ok {
+				return newError("function given to `update` on an ATOMIC_INT must return INTEGER, got %s", val.Type())
+			}
+			if cell.CompareAndSwap(old, n.Value) {
+				return n
+			}
+		}
+	case *object.AtomicRef:
+		if len(args) != 2 {
+			return newError("wrong number of arguments to `update` on an ATOMIC_REF. got=%d, want=2", len(args))
+		}
+		same := func(a, b object.Object) bool { return a == b }
+		for {
+			old := cell.Load()
+			val := e.applyFunction(args[1], []object.Object{old}, nil)
+			if isError(val) {
+				return val
+			}
+			if cell.CompareAndSwap(old, val, same) {
+				return val
+			}
+		}
+	}
+	return newError("first argument to `update` must be ATOMIC_INT, ATOMIC_REF, or CONCURRENT_MAP, got %s", args[0].Type())
+}
+
+// builtinConcurrentMap returns an empty concurrent map
+func builtinConcurrentMap(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments to `concurrent_map`. got=%d, want=0", len(args))
+	}
+	return object.NewConcurrentMap()
+}
+
+// builtinDelete removes the key from a concurrent map and returns true if it existed
+func builtinDelete(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments to `delete`. got=%d, want=2", len(args))
+	}
+	m, ok := args[0].(*object.ConcurrentMap)
+	if !ok {
+		return newError("first argument to `delete` must be CONCURRENT_MAP, got %s", args[0].Type())
+	}
+	key, ok := args[1].(object.Hashable)
+	if !ok {
+		return newError("unusable as a map key: %s", args[1].Type())
+	}
+	return nativeToBooleanObject(m.Delete(key))
+}
+
+// builtinConcurrentQueue returns an empty concurrent queue
+func builtinConcurrentQueue(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments to `concurrent_queue`. got=%d, want=0", len(args))
+	}
+	return &object.ConcurrentQueue{}
+}
+
+// queueArg returns the first argument as a concurrent queue after checking the number of arguments
+func queueArg(name string, args []object.Object, want int) (*object.ConcurrentQueue, *object.Error) {
+	if len(args) != want {
+		return nil, newError("wrong number of arguments to `%s`. got=%d, want=%d", name, len(args), want)
+	}
+	q, ok := args[0].(*object.ConcurrentQueue)
+	if !ok {
+		return nil, newError("first argument to `%s` must be CONCURRENT_QUEUE, got %s", name, args[0].Type())
+	}
+	return q, nil
+}
+
+// builtinPush adds the value to the back of the queue
+func builtinPush(args ...object.Object) object.Object {
+	q, errObj := queueArg("push", args, 2)
+	if errObj != nil {
+		return errObj
+	}
+	q.Push(args[1])
+	return NULL
+}
+
+// builtinPop removes and returns the value at the front of the queue, or null if it is empty
+func builtinPop(args ...object.Object) object.Object {
+	q, errObj := queueArg("pop", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	if val, ok := q.Pop(); ok {
+		return val
+	}
+	return NULL
+}
@@ -0,0 +1,212 @@
+package evaluator
+
+import (
+	"blue/ast"
+	"blue/object"
+	"blue/token"
+)
+
+// normalizeIndex returns the index counting from the end when it is negative ie. -1
+// is the last element, ok is false if the index is out of range
+func normalizeIndex(idx, length int64) (int64, bool) {
+	if idx < 0 {
+		idx += length
+	}
+	return idx, idx >= 0 && idx < length
+}
+
+// sliceBounds are the resolved start, end, and step of a slice
+type sliceBounds struct {
+	start, end, step int64
+}
+
+// indices returns the indices selected by the slice
+func (b sliceBounds) indices() []int64 {
+	indices := []int64{}
+	for i := b.start; (b.step > 0 && i < b.end) || (b.step < 0 && i > b.end); i += b.step {
+		indices = append(indices, i)
+	}
+	return indices
+}
+
+// evalSliceBounds evaluates the parts of the slice and resolves them against the length
+// of the sliced value the same way as python, out of range bounds are clamped
+func (e *Evaluator) evalSliceBounds(node *ast.SliceExpression, length int64) (sliceBounds, *object.Error) {
+	b := sliceBounds{}
+	start, hasStart, errObj := e.evalSliceIndex(node.Start)
+	if errObj != nil {
+		return b, errObj
+	}
+	end, hasEnd, errObj := e.evalSliceIndex(node.End)
+	if errObj != nil {
+		return b, errObj
+	}
+	step, hasStep, errObj := e.evalSliceIndex(node.Step)
+	if errObj != nil {
+		return b, errObj
+	}
+	if !hasStep {
+		step = 1
+	}
+	if step == 0 {
+		return b, newError("slice step cannot be zero")
+	}
+	b.step = step
+
+	lower, upper := int64(0), length
+	if b.step < 0 {
+		lower, upper = -1, length-1
+	}
+	clamp := func(idx int64) int64 {
+		if idx < 0 {
+			idx += length
+			if idx < lower {
+				return lower
+			}
+		} else if idx > upper {
+			return upper
+		}
+		return idx
+	}
+	switch {
+	case hasStart:
+		b.start = clamp(start)
+	case b.step < 0:
+		b.start = upper
+	default:
+		b.start = lower
+	}
+	switch {
+	case hasEnd:
+		b.end = clamp(end)
+	case b.step < 0:
+		b.end = lower
+	default:
+		b.end = upper
+	}
+	return b, nil
+}
+
+// evalSliceIndex evaluates a part of a slice, ok is false if it was left out or is null
+func (e *Evaluator) evalSliceIndex(exp ast.Expression) (int64, bool, *object.Error) {
+	if exp == nil {
+		return 0, false, nil
+	}
+	obj := e.Eval(exp)
+	if errObj, ok := obj.(*object.Error); ok {
+		return 0, false, errObj
+	}
+	if obj == NULL {
+		return 0, false, nil
+	}
+	n, ok := obj.(*object.Integer)
+	if !ok {
+		return 0, false, newError("slice indices must be INTEGER, got %s", obj.Type())
+	}
+	return n.Value, true, nil
+}
+
+// evalSliceExpression returns a new list or string with the selected elements
+func (e *Evaluator) evalSliceExpression(node *ast.SliceExpression) (object.Object, bool) {
+	left, short := e.evalChainLeft(node.Left, node.Optional)
+	if short || isError(left) {
+		return left, short
+	}
+	return e.evalSlice(left, node), false
+}
+
+// symbolSlice returns the slice `xs[:n]` for the index expression `xs[:n]`, ok is false
+// if the index is not a symbol or obj cannot be sliced so it is indexed with the symbol
+func symbolSlice(node *ast.IndexExpression, obj object.Object) (*ast.SliceExpression, bool) {
+	sym, ok := node.Index.(*ast.SymbolLiteral)
+	if !ok || (obj.Type() != object.LIST_OBJ && obj.Type() != object.STRING_OBJ) {
+		return nil, false
+	}
+	tok := token.Token{Type: token.IDENT, Literal: sym.Value, Span: token.Span{Start: sym.Token.Span.Start + 1, End: sym.Token.Span.End}}
+	end := &ast.Identifier{Token: tok, Value: sym.Value}
+	return &ast.SliceExpression{Token: node.Token, Left: node.Left, End: end}, true
+}
+
+// evalSlice slices the already evaluated list or string, strings are sliced by code point
+func (e *Evaluator) evalSlice(left object.Object, node *ast.SliceExpression) object.Object {
+	switch left := left.(type) {
+	case *object.List:
+		all := left.Elements()
+		b, errObj := e.evalSliceBounds(node, int64(len(all)))
+		if errObj != nil {
+			return errObj
+		}
+		elements := []object.Object{}
+		for _, i := range b.indices() {
+			elements = append(elements, all[i])
+		}
+		return object.NewList(elements)
+	case *object.String:
+		runes := []rune(left.Value)
+		b, errObj := e.evalSliceBounds(node, int64(len(runes)))
+		if errObj != nil {
+			return errObj
+		}
+		out := []rune{}
+		for _, i := range b.indices() {
+			out = append(out, runes[i])
+		}
+		return &object.String{Value: string(out)}
+	}
+	return newError("slice operator not supported: %s", left.Type())
+}
+
+// evalSliceAssignment replaces the selected elements of a list with the elements of val
+// a slice with a step must be given exactly as many elements as it selects, for compound
+// assignments op is applied to the current slice and val first
+func (e *Evaluator) evalSliceAssignment(left object.Object, node *ast.SliceExpression, op string, val object.Object) object.Object {
+	list, ok := left.(*object.List)
+	if !ok {
+		return newError("slice assignment not supported: %s", left.Type())
+	}
+	if op != "" {
+		cur := e.evalSlice(list, node)
+		if isError(cur) {
+			return cur
+		}
+		val = e.evalInfix(op, cur, val)
+		if isError(val) {
+			return val
+		}
+	}
+	elements, errObj := iterableToElements(val)
+	if errObj != nil {
+		return errObj
+	}
+	// the bounds are evaluated before the list is locked as they can run any code
+	length := list.Len()
+	b, errObj := e.evalSliceBounds(node, int64(length))
+	if errObj != nil {
+		return errObj
+	}
+	indices := b.indices()
+	if b.step != 1 && len(indices) != len(elements) {
+		return newError("cannot assign %d elements to a slice of %d elements with step %d", len(elements), len(indices), b.step)
+	}
+	if b.end < b.start {
+		b.end = b.start
+	}
+
+	var result object.Object = NULL
+	list.Update(func(current []object.Object) []object.Object {
+		if len(current) != length {
+			result = newError("cannot assign to the slice, another task changed the length of the list")
+			return current
+		}
+		if b.step != 1 {
+			for i, idx := range indices {
+				current[idx] = elements[i]
+			}
+			return current
+		}
+		updated := append([]object.Object{}, current[:b.start]...)
+		updated = append(updated, elements...)
+		return append(updated, current[b.end:]...)
+	})
+	return result
+}
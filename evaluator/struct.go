@@ -0,0 +1,119 @@
+package evaluator
+
+import (
+	"blue/ast"
+	"blue/object"
+)
+
+// evalStructStatement creates the struct object and binds it to the struct's name
+func (e *Evaluator) evalStructStatement(node *ast.StructStatement) object.Object {
+	st := &object.Struct{
+		Name:     node.Name.Value,
+		Defaults: node.Defaults,
+		Methods:  make(map[string]*object.Function, len(node.Methods)),
+		Env:      e.env,
+	}
+	for _, f := range node.Fields {
+		if st.FieldIndex(f.Value) != -1 {
+			return newError("duplicate field %s in struct %s", f.Value, st.Name)
+		}
+		st.Fields = append(st.Fields, f.Value)
+	}
+	for _, m := range node.Methods {
+		name := m.Name.Value
+		if st.FieldIndex(name) != -1 {
+			return newError("method %s of struct %s has the same name as a field", name, st.Name)
+		}
+		if _, ok := st.Methods[name]; ok {
+			return newError("duplicate method %s in struct %s", name, st.Name)
+		}
+		if len(m.Parameters) == 0 {
+			return newError("method %s of struct %s must take the receiver as its first parameter", name, st.Name)
+		}
+		st.Methods[name] = &object.Function{Parameters: m.Parameters, DefaultParameters: m.ParameterExpressions, Body: m.Body, Env: e.env, ParameterTypes: m.ParameterTypes, ReturnType: m.ReturnType}
+	}
+	st.Call = func(method *object.Function, args ...object.Object) object.Object {
+		return e.applyFunction(method, args, nil)
+	}
+	e.env.SetImmutable(st.Name, st)
+	return NULL
+}
+
+// newStructInstance constructs an instance from positional or named arguments
+// fields that are not given use their default or it is an error
+func (e *Evaluator) newStructInstance(st *object.Struct, args []object.Object, namedArgs map[string]object.Object) object.Object {
+	if len(args) > len(st.Fields) {
+		return newError("wrong number of arguments to %s. want at most %d, got=%d", st.Name, len(st.Fields), len(args))
+	}
+	fields := make([]object.Object, len(st.Fields))
+	copy(fields, args)
+	for name, val := range namedArgs {
+		idx := st.FieldIndex(name)
+		if idx == -1 {
+			return newError("%s has no field %q", st.Name, name)
+		}
+		if fields[idx] != nil {
+			return newError("field %q of %s given more than once", name, st.Name)
+		}
+		fields[idx] = val
+	}
+	defaultsEval := e.withEnv(object.NewEnclosedEnvironment(st.Env))
+	for i, f := range fields {
+		if f != nil {
+			continue
+		}
+		if st.Defaults[i] == nil {
+			return newError("missing field %q for %s", st.Fields[i], st.Name)
+		}
+		val := defaultsEval.Eval(st.Defaults[i])
+		if isError(val) {
+			return val
+		}
+		fields[i] = val
+	}
+	return &object.StructInstance{Struct: st, Fields: fields}
+}
+
+// evalStructMember returns the field of the instance or its method bound to it
+func evalStructMember(inst *object.StructInstance, name string) object.Object {
+	if idx := inst.Struct.FieldIndex(name); idx != -1 {
+		return inst.Fields[idx]
+	}
+	if method, ok := inst.Struct.Methods[name]; ok {
+		return &object.BoundMethod{Receiver: inst, Method: method}
+	}
+	if res, ok := inst.CallMethod("index", &object.String{Value: name}); ok {
+		return res
+	}
+	return newError("%s has no field %q", inst.Struct.Name, name)
+}
+
+// evalStructTypeMember returns the unbound method ie. `Point.norm(p)`
+func evalStructTypeMember(st *object.Struct, name string) object.Object {
+	if method, ok := st.Methods[name]; ok {
+		return method
+	}
+	return newError("struct %s has no method %q", st.Name, name)
+}
+
+// setStructField assigns the field of the instance, only declared fields can be set
+// unless the struct defines `set_index`
+func setStructField(inst *object.StructInstance, index, val object.Object) object.Object {
+	name, ok := index.(*object.String)
+	if ok {
+		if idx := inst.Struct.FieldIndex(name.Value); idx != -1 {
+			inst.Fields[idx] = val
+			return NULL
+		}
+	}
+	if res, ok := inst.CallMethod("set_index", index, val); ok {
+		if isError(res) {
+			return res
+		}
+		return NULL
+	}
+	if !ok {
+		return newError("field name must be a STRING, got %s", index.Type())
+	}
+	return newError("%s has no field %q", inst.Struct.Name, name.Value)
+}
@@ -0,0 +1,166 @@
+package evaluator
+
+import (
+	"blue/object"
+	"context"
+	"time"
+)
+
+// Durations are milliseconds and may be written with a unit ie. `200ms`, `5s`, `2m`,
+// or `1h`, or built from a string with `duration("1h30m")`. Timers run a function
+// later on their own goroutine:
+//
+//	val t = after(5s, fun() { println("five seconds later") })
+//	val poll = every(200ms, fun() { check() })
+//	cancel(poll)
+//
+// `await t` returns the result of the function of an `after` timer. A timer started in
+// a scope belongs to it like a spawned task, the scope waits until the timer finishes
+// or is cancelled. `with_timeout(1s, fn)` calls fn and returns a timeout error if it is
+// still running after the duration, fn stops at its next cancellation point.
+
+// builtinDuration returns the milliseconds of a duration string ie. duration("1m30s")
+func builtinDuration(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments to `duration`. got=%d, want=1", len(args))
+	}
+	s, ok := args[0].(*object.String)
+	if !ok {
+		return newError("argument to `duration` must be STRING, got %s", args[0].Type())
+	}
+	d, err := time.ParseDuration(s.Value)
+	if err != nil {
+		return newError("could not parse %q as a duration", s.Value)
+	}
+	if d%time.Millisecond != 0 {
+		return newError("duration %q is not a whole number of milliseconds", s.Value)
+	}
+	return &object.Integer{Value: d.Milliseconds()}
+}
+
+// timerArgs returns the duration and the function passed to `after` or `every`
+func timerArgs(name string, args []object.Object) (time.Duration, object.Object, *object.Error) {
+	if len(args) != 2 {
+		return 0, nil, newError("wrong number of arguments to `%s`. got=%d, want=2", name, len(args))
+	}
+	d, errObj := durationArg(name, args[:1])
+	if errObj != nil {
+		return 0, nil, errObj
+	}
+	if !IsCallable(args[1]) {
+		return 0, nil, newError("second argument to `%s` must be a function, got %s", name, args[1].Type())
+	}
+	return d, args[1], nil
+}
+
+// builtinAfter calls the function once after the duration unless it is cancelled
+func (e *Evaluator) builtinAfter(args ...object.Object) object.Object {
+	d, fn, errObj := timerArgs("after", args)
+	if errObj != nil {
+		return errObj
+	}
+	return e.startTimer(d, fn, false)
+}
+
+// builtinEvery calls the function each time the duration passes until it is cancelled
+// or the function fails
+func (e *Evaluator) builtinEvery(args ...object.Object) object.Object {
+	d, fn, errObj := timerArgs("every", args)
+	if errObj != nil {
+		return errObj
+	}
+	if d <= 0 {
+		return newError("argument to `every` must be a duration greater than 0")
+	}
+	return e.startTimer(d, fn, true)
+}
+
+// startTimer schedules the function on its own goroutine, the result of the timer is
+// the result of the last call, an error stops it, and a cancelled timer returns null
+func (e *Evaluator) startTimer(d time.Duration, fn object.Object, repeat bool) *object.Timer {
+	timer := object.NewTimer()
+	te := e.withEnv(e.env)
+	te.gen = nil
+	te.actor = object.NewPid()
+	if e.group != nil {
+		e.group.wg.Add(1)
+	}
+	go func() {
+		var result object.Object = NULL
+		defer func() {
+			if r := recover(); r != nil {
+				result = newError("timer panicked: %v", r)
+			}
+			te.actor.Exit(exitReason(te.actor, result))
+			timer.Finish(result)
+			if te.group != nil {
+				if isError(result) {
+					te.group.fail(result)
+				}
+				te.group.wg.Done()
+			}
+		}()
+		var tick <-chan time.Time
+		if repeat {
+			ticker := time.NewTicker(d)
+			defer ticker.Stop()
+			tick = ticker.C
+		} else {
+			t := time.NewTimer(d)
+			defer t.Stop()
+			tick = t.C
+		}
+		for {
+			select {
+			case <-tick:
+			case <-timer.Stopped():
+				return
+			case <-te.ctx.Done():
+				result = newCancelledError()
+				return
+			}
+			result = te.applyFunction(fn, []object.Object{}, nil)
+			timer.Ran()
+			if !repeat || isError(result) {
+				return
+			}
+		}
+	}()
+	return timer
+}
+
+// builtinCancel stops a timer, it returns false if the timer already finished
+func builtinCancel(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments to `cancel`. got=%d, want=1", len(args))
+	}
+	timer, ok := args[0].(*object.Timer)
+	if !ok {
+		return newError("argument to `cancel` must be TIMER, got %s", args[0].Type())
+	}
+	return nativeToBooleanObject(timer.Cancel())
+}
+
+// builtinWithTimeout calls the function and returns a timeout error if it has not
+// returned by the end of the duration
+func (e *Evaluator) builtinWithTimeout(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments to `with_timeout`. got=%d, want=2", len(args))
+	}
+	d, errObj := durationArg("with_timeout", args[:1])
+	if errObj != nil {
+		return errObj
+	}
+	if !IsCallable(args[1]) {
+		return newError("second argument to `with_timeout` must be a function, got %s", args[1].Type())
+	}
+	ctx, cancel := context.WithTimeout(e.ctx, d)
+	defer cancel()
+	te := e.withEnv(e.env)
+	te.ctx = ctx
+	result := te.applyFunction(args[1], []object.Object{}, nil)
+	if ctx.Err() == context.DeadlineExceeded && e.ctx.Err() == nil {
+		return newError("timed out after %s", d)
+	}
+	return result
+}
@@ -0,0 +1,201 @@
+package evaluator
+
+import (
+	"blue/ast"
+	"blue/object"
+)
+
+// User types overload operators and builtins by defining methods with these names:
+//   + - * / // % **        add, sub, mul, div, floor_div, mod, pow
+//   reflected operators    radd, rsub, ... called on the right side when the left
+//                          side does not define the operator ie. `2 * v` calls v.rmul(2)
+//   unary -                neg
+//   == !=                  equals
+//   < > <= >=              compare, returns a negative, zero, or positive INTEGER
+//   x[i], x[i] = v         index, set_index, used when i is not a field or method
+//   in, not in             contains
+//   for, len, str          iter, len, to_string
+//   map keys and sets      hash, returns an INTEGER
+
+// operatorMethods are the method names for the overloadable arithmetic operators
+var operatorMethods = map[string]string{
+	"+":  "add",
+	"-":  "sub",
+	"*":  "mul",
+	"/":  "div",
+	"//": "floor_div",
+	"%":  "mod",
+	"**": "pow",
+}
+
+// evalTraitStatement creates the trait object and binds it to the trait's name
+func (e *Evaluator) evalTraitStatement(node *ast.TraitStatement) object.Object {
+	trait := &object.Trait{Name: node.Name.Value, Defaults: map[string]*object.Function{}}
+	seen := map[string]bool{}
+	for _, m := range node.Methods {
+		name := m.Name.Value
+		if seen[name] {
+			return newError("duplicate method %s in trait %s", name, trait.Name)
+		}
+		seen[name] = true
+		if len(m.Parameters) == 0 {
+			return newError("method %s of trait %s must take the receiver as its first parameter", name, trait.Name)
+		}
+		if m.Body == nil {
+			trait.Required = append(trait.Required, name)
+			continue
+		}
+		trait.Defaults[name] = &object.Function{Parameters: m.Parameters, DefaultParameters: m.ParameterExpressions, Body: m.Body, Env: e.env, ParameterTypes: m.ParameterTypes, ReturnType: m.ReturnType}
+	}
+	e.env.SetImmutable(trait.Name, trait)
+	return NULL
+}
+
+// evalImplStatement adds the methods to the struct, when a trait is given every required
+// method must be defined and the trait's defaults are added for the methods that are not
+func (e *Evaluator) evalImplStatement(node *ast.ImplStatement) object.Object {
+	target, ok := e.env.Get(node.Target.Value)
+	if !ok {
+		return newError("identifier not found: %s", node.Target.Value)
+	}
+	st, ok := target.(*object.Struct)
+	if !ok {
+		return newError("impl is only supported for structs, got %s", target.Type())
+	}
+	var trait *object.Trait
+	if node.Trait != nil {
+		obj, ok := e.env.Get(node.Trait.Value)
+		if !ok {
+			return newError("identifier not found: %s", node.Trait.Value)
+		}
+		if trait, ok = obj.(*object.Trait); !ok {
+			return newError("%s is not a trait, got %s", node.Trait.Value, obj.Type())
+		}
+		for _, t := range st.Traits {
+			if t == trait {
+				return newError("%s already implements %s", st.Name, trait.Name)
+			}
+		}
+	}
+
+	methods := make(map[string]*object.Function, len(node.Methods))
+	for _, m := range node.Methods {
+		name := m.Name.Value
+		if st.FieldIndex(name) != -1 {
+			return newError("method %s of struct %s has the same name as a field", name, st.Name)
+		}
+		if _, ok := st.Methods[name]; ok {
+			return newError("duplicate method %s in struct %s", name, st.Name)
+		}
+		if _, ok := methods[name]; ok {
+			return newError("duplicate method %s in struct %s", name, st.Name)
+		}
+		if len(m.Parameters) == 0 {
+			return newError("method %s of struct %s must take the receiver as its first parameter", name, st.Name)
+		}
+		methods[name] = &object.Function{Parameters: m.Parameters, DefaultParameters: m.ParameterExpressions, Body: m.Body, Env: e.env, ParameterTypes: m.ParameterTypes, ReturnType: m.ReturnType}
+	}
+	if trait != nil {
+		for _, name := range trait.Required {
+			_, defined := methods[name]
+			_, existing := st.Methods[name]
+			if !defined && !existing {
+				return newError("%s does not implement %s from trait %s", st.Name, name, trait.Name)
+			}
+		}
+		for name, fn := range trait.Defaults {
+			_, defined := methods[name]
+			_, existing := st.Methods[name]
+			if !defined && !existing && st.FieldIndex(name) == -1 {
+				methods[name] = fn
+			}
+		}
+		st.Traits = append(st.Traits, trait)
+	}
+	for name, fn := range methods {
+		st.Methods[name] = fn
+	}
+	return NULL
+}
+
+// implements returns true if the value is an instance of a struct that implements the trait
+func implements(value object.Object, trait *object.Trait) bool {
+	var st *object.Struct
+	switch value := value.(type) {
+	case *object.StructInstance:
+		st = value.Struct
+	case *object.Struct:
+		st = value
+	default:
+		return false
+	}
+	for _, t := range st.Traits {
+		if t == trait {
+			return true
+		}
+	}
+	return false
+}
+
+// callMethod calls the protocol method of a user type, ok is false if obj
+// is not a user type or it does not define the method
+func callMethod(obj object.Object, name string, args ...object.Object) (object.Object, bool) {
+	inst, ok := obj.(*object.StructInstance)
+	if !ok {
+		return nil, false
+	}
+	return inst.CallMethod(name, args...)
+}
+
+// evalOverloadedInfix applies the operator with the methods of a user type, ok is
+// false if neither side overloads the operator
+func evalOverloadedInfix(operator string, left, right object.Object) (object.Object, bool) {
+	if name, ok := operatorMethods[operator]; ok {
+		if res, ok := callMethod(left, name, right); ok {
+			return res, true
+		}
+		return callMethod(right, "r"+name, left)
+	}
+
+	switch operator {
+	case "==", "!=":
+		res, ok := callMethod(left, "equals", right)
+		if !ok {
+			if res, ok = callMethod(right, "equals", left); !ok {
+				return nil, false
+			}
+		}
+		if isError(res) {
+			return res, true
+		}
+		return nativeToBooleanObject(isTruthy(res) == (operator == "==")), true
+	case "<", ">", "<=", ">=":
+		reflected := false
+		res, ok := callMethod(left, "compare", right)
+		if !ok {
+			if res, ok = callMethod(right, "compare", left); !ok {
+				return nil, false
+			}
+			reflected = true
+		}
+		if isError(res) {
+			return res, true
+		}
+		cmp, ok := res.(*object.Integer)
+		if !ok {
+			return newError("compare must return an INTEGER, got %s", res.Type()), true
+		}
+		sign := 0
+		switch {
+		case cmp.Value < 0:
+			sign = -1
+		case cmp.Value > 0:
+			sign = 1
+		}
+		if reflected {
+			sign = -sign
+		}
+		return compareResult(operator, sign), true
+	}
+	return nil, false
+}
@@ -0,0 +1,181 @@
+package evaluator
+
+import (
+	"blue/ast"
+	"blue/object"
+	"blue/token"
+	"fmt"
+)
+
+// Type annotations are optional and checked when a function is called and returns,
+// and when an annotated var or val is bound or a typed var is reassigned. The names are:
+//   any                        every value
+//   int float num str bool     num is any number ie. int, float, rational, decimal, complex
+//   none (or null)             null
+//   list[T] set[T] map[K, V]   the element types are optional ie. `list` is any list
+//   fun                        anything that can be called
+//   symbol rational decimal complex generator quote task channel pid timer
+//   mutex rwmutex atomic_int atomic_ref concurrent_map concurrent_queue
+//   A | B                      either type
+// any other name must be a struct, enum, or trait in scope, a trait matches the
+// instances of every struct that implements it
+
+// typeNames are the object types matched by each builtin type name
+var typeNames = map[string][]object.Type{
+	"int":       {object.INTEGER_OBJ, object.BIG_INTEGER_OBJ},
+	"float":     {object.FLOAT_OBJ},
+	"num":       {object.INTEGER_OBJ, object.BIG_INTEGER_OBJ, object.FLOAT_OBJ, object.RATIONAL_OBJ, object.DECIMAL_OBJ, object.COMPLEX_OBJ},
+	"rational":  {object.RATIONAL_OBJ},
+	"decimal":   {object.DECIMAL_OBJ},
+	"complex":   {object.COMPLEX_OBJ},
+	"str":       {object.STRING_OBJ},
+	"bool":      {object.BOOLEAN_OBJ},
+	"none":      {object.NULL_OBJ},
+	"null":      {object.NULL_OBJ},
+	"symbol":    {object.SYMBOL_OBJ},
+	"list":      {object.LIST_OBJ},
+	"map":       {object.MAP_OBJ},
+	"set":       {object.SET_OBJ},
+	"fun":       {object.FUNCTION_OBJ, object.BUILTIN_OBJ, object.BOUND_METHOD_OBJ, object.STRUCT_OBJ, object.ENUM_VARIANT_OBJ},
+	"generator": {object.GENERATOR_OBJ},
+	"quote":     {object.QUOTE_OBJ},
+	"task":      {object.TASK_OBJ},
+	"channel":   {object.CHANNEL_OBJ},
+	"pid":       {object.PID_OBJ},
+	"timer":     {object.TIMER_OBJ},
+
+	"mutex":            {object.MUTEX_OBJ},
+	"rwmutex":          {object.RWMUTEX_OBJ},
+	"atomic_int":       {object.ATOMIC_INT_OBJ},
+	"atomic_ref":       {object.ATOMIC_REF_OBJ},
+	"concurrent_map":   {object.CONCURRENT_MAP_OBJ},
+	"concurrent_queue": {object.CONCURRENT_QUEUE_OBJ},
+}
+
+// typeParamCounts is the number of element types each generic type takes
+var typeParamCounts = map[string]int{"list": 1, "set": 1, "map": 2}
+
+// newTypeError returns an error for a value that does not match the annotation
+func newTypeError(span token.Span, format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: "type error: " + fmt.Sprintf(format, a...), Span: &span}
+}
+
+// checkType returns an error if val does not match the annotation, what describes the
+// value in the message ie. `parameter "a"` and span is the location that is reported
+func (e *Evaluator) checkType(typ *ast.TypeAnnotation, val object.Object, what string, span token.Span) *object.Error {
+	ok, errObj := e.matchesType(typ, val)
+	if errObj != nil {
+		return errObj
+	}
+	if !ok {
+		return newTypeError(span, "%s must be %s, got %s", what, typ.String(), val.Type())
+	}
+	return nil
+}
+
+// matchesType returns true if val is of the annotated type, an error is returned
+// for names that are not types or generics with the wrong number of element types
+func (e *Evaluator) matchesType(typ *ast.TypeAnnotation, val object.Object) (bool, *object.Error) {
+	if len(typ.Union) > 0 {
+		for _, alt := range typ.Union {
+			ok, errObj := e.matchesType(alt, val)
+			if errObj != nil || ok {
+				return ok, errObj
+			}
+		}
+		return false, nil
+	}
+	if want, ok := typeParamCounts[typ.Name]; ok && len(typ.Params) != 0 && len(typ.Params) != want {
+		return false, newTypeError(typ.Span, "%s takes %d element types, got %d", typ.Name, want, len(typ.Params))
+	} else if !ok && len(typ.Params) != 0 {
+		return false, newTypeError(typ.Span, "%s does not take element types", typ.Name)
+	}
+	if typ.Name == "any" {
+		return true, nil
+	}
+
+	types, ok := typeNames[typ.Name]
+	if !ok {
+		return e.matchesUserType(typ, val)
+	}
+	matched := false
+	for _, t := range types {
+		if val.Type() == t {
+			matched = true
+			break
+		}
+	}
+	if !matched || len(typ.Params) == 0 {
+		return matched, nil
+	}
+
+	switch val := val.(type) {
+	case *object.List:
+		return e.allMatchType(typ.Params[0], val.Elements())
+	case *object.Set:
+		return e.allMatchType(typ.Params[0], val.Elements())
+	case *object.Map:
+		keys := make([]object.Object, 0, val.Len())
+		values := make([]object.Object, 0, val.Len())
+		for _, pair := range val.Pairs() {
+			keys = append(keys, pair.Key)
+			values = append(values, pair.Value)
+		}
+		if ok, errObj := e.allMatchType(typ.Params[0], keys); !ok || errObj != nil {
+			return ok, errObj
+		}
+		return e.allMatchType(typ.Params[1], values)
+	}
+	return true, nil
+}
+
+// allMatchType returns true if every value is of the annotated type
+func (e *Evaluator) allMatchType(typ *ast.TypeAnnotation, vals []object.Object) (bool, *object.Error) {
+	for _, val := range vals {
+		if ok, errObj := e.matchesType(typ, val); !ok || errObj != nil {
+			return ok, errObj
+		}
+	}
+	return true, nil
+}
+
+// matchesUserType returns true if val belongs to the struct, enum, or trait that the annotation
+// names, or is a value of the Go type registered with that name
+func (e *Evaluator) matchesUserType(typ *ast.TypeAnnotation, val object.Object) (bool, *object.Error) {
+	if m, ok := val.(object.Members); ok && string(m.Type()) == typ.Name {
+		return true, nil
+	}
+	obj, ok := e.env.Get(typ.Name)
+	if !ok {
+		return false, newTypeError(typ.Span, "unknown type %s", typ.Name)
+	}
+	switch t := obj.(type) {
+	case *object.Struct:
+		inst, ok := val.(*object.StructInstance)
+		return ok && inst.Struct == t, nil
+	case *object.Enum:
+		ev, ok := val.(*object.EnumValue)
+		return ok && ev.Variant.Enum == t, nil
+	case *object.Trait:
+		_, isInstance := val.(*object.StructInstance)
+		return isInstance && implements(val, t), nil
+	}
+	return false, newTypeError(typ.Span, "%s is not a type, got %s", typ.Name, obj.Type())
+}
+
+// checkParameterTypes checks the arguments bound in env against the function's
+// parameter annotations and records the types so reassignments are checked too
+func (e *Evaluator) checkParameterTypes(fn *object.Function, env *object.Environment) *object.Error {
+	for i, typ := range fn.ParameterTypes {
+		if typ == nil {
+			continue
+		}
+		name := fn.Parameters[i].Value
+		val, _ := env.Get(name)
+		if errObj := e.withEnv(env).checkType(typ, val, fmt.Sprintf("parameter %q", name), typ.Span); errObj != nil {
+			return errObj
+		}
+		env.SetType(name, typ)
+	}
+	return nil
+}
@@ -0,0 +1,717 @@
+package interp
+
+import (
+	"blue/evaluator"
+	"blue/object"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"time"
+)
+
+var (
+	objectType   = reflect.TypeOf((*object.Object)(nil)).Elem()
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+	durationType = reflect.TypeOf(time.Duration(0))
+	bigIntType   = reflect.TypeOf((*big.Int)(nil))
+	bigRatType   = reflect.TypeOf((*big.Rat)(nil))
+)
+
+// ToObject converts a Go value to a blue value:
+//
+//	bool                              bool
+//	ints, uints                       int (a uint64 that does not fit is a big int)
+//	floats, complex numbers           float, complex
+//	string                            str
+//	*big.Int, *big.Rat                int, rational
+//	time.Duration                     int of milliseconds like the duration literals
+//	slices and arrays                 list
+//	maps                              map
+//	structs                           map of the exported fields, a `blue:"name"` tag renames a field
+//	                                  (values of types registered with RegisterType keep their type)
+//	pointers and interfaces           the value they point to, or null if they are nil
+//	funcs                             a builtin that converts its arguments with ToGo
+//
+// A nil value is null and a blue object is passed through unchanged.
+func (in *Interpreter) ToObject(v interface{}) (object.Object, error) {
+	if v == nil {
+		return evaluator.NULL, nil
+	}
+	if obj, ok := v.(object.Object); ok {
+		return obj, nil
+	}
+	return in.valueToObject(reflect.ValueOf(v))
+}
+
+// visit is a pointer, map or slice that is being converted
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// valueToObject converts the reflected Go value to a blue value
+func (in *Interpreter) valueToObject(v reflect.Value) (object.Object, error) {
+	return in.toObject(v, map[visit]bool{})
+}
+
+// toObject converts the reflected Go value to a blue value, path holds the pointers,
+// maps and slices the value is inside of so that a value that contains itself is
+// an error instead of converting forever
+func (in *Interpreter) toObject(v reflect.Value, path map[visit]bool) (object.Object, error) {
+	if v.Type().Implements(objectType) && v.CanInterface() {
+		if obj, ok := v.Interface().(object.Object); ok && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+			return obj, nil
+		}
+	}
+	switch v.Type() {
+	case durationType:
+		return &object.Integer{Value: v.Interface().(time.Duration).Milliseconds()}, nil
+	case bigIntType:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		n := v.Interface().(*big.Int)
+		if n.IsInt64() {
+			return &object.Integer{Value: n.Int64()}, nil
+		}
+		return &object.BigInteger{Value: new(big.Int).Set(n)}, nil
+	case bigRatType:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		r := v.Interface().(*big.Rat)
+		if r.IsInt() && r.Num().IsInt64() {
+			return &object.Integer{Value: r.Num().Int64()}, nil
+		}
+		return &object.Rational{Value: new(big.Rat).Set(r)}, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			break
+		}
+		key := visit{ptr: v.Pointer(), typ: v.Type()}
+		if path[key] {
+			return nil, fmt.Errorf("cannot convert %s, it contains itself", v.Type())
+		}
+		path[key] = true
+		defer delete(path, key)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return evaluator.TRUE, nil
+		}
+		return evaluator.FALSE, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := v.Uint(); u > math.MaxInt64 {
+			return &object.BigInteger{Value: new(big.Int).SetUint64(u)}, nil
+		}
+		return &object.Integer{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: v.Float()}, nil
+	case reflect.Complex64, reflect.Complex128:
+		return &object.Complex{Value: v.Complex()}, nil
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+	case reflect.Slice, reflect.Array:
+		elems := make([]object.Object, v.Len())
+		for i := range elems {
+			elem, err := in.toObject(v.Index(i), path)
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = elem
+		}
+		return object.NewList(elems), nil
+	case reflect.Map:
+		return in.mapToObject(v, path)
+	case reflect.Struct:
+		if name, ok := in.typeName(v.Type()); ok {
+			p := reflect.New(v.Type())
+			p.Elem().Set(v)
+			return &goValue{in: in, name: name, v: p}, nil
+		}
+		return in.structToObject(v, path)
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		if v.Kind() == reflect.Ptr {
+			if name, ok := in.typeName(v.Type().Elem()); ok {
+				return &goValue{in: in, name: name, v: v}, nil
+			}
+		}
+		return in.toObject(v.Elem(), path)
+	case reflect.Func:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return in.funcToBuiltin(v.Type().String(), v), nil
+	}
+	return nil, fmt.Errorf("cannot convert %s to a blue value", v.Type())
+}
+
+// mapToObject converts a Go map to a blue map, the keys are sorted so the
+// order of the map is the same every time
+func (in *Interpreter) mapToObject(v reflect.Value, path map[visit]bool) (object.Object, error) {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return lessValue(keys[i], keys[j]) })
+	m := object.NewMap()
+	for _, k := range keys {
+		key, err := in.toObject(k, path)
+		if err != nil {
+			return nil, err
+		}
+		hashable, ok := key.(object.Hashable)
+		if !ok {
+			return nil, fmt.Errorf("cannot use %s as a map key", key.Type())
+		}
+		val, err := in.toObject(v.MapIndex(k), path)
+		if err != nil {
+			return nil, err
+		}
+		m.Set(hashable, val)
+	}
+	return m, nil
+}
+
+// lessValue orders map keys of the basic kinds, other keys keep the order of the map
+func lessValue(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	}
+	return false
+}
+
+// structToObject converts a Go struct to a blue map of its exported fields
+func (in *Interpreter) structToObject(v reflect.Value, path map[visit]bool) (object.Object, error) {
+	m := object.NewMap()
+	for i, f := range structFields(v.Type()) {
+		if f.name == "" {
+			continue
+		}
+		val, err := in.toObject(v.Field(i), path)
+		if err != nil {
+			return nil, fmt.Errorf("field %s of %s: %w", f.field, v.Type(), err)
+		}
+		m.Set(&object.String{Value: f.name}, val)
+	}
+	return m, nil
+}
+
+// structField is a field of a Go struct and the name it has in blue
+type structField struct {
+	field string
+	name  string // name is empty for the fields that are not converted
+}
+
+// structFields returns the fields of the struct type in order, unexported fields
+// and fields tagged `blue:"-"` have an empty name
+func structFields(t reflect.Type) []structField {
+	fields := make([]structField, t.NumField())
+	for i := range fields {
+		f := t.Field(i)
+		fields[i].field = f.Name
+		if f.PkgPath != "" {
+			continue
+		}
+		switch tag := f.Tag.Get("blue"); tag {
+		case "-":
+		case "":
+			fields[i].name = f.Name
+		default:
+			fields[i].name = tag
+		}
+	}
+	return fields
+}
+
+// funcToBuiltin wraps a Go func in a builtin that converts its arguments to the types
+// of the parameters and its results back to blue values, when the last result is an
+// error a non nil error is returned as a blue error
+func (in *Interpreter) funcToBuiltin(name string, fn reflect.Value) *object.Builtin {
+	t := fn.Type()
+	return &object.Builtin{Fun: func(args ...object.Object) (result object.Object) {
+		numIn := t.NumIn()
+		if t.IsVariadic() && len(args) < numIn-1 {
+			return &object.Error{Message: fmt.Sprintf("wrong number of arguments to `%s`. got=%d, want at least %d", name, len(args), numIn-1)}
+		}
+		if !t.IsVariadic() && len(args) != numIn {
+			return &object.Error{Message: fmt.Sprintf("wrong number of arguments to `%s`. got=%d, want=%d", name, len(args), numIn)}
+		}
+		vals := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			pt := paramType(t, i)
+			val, err := in.convertTo(arg, pt)
+			if err != nil {
+				return &object.Error{Message: fmt.Sprintf("argument %d to `%s`: %s", i+1, name, err)}
+			}
+			vals[i] = val
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				result = &object.Error{Message: fmt.Sprintf("`%s` panicked: %v", name, r)}
+			}
+		}()
+		return in.resultsToObject(name, t, fn.Call(vals))
+	}}
+}
+
+// paramType returns the type of the ith argument to the func type
+func paramType(t reflect.Type, i int) reflect.Type {
+	if t.IsVariadic() && i >= t.NumIn()-1 {
+		return t.In(t.NumIn() - 1).Elem()
+	}
+	return t.In(i)
+}
+
+// resultsToObject converts the results of a Go func, no results is null, one result
+// is its value and more results are a list
+func (in *Interpreter) resultsToObject(name string, t reflect.Type, out []reflect.Value) object.Object {
+	if n := len(out); n > 0 && t.Out(n-1) == errorType {
+		if err, _ := out[n-1].Interface().(error); err != nil {
+			var blueErr *Error
+			if errors.As(err, &blueErr) {
+				// the error of a blue function that was passed to the Go func
+				return &object.Error{Message: blueErr.Message}
+			}
+			return &object.Error{Message: err.Error()}
+		}
+		out = out[:n-1]
+	}
+	objs := make([]object.Object, len(out))
+	for i, v := range out {
+		obj, err := in.valueToObject(v)
+		if err != nil {
+			return &object.Error{Message: fmt.Sprintf("result of `%s`: %s", name, err)}
+		}
+		objs[i] = obj
+	}
+	switch len(objs) {
+	case 0:
+		return evaluator.NULL
+	case 1:
+		return objs[0]
+	}
+	return object.NewList(objs)
+}
+
+// ToGo converts a blue value to a Go value:
+//
+//	int, big int, rational            int64, *big.Int, *big.Rat
+//	decimal                           *big.Rat of its exact value
+//	float, complex                    float64, complex128
+//	str, symbol                       string
+//	bool                              bool
+//	null                              nil
+//	list, set                         []interface{}
+//	map                               map[string]interface{} when every key is a str
+//	                                  and map[interface{}]interface{} otherwise
+//	struct instance                   map[string]interface{} of its fields
+//	function                          func(...interface{}) (interface{}, error)
+//	value of a registered type        pointer to the Go value
+//
+// Every other value is returned as the blue object.
+func (in *Interpreter) ToGo(obj object.Object) interface{} {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return obj.Value
+	case *object.BigInteger:
+		return new(big.Int).Set(obj.Value)
+	case *object.Rational:
+		return new(big.Rat).Set(obj.Value)
+	case *object.Decimal:
+		return obj.Rat()
+	case *object.Float:
+		return obj.Value
+	case *object.Complex:
+		return obj.Value
+	case *object.String:
+		return obj.Value
+	case *object.Symbol:
+		return obj.Name
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
+		return nil
+	case *object.List:
+		return in.listToGo(obj.Elements())
+	case *object.Set:
+		return in.listToGo(obj.Elements())
+	case *object.Map:
+		return in.mapToGo(obj)
+	case *goValue:
+		return obj.v.Interface()
+	case *object.StructInstance:
+		fields := make(map[string]interface{}, len(obj.Fields))
+		for i, f := range obj.Struct.Fields {
+			fields[f] = in.ToGo(obj.Fields[i])
+		}
+		return fields
+	case *object.Function, *object.Builtin, *object.BoundMethod:
+		return func(args ...interface{}) (interface{}, error) {
+			objs := make([]object.Object, len(args))
+			for i, arg := range args {
+				val, err := in.ToObject(arg)
+				if err != nil {
+					return nil, err
+				}
+				objs[i] = val
+			}
+			result := in.e.Call(obj, objs...)
+			if errObj, ok := result.(*object.Error); ok {
+				return nil, &Error{Kind: "EvaluatorError", Message: errObj.Message, Filename: in.opts.Filename}
+			}
+			return in.ToGo(result), nil
+		}
+	}
+	return obj
+}
+
+// listToGo converts the elements to a slice of Go values
+func (in *Interpreter) listToGo(elems []object.Object) []interface{} {
+	out := make([]interface{}, len(elems))
+	for i, elem := range elems {
+		out[i] = in.ToGo(elem)
+	}
+	return out
+}
+
+// mapToGo converts the map to a map with string keys if it can, the keys that
+// cannot be Go map keys are converted to their Inspect string
+func (in *Interpreter) mapToGo(m *object.Map) interface{} {
+	pairs := m.Pairs()
+	strKeys := true
+	for _, pair := range pairs {
+		if _, ok := pair.Key.(*object.String); !ok {
+			strKeys = false
+			break
+		}
+	}
+	if strKeys {
+		out := make(map[string]interface{}, len(pairs))
+		for _, pair := range pairs {
+			out[pair.Key.(*object.String).Value] = in.ToGo(pair.Value)
+		}
+		return out
+	}
+	out := make(map[interface{}]interface{}, len(pairs))
+	for _, pair := range pairs {
+		key := in.ToGo(pair.Key)
+		if key != nil && !reflect.TypeOf(key).Comparable() {
+			key = pair.Key.Inspect()
+		}
+		out[key] = in.ToGo(pair.Value)
+	}
+	return out
+}
+
+// convertTo converts the blue value to a Go value of type t, it is used for the
+// arguments of Go funcs and the results of blue functions passed to Go as funcs
+func (in *Interpreter) convertTo(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		if v := in.ToGo(obj); v != nil {
+			return reflect.ValueOf(v), nil
+		}
+		return reflect.Zero(t), nil
+	}
+	if reflect.TypeOf(obj).AssignableTo(t) {
+		return reflect.ValueOf(obj), nil
+	}
+	if gv, ok := obj.(*goValue); ok {
+		if gv.v.Type().AssignableTo(t) {
+			return gv.v, nil
+		}
+		if gv.v.Elem().Type().AssignableTo(t) {
+			return gv.v.Elem(), nil
+		}
+	}
+	mismatch := func() (reflect.Value, error) {
+		return reflect.Value{}, fmt.Errorf("cannot use %s as %s", obj.Type(), t)
+	}
+
+	switch t {
+	case durationType:
+		if i, ok := obj.(*object.Integer); ok {
+			return reflect.ValueOf(time.Duration(i.Value) * time.Millisecond), nil
+		}
+		return mismatch()
+	case bigIntType:
+		switch obj := obj.(type) {
+		case *object.Integer:
+			return reflect.ValueOf(big.NewInt(obj.Value)), nil
+		case *object.BigInteger:
+			return reflect.ValueOf(new(big.Int).Set(obj.Value)), nil
+		}
+		return mismatch()
+	case bigRatType:
+		switch obj := obj.(type) {
+		case *object.Integer:
+			return reflect.ValueOf(new(big.Rat).SetInt64(obj.Value)), nil
+		case *object.Rational:
+			return reflect.ValueOf(new(big.Rat).Set(obj.Value)), nil
+		case *object.Decimal:
+			return reflect.ValueOf(obj.Rat()), nil
+		}
+		return mismatch()
+	}
+
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Bool:
+		b, ok := obj.(*object.Boolean)
+		if !ok {
+			return mismatch()
+		}
+		v.SetBool(b.Value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := obj.(*object.Integer)
+		if !ok {
+			return mismatch()
+		}
+		if v.OverflowInt(i.Value) {
+			return reflect.Value{}, fmt.Errorf("%d overflows %s", i.Value, t)
+		}
+		v.SetInt(i.Value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := obj.(*object.Integer)
+		if !ok {
+			return mismatch()
+		}
+		if i.Value < 0 || v.OverflowUint(uint64(i.Value)) {
+			return reflect.Value{}, fmt.Errorf("%d overflows %s", i.Value, t)
+		}
+		v.SetUint(uint64(i.Value))
+	case reflect.Float32, reflect.Float64:
+		switch n := obj.(type) {
+		case *object.Float:
+			v.SetFloat(n.Value)
+		case *object.Integer:
+			v.SetFloat(float64(n.Value))
+		default:
+			return mismatch()
+		}
+	case reflect.Complex64, reflect.Complex128:
+		switch n := obj.(type) {
+		case *object.Complex:
+			v.SetComplex(n.Value)
+		case *object.Float:
+			v.SetComplex(complex(n.Value, 0))
+		case *object.Integer:
+			v.SetComplex(complex(float64(n.Value), 0))
+		default:
+			return mismatch()
+		}
+	case reflect.String:
+		switch s := obj.(type) {
+		case *object.String:
+			v.SetString(s.Value)
+		case *object.Symbol:
+			v.SetString(s.Name)
+		default:
+			return mismatch()
+		}
+	case reflect.Slice:
+		if s, ok := obj.(*object.String); ok && t.Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(s.Value))
+			break
+		}
+		if _, ok := obj.(*object.Null); ok {
+			break
+		}
+		l, ok := obj.(*object.List)
+		if !ok {
+			return mismatch()
+		}
+		elements := l.Elements()
+		v.Set(reflect.MakeSlice(t, len(elements), len(elements)))
+		for i, elem := range elements {
+			ev, err := in.convertTo(elem, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+			}
+			v.Index(i).Set(ev)
+		}
+	case reflect.Array:
+		l, ok := obj.(*object.List)
+		if !ok {
+			return mismatch()
+		}
+		elements := l.Elements()
+		if len(elements) != t.Len() {
+			return reflect.Value{}, fmt.Errorf("cannot use a list of %d elements as %s", len(elements), t)
+		}
+		for i, elem := range elements {
+			ev, err := in.convertTo(elem, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+			}
+			v.Index(i).Set(ev)
+		}
+	case reflect.Map:
+		if _, ok := obj.(*object.Null); ok {
+			break
+		}
+		m, ok := obj.(*object.Map)
+		if !ok {
+			return mismatch()
+		}
+		v.Set(reflect.MakeMapWithSize(t, m.Len()))
+		for _, pair := range m.Pairs() {
+			kv, err := in.convertTo(pair.Key, t.Key())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %s: %w", pair.Key.Inspect(), err)
+			}
+			ev, err := in.convertTo(pair.Value, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("value of %s: %w", pair.Key.Inspect(), err)
+			}
+			v.SetMapIndex(kv, ev)
+		}
+	case reflect.Struct:
+		m, ok := obj.(*object.Map)
+		if !ok {
+			return mismatch()
+		}
+		if err := in.setStructFields(v, m); err != nil {
+			return reflect.Value{}, err
+		}
+	case reflect.Ptr:
+		if _, ok := obj.(*object.Null); ok {
+			break
+		}
+		ev, err := in.convertTo(obj, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(ev)
+		v.Set(p)
+	case reflect.Interface:
+		if _, ok := obj.(*object.Null); ok {
+			break
+		}
+		return mismatch()
+	case reflect.Func:
+		if _, ok := obj.(*object.Null); ok {
+			break
+		}
+		if !evaluator.IsCallable(obj) {
+			return mismatch()
+		}
+		v.Set(in.makeFunc(obj, t))
+	default:
+		return mismatch()
+	}
+	return v, nil
+}
+
+// setStructFields sets the fields of the struct to the values of the map with the same names
+func (in *Interpreter) setStructFields(v reflect.Value, m *object.Map) error {
+	fields := structFields(v.Type())
+	for _, pair := range m.Pairs() {
+		key, ok := pair.Key.(*object.String)
+		if !ok {
+			return fmt.Errorf("cannot use %s as a field name of %s", pair.Key.Type(), v.Type())
+		}
+		index := -1
+		for i, f := range fields {
+			if f.name != "" && f.name == key.Value {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			return fmt.Errorf("%s has no field %s", v.Type(), key.Value)
+		}
+		fv, err := in.convertTo(pair.Value, v.Field(index).Type())
+		if err != nil {
+			return fmt.Errorf("field %s of %s: %w", key.Value, v.Type(), err)
+		}
+		v.Field(index).Set(fv)
+	}
+	return nil
+}
+
+// makeFunc returns a Go func of type t that calls the blue function, when the
+// function fails the error is returned if the last result of t is an error and
+// otherwise the func panics with it
+func (in *Interpreter) makeFunc(fn object.Object, t reflect.Type) reflect.Value {
+	return reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+		fail := func(err error) []reflect.Value {
+			if t.NumOut() == 0 || t.Out(t.NumOut()-1) != errorType {
+				panic(err)
+			}
+			out := make([]reflect.Value, t.NumOut())
+			for i := range out {
+				out[i] = reflect.Zero(t.Out(i))
+			}
+			out[len(out)-1] = reflect.ValueOf(&err).Elem()
+			return out
+		}
+
+		objs := make([]object.Object, 0, len(args))
+		for i, arg := range args {
+			if t.IsVariadic() && i == len(args)-1 {
+				for j := 0; j < arg.Len(); j++ {
+					obj, err := in.valueToObject(arg.Index(j))
+					if err != nil {
+						return fail(err)
+					}
+					objs = append(objs, obj)
+				}
+				break
+			}
+			obj, err := in.valueToObject(arg)
+			if err != nil {
+				return fail(err)
+			}
+			objs = append(objs, obj)
+		}
+		result := in.e.Call(fn, objs...)
+		if errObj, ok := result.(*object.Error); ok {
+			return fail(&Error{Kind: "EvaluatorError", Message: errObj.Message, Filename: in.opts.Filename})
+		}
+
+		numOut := t.NumOut()
+		if numOut > 0 && t.Out(numOut-1) == errorType {
+			numOut--
+		}
+		results := []object.Object{result}
+		if numOut > 1 {
+			l, ok := result.(*object.List)
+			if !ok || l.Len() != numOut {
+				return fail(fmt.Errorf("function must return a list of %d values, got %s", numOut, result.Inspect()))
+			}
+			results = l.Elements()
+		}
+		out := make([]reflect.Value, t.NumOut())
+		for i := 0; i < t.NumOut(); i++ {
+			if i >= numOut {
+				out[i] = reflect.Zero(t.Out(i))
+				continue
+			}
+			v, err := in.convertTo(results[i], t.Out(i))
+			if err != nil {
+				return fail(fmt.Errorf("result of function: %w", err))
+			}
+			out[i] = v
+		}
+		return out
+	})
+}
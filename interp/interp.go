@@ -0,0 +1,179 @@
+// interp embeds the blue programming language in Go programs
+//
+//	in := interp.New(interp.Options{})
+//	in.Set("limit", 10)
+//	in.Eval("fun allowed(n) { n < limit }")
+//	ok, err := in.Call("allowed", 3)
+//
+// Go values are converted to blue values when they are passed to blue and blue values
+// are converted back when they are returned, see ToObject and ToGo. Go funcs are
+// exposed with Register and Go struct types, with their fields and methods, with
+// RegisterType.
+package interp
+
+import (
+	"blue/evaluator"
+	"blue/lexer"
+	"blue/object"
+	"blue/parser"
+	"blue/token"
+	"blue/types"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Options configures an Interpreter
+type Options struct {
+	// Filename is the name used in the errors of Eval, it defaults to "<string>"
+	Filename string
+	// Check type checks programs before running them, a program with type errors does not run
+	Check bool
+}
+
+// Interpreter runs blue programs that share one top level environment, values
+// defined by one Eval can be used by the next
+type Interpreter struct {
+	opts Options
+	e    *evaluator.Evaluator
+
+	mu    sync.RWMutex
+	types map[reflect.Type]string // types are the names of the struct types registered with RegisterType
+}
+
+// New returns an interpreter with an empty top level environment
+func New(opts Options) *Interpreter {
+	return newInterpreter(opts, evaluator.New())
+}
+
+// newInterpreter returns an interpreter that evaluates with e
+func newInterpreter(opts Options, e *evaluator.Evaluator) *Interpreter {
+	if opts.Filename == "" {
+		opts.Filename = "<string>"
+	}
+	return &Interpreter{opts: opts, e: e, types: map[reflect.Type]string{}}
+}
+
+// Error is a parser, type, or runtime error of a blue program
+type Error struct {
+	Kind     string // Kind is ParserError, TypeError, or EvaluatorError
+	Message  string
+	Filename string
+	Line     int // Line and Column start at 1, they are 0 when the position is not known
+	Column   int
+}
+
+// Error returns the message with its position ie. `rules.b:3:7: EvaluatorError: ...`
+func (err *Error) Error() string {
+	msg := err.Kind + ": " + err.Message
+	if err.Line == 0 {
+		return msg
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", err.Filename, err.Line, err.Column, msg)
+}
+
+// newError returns the error at the span of the source, span may be nil
+func newError(kind, msg, filename, src string, span *token.Span) *Error {
+	err := &Error{Kind: kind, Message: msg, Filename: filename}
+	if span != nil {
+		err.Line, err.Column = position(src, span.Start)
+	}
+	return err
+}
+
+// position returns the line and column of the rune offset in the source
+func position(src string, offset int) (int, int) {
+	line, col := 1, 1
+	for i, r := range []rune(src) {
+		if i == offset {
+			break
+		}
+		if r == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
+
+// Eval runs the source and returns its value converted with ToGo
+func (in *Interpreter) Eval(src string) (interface{}, error) {
+	return in.eval(src, in.opts.Filename)
+}
+
+// EvalFile runs the file and returns its value converted with ToGo
+func (in *Interpreter) EvalFile(path string) (interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return in.eval(string(data), path)
+}
+
+// eval parses, optionally checks, and runs the source
+func (in *Interpreter) eval(src, filename string) (interface{}, error) {
+	p := parser.New(lexer.New(src, filename))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, newError("ParserError", strings.Join(p.Errors(), "; "), filename, src, nil)
+	}
+	if in.opts.Check {
+		if errs := types.Check(program); len(errs) != 0 {
+			return nil, newError("TypeError", errs[0].Message, filename, src, &errs[0].Span)
+		}
+	}
+	result := in.e.Eval(program)
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, newError("EvaluatorError", errObj.Message, filename, src, errObj.Span)
+	}
+	return in.ToGo(result), nil
+}
+
+// Call calls the blue function bound to name with the arguments converted with
+// ToObject and returns its result converted with ToGo
+func (in *Interpreter) Call(name string, args ...interface{}) (interface{}, error) {
+	fn, ok := in.e.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%s is not defined", name)
+	}
+	objs := make([]object.Object, len(args))
+	for i, arg := range args {
+		obj, err := in.ToObject(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d to %s: %w", i+1, name, err)
+		}
+		objs[i] = obj
+	}
+	result := in.e.Call(fn, objs...)
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, &Error{Kind: "EvaluatorError", Message: errObj.Message, Filename: in.opts.Filename}
+	}
+	return in.ToGo(result), nil
+}
+
+// Set binds name to the value converted with ToObject so that programs can use it
+func (in *Interpreter) Set(name string, v interface{}) error {
+	if fn := reflect.ValueOf(v); fn.Kind() == reflect.Func && !fn.IsNil() {
+		// the builtin is named after the binding so its errors say what was called
+		in.e.Set(name, in.funcToBuiltin(name, fn))
+		return nil
+	}
+	obj, err := in.ToObject(v)
+	if err != nil {
+		return fmt.Errorf("cannot set %s: %w", name, err)
+	}
+	in.e.Set(name, obj)
+	return nil
+}
+
+// Get returns the value bound to name converted with ToGo, ok is false if it is not defined
+func (in *Interpreter) Get(name string) (interface{}, bool) {
+	obj, ok := in.e.Get(name)
+	if !ok {
+		return nil, false
+	}
+	return in.ToGo(obj), true
+}
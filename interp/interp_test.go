@@ -0,0 +1,486 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type rule struct {
+	Name    string
+	Limit   int `blue:"limit"`
+	Weights []float64
+	secret  string
+	Skipped bool `blue:"-"`
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1 + 2", int64(3)},
+		{"2 ** 100", new(big.Int).Lsh(big.NewInt(1), 100)},
+		{"1 / 4", big.NewRat(1, 4)},
+		{"1.5d", big.NewRat(3, 2)},
+		{"-0.250d", big.NewRat(-1, 4)},
+		{"1.5 * 2", 3.0},
+		{`"a" + "b"`, "ab"},
+		{":ok", "ok"},
+		{"1 < 2", true},
+		{"null", nil},
+		{`[1, "a", [true]]`, []interface{}{int64(1), "a", []interface{}{true}}},
+		{`{"a": 1, "b": [2]}`, map[string]interface{}{"a": int64(1), "b": []interface{}{int64(2)}}},
+		{"struct P { x }\n{1: \"a\", P(2): \"b\"}", map[interface{}]interface{}{int64(1): "a", "P{x: 2}": "b"}},
+		{"struct P { x, y }\nP(1, 2)", map[string]interface{}{"x": int64(1), "y": int64(2)}},
+		{"1.5s", int64(1500)},
+	}
+
+	for _, tt := range tests {
+		got, err := New(Options{}).Eval(tt.input)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: wrong value. want=%#v, got=%#v", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSetAndGet(t *testing.T) {
+	in := New(Options{})
+	values := map[string]interface{}{
+		"n":        int32(7),
+		"big":      uint64(1 << 63),
+		"f":        float32(0.5),
+		"s":        "hi",
+		"xs":       []int{1, 2, 3},
+		"arr":      [2]string{"a", "b"},
+		"m":        map[string]int{"b": 2, "a": 1},
+		"r":        rule{Name: "max", Limit: 3, Weights: []float64{0.5}, secret: "x", Skipped: true},
+		"p":        &rule{Name: "ptr"},
+		"none":     (*rule)(nil),
+		"timeout":  1500 * time.Millisecond,
+		"obj_list": []interface{}{1, "a", nil},
+	}
+	for name, v := range values {
+		if err := in.Set(name, v); err != nil {
+			t.Fatalf("Set(%s): %s", name, err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"n + 1", "8"},
+		{"big", "9223372036854775808"},
+		{"f * 2", "1"},
+		{`s + "!"`, "hi!"},
+		{"xs[1:]", "[2, 3]"},
+		{"arr", `["a", "b"]`},
+		{"m", `{"a": 1, "b": 2}`},
+		{"[r.Name, r.limit, r.Weights, r.secret, r.Skipped]", `["max", 3, [0.5], null, null]`},
+		{"p.Name", "ptr"},
+		{"none", "null"},
+		{"timeout", "1500"},
+		{"obj_list", `[1, "a", null]`},
+	}
+	for _, tt := range tests {
+		got, err := in.Eval(tt.input)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tt.input, err)
+			continue
+		}
+		obj, _ := in.ToObject(got)
+		if obj.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. want=%s, got=%s", tt.input, tt.expected, obj.Inspect())
+		}
+	}
+
+	if _, err := in.Eval("var total = 0; for (x in xs) { total += x }"); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := in.Get("total"); !ok || got != int64(6) {
+		t.Errorf("wrong total. got=%v, %v", got, ok)
+	}
+	price, err := in.Eval("19.99d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := in.Set("price", price); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := in.Eval("[price == 19.99d, price * 100]"); err != nil || !reflect.DeepEqual(got, []interface{}{true, int64(1999)}) {
+		t.Errorf("decimal did not round trip. got=%#v, %v", got, err)
+	}
+	if _, ok := in.Get("missing"); ok {
+		t.Errorf("expected missing to be undefined")
+	}
+	if err := in.Set("ch", make(chan int)); err == nil || err.Error() != "cannot set ch: cannot convert chan int to a blue value" {
+		t.Errorf("wrong error for a channel. got=%v", err)
+	}
+}
+
+type node struct {
+	Name   string
+	Parent *node
+}
+
+func TestSelfReference(t *testing.T) {
+	in := New(Options{})
+	root := &node{Name: "root"}
+	root.Parent = root
+	loop := []interface{}{1}
+	loop[0] = loop
+	m := map[string]interface{}{}
+	m["self"] = m
+
+	want := "cannot set root: field Parent of interp.node: cannot convert *interp.node, it contains itself"
+	if err := in.Set("root", root); err == nil || err.Error() != want {
+		t.Errorf("wrong error for a struct that refers to itself. got=%v", err)
+	}
+	if _, err := in.ToObject(*root); err == nil {
+		t.Errorf("expected an error for a struct that refers to itself")
+	}
+	if _, err := in.ToObject(loop); err == nil {
+		t.Errorf("expected an error for a slice that contains itself")
+	}
+	if _, err := in.ToObject(m); err == nil {
+		t.Errorf("expected an error for a map that contains itself")
+	}
+	if err := in.Set("get_root", func() *node { return root }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := in.Eval("get_root()"); err == nil || !strings.Contains(err.Error(), "result of `get_root`") {
+		t.Errorf("wrong error for a func that returns a struct that refers to itself. got=%v", err)
+	}
+
+	// a value that is only shared is not a cycle
+	shared := &node{Name: "shared"}
+	obj, err := in.ToObject([]*node{shared, {Name: "child", Parent: shared}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"Name": "shared", "Parent": null}, {"Name": "child", "Parent": {"Name": "shared", "Parent": null}}]`; obj.Inspect() != want {
+		t.Errorf("wrong value for shared pointers. want=%s, got=%s", want, obj.Inspect())
+	}
+}
+
+func TestCall(t *testing.T) {
+	in := New(Options{})
+	_, err := in.Eval(`
+fun allowed(r, n) { n <= r.limit }
+fun pair(a, b) { [b, a] }
+fun fail() { 1 + true }
+val adder = |n| => { |x| => { x + n } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := in.Call("allowed", rule{Limit: 3}, 2)
+	if err != nil || got != true {
+		t.Errorf("wrong result of allowed. got=%v, %v", got, err)
+	}
+	got, err = in.Call("pair", "a", 1.5)
+	if err != nil || !reflect.DeepEqual(got, []interface{}{1.5, "a"}) {
+		t.Errorf("wrong result of pair. got=%v, %v", got, err)
+	}
+	if _, err := in.Call("fail"); err == nil || err.Error() != "EvaluatorError: type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong error of fail. got=%v", err)
+	}
+	if _, err := in.Call("nope"); err == nil || err.Error() != "nope is not defined" {
+		t.Errorf("wrong error of nope. got=%v", err)
+	}
+
+	// a blue function returned to Go is a func that calls it
+	add, err := in.Call("adder", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn, ok := add.(func(...interface{}) (interface{}, error))
+	if !ok {
+		t.Fatalf("expected a func, got=%T", add)
+	}
+	if got, err := fn(5); err != nil || got != int64(15) {
+		t.Errorf("wrong result of the adder. got=%v, %v", got, err)
+	}
+}
+
+func TestGoFuncs(t *testing.T) {
+	in := New(Options{})
+	funcs := map[string]interface{}{
+		"upper": strings.ToUpper,
+		"join":  strings.Join,
+		"sum": func(xs ...int) int {
+			n := 0
+			for _, x := range xs {
+				n += x
+			}
+			return n
+		},
+		"div": func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, errors.New("division by zero")
+			}
+			return a / b, nil
+		},
+		"apply":  func(f func(int) int, x int) int { return f(x) },
+		"check":  func(f func(string) (bool, error)) (bool, error) { return f("x") },
+		"limit":  func(r rule) int { return r.Limit },
+		"split":  func(s string) (string, string) { i := strings.Index(s, "="); return s[:i], s[i+1:] },
+		"noop":   func() {},
+		"wait":   func(d time.Duration) string { return d.String() },
+		"boom":   func() { panic("oops") },
+		"bytes":  func(b []byte) int { return len(b) },
+		"opt":    func(r *rule) bool { return r == nil },
+		"small":  func(n int8) int8 { return n },
+		"half":   func(r *big.Rat) *big.Rat { return r.Quo(r, big.NewRat(2, 1)) },
+		"nested": map[string]interface{}{"double": func(n int) int { return n * 2 }},
+	}
+	for name, fn := range funcs {
+		if err := in.Set(name, fn); err != nil {
+			t.Fatalf("Set(%s): %s", name, err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`upper("abc")`, "ABC"},
+		{`join(["a", "b"], "-")`, "a-b"},
+		{"sum(1, 2, 3)", "6"},
+		{"sum()", "0"},
+		{"div(7, 2)", "3"},
+		{"div(1, 0)", "EvaluatorError: division by zero"},
+		{"apply(|x| => { x * 3 }, 4)", "12"},
+		{`check(|s| => { s == "x" })`, "true"},
+		{"check(|s| => { 1 + true })", "EvaluatorError: type mismatch: INTEGER + BOOLEAN"},
+		{`limit({"limit": 9, "Name": "a"})`, "9"},
+		{`limit({"nope": 1})`, "EvaluatorError: argument 1 to `limit`: interp.rule has no field nope"},
+		{`split("a=b")`, `["a", "b"]`},
+		{"noop()", "null"},
+		{"wait(2s)", "2s"},
+		{"boom()", "EvaluatorError: `boom` panicked: oops"},
+		{`bytes("héllo")`, "6"},
+		{"opt(null)", "true"},
+		{"small(200)", "EvaluatorError: argument 1 to `small`: 200 overflows int8"},
+		{`upper(1)`, "EvaluatorError: argument 1 to `upper`: cannot use INTEGER as string"},
+		{`upper()`, "EvaluatorError: wrong number of arguments to `upper`. got=0, want=1"},
+		{"nested.double(4)", "8"},
+		{"half(2.5d)", "5/4"},
+	}
+	for _, tt := range tests {
+		got, err := in.Eval(tt.input)
+		if err != nil {
+			var blueErr *Error
+			if !errors.As(err, &blueErr) {
+				t.Errorf("%s: unexpected error %s", tt.input, err)
+				continue
+			}
+			if msg := blueErr.Kind + ": " + blueErr.Message; msg != tt.expected {
+				t.Errorf("%s: wrong error. want=%s, got=%s", tt.input, tt.expected, msg)
+			}
+			continue
+		}
+		obj, _ := in.ToObject(got)
+		if obj.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. want=%s, got=%s", tt.input, tt.expected, obj.Inspect())
+		}
+	}
+}
+
+type point struct {
+	X, Y float64
+}
+
+func (p point) Dist(q point) float64 { return math.Hypot(q.X-p.X, q.Y-p.Y) }
+
+func (p *point) Scale(k float64) { p.X, p.Y = p.X*k, p.Y*k }
+
+type account struct {
+	Owner   string
+	Balance int `blue:"balance"`
+}
+
+func (a *account) Withdraw(n int) (int, error) {
+	if n > a.Balance {
+		return a.Balance, fmt.Errorf("%s cannot withdraw %d, the balance is %d", a.Owner, n, a.Balance)
+	}
+	a.Balance -= n
+	return a.Balance, nil
+}
+
+func (a *account) String() string { return "<account " + a.Owner + ">" }
+
+func TestRegister(t *testing.T) {
+	in := New(Options{})
+	if err := in.RegisterType("Point", point{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := in.RegisterType("Account", &account{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := in.Register("origin", func() point { return point{} }); err != nil {
+		t.Fatal(err)
+	}
+	if err := in.Register("atoi", strconv.Atoi); err != nil {
+		t.Fatal(err)
+	}
+	if err := in.Register("total", func(as []*account) int {
+		n := 0
+		for _, a := range as {
+			n += a.Balance
+		}
+		return n
+	}); err != nil {
+		t.Fatal(err)
+	}
+	shared := &account{Owner: "go", Balance: 10}
+	if err := in.Set("shared", shared); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Point(3, 4)", "Point{X: 3, Y: 4}"},
+		{"Point(1)", "Point{X: 1, Y: 0}"},
+		{"val p = Point(3, 4); [p.X, p.Y, p.Dist(origin())]", "[3, 4, 5]"},
+		{"val p = Point(1, 2); p.Scale(2); p.X += 1; p", "Point{X: 3, Y: 4}"},
+		{"val p = Point(1, 2); val q = p; q.X = 9; p.X", "9"},
+		{"val a = Account(\"ann\", 5); [a.Withdraw(2), a.balance, a]", "[3, 3, <account ann>]"},
+		{"Account(\"bob\", 1).Withdraw(5)", "bob cannot withdraw 5, the balance is 1"},
+		{"shared.Withdraw(4); shared.balance", "6"},
+		{"total([shared, Account(\"c\", 4)])", "10"},
+		{"atoi(\"42\")", "42"},
+		{"atoi(\"x\")", `strconv.Atoi: parsing "x": invalid syntax`},
+		{"Point(1, 2).Z", `Point has no field or method "Z"`},
+		{"val p = Point(); p.Z = 1", `Point has no field "Z"`},
+		{"val p = Point(); p.X = \"a\"", "field X of Point: cannot use STRING as float64"},
+		{"Point(1, 2, 3)", "wrong number of arguments to `Point`. want at most 2, got=3"},
+		{"Point(1, 2).Dist(1)", "argument 1 to `Point.Dist`: cannot use INTEGER as interp.point"},
+		{"fun norm(p: Point) -> float { p.Dist(origin()) }\nnorm(Point(0, 2))", "2"},
+	}
+	for _, tt := range tests {
+		got, err := in.Eval(tt.input)
+		if err != nil {
+			var blueErr *Error
+			if !errors.As(err, &blueErr) || blueErr.Message != tt.expected {
+				t.Errorf("%s: wrong error. want=%s, got=%s", tt.input, tt.expected, err)
+			}
+			continue
+		}
+		obj, _ := in.ToObject(got)
+		if obj.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. want=%s, got=%s", tt.input, tt.expected, obj.Inspect())
+		}
+	}
+
+	if shared.Balance != 6 {
+		t.Errorf("blue did not update the Go value. got balance=%d", shared.Balance)
+	}
+	got, err := in.Eval("Point(1, 2)")
+	if p, ok := got.(*point); err != nil || !ok || *p != (point{1, 2}) {
+		t.Errorf("expected a *point, got=%#v, %v", got, err)
+	}
+	if err := in.RegisterType("Bad", 1); err == nil || err.Error() != "cannot register Bad: int is not a struct" {
+		t.Errorf("wrong error for a non struct. got=%v", err)
+	}
+	if err := in.Register("bad", 1); err == nil || err.Error() != "cannot register bad: int is not a func" {
+		t.Errorf("wrong error for a non func. got=%v", err)
+	}
+}
+
+func TestModules(t *testing.T) {
+	RegisterModule("test/geo", Module{
+		Funcs:  map[string]interface{}{"Origin": func() point { return point{} }, "Atoi": strconv.Atoi},
+		Values: map[string]interface{}{"Unit": 1.0, "Name": "geo"},
+		Types:  map[string]interface{}{"Point": point{}},
+	})
+	RegisterModule("test/bad", Module{Funcs: map[string]interface{}{"F": 1}})
+	RegisterModule("example.com/go-units.v2", Module{Values: map[string]interface{}{"Name": "units"}})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"import test/geo; geo.Name", "geo"},
+		{"import test/geo; geo.Point(3, 4).Dist(geo.Origin()) * geo.Unit", "5"},
+		{"import test/geo; str(geo.Point(1, 2))", "geo.Point{X: 1, Y: 2}"},
+		{"import test/geo; geo.Atoi(\"x\")", `strconv.Atoi: parsing "x": invalid syntax`},
+		{"import test/geo; geo", "<module test/geo>"},
+		{"import test/geo; geo.Nope", `MODULE has no field or method "Nope"`},
+		{"import test/geo; geo.Name = \"x\"", "cannot assign to test/geo.Name, the members of a module are read only"},
+		{"import test/geo; geo = 1", `"geo" is immutable and cannot be reassigned`},
+		{"import test/bad", "cannot register bad.F: int is not a func"},
+		{"import test/nope", `no module is registered as "test/nope"`},
+		{"import example.com/go-units.v2; units.Name", "units"},
+	}
+	for _, tt := range tests {
+		got, err := New(Options{}).Eval(tt.input)
+		if err != nil {
+			var blueErr *Error
+			if !errors.As(err, &blueErr) || blueErr.Message != tt.expected {
+				t.Errorf("%s: wrong error. want=%s, got=%s", tt.input, tt.expected, err)
+			}
+			continue
+		}
+		in := New(Options{})
+		obj, _ := in.ToObject(got)
+		if obj.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. want=%s, got=%s", tt.input, tt.expected, obj.Inspect())
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		opts     Options
+		input    string
+		expected string
+	}{
+		{Options{}, "val x = 1\nx + true", "EvaluatorError: type mismatch: INTEGER + BOOLEAN"},
+		{Options{Filename: "rules.b"}, "val x = 1\nval y: str = x", "rules.b:2:8: EvaluatorError: type error: "},
+		{Options{Filename: "rules.b"}, "val x = (", "ParserError: "},
+		{Options{Check: true}, "val x = 1\nval y = x + \"a\"", "<string>:2:11: TypeError: type mismatch: int + str"},
+	}
+
+	for _, tt := range tests {
+		_, err := New(tt.opts).Eval(tt.input)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("%q: wrong error. want prefix %q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestEvalFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.b")
+	if err := os.WriteFile(path, []byte("fun score(n) { n * weight }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	in := New(Options{})
+	if err := in.Set("weight", 3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := in.EvalFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := in.Call("score", 4); err != nil || got != int64(12) {
+		t.Errorf("wrong score. got=%v, %v", got, err)
+	}
+	if _, err := in.EvalFile(filepath.Join(t.TempDir(), "missing.b")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing file error, got=%v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package interp
+
+import (
+	"blue/evaluator"
+	"blue/object"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Register binds name to a builtin that calls the Go func, the arguments are
+// converted to the types of its parameters and its results are converted back,
+// when the last result is an error a non nil error is returned as a blue error
+//
+//	in.Register("parse_int", strconv.Atoi)
+func (in *Interpreter) Register(name string, fn interface{}) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return fmt.Errorf("cannot register %s: %T is not a func", name, fn)
+	}
+	in.e.Set(name, in.funcToBuiltin(name, v))
+	return nil
+}
+
+// RegisterType makes a Go struct type usable from blue, pass a value of the type or a
+// pointer to it. Its values are passed to blue by reference instead of as maps, their
+// exported fields and methods are reached with member access ie. `p.X = 1` and
+// `p.Dist(q)`, and name is bound to a constructor that sets the fields in order
+//
+//	in.RegisterType("Point", Point{})
+//	in.Eval("val p = Point(1, 2); p.Dist(Point(4, 6))")
+func (in *Interpreter) RegisterType(name string, v interface{}) error {
+	constructor, err := in.registerType(name, v)
+	if err != nil {
+		return err
+	}
+	in.e.Set(name, constructor)
+	return nil
+}
+
+// registerType records the name of the struct type and returns its constructor
+func (in *Interpreter) registerType(name string, v interface{}) (*object.Builtin, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot register %s: %T is not a struct", name, v)
+	}
+	in.mu.Lock()
+	in.types[t] = name
+	in.mu.Unlock()
+	return &object.Builtin{Fun: func(args ...object.Object) object.Object {
+		return in.newGoValue(name, t, args)
+	}}, nil
+}
+
+// Module is a Go package that blue programs can import, the bindings generated by
+// `blue bindgen` register one for each package
+type Module struct {
+	Funcs  map[string]interface{} // Funcs are called like the funcs passed to Register
+	Values map[string]interface{} // Values are the constants converted with ToObject
+	Types  map[string]interface{} // Types are struct types as passed to RegisterType
+}
+
+// RegisterModule makes the module importable with `import path` by every interpreter and
+// by the blue command, its types are named after the last element of the path ie.
+// `strings.Builder`
+func RegisterModule(path string, m Module) {
+	evaluator.RegisterModule(path, func(e *evaluator.Evaluator) object.Object {
+		return newInterpreter(Options{}, e).module(path, m)
+	})
+}
+
+// module converts the members of the Go package to a blue module
+func (in *Interpreter) module(path string, m Module) object.Object {
+	prefix := path[strings.LastIndex(path, "/")+1:] + "."
+	mod := &object.Module{Path: path, Values: map[string]object.Object{}}
+	for name, v := range m.Types {
+		constructor, err := in.registerType(prefix+name, v)
+		if err != nil {
+			return &object.Error{Message: err.Error()}
+		}
+		mod.Values[name] = constructor
+	}
+	for name, fn := range m.Funcs {
+		v := reflect.ValueOf(fn)
+		if v.Kind() != reflect.Func || v.IsNil() {
+			return &object.Error{Message: fmt.Sprintf("cannot register %s%s: %T is not a func", prefix, name, fn)}
+		}
+		mod.Values[name] = in.funcToBuiltin(prefix+name, v)
+	}
+	for name, v := range m.Values {
+		obj, err := in.ToObject(v)
+		if err != nil {
+			return &object.Error{Message: fmt.Sprintf("cannot convert %s%s: %s", prefix, name, err)}
+		}
+		mod.Values[name] = obj
+	}
+	return mod
+}
+
+// typeName returns the name the struct type was registered as, ok is false if it was not
+func (in *Interpreter) typeName(t reflect.Type) (string, bool) {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	name, ok := in.types[t]
+	return name, ok
+}
+
+// newGoValue constructs a value of the registered type, the arguments are its fields in
+// order and the fields that are not given are left as zero values
+func (in *Interpreter) newGoValue(name string, t reflect.Type, args []object.Object) object.Object {
+	p := reflect.New(t)
+	var fields []int
+	for i, f := range structFields(t) {
+		if f.name != "" {
+			fields = append(fields, i)
+		}
+	}
+	if len(args) > len(fields) {
+		return &object.Error{Message: fmt.Sprintf("wrong number of arguments to `%s`. want at most %d, got=%d", name, len(fields), len(args))}
+	}
+	for i, arg := range args {
+		field := p.Elem().Field(fields[i])
+		v, err := in.convertTo(arg, field.Type())
+		if err != nil {
+			return &object.Error{Message: fmt.Sprintf("field %s of %s: %s", t.Field(fields[i]).Name, name, err)}
+		}
+		field.Set(v)
+	}
+	return &goValue{in: in, name: name, v: p}
+}
+
+// goValue is a value of a registered Go struct type, v is a pointer to the struct so that
+// its fields can be set and methods with pointer receivers can be called
+type goValue struct {
+	in   *Interpreter
+	name string
+	v    reflect.Value
+}
+
+// Type returns the name the type was registered as
+func (gv *goValue) Type() object.Type { return object.Type(gv.name) }
+
+// Inspect returns the result of the String method or the value ie. Point{X: 1, Y: 2}
+func (gv *goValue) Inspect() string {
+	if s, ok := gv.v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	fields := []string{}
+	for i, f := range structFields(gv.v.Elem().Type()) {
+		if f.name == "" {
+			continue
+		}
+		val := fmt.Sprint(gv.v.Elem().Field(i).Interface())
+		if obj, err := gv.in.valueToObject(gv.v.Elem().Field(i)); err == nil {
+			val = obj.Inspect()
+			if s, ok := obj.(*object.String); ok {
+				val = fmt.Sprintf("%q", s.Value)
+			}
+		}
+		fields = append(fields, f.name+": "+val)
+	}
+	return gv.name + "{" + strings.Join(fields, ", ") + "}"
+}
+
+// field returns the index of the field with the blue name or -1
+func (gv *goValue) field(name string) int {
+	for i, f := range structFields(gv.v.Elem().Type()) {
+		if f.name != "" && f.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Member returns the field converted to a blue value or the method as a builtin
+func (gv *goValue) Member(name string) (object.Object, bool) {
+	if i := gv.field(name); i != -1 {
+		obj, err := gv.in.valueToObject(gv.v.Elem().Field(i))
+		if err != nil {
+			return &object.Error{Message: fmt.Sprintf("field %s of %s: %s", name, gv.name, err)}, true
+		}
+		return obj, true
+	}
+	if method := gv.v.MethodByName(name); method.IsValid() {
+		return gv.in.funcToBuiltin(gv.name+"."+name, method), true
+	}
+	return nil, false
+}
+
+// SetMember converts the value to the type of the field and assigns it
+func (gv *goValue) SetMember(name string, val object.Object) error {
+	i := gv.field(name)
+	if i == -1 {
+		return fmt.Errorf("%s has no field %q", gv.name, name)
+	}
+	field := gv.v.Elem().Field(i)
+	v, err := gv.in.convertTo(val, field.Type())
+	if err != nil {
+		return fmt.Errorf("field %s of %s: %s", name, gv.name, err)
+	}
+	field.Set(v)
+	return nil
+}
@@ -17,6 +17,9 @@ type Lexer struct {
 	ch      rune // current char under examination
 	prevCh  rune // previous char read
 
+	prevType token.Type // prevType is the type of the token returned before the current one
+	newline  bool       // newline is true if the whitespace before the current token has a newline
+
 	filename string // filename is the name to print to the terminal for span
 }
 
@@ -30,6 +33,13 @@ func New(input, filename string) *Lexer {
 // NextToken matches against a byte and if it succeeds it will
 // read the next char and return a token struct
 func (l *Lexer) NextToken() token.Token {
+	tok := l.nextToken()
+	l.prevType = tok.Type
+	return tok
+}
+
+// nextToken reads the next token
+func (l *Lexer) nextToken() token.Token {
 	var tok token.Token
 
 	l.skipWhitespace()
@@ -75,6 +85,8 @@ func (l *Lexer) NextToken() token.Token {
 	case '-':
 		if l.peekChar() == '=' {
 			tok = l.makeTwoCharToken(token.MINUSEQ)
+		} else if l.peekChar() == '>' {
+			tok = l.makeTwoCharToken(token.ARROW)
 		} else {
 			tok = newToken(token.MINUS, l.ch, l.pos)
 		}
@@ -125,6 +137,8 @@ func (l *Lexer) NextToken() token.Token {
 	case '|':
 		if l.peekChar() == '=' {
 			tok = l.makeTwoCharToken(token.OREQ)
+		} else if l.peekChar() == '>' {
+			tok = l.makeTwoCharToken(token.PIPELINE)
 		} else {
 			tok = newToken(token.PIPE, l.ch, l.pos)
 		}
@@ -147,6 +161,8 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.HASH, l.ch, l.pos)
 			l.readSingleLineComment()
 		}
+	case '@':
+		tok = newToken(token.AT, l.ch, l.pos)
 	case '%':
 		if l.peekChar() == '=' {
 			tok = l.makeTwoCharToken(token.PERCENTEQ)
@@ -160,6 +176,11 @@ func (l *Lexer) NextToken() token.Token {
 			} else {
 				tok = l.makeTwoCharToken(token.RANGE)
 			}
+		} else if isDigit(l.peekChar()) && !endsOperand(l.prevCh) {
+			start := l.pos
+			tok.Type, tok.Literal = l.readNumber()
+			tok.Span = token.Span{Start: start, End: l.pos}
+			return tok
 		} else {
 			tok = newToken(token.DOT, l.ch, l.pos)
 		}
@@ -174,11 +195,23 @@ func (l *Lexer) NextToken() token.Token {
 		tok.Literal = l.readExecString()
 		return tok
 	case ':':
+		// `:` directly followed by an identifier is a symbol where an operand can start,
+		// after an operand on the same line it is a COLON ie. `{a: b}`, `{"k" :v}`,
+		// and `fun f(a : int)`
+		if isLetter(l.peekChar()) && (l.newline || !endsOperandToken(l.prevType)) {
+			start := l.pos
+			l.readChar()
+			tok.Type = token.SYMBOL
+			tok.Literal = l.readIdentifier()
+			tok.Span = token.Span{Start: start, End: l.pos}
+			return tok
+		}
 		tok = newToken(token.COLON, l.ch, l.pos)
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
 	case '"':
+		start := l.pos
 		if l.peekChar() == '"' && l.peekNextChar() == '"' {
 			str := l.readRawString()
 			tok.Type = token.RAW_STRING
@@ -192,12 +225,30 @@ func (l *Lexer) NextToken() token.Token {
 				tok.Literal = str
 			}
 		}
+		if tok.Type != token.ILLEGAL {
+			// the span ends on the closing quote like the span of a single char token
+			tok.Span = token.Span{Start: start, End: l.pos}
+		}
 	default:
 		start := l.pos
+		if l.ch == '?' && isOptionalOperator(l.peekChar()) {
+			switch l.peekChar() {
+			case '.':
+				tok = l.makeTwoCharToken(token.OPTDOT)
+			case '[':
+				tok = l.makeTwoCharToken(token.OPTLBRACKET)
+			default:
+				tok = l.makeTwoCharToken(token.COALESCE)
+			}
+			break
+		}
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
-			end := l.pos
 			tok.Type = token.LookupIdent(tok.Literal)
+			if tok.Type == token.NOT && l.readNotIn() {
+				tok.Type, tok.Literal = token.NOTIN, "not in"
+			}
+			end := l.pos
 			tok.Span = token.Span{Start: start, End: end}
 			return tok
 		} else if isDigit(l.ch) {
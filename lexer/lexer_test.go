@@ -352,8 +352,7 @@ func TestNextTokenNumbers(t *testing.T) {
 		{token.DOT, "."},
 		{token.INT, "12"},
 		{token.FLOAT, "12_1234.12345_12"},
-		{token.IDENT, "_"},
-		{token.INT, "1234"},
+		{token.IDENT, "_1234"},
 		{token.EOF, ""},
 	}
 
@@ -408,6 +407,139 @@ func TestNextTokenNewKeywords(t *testing.T) {
 	}
 }
 
+func TestNextTokenNotIn(t *testing.T) {
+	input := `x not in xs; not inside; not in2; not in_x; not	in; not
+in`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.IDENT, "x"},
+		{token.NOTIN, "not in"},
+		{token.IDENT, "xs"},
+		{token.SEMICOLON, ";"},
+		{token.NOT, "not"},
+		{token.IDENT, "inside"},
+		{token.SEMICOLON, ";"},
+		{token.NOT, "not"},
+		{token.IDENT, "in2"},
+		{token.SEMICOLON, ";"},
+		{token.NOT, "not"},
+		{token.IDENT, "in_x"},
+		{token.SEMICOLON, ";"},
+		{token.NOTIN, "not in"},
+		{token.SEMICOLON, ";"},
+		{token.NOT, "not"},
+		{token.IN, "in"},
+		{token.EOF, ""},
+	}
+
+	l := New(input, "<string>")
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokenType wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - tokenLiteral wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNextTokenIdentifiersWithDigits(t *testing.T) {
+	input := `log10 atan2 x1y2 h2 1x, :v2 _1`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.IDENT, "log10"},
+		{token.IDENT, "atan2"},
+		{token.IDENT, "x1y2"},
+		{token.IDENT, "h2"},
+		{token.INT, "1"},
+		{token.IDENT, "x"},
+		{token.COMMA, ","},
+		{token.SYMBOL, "v2"},
+		{token.IDENT, "_1"},
+		{token.EOF, ""},
+	}
+
+	l := New(input, "<string>")
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokenType wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - tokenLiteral wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNextTokenOptionalAndPipeline(t *testing.T) {
+	input := `user?.name m?["k"] f?.() x??y empty?(xs) a ?? b |> |x|`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.IDENT, "user"},
+		{token.OPTDOT, "?."},
+		{token.IDENT, "name"},
+		{token.IDENT, "m"},
+		{token.OPTLBRACKET, "?["},
+		{token.STRING, "k"},
+		{token.RBRACKET, "]"},
+		{token.IDENT, "f"},
+		{token.OPTDOT, "?."},
+		{token.LPAREN, "("},
+		{token.RPAREN, ")"},
+		{token.IDENT, "x"},
+		{token.COALESCE, "??"},
+		{token.IDENT, "y"},
+		{token.IDENT, "empty?"},
+		{token.LPAREN, "("},
+		{token.IDENT, "xs"},
+		{token.RPAREN, ")"},
+		{token.IDENT, "a"},
+		{token.COALESCE, "??"},
+		{token.IDENT, "b"},
+		{token.PIPELINE, "|>"},
+		{token.PIPE, "|"},
+		{token.IDENT, "x"},
+		{token.PIPE, "|"},
+		{token.EOF, ""},
+	}
+
+	l := New(input, "<string>")
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokenType wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - tokenLiteral wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
 func TestNextTokenStrings(t *testing.T) {
 	input := `"Hello #{world}!";`
 
@@ -500,3 +632,313 @@ $
 		}
 	}
 }
+
+func TestNextTokenRationals(t *testing.T) {
+	input := `1r 0.75r 1_000r 3rd 19.99d 5d 2do`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.RATIONAL, "1r"},
+		{token.RATIONAL, "0.75r"},
+		{token.RATIONAL, "1_000r"},
+		{token.INT, "3"},
+		{token.IDENT, "rd"},
+		{token.DECIMAL, "19.99d"},
+		{token.DECIMAL, "5d"},
+		{token.INT, "2"},
+		{token.IDENT, "do"},
+		{token.EOF, ""},
+	}
+
+	l := New(input, "<string>")
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokenType wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - tokenLiteral wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNextTokenDurations(t *testing.T) {
+	input := `200ms 5s 1.5m 2h 1_000ms 5min 3sec 2hx`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.DURATION, "200ms"},
+		{token.DURATION, "5s"},
+		{token.DURATION, "1.5m"},
+		{token.DURATION, "2h"},
+		{token.DURATION, "1_000ms"},
+		{token.INT, "5"},
+		{token.IDENT, "min"},
+		{token.INT, "3"},
+		{token.IDENT, "sec"},
+		{token.INT, "2"},
+		{token.IDENT, "hx"},
+		{token.EOF, ""},
+	}
+
+	l := New(input, "<string>")
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokenType wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - tokenLiteral wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNextTokenNumberForms(t *testing.T) {
+	input := `1e9 2.5e-3 1E+2 .5 0x1.8p1 0x1p-2 3i 2.5i 1e3i 1.5e3d x.y 1..2 1ex 3else`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.FLOAT, "1e9"},
+		{token.FLOAT, "2.5e-3"},
+		{token.FLOAT, "1E+2"},
+		{token.FLOAT, ".5"},
+		{token.FLOAT, "0x1.8p1"},
+		{token.FLOAT, "0x1p-2"},
+		{token.IMAGINARY, "3i"},
+		{token.IMAGINARY, "2.5i"},
+		{token.IMAGINARY, "1e3i"},
+		{token.DECIMAL, "1.5e3d"},
+		{token.IDENT, "x"},
+		{token.DOT, "."},
+		{token.IDENT, "y"},
+		{token.INT, "1"},
+		{token.RANGE, ".."},
+		{token.INT, "2"},
+		{token.INT, "1"},
+		{token.IDENT, "ex"},
+		{token.INT, "3"},
+		{token.ELSE, "else"},
+		{token.EOF, ""},
+	}
+
+	l := New(input, "<string>")
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokenType wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - tokenLiteral wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestIllegalNumbers(t *testing.T) {
+	input := `1__0 0x 1_ 0b102 0x1.8 1e+_2 ٣ 12½ 0o 1e 1E 1.5e 1e+`
+
+	tests := []struct {
+		expectedLiteral string
+		expectedSpan    token.Span
+	}{
+		{"1__0", token.Span{Start: 0, End: 4}},
+		{"0x", token.Span{Start: 5, End: 7}},
+		{"1_", token.Span{Start: 8, End: 10}},
+		{"0b102", token.Span{Start: 11, End: 16}},
+		{"0x1.8", token.Span{Start: 17, End: 22}},
+		{"1e+_2", token.Span{Start: 23, End: 28}},
+		{"٣", token.Span{Start: 29, End: 29}},
+		{"12½", token.Span{Start: 31, End: 34}},
+		{"0o", token.Span{Start: 35, End: 37}},
+		{"1e", token.Span{Start: 38, End: 40}},
+		{"1E", token.Span{Start: 41, End: 43}},
+		{"1.5e", token.Span{Start: 44, End: 48}},
+		{"1e+", token.Span{Start: 49, End: 52}},
+	}
+
+	l := New(input, "<string>")
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != token.ILLEGAL {
+			t.Fatalf("test[%d] - tokenType wrong. expected=%q, got=%q (%q)",
+				i, token.ILLEGAL, tok.Type, tok.Literal)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - tokenLiteral wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Span != tt.expectedSpan {
+			t.Fatalf("test[%d] - span wrong. expected=%s, got=%s",
+				i, tt.expectedSpan, tok.Span)
+		}
+	}
+}
+
+func TestNextTokenSymbols(t *testing.T) {
+	input := `:idle {a:b, :c: 1} x[:n] fun f(a : int) {"k" :v, "j": :w} y
+:ok empty?:`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.SYMBOL, "idle"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "a"},
+		{token.COLON, ":"},
+		{token.IDENT, "b"},
+		{token.COMMA, ","},
+		{token.SYMBOL, "c"},
+		{token.COLON, ":"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "x"},
+		{token.LBRACKET, "["},
+		{token.SYMBOL, "n"},
+		{token.RBRACKET, "]"},
+		{token.FUNCTION, "fun"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COLON, ":"},
+		{token.IDENT, "int"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.STRING, "k"},
+		{token.COLON, ":"},
+		{token.IDENT, "v"},
+		{token.COMMA, ","},
+		{token.STRING, "j"},
+		{token.COLON, ":"},
+		{token.SYMBOL, "w"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "y"},
+		{token.SYMBOL, "ok"},
+		{token.IDENT, "empty?"},
+		{token.COLON, ":"},
+		{token.EOF, ""},
+	}
+
+	l := New(input, "<string>")
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokenType wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - tokenLiteral wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNextTokenDecorator(t *testing.T) {
+	input := "@memo\n@retry(times=3)\nfun f() {}"
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.AT, "@"},
+		{token.IDENT, "memo"},
+		{token.AT, "@"},
+		{token.IDENT, "retry"},
+		{token.LPAREN, "("},
+		{token.IDENT, "times"},
+		{token.ASSIGN, "="},
+		{token.INT, "3"},
+		{token.RPAREN, ")"},
+		{token.FUNCTION, "fun"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := New(input, "<string>")
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokenType wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - tokenLiteral wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNextTokenTypeAnnotations(t *testing.T) {
+	input := "fun f(a: list[int]) -> int | none {}"
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.FUNCTION, "fun"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COLON, ":"},
+		{token.IDENT, "list"},
+		{token.LBRACKET, "["},
+		{token.IDENT, "int"},
+		{token.RBRACKET, "]"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "->"},
+		{token.IDENT, "int"},
+		{token.PIPE, "|"},
+		{token.IDENT, "none"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := New(input, "<string>")
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokenType wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - tokenLiteral wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"blue/token"
 	"encoding/hex"
 	"strings"
+	"unicode"
 )
 
 // readChar gives us the next character and advances out position
@@ -36,62 +37,199 @@ func (l *Lexer) peekNextChar() rune {
 }
 
 // readNumber will keep consuming valid digits of the input according to `isDigit`
-// and return the string
-// TODO: readNumber can be refactored to be cleaner
+// and return the string. It handles `_` digit grouping, a fraction, an exponent
+// ie. `2.5e-3`, the base prefixes `0x`, `0o`, `0b`, hex floats ie. `0x1.8p1`, and
+// the suffixes `r` (rational), `d` (decimal), and `i` (imaginary). A malformed
+// number is returned as a single ILLEGAL token ie. `1__0` or `0x`
 func (l *Lexer) readNumber() (token.Type, string) {
 	position := l.pos
-	if l.ch == '0' {
-		if l.peekChar() == 'x' && isHexChar(l.peekNextChar()) {
-			// consume the 0 and x and continue to the number
-			l.readChar()
+	if l.ch == '0' && (l.peekChar() == 'x' || l.peekChar() == 'o' || l.peekChar() == 'b') {
+		return l.readBasedNumber()
+	}
+	typ := token.Type(token.INT)
+	if l.ch == '.' {
+		// a leading dot ie. `.5`
+		typ = token.FLOAT
+		l.readChar()
+	}
+	if !l.readDigits(isDigit) {
+		return l.readIllegalNumber(position)
+	}
+	if typ == token.INT && l.ch == '.' && isDigit(l.peekChar()) {
+		typ = token.FLOAT
+		l.readChar()
+		if !l.readDigits(isDigit) {
+			return l.readIllegalNumber(position)
+		}
+	}
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if isDigit(next) || ((next == '+' || next == '-') && isDigit(l.peekNextChar())) {
+			typ = token.FLOAT
 			l.readChar()
-			for isHexChar(l.ch) || (l.ch == '_' && isHexChar(l.peekChar())) {
+			if l.ch == '+' || l.ch == '-' {
 				l.readChar()
 			}
-			return token.HEX, string(toRunes(l.input)[position:l.pos])
-		} else if l.peekChar() == 'o' && isOctalChar(l.peekNextChar()) {
-			// consume the 0 and the o and continue to the number
+			if !l.readDigits(isDigit) {
+				return l.readIllegalNumber(position)
+			}
+		} else if next == '+' || next == '-' {
+			// an exponent sign without digits ie. `1e+`
 			l.readChar()
 			l.readChar()
-			for isOctalChar(l.ch) || (l.ch == '_' && isOctalChar(l.peekChar())) {
-				l.readChar()
-			}
-			return token.OCTAL, string(toRunes(l.input)[position:l.pos])
-		} else if l.peekChar() == 'b' && isBinaryChar(l.peekNextChar()) {
-			// consume the 0 and the b and continue to the number
+			return l.readIllegalNumber(position)
+		} else if !isLetter(next) {
+			// an exponent without digits ie. `1e`, a letter after the `e` makes it
+			// an identifier instead ie. `3else`
 			l.readChar()
+			return l.readIllegalNumber(position)
+		}
+	}
+	if unicode.IsNumber(l.ch) {
+		return l.readIllegalNumber(position)
+	}
+	// A trailing ms, s, m, or h makes the number a duration ie. `200ms` or `1.5s`
+	if unit := l.durationUnitLen(); unit > 0 {
+		for i := 0; i < unit; i++ {
 			l.readChar()
-			for isBinaryChar(l.ch) || (l.ch == '_' && isBinaryChar(l.peekChar())) {
-				l.readChar()
-			}
-			return token.BINARY, string(toRunes(l.input)[position:l.pos])
 		}
+		return token.DURATION, string(toRunes(l.input)[position:l.pos])
+	}
+	// A trailing r makes the number an exact rational ie. `1.5r`, a trailing d
+	// makes it an exact decimal ie. `19.99d`, and a trailing i makes it imaginary ie. `3i`
+	if (l.ch == 'r' || l.ch == 'd' || l.ch == 'i') && !isLetter(l.peekChar()) && !isDigit(l.peekChar()) {
+		switch l.ch {
+		case 'r':
+			typ = token.RATIONAL
+		case 'd':
+			typ = token.DECIMAL
+		case 'i':
+			typ = token.IMAGINARY
+		}
+		l.readChar()
+	}
+	return typ, string(toRunes(l.input)[position:l.pos])
+}
+
+// durationUnitLen returns the length of the duration unit at the current char
+// or 0 if the number does not end with one
+func (l *Lexer) durationUnitLen() int {
+	unit := 0
+	switch {
+	case l.ch == 'm' && l.peekChar() == 's':
+		unit = 2
+	case l.ch == 's' || l.ch == 'm' || l.ch == 'h':
+		unit = 1
+	default:
+		return 0
+	}
+	// the unit must end the number ie. `5min` is not a duration
+	next := l.peekChar()
+	if unit == 2 {
+		next = l.peekNextChar()
+	}
+	if isLetter(next) || isDigit(next) {
+		return 0
+	}
+	return unit
+}
+
+// readBasedNumber reads a number with a `0x`, `0o`, or `0b` prefix
+// hex numbers may also be hex floats with a `p` exponent ie. `0x1.8p1`
+func (l *Lexer) readBasedNumber() (token.Type, string) {
+	position := l.pos
+	typ, valid := token.Type(token.HEX), isHexChar
+	switch l.peekChar() {
+	case 'o':
+		typ, valid = token.OCTAL, isOctalChar
+	case 'b':
+		typ, valid = token.BINARY, isBinaryChar
 	}
-	dotFlag := false
-	for isDigit(l.ch) || (l.ch == '_' && isDigit(l.peekChar())) {
-		if l.peekChar() == '.' && !dotFlag && l.peekNextChar() != '.' {
-			dotFlag = true
+	// consume the 0 and the base char and continue to the number
+	l.readChar()
+	l.readChar()
+	if !l.readDigits(valid) {
+		return l.readIllegalNumber(position)
+	}
+	if typ == token.HEX && ((l.ch == '.' && isHexChar(l.peekChar())) || l.ch == 'p' || l.ch == 'P') {
+		typ = token.FLOAT
+		if l.ch == '.' {
 			l.readChar()
+			if !l.readDigits(isHexChar) {
+				return l.readIllegalNumber(position)
+			}
+		}
+		// hex floats must have an exponent
+		if l.ch != 'p' && l.ch != 'P' {
+			return l.readIllegalNumber(position)
+		}
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
 			l.readChar()
 		}
+		if !l.readDigits(isDigit) {
+			return l.readIllegalNumber(position)
+		}
+	}
+	if isDigit(l.ch) || unicode.IsNumber(l.ch) {
+		return l.readIllegalNumber(position)
+	}
+	return typ, string(toRunes(l.input)[position:l.pos])
+}
+
+// readDigits consumes digits according to valid and `_` separators, it returns
+// false if there were no digits or a `_` was not between two digits
+func (l *Lexer) readDigits(valid func(rune) bool) bool {
+	ok := valid(l.ch)
+	for valid(l.ch) || l.ch == '_' {
+		if l.ch == '_' && !valid(l.peekChar()) {
+			ok = false
+		}
 		l.readChar()
 	}
-	if dotFlag {
-		return token.FLOAT, string(toRunes(l.input)[position:l.pos])
+	return ok
+}
+
+// readIllegalNumber consumes the rest of a malformed number starting at position
+// and returns it as an ILLEGAL token
+func (l *Lexer) readIllegalNumber(position int) (token.Type, string) {
+	for isLetter(l.ch) || unicode.IsNumber(l.ch) || (l.ch == '.' && unicode.IsNumber(l.peekChar())) {
+		l.readChar()
 	}
-	return token.INT, string(toRunes(l.input)[position:l.pos])
+	return token.ILLEGAL, string(toRunes(l.input)[position:l.pos])
 }
 
-// readIdentifier will keep consuming valid letters out of the input according to `isLetter`
-// and return the string
+// readIdentifier will keep consuming valid letters out of the input according to `isLetter`,
+// and digits after the first letter ie. `log10`, and return the string, a `?` that starts
+// `?.`, `?[`, or `??` is not part of the identifier
 func (l *Lexer) readIdentifier() string {
 	position := l.pos
-	for isLetter(l.ch) {
+	for (isLetter(l.ch) || isDigit(l.ch)) && !(l.ch == '?' && isOptionalOperator(l.peekChar())) {
 		l.readChar()
 	}
 	return string(toRunes(l.input)[position:l.pos])
 }
 
+// readNotIn consumes the ` in` following `not` so that `not in` is a single NOTIN
+// token, if `not` is not followed by `in` nothing is consumed and false is returned
+func (l *Lexer) readNotIn() bool {
+	runes := toRunes(l.input)
+	i := l.pos
+	for i < len(runes) && (runes[i] == ' ' || runes[i] == '\t') {
+		i++
+	}
+	if i == l.pos || i+1 >= len(runes) || runes[i] != 'i' || runes[i+1] != 'n' {
+		return false
+	}
+	if i+2 < len(runes) && (isLetter(runes[i+2]) || isDigit(runes[i+2])) {
+		return false
+	}
+	for l.pos < i+2 {
+		l.readChar()
+	}
+	return true
+}
+
 // readSingleLineComment will continue to consume input until the EOL is reached
 func (l *Lexer) readSingleLineComment() {
 	for l.ch != 0 {
@@ -196,7 +334,11 @@ func (l *Lexer) readString() (string, error) {
 // skipWhitespace will continue to advance if the current byte is considered
 // a whitespace character such as ' ', '\t', '\n', '\r'
 func (l *Lexer) skipWhitespace() {
+	l.newline = false
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+		if l.ch == '\n' {
+			l.newline = true
+		}
 		l.readChar()
 	}
 }
@@ -204,18 +346,18 @@ func (l *Lexer) skipWhitespace() {
 // makeTwoCharToken takes a tokens type and returns the new token
 // while advancing the readPosition and current char
 func (l *Lexer) makeTwoCharToken(typ token.Type) token.Token {
-	ch := l.ch
+	ch, start := l.ch, l.pos
 	// consume next char because we know it is an =
 	l.readChar()
-	return token.Token{Type: typ, Literal: string(ch) + string(l.ch)}
+	return token.Token{Type: typ, Literal: string(ch) + string(l.ch), Span: token.Span{Start: start, End: l.pos}}
 }
 
 // makeThreeCharToken takes a tokens type and returns the new token
 // while advancing the readPosition and current char to the proper position
 func (l *Lexer) makeThreeCharToken(typ token.Type) token.Token {
-	ch := l.ch
+	ch, start := l.ch, l.pos
 	l.readChar()
 	ch1 := l.ch
 	l.readChar()
-	return token.Token{Type: typ, Literal: string(ch) + string(ch1) + string(l.ch)}
+	return token.Token{Type: typ, Literal: string(ch) + string(ch1) + string(l.ch), Span: token.Span{Start: start, End: l.pos}}
 }
@@ -26,9 +26,34 @@ func isLetter(ch rune) bool {
 	return unicode.IsLetter(rune(ch)) || ch == '_' || ch == '?'
 }
 
-// isDigit will return true if the rune matches the below pattern
+// isOptionalOperator returns true if a `?` followed by ch is one of `?.`, `?[`, or `??`
+// rather than part of an identifier like `empty?`
+func isOptionalOperator(ch rune) bool {
+	return ch == '.' || ch == '[' || ch == '?'
+}
+
+// isDigit will return true if the rune is an ascii digit, other unicode
+// numbers such as `٣` or `½` are not valid in number literals
 func isDigit(ch rune) bool {
-	return '0' <= ch && ch <= '9' || unicode.IsNumber(ch)
+	return '0' <= ch && ch <= '9'
+}
+
+// endsOperand will return true if the rune can be the last char of an operand
+// it is used to tell a member access `.` apart from a leading dot float ie. `.5`
+func endsOperand(ch rune) bool {
+	return isLetter(ch) || isDigit(ch) || ch == ')' || ch == ']' || ch == '}' || ch == '"'
+}
+
+// endsOperandToken will return true if a token of the type can be the last token of an
+// operand, it is used to tell a COLON after an operand apart from a symbol ie. `:name`
+func endsOperandToken(typ token.Type) bool {
+	switch typ {
+	case token.IDENT, token.INT, token.FLOAT, token.HEX, token.OCTAL, token.BINARY, token.RATIONAL,
+		token.DECIMAL, token.IMAGINARY, token.DURATION, token.SYMBOL, token.STRING, token.RAW_STRING,
+		token.BACKTICK, token.TRUE, token.FALSE, token.NULL_KW, token.RPAREN, token.RBRACKET, token.RBRACE:
+		return true
+	}
+	return false
 }
 
 // isHexChar will return true if the rune given is a hex character
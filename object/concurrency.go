@@ -0,0 +1,390 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Task is a function running on its own goroutine, it is returned by `spawn`
+type Task struct {
+	done   chan struct{}
+	result Object
+}
+
+// NewTask returns a task that is running until Finish is called
+func NewTask() *Task {
+	return &Task{done: make(chan struct{})}
+}
+
+// Finish records the result of the task and wakes up everyone waiting for it
+func (t *Task) Finish(result Object) {
+	t.result = result
+	close(t.done)
+}
+
+// Wait blocks until the task is finished and returns its result, or the error of
+// the context if it is done first
+func (t *Task) Wait(ctx context.Context) (Object, error) {
+	select {
+	case <-t.done:
+		return t.result, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Done returns a channel that is closed once the task is finished
+func (t *Task) Done() <-chan struct{} { return t.done }
+
+// Type returns TASK_OBJ
+func (t *Task) Type() Type { return TASK_OBJ }
+
+// Inspect returns whether the task is still running ie. <task running>
+func (t *Task) Inspect() string {
+	select {
+	case <-t.done:
+		return "<task done>"
+	default:
+		return "<task running>"
+	}
+}
+
+// Timer is a function scheduled by `after` to run once or by `every` to run repeatedly,
+// it finishes like a task once the function returns, fails, or the timer is cancelled
+type Timer struct {
+	*Task
+	stop chan struct{}
+	once sync.Once
+	runs int64
+}
+
+// NewTimer returns a timer that is scheduled until it finishes or is cancelled
+func NewTimer() *Timer {
+	return &Timer{Task: NewTask(), stop: make(chan struct{})}
+}
+
+// Cancel stops the timer, it returns false if it had already finished or been cancelled
+func (t *Timer) Cancel() bool {
+	cancelled := false
+	t.once.Do(func() {
+		select {
+		case <-t.done:
+		default:
+			close(t.stop)
+			cancelled = true
+		}
+	})
+	return cancelled
+}
+
+// Stopped returns a channel that is closed once the timer is cancelled
+func (t *Timer) Stopped() <-chan struct{} { return t.stop }
+
+// Ran records that the function of the timer ran once more
+func (t *Timer) Ran() { atomic.AddInt64(&t.runs, 1) }
+
+// Runs returns the number of times the function of the timer has run
+func (t *Timer) Runs() int64 { return atomic.LoadInt64(&t.runs) }
+
+// isClosed returns true if the channel is closed
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// Type returns TIMER_OBJ
+func (t *Timer) Type() Type { return TIMER_OBJ }
+
+// Inspect returns the state of the timer and how often it ran ie. <timer scheduled, ran 2 times>
+func (t *Timer) Inspect() string {
+	state := "scheduled"
+	if isClosed(t.stop) {
+		state = "cancelled"
+	} else if isClosed(t.done) {
+		state = "done"
+	}
+	return "<timer " + state + ", ran " + strconv.FormatInt(t.Runs(), 10) + " times>"
+}
+
+// Channel passes values between tasks, sends block until the value is received
+// unless the channel is buffered and its buffer is not full
+type Channel struct {
+	ch     chan Object
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewChannel returns a channel that buffers up to size values
+func NewChannel(size int) *Channel {
+	return &Channel{ch: make(chan Object, size)}
+}
+
+// Chan returns the underlying Go channel
+func (c *Channel) Chan() chan Object { return c.ch }
+
+// ErrClosedChannel is returned when sending on a closed channel
+var ErrClosedChannel = errors.New("send on closed channel")
+
+// Send sends the value, it returns ErrClosedChannel if the channel is closed or the
+// error of the context if it is done before the value is sent
+func (c *Channel) Send(ctx context.Context, val Object) (err error) {
+	// the channel may be closed while the send is blocked which panics
+	defer func() {
+		if recover() != nil {
+			err = ErrClosedChannel
+		}
+	}()
+	select {
+	case c.ch <- val:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Recv receives a value, ok is false once the channel is closed and empty, err is the
+// error of the context if it is done before a value is received
+func (c *Channel) Recv(ctx context.Context) (val Object, ok bool, err error) {
+	select {
+	case val, ok = <-c.ch:
+		return val, ok, nil
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+// Close closes the channel, it returns false if the channel was already closed
+func (c *Channel) Close() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	c.closed = true
+	close(c.ch)
+	return true
+}
+
+// Type returns CHANNEL_OBJ
+func (c *Channel) Type() Type { return CHANNEL_OBJ }
+
+// Inspect returns the number of buffered values and the capacity ie. <channel 1/4>
+func (c *Channel) Inspect() string {
+	return "<channel " + strconv.Itoa(len(c.ch)) + "/" + strconv.Itoa(cap(c.ch)) + ">"
+}
+
+// nextPid is the id of the last actor created
+var nextPid int64
+
+// Pid is an actor, messages sent to it are queued in its mailbox until the actor
+// receives them. When the actor exits its links and monitors are told why, the
+// reason is `:normal`, `:killed`, or the message of the error it crashed with
+type Pid struct {
+	ID int64
+
+	mu         sync.Mutex
+	mailbox    []Object
+	signal     chan struct{} // signal has a value when messages were sent since the last wait
+	killed     chan struct{} // killed is closed once the actor is asked to stop
+	killReason Object
+	done       chan struct{} // done is closed once the actor has exited
+	reason     Object
+	links      map[*Pid]bool
+	trapExits  bool
+	watchers   []func(reason Object)
+}
+
+// NewPid returns a new actor with an empty mailbox
+func NewPid() *Pid {
+	return &Pid{
+		ID:     atomic.AddInt64(&nextPid, 1),
+		signal: make(chan struct{}, 1),
+		killed: make(chan struct{}),
+		done:   make(chan struct{}),
+		links:  map[*Pid]bool{},
+	}
+}
+
+// Send puts the message in the mailbox, messages sent to an actor that has exited are dropped
+func (p *Pid) Send(msg Object) {
+	p.mu.Lock()
+	select {
+	case <-p.done:
+		p.mu.Unlock()
+		return
+	default:
+	}
+	p.mailbox = append(p.mailbox, msg)
+	p.mu.Unlock()
+	select {
+	case p.signal <- struct{}{}:
+	default:
+	}
+}
+
+// Messages returns the messages in the mailbox starting at the index from
+func (p *Pid) Messages(from int) []Object {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if from >= len(p.mailbox) {
+		return nil
+	}
+	return append([]Object{}, p.mailbox[from:]...)
+}
+
+// Take removes the message at index i from the mailbox, only the actor itself takes
+// messages so the indexes returned by Messages stay valid until it does
+func (p *Pid) Take(i int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mailbox = append(p.mailbox[:i], p.mailbox[i+1:]...)
+}
+
+// Signal returns a channel that receives a value after a message is sent
+func (p *Pid) Signal() <-chan struct{} { return p.signal }
+
+// Kill asks the actor to stop, it exits with the reason the next time it waits for a message
+func (p *Pid) Kill(reason Object) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.killReason != nil {
+		return
+	}
+	p.killReason = reason
+	close(p.killed)
+}
+
+// Killed returns a channel that is closed once the actor is asked to stop
+func (p *Pid) Killed() <-chan struct{} { return p.killed }
+
+// KillReason returns the reason the actor was asked to stop or nil
+func (p *Pid) KillReason() Object {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.killReason
+}
+
+// Done returns a channel that is closed once the actor has exited
+func (p *Pid) Done() <-chan struct{} { return p.done }
+
+// Alive returns true until the actor has exited
+func (p *Pid) Alive() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// TrapExits makes the actor receive `[:exit, pid, reason]` messages when a linked
+// actor crashes instead of being killed with it
+func (p *Pid) TrapExits() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.trapExits = true
+}
+
+// Watch calls fn with the reason once the actor exits, right away if it already has
+func (p *Pid) Watch(fn func(reason Object)) {
+	p.mu.Lock()
+	if p.reason == nil {
+		p.watchers = append(p.watchers, fn)
+		p.mu.Unlock()
+		return
+	}
+	reason := p.reason
+	p.mu.Unlock()
+	fn(reason)
+}
+
+// Monitor sends `[:down, p, reason]` to the watcher once p exits
+func (p *Pid) Monitor(watcher *Pid) {
+	p.Watch(func(reason Object) {
+		watcher.Send(NewList([]Object{Intern("down"), p, reason}))
+	})
+}
+
+// Link links the two actors so that when one of them crashes the other is killed
+// with the same reason, or told about it if it traps exits
+func (p *Pid) Link(other *Pid) {
+	if p == other {
+		return
+	}
+	first, second := p, other
+	if first.ID > second.ID {
+		first, second = second, first
+	}
+	first.mu.Lock()
+	second.mu.Lock()
+	pReason, otherReason := p.reason, other.reason
+	if pReason == nil && otherReason == nil {
+		p.links[other] = true
+		other.links[p] = true
+	}
+	second.mu.Unlock()
+	first.mu.Unlock()
+	if otherReason != nil {
+		p.exitSignal(other, otherReason)
+	} else if pReason != nil {
+		other.exitSignal(p, pReason)
+	}
+}
+
+// exitSignal tells the actor that the linked actor from exited with the reason
+func (p *Pid) exitSignal(from *Pid, reason Object) {
+	if reason == Intern("normal") {
+		return
+	}
+	p.mu.Lock()
+	trap := p.trapExits
+	p.mu.Unlock()
+	if trap {
+		p.Send(NewList([]Object{Intern("exit"), from, reason}))
+		return
+	}
+	p.Kill(reason)
+}
+
+// Exit records why the actor stopped and tells its links and monitors
+func (p *Pid) Exit(reason Object) {
+	p.mu.Lock()
+	if p.reason != nil {
+		p.mu.Unlock()
+		return
+	}
+	p.reason = reason
+	close(p.done)
+	p.mailbox = nil
+	links := make([]*Pid, 0, len(p.links))
+	for l := range p.links {
+		links = append(links, l)
+	}
+	p.links = map[*Pid]bool{}
+	watchers := p.watchers
+	p.watchers = nil
+	p.mu.Unlock()
+
+	for _, l := range links {
+		l.mu.Lock()
+		delete(l.links, p)
+		l.mu.Unlock()
+		l.exitSignal(p, reason)
+	}
+	for _, fn := range watchers {
+		fn(reason)
+	}
+}
+
+// Type returns PID_OBJ
+func (p *Pid) Type() Type { return PID_OBJ }
+
+// Inspect returns the id of the actor ie. <pid 3>
+func (p *Pid) Inspect() string { return "<pid " + strconv.FormatInt(p.ID, 10) + ">" }
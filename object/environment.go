@@ -0,0 +1,116 @@
+package object
+
+import (
+	"blue/ast"
+	"sync"
+)
+
+// Environment is the store of identifiers to objects for a scope, it is safe
+// to use from multiple tasks at once
+type Environment struct {
+	mu        sync.RWMutex
+	store     map[string]Object
+	immutable map[string]bool
+	types     map[string]*ast.TypeAnnotation // types are the annotations of typed bindings, nil until one is set
+	outer     *Environment
+}
+
+// NewEnvironment returns a new top level environment
+func NewEnvironment() *Environment {
+	return &Environment{store: make(map[string]Object), immutable: make(map[string]bool)}
+}
+
+// NewEnclosedEnvironment returns a new environment with outer as its parent scope
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+// Get returns the object bound to name in this scope or any outer scope
+func (e *Environment) Get(name string) (Object, bool) {
+	e.mu.RLock()
+	obj, ok := e.store[name]
+	e.mu.RUnlock()
+	if !ok && e.outer != nil {
+		obj, ok = e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+// Set binds name to val in this scope as a mutable (var) binding
+func (e *Environment) Set(name string, val Object) Object {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.store[name] = val
+	delete(e.immutable, name)
+	delete(e.types, name)
+	return val
+}
+
+// SetImmutable binds name to val in this scope as an immutable (val) binding
+func (e *Environment) SetImmutable(name string, val Object) Object {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.store[name] = val
+	e.immutable[name] = true
+	delete(e.types, name)
+	return val
+}
+
+// SetType records the annotation of the binding of name in this scope, values
+// assigned to it later are checked against the type
+func (e *Environment) SetType(name string, typ *ast.TypeAnnotation) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.types == nil {
+		e.types = make(map[string]*ast.TypeAnnotation)
+	}
+	e.types[name] = typ
+}
+
+// TypeOf returns the annotation of the closest binding of name, nil if it has none
+func (e *Environment) TypeOf(name string) *ast.TypeAnnotation {
+	e.mu.RLock()
+	_, ok := e.store[name]
+	typ := e.types[name]
+	e.mu.RUnlock()
+	if ok {
+		return typ
+	}
+	if e.outer != nil {
+		return e.outer.TypeOf(name)
+	}
+	return nil
+}
+
+// IsImmutable returns true if the closest binding of name is immutable
+func (e *Environment) IsImmutable(name string) bool {
+	e.mu.RLock()
+	_, ok := e.store[name]
+	immutable := e.immutable[name]
+	e.mu.RUnlock()
+	if ok {
+		return immutable
+	}
+	if e.outer != nil {
+		return e.outer.IsImmutable(name)
+	}
+	return false
+}
+
+// Assign rebinds name in the closest scope that defines it
+// it returns false if name is not defined in any scope
+func (e *Environment) Assign(name string, val Object) bool {
+	e.mu.Lock()
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		e.mu.Unlock()
+		return true
+	}
+	e.mu.Unlock()
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return false
+}
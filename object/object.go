@@ -0,0 +1,1163 @@
+// object contains the runtime representation of all the values
+// that the evaluator of the blue programming language works with
+package object
+
+import (
+	"blue/ast"
+	"blue/token"
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Type is the string representation of an object's type
+type Type string
+
+// Object Type Literals
+const (
+	// INTEGER_OBJ is the type of an integer object
+	INTEGER_OBJ = "INTEGER"
+	// BIG_INTEGER_OBJ is the type of an integer object that does not fit in an int64
+	BIG_INTEGER_OBJ = "BIG_INTEGER"
+	// RATIONAL_OBJ is the type of an exact rational object
+	RATIONAL_OBJ = "RATIONAL"
+	// DECIMAL_OBJ is the type of an exact base 10 decimal object
+	DECIMAL_OBJ = "DECIMAL"
+	// FLOAT_OBJ is the type of a float object
+	FLOAT_OBJ = "FLOAT"
+	// COMPLEX_OBJ is the type of a complex number object
+	COMPLEX_OBJ = "COMPLEX"
+	// BOOLEAN_OBJ is the type of a boolean object
+	BOOLEAN_OBJ = "BOOLEAN"
+	// NULL_OBJ is the type of the null object
+	NULL_OBJ = "NULL"
+	// STRING_OBJ is the type of a string object
+	STRING_OBJ = "STRING"
+	// SYMBOL_OBJ is the type of a symbol object
+	SYMBOL_OBJ = "SYMBOL"
+	// RETURN_VALUE_OBJ is the type of a return value wrapper object
+	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	// ERROR_OBJ is the type of an error object
+	ERROR_OBJ = "ERROR"
+	// FUNCTION_OBJ is the type of a function object
+	FUNCTION_OBJ = "FUNCTION"
+	// BUILTIN_OBJ is the type of a builtin function object
+	BUILTIN_OBJ = "BUILTIN"
+	// LIST_OBJ is the type of a list object
+	LIST_OBJ = "LIST"
+	// MAP_OBJ is the type of a map object
+	MAP_OBJ = "MAP"
+	// SET_OBJ is the type of a set object
+	SET_OBJ = "SET"
+	// ENUM_OBJ is the type of an enum declaration object
+	ENUM_OBJ = "ENUM"
+	// ENUM_VARIANT_OBJ is the type of an enum variant constructor object
+	ENUM_VARIANT_OBJ = "ENUM_VARIANT"
+	// STRUCT_OBJ is the type of a struct declaration object
+	STRUCT_OBJ = "STRUCT"
+	// TRAIT_OBJ is the type of a trait declaration object
+	TRAIT_OBJ = "TRAIT"
+	// BOUND_METHOD_OBJ is the type of a method bound to its receiver
+	BOUND_METHOD_OBJ = "BOUND_METHOD"
+	// GENERATOR_OBJ is the type of a lazy sequence of values
+	GENERATOR_OBJ = "GENERATOR"
+	// QUOTE_OBJ is the type of a piece of quoted code
+	QUOTE_OBJ = "QUOTE"
+	// MACRO_OBJ is the type of a macro definition
+	MACRO_OBJ = "MACRO"
+	// TASK_OBJ is the type of a function running on its own goroutine
+	TASK_OBJ = "TASK"
+	// CHANNEL_OBJ is the type of a channel that tasks send values over
+	CHANNEL_OBJ = "CHANNEL"
+	// MODULE_OBJ is the type of a module bound by `import`
+	MODULE_OBJ = "MODULE"
+	// TIMER_OBJ is the type of a function scheduled to run later by `after` or `every`
+	TIMER_OBJ = "TIMER"
+	// PID_OBJ is the type of an actor that receives messages in its mailbox
+	PID_OBJ = "PID"
+	// MUTEX_OBJ is the type of a lock held by one task at a time
+	MUTEX_OBJ = "MUTEX"
+	// RWMUTEX_OBJ is the type of a lock held by one writer or many readers
+	RWMUTEX_OBJ = "RWMUTEX"
+	// READ_LOCK_OBJ is the type of the read side of a rwmutex
+	READ_LOCK_OBJ = "READ_LOCK"
+	// ATOMIC_INT_OBJ is the type of an integer cell updated atomically
+	ATOMIC_INT_OBJ = "ATOMIC_INT"
+	// ATOMIC_REF_OBJ is the type of a cell holding any value updated atomically
+	ATOMIC_REF_OBJ = "ATOMIC_REF"
+	// CONCURRENT_MAP_OBJ is the type of a map that tasks can share
+	CONCURRENT_MAP_OBJ = "CONCURRENT_MAP"
+	// CONCURRENT_QUEUE_OBJ is the type of a first in first out queue that tasks can share
+	CONCURRENT_QUEUE_OBJ = "CONCURRENT_QUEUE"
+)
+
+// Object is the interface that every value in the evaluator satisfies
+type Object interface {
+	// Type returns the object's type
+	Type() Type
+	// Inspect returns the object's value as a string for printing
+	Inspect() string
+}
+
+// HashKey is the key used to store objects in maps and sets
+type HashKey struct {
+	Type  Type
+	Value uint64
+}
+
+// Hashable is any object that can be used as a map key or set element
+type Hashable interface {
+	HashKey() HashKey
+}
+
+// Integer is the int64 object
+type Integer struct {
+	Value int64
+}
+
+// Type returns INTEGER_OBJ
+func (i *Integer) Type() Type { return INTEGER_OBJ }
+
+// Inspect returns the integer as a string
+func (i *Integer) Inspect() string { return strconv.FormatInt(i.Value, 10) }
+
+// HashKey returns the integer's hash key
+func (i *Integer) HashKey() HashKey { return numberHashKey(float64(i.Value)) }
+
+// BigInteger is the arbitrary precision integer object, it is only used
+// for values that do not fit in an Integer
+type BigInteger struct {
+	Value *big.Int
+}
+
+// Type returns BIG_INTEGER_OBJ
+func (bi *BigInteger) Type() Type { return BIG_INTEGER_OBJ }
+
+// Inspect returns the big integer as a string
+func (bi *BigInteger) Inspect() string { return bi.Value.String() }
+
+// HashKey returns the big integer's hash key
+func (bi *BigInteger) HashKey() HashKey {
+	f, _ := new(big.Float).SetInt(bi.Value).Float64()
+	return numberHashKey(f)
+}
+
+// Rational is the exact rational object, it is only used for values
+// that are not whole numbers
+type Rational struct {
+	Value *big.Rat
+}
+
+// Type returns RATIONAL_OBJ
+func (r *Rational) Type() Type { return RATIONAL_OBJ }
+
+// Inspect returns the rational as numerator/denominator
+func (r *Rational) Inspect() string { return r.Value.RatString() }
+
+// HashKey returns the rational's hash key
+func (r *Rational) HashKey() HashKey {
+	f, _ := r.Value.Float64()
+	return numberHashKey(f)
+}
+
+// Decimal is the exact base 10 number object, its value is Unscaled * 10^-Scale
+// ie. 19.99 is stored as Unscaled 1999 and Scale 2
+type Decimal struct {
+	Unscaled *big.Int
+	Scale    int
+}
+
+// Type returns DECIMAL_OBJ
+func (d *Decimal) Type() Type { return DECIMAL_OBJ }
+
+// Inspect returns the decimal as a string with all of its digits after the point
+func (d *Decimal) Inspect() string {
+	digits := new(big.Int).Abs(d.Unscaled).String()
+	sign := ""
+	if d.Unscaled.Sign() < 0 {
+		sign = "-"
+	}
+	if d.Scale <= 0 {
+		return sign + digits + strings.Repeat("0", -d.Scale)
+	}
+	if len(digits) <= d.Scale {
+		digits = strings.Repeat("0", d.Scale-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-d.Scale] + "." + digits[len(digits)-d.Scale:]
+}
+
+// Rat returns the exact rational value of the decimal
+func (d *Decimal) Rat() *big.Rat {
+	if d.Scale < 0 {
+		pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-d.Scale)), nil)
+		return new(big.Rat).SetInt(new(big.Int).Mul(d.Unscaled, pow))
+	}
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.Scale)), nil)
+	return new(big.Rat).SetFrac(d.Unscaled, pow)
+}
+
+// HashKey returns the decimal's hash key
+func (d *Decimal) HashKey() HashKey {
+	f, _ := d.Rat().Float64()
+	return numberHashKey(f)
+}
+
+// Float is the float64 object
+type Float struct {
+	Value float64
+}
+
+// Type returns FLOAT_OBJ
+func (f *Float) Type() Type { return FLOAT_OBJ }
+
+// Inspect returns the float as a string
+func (f *Float) Inspect() string { return strconv.FormatFloat(f.Value, 'f', -1, 64) }
+
+// HashKey returns the float's hash key
+func (f *Float) HashKey() HashKey { return numberHashKey(f.Value) }
+
+// Complex is the complex128 object
+type Complex struct {
+	Value complex128
+}
+
+// Type returns COMPLEX_OBJ
+func (c *Complex) Type() Type { return COMPLEX_OBJ }
+
+// Inspect returns the complex number as a string ie. (1+2i)
+func (c *Complex) Inspect() string { return strconv.FormatComplex(c.Value, 'f', -1, 128) }
+
+// HashKey returns the complex number's hash key, a complex number without an
+// imaginary part has the key of its real part
+func (c *Complex) HashKey() HashKey {
+	if imag(c.Value) == 0 {
+		return numberHashKey(real(c.Value))
+	}
+	key := numberHashKey(real(c.Value))
+	key.Value = key.Value*31 + numberHashKey(imag(c.Value)).Value
+	return key
+}
+
+// numberKey is the type of the hash keys of all numbers, numbers that are equal
+// with == have the same key whatever their type ie. 1, 1d, 1.0 and 1+0i
+const numberKey Type = "NUMBER"
+
+// numberHashKey returns the hash key of a number from its closest float64, exact
+// numbers that only round to the same float are told apart by keysEqual
+func numberHashKey(f float64) HashKey {
+	if f == 0 {
+		f = 0 // -0 and 0 are equal
+	}
+	return HashKey{Type: numberKey, Value: math.Float64bits(f)}
+}
+
+// isNumber returns true if the object is one of the numbers
+func isNumber(obj Object) bool {
+	switch obj.(type) {
+	case *Integer, *BigInteger, *Rational, *Decimal, *Float, *Complex:
+		return true
+	}
+	return false
+}
+
+// numbersEqual compares numbers like ==, they are compared as complex numbers or
+// floats when one of them is and exactly otherwise
+func numbersEqual(a, b Object) bool {
+	_, ac := a.(*Complex)
+	_, bc := b.(*Complex)
+	if ac || bc {
+		return numberComplex(a) == numberComplex(b)
+	}
+	_, af := a.(*Float)
+	_, bf := b.(*Float)
+	if af || bf {
+		return real(numberComplex(a)) == real(numberComplex(b))
+	}
+	return numberRat(a).Cmp(numberRat(b)) == 0
+}
+
+// numberRat returns the exact value of an integer, rational or decimal
+func numberRat(obj Object) *big.Rat {
+	switch obj := obj.(type) {
+	case *Integer:
+		return new(big.Rat).SetInt64(obj.Value)
+	case *BigInteger:
+		return new(big.Rat).SetInt(obj.Value)
+	case *Rational:
+		return obj.Value
+	case *Decimal:
+		return obj.Rat()
+	}
+	return nil
+}
+
+// numberComplex returns the closest complex128 to a number
+func numberComplex(obj Object) complex128 {
+	switch obj := obj.(type) {
+	case *Complex:
+		return obj.Value
+	case *Float:
+		return complex(obj.Value, 0)
+	}
+	f, _ := numberRat(obj).Float64()
+	return complex(f, 0)
+}
+
+// Boolean is the boolean object
+type Boolean struct {
+	Value bool
+}
+
+// Type returns BOOLEAN_OBJ
+func (b *Boolean) Type() Type { return BOOLEAN_OBJ }
+
+// Inspect returns true or false
+func (b *Boolean) Inspect() string { return strconv.FormatBool(b.Value) }
+
+// HashKey returns the boolean's hash key
+func (b *Boolean) HashKey() HashKey {
+	if b.Value {
+		return HashKey{Type: b.Type(), Value: 1}
+	}
+	return HashKey{Type: b.Type(), Value: 0}
+}
+
+// Null is the null object
+type Null struct{}
+
+// Type returns NULL_OBJ
+func (n *Null) Type() Type { return NULL_OBJ }
+
+// Inspect returns null
+func (n *Null) Inspect() string { return "null" }
+
+// String is the string object
+type String struct {
+	Value string
+}
+
+// Type returns STRING_OBJ
+func (s *String) Type() Type { return STRING_OBJ }
+
+// Inspect returns the string value without quotes
+func (s *String) Inspect() string { return s.Value }
+
+// HashKey returns the string's hash key
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+// Symbol is the interned symbol object, there is only ever one Symbol
+// for a name so symbols can be compared by pointer
+type Symbol struct {
+	Name string
+}
+
+// symbols is the intern table of all symbols created so far
+var (
+	symbolsMu sync.Mutex
+	symbols   = map[string]*Symbol{}
+)
+
+// Intern returns the one Symbol for the name, creating it if needed
+func Intern(name string) *Symbol {
+	symbolsMu.Lock()
+	defer symbolsMu.Unlock()
+	if sym, ok := symbols[name]; ok {
+		return sym
+	}
+	sym := &Symbol{Name: name}
+	symbols[name] = sym
+	return sym
+}
+
+// Type returns SYMBOL_OBJ
+func (s *Symbol) Type() Type { return SYMBOL_OBJ }
+
+// Inspect returns the symbol with its leading `:`
+func (s *Symbol) Inspect() string { return ":" + s.Name }
+
+// HashKey returns the symbol's hash key
+func (s *Symbol) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Name))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+// ReturnValue wraps the object being returned so that evaluation can stop early
+type ReturnValue struct {
+	Value Object
+}
+
+// Type returns RETURN_VALUE_OBJ
+func (rv *ReturnValue) Type() Type { return RETURN_VALUE_OBJ }
+
+// Inspect returns the wrapped value's Inspect
+func (rv *ReturnValue) Inspect() string { return rv.Value.Inspect() }
+
+// Error is the error object that stops evaluation
+type Error struct {
+	Message string
+	Span    *token.Span // Span is the location in the source the error refers to, nil if it is not known
+}
+
+// Type returns ERROR_OBJ
+func (e *Error) Type() Type { return ERROR_OBJ }
+
+// Inspect returns the error message
+func (e *Error) Inspect() string { return "EvaluatorError: " + e.Message }
+
+// Function is the function object that captures its defining environment
+type Function struct {
+	Parameters        []*ast.Identifier
+	DefaultParameters []ast.Expression // DefaultParameters is nil or an expression for each parameter
+	Body              *ast.BlockStatement
+	Env               *Environment
+	ParameterTypes    []*ast.TypeAnnotation // ParameterTypes is nil or the checked type of each parameter
+	ReturnType        *ast.TypeAnnotation   // ReturnType is nil or the checked type of the return value
+}
+
+// Type returns FUNCTION_OBJ
+func (f *Function) Type() Type { return FUNCTION_OBJ }
+
+// Inspect returns the function as a string
+func (f *Function) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for i, p := range f.Parameters {
+		if i < len(f.ParameterTypes) && f.ParameterTypes[i] != nil {
+			params = append(params, p.String()+": "+f.ParameterTypes[i].String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+
+	out.WriteString("fun(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(")")
+	if f.ReturnType != nil {
+		out.WriteString(" -> " + f.ReturnType.String())
+	}
+	out.WriteString(" {\n")
+	out.WriteString(f.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
+// BuiltinFunction is the go function signature of a builtin
+type BuiltinFunction func(args ...Object) Object
+
+// Builtin is the builtin function object
+type Builtin struct {
+	Fun BuiltinFunction
+}
+
+// Type returns BUILTIN_OBJ
+func (b *Builtin) Type() Type { return BUILTIN_OBJ }
+
+// Inspect returns builtin function
+func (b *Builtin) Inspect() string { return "builtin function" }
+
+// List is the list object, its elements are guarded by a lock so that tasks sharing
+// a list never see it half changed
+type List struct {
+	mu       sync.RWMutex
+	elements []Object
+	rng      *intRange // rng is set while the list is a range whose elements are not built
+	owner    *Pid      // owner is the task that bound the list with val, only it may mutate the list
+}
+
+// intRange is the n integers start, start+step, ... of a range
+type intRange struct {
+	start, step, n int64
+}
+
+// at returns the element at the index of the range
+func (r *intRange) at(i int64) Object {
+	return &Integer{Value: r.start + i*r.step}
+}
+
+// NewList returns a list object that holds the elements
+func NewList(elements []Object) *List {
+	return &List{elements: elements}
+}
+
+// NewRange returns the list of the integers from start to end, end is included if
+// inclusive. Its elements are only built once the list is changed, so a range like
+// 1..1000000000 is created, indexed, and searched without them
+func NewRange(start, end int64, inclusive bool) *List {
+	rng := &intRange{start: start, step: 1, n: end - start}
+	if start > end {
+		rng.step, rng.n = -1, start-end
+	}
+	if inclusive {
+		rng.n++
+	}
+	return &List{rng: rng}
+}
+
+// Range returns the first element, the step, and the number of elements of a range
+// that has not been changed, ok is false for any other list
+func (l *List) Range() (start, step, n int64, ok bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.rng == nil {
+		return 0, 0, 0, false
+	}
+	return l.rng.start, l.rng.step, l.rng.n, true
+}
+
+// Owner returns the task that owns the list, it is nil if any task may mutate it
+func (l *List) Owner() *Pid {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.owner
+}
+
+// SetOwner gives the list to the task
+func (l *List) SetOwner(owner *Pid) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.owner = owner
+}
+
+// Elements returns a copy of the elements
+func (l *List) Elements() []Object {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.rng != nil {
+		elements := make([]Object, l.rng.n)
+		for i := range elements {
+			elements[i] = l.rng.at(int64(i))
+		}
+		return elements
+	}
+	return append([]Object{}, l.elements...)
+}
+
+// Len returns the number of elements
+func (l *List) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.rng != nil {
+		return int(l.rng.n)
+	}
+	return len(l.elements)
+}
+
+// Get returns the element at the index, ok is false if it is out of range
+func (l *List) Get(i int64) (Object, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.rng != nil {
+		if i < 0 || i >= l.rng.n {
+			return nil, false
+		}
+		return l.rng.at(i), true
+	}
+	if i < 0 || i >= int64(len(l.elements)) {
+		return nil, false
+	}
+	return l.elements[i], true
+}
+
+// Update calls fn with the elements while no other task can use the list, fn may
+// change them in place and returns the elements the list holds afterwards
+func (l *List) Update(fn func(elements []Object) []Object) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rng != nil {
+		l.elements = make([]Object, l.rng.n)
+		for i := range l.elements {
+			l.elements[i] = l.rng.at(int64(i))
+		}
+		l.rng = nil
+	}
+	l.elements = fn(l.elements)
+}
+
+// Type returns LIST_OBJ
+func (l *List) Type() Type { return LIST_OBJ }
+
+// Inspect returns the list as a string
+func (l *List) Inspect() string {
+	elements := []string{}
+	for _, e := range l.Elements() {
+		elements = append(elements, inspectNested(e))
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// MapPair is the key and value stored in a map
+type MapPair struct {
+	Key   Object
+	Value Object
+}
+
+// Map is the map object, keys with the same hash key share a bucket and are told
+// apart with keysEqual. The pairs are guarded by a lock so that tasks sharing a map
+// never see it half changed
+type Map struct {
+	mu      sync.RWMutex
+	buckets map[HashKey][]*MapPair
+	order   []*MapPair // order keeps the insertion order of the pairs
+	owner   *Pid       // owner is the task that bound the map with val, only it may mutate the map
+}
+
+// NewMap returns an empty map object
+func NewMap() *Map {
+	return &Map{buckets: make(map[HashKey][]*MapPair)}
+}
+
+// Owner returns the task that owns the map, it is nil if any task may mutate it
+func (m *Map) Owner() *Pid {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.owner
+}
+
+// SetOwner gives the map to the task
+func (m *Map) SetOwner(owner *Pid) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.owner = owner
+}
+
+// find returns the pair for key or nil if it does not exist
+func (m *Map) find(hk HashKey, key Object) *MapPair {
+	for _, pair := range m.buckets[hk] {
+		if keysEqual(pair.Key, key) {
+			return pair
+		}
+	}
+	return nil
+}
+
+// Set will insert or replace the value for key in the map
+func (m *Map) Set(key Hashable, value Object) {
+	hk := key.HashKey()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if pair := m.find(hk, key.(Object)); pair != nil {
+		pair.Value = value
+		return
+	}
+	pair := &MapPair{Key: key.(Object), Value: value}
+	m.buckets[hk] = append(m.buckets[hk], pair)
+	m.order = append(m.order, pair)
+}
+
+// Delete removes the key from the map and returns true if it existed
+func (m *Map) Delete(key Hashable) bool {
+	hk := key.HashKey()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pair := m.find(hk, key.(Object))
+	if pair == nil {
+		return false
+	}
+	m.buckets[hk] = removePair(m.buckets[hk], pair)
+	if len(m.buckets[hk]) == 0 {
+		delete(m.buckets, hk)
+	}
+	m.order = removePair(m.order, pair)
+	return true
+}
+
+// removePair returns the pairs without the pair
+func removePair(pairs []*MapPair, pair *MapPair) []*MapPair {
+	for i, p := range pairs {
+		if p == pair {
+			return append(pairs[:i:i], pairs[i+1:]...)
+		}
+	}
+	return pairs
+}
+
+// Get returns the value for key in the map and if it existed
+func (m *Map) Get(key Hashable) (Object, bool) {
+	hk := key.HashKey()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	pair := m.find(hk, key.(Object))
+	if pair == nil {
+		return nil, false
+	}
+	return pair.Value, true
+}
+
+// Len returns the number of pairs in the map
+func (m *Map) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.order)
+}
+
+// Pairs returns a copy of the pairs in insertion order
+func (m *Map) Pairs() []MapPair {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	pairs := make([]MapPair, len(m.order))
+	for i, pair := range m.order {
+		pairs[i] = *pair
+	}
+	return pairs
+}
+
+// Type returns MAP_OBJ
+func (m *Map) Type() Type { return MAP_OBJ }
+
+// Inspect returns the map as a string
+func (m *Map) Inspect() string {
+	pairs := []string{}
+	for _, pair := range m.Pairs() {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", inspectNested(pair.Key), inspectNested(pair.Value)))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// Set is the set object, elements with the same hash key share a bucket and are
+// told apart with keysEqual. The elements are guarded by a lock like those of a map
+type Set struct {
+	mu      sync.RWMutex
+	buckets map[HashKey][]Object
+	order   []Object // order keeps the insertion order of the elements
+}
+
+// NewSet returns an empty set object
+func NewSet() *Set {
+	return &Set{buckets: make(map[HashKey][]Object)}
+}
+
+// contains returns true if the element is in the bucket of the hash key
+func (s *Set) contains(hk HashKey, elem Object) bool {
+	for _, e := range s.buckets[hk] {
+		if keysEqual(e, elem) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add will insert the element into the set if it does not already exist
+func (s *Set) Add(elem Hashable) {
+	hk := elem.HashKey()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.contains(hk, elem.(Object)) {
+		s.buckets[hk] = append(s.buckets[hk], elem.(Object))
+		s.order = append(s.order, elem.(Object))
+	}
+}
+
+// Contains returns true if the element is in the set
+func (s *Set) Contains(elem Hashable) bool {
+	hk := elem.HashKey()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.contains(hk, elem.(Object))
+}
+
+// Len returns the number of elements in the set
+func (s *Set) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.order)
+}
+
+// Elements returns a copy of the elements in insertion order
+func (s *Set) Elements() []Object {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Object{}, s.order...)
+}
+
+// Type returns SET_OBJ
+func (s *Set) Type() Type { return SET_OBJ }
+
+// Inspect returns the set as a string
+func (s *Set) Inspect() string {
+	elements := []string{}
+	for _, e := range s.Elements() {
+		elements = append(elements, inspectNested(e))
+	}
+	return "{" + strings.Join(elements, ", ") + "}"
+}
+
+// keysEqual returns true if the keys, whose hash keys are the same, are the same key.
+// A struct with an `equals` method decides for itself, the hash keys of other values
+// are only the same by chance when their values differ
+func keysEqual(a, b Object) bool {
+	if si, ok := a.(*StructInstance); ok {
+		if res, ok := si.CallMethod("equals", b); ok {
+			eq, ok := res.(*Boolean)
+			return ok && eq.Value
+		}
+	}
+	if a == b {
+		return true
+	}
+	if isNumber(a) && isNumber(b) {
+		return numbersEqual(a, b)
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch a := a.(type) {
+	case *Boolean:
+		return a.Value == b.(*Boolean).Value
+	case *String:
+		return a.Value == b.(*String).Value
+	case *Symbol:
+		return a.Name == b.(*Symbol).Name
+	case *EnumValue:
+		b := b.(*EnumValue)
+		return a.Variant == b.Variant && fieldsEqual(a.Payload, b.Payload)
+	case *StructInstance:
+		b := b.(*StructInstance)
+		return a.Struct == b.Struct && fieldsEqual(a.Fields, b.Fields)
+	}
+	return a.Inspect() == b.Inspect()
+}
+
+// fieldsEqual compares the fields of struct instances or enum payloads like hashObjects
+// hashes them, fields that are not hashable are compared by their Inspect
+func fieldsEqual(a, b []Object) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		_, aok := a[i].(Hashable)
+		_, bok := b[i].(Hashable)
+		if aok && bok {
+			if !keysEqual(a[i], b[i]) {
+				return false
+			}
+		} else if a[i].Inspect() != b[i].Inspect() {
+			return false
+		}
+	}
+	return true
+}
+
+// Enum is the enum declaration object, its variants are accessed with `.`
+type Enum struct {
+	Name     string
+	Variants []*EnumVariant
+}
+
+// Variant returns the variant with the name or nil if there is none
+func (e *Enum) Variant(name string) *EnumVariant {
+	for _, v := range e.Variants {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Type returns ENUM_OBJ
+func (e *Enum) Type() Type { return ENUM_OBJ }
+
+// Inspect returns the enum as it was declared
+func (e *Enum) Inspect() string {
+	variants := []string{}
+	for _, v := range e.Variants {
+		if len(v.Fields) == 0 {
+			variants = append(variants, v.Name)
+		} else {
+			variants = append(variants, v.Name+"("+strings.Join(v.Fields, ", ")+")")
+		}
+	}
+	return "enum " + e.Name + " { " + strings.Join(variants, ", ") + " }"
+}
+
+// EnumVariant is a variant of an enum, variants with fields are called to construct
+// a value and variants without fields have a single Value
+type EnumVariant struct {
+	Enum   *Enum
+	Name   string
+	Fields []string
+	Value  *EnumValue // Value is only set for variants without fields
+}
+
+// Type returns ENUM_VARIANT_OBJ
+func (ev *EnumVariant) Type() Type { return ENUM_VARIANT_OBJ }
+
+// Inspect returns the variant's constructor ie. Shape.Circle(r)
+func (ev *EnumVariant) Inspect() string {
+	return ev.Enum.Name + "." + ev.Name + "(" + strings.Join(ev.Fields, ", ") + ")"
+}
+
+// EnumValue is a value of an enum, its type is the name of the enum
+type EnumValue struct {
+	Variant *EnumVariant
+	Payload []Object
+}
+
+// Type returns the name of the enum the value belongs to
+func (ev *EnumValue) Type() Type { return Type(ev.Variant.Enum.Name) }
+
+// Inspect returns the value ie. Shape.Circle(1) or Shape.Empty
+func (ev *EnumValue) Inspect() string {
+	name := ev.Variant.Enum.Name + "." + ev.Variant.Name
+	if len(ev.Variant.Fields) == 0 {
+		return name
+	}
+	payload := []string{}
+	for _, p := range ev.Payload {
+		payload = append(payload, inspectNested(p))
+	}
+	return name + "(" + strings.Join(payload, ", ") + ")"
+}
+
+// HashKey returns the enum value's hash key
+func (ev *EnumValue) HashKey() HashKey {
+	return HashKey{Type: ev.Type(), Value: hashObjects(ev.Variant.Enum.Name+"."+ev.Variant.Name, ev.Payload)}
+}
+
+// Struct is the struct declaration object, calling it constructs an instance
+type Struct struct {
+	Name     string
+	Fields   []string
+	Defaults []ast.Expression     // Defaults is nil or the default expression for each field
+	Methods  map[string]*Function // Methods take the receiver as their first parameter
+	Env      *Environment         // Env is the environment the defaults are evaluated in
+	Traits   []*Trait             // Traits are the traits implemented with `impl Trait for Name`
+	// Call applies a method, it is set by the evaluator so that Inspect and HashKey
+	// can use the `to_string` and `hash` methods
+	Call func(method *Function, args ...Object) Object
+}
+
+// FieldIndex returns the index of the field or -1 if the struct has no such field
+func (s *Struct) FieldIndex(name string) int {
+	for i, f := range s.Fields {
+		if f == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Type returns STRUCT_OBJ
+func (s *Struct) Type() Type { return STRUCT_OBJ }
+
+// Inspect returns the struct's name and fields
+func (s *Struct) Inspect() string {
+	return "struct " + s.Name + " { " + strings.Join(s.Fields, ", ") + " }"
+}
+
+// StructInstance is a value of a struct, its type is the name of the struct
+type StructInstance struct {
+	Struct *Struct
+	Fields []Object // Fields are the values in the same order as Struct.Fields
+}
+
+// Type returns the name of the struct the instance belongs to
+func (si *StructInstance) Type() Type { return Type(si.Struct.Name) }
+
+// CallMethod calls the instance's method with the instance as the receiver, ok is
+// false if the struct does not have the method
+func (si *StructInstance) CallMethod(name string, args ...Object) (Object, bool) {
+	method, ok := si.Struct.Methods[name]
+	if !ok || si.Struct.Call == nil {
+		return nil, false
+	}
+	return si.Struct.Call(method, append([]Object{si}, args...)...), true
+}
+
+// Inspect returns the result of the `to_string` method or the instance ie. Point{x: 1, y: 2}
+func (si *StructInstance) Inspect() string {
+	if res, ok := si.CallMethod("to_string"); ok {
+		if s, ok := res.(*String); ok {
+			return s.Value
+		}
+		return res.Inspect()
+	}
+	fields := []string{}
+	for i, f := range si.Struct.Fields {
+		fields = append(fields, f+": "+inspectNested(si.Fields[i]))
+	}
+	return si.Struct.Name + "{" + strings.Join(fields, ", ") + "}"
+}
+
+// HashKey returns the instance's hash key which is based on the result of the
+// `hash` method or its field values
+func (si *StructInstance) HashKey() HashKey {
+	if res, ok := si.CallMethod("hash"); ok {
+		if i, ok := res.(*Integer); ok {
+			return HashKey{Type: si.Type(), Value: uint64(i.Value)}
+		}
+	}
+	return HashKey{Type: si.Type(), Value: hashObjects(si.Struct.Name, si.Fields)}
+}
+
+// Trait is a set of methods a struct can implement, methods with a body are defaults
+type Trait struct {
+	Name     string
+	Required []string             // Required are the methods every implementation must define
+	Defaults map[string]*Function // Defaults are used when the implementation does not define them
+}
+
+// Type returns TRAIT_OBJ
+func (t *Trait) Type() Type { return TRAIT_OBJ }
+
+// Inspect returns the trait's name and methods
+func (t *Trait) Inspect() string {
+	methods := append([]string{}, t.Required...)
+	for name := range t.Defaults {
+		methods = append(methods, name)
+	}
+	sort.Strings(methods)
+	return "trait " + t.Name + " { " + strings.Join(methods, ", ") + " }"
+}
+
+// BoundMethod is a struct method with its receiver, the receiver is
+// passed as the first argument when it is called
+type BoundMethod struct {
+	Receiver Object
+	Method   *Function
+}
+
+// Type returns BOUND_METHOD_OBJ
+func (bm *BoundMethod) Type() Type { return BOUND_METHOD_OBJ }
+
+// Inspect returns the method as a string
+func (bm *BoundMethod) Inspect() string { return bm.Method.Inspect() }
+
+// Members is an object whose fields and methods are provided by Go code ie. a value of
+// a Go type registered with the interp package, they are reached with member access
+type Members interface {
+	Object
+	// Member returns the field or the bound method, ok is false if there is neither
+	Member(name string) (Object, bool)
+	// SetMember assigns the field
+	SetMember(name string, val Object) error
+}
+
+// Module is a module bound by `import`, its members are read with member access
+type Module struct {
+	Path   string
+	Values map[string]Object
+}
+
+// Type returns MODULE_OBJ
+func (m *Module) Type() Type { return MODULE_OBJ }
+
+// Inspect returns the path of the module ie. <module path/filepath>
+func (m *Module) Inspect() string { return "<module " + m.Path + ">" }
+
+// Member returns the member of the module
+func (m *Module) Member(name string) (Object, bool) {
+	val, ok := m.Values[name]
+	return val, ok
+}
+
+// SetMember returns an error, the members of a module cannot be reassigned
+func (m *Module) SetMember(name string, val Object) error {
+	return fmt.Errorf("cannot assign to %s.%s, the members of a module are read only", m.Path, name)
+}
+
+// Generator is a lazy sequence of values, it is returned by calling a function that
+// yields and by the lazy helpers such as take and zip
+type Generator struct {
+	Name string
+	// Next returns the next value, ok is false once the generator is exhausted
+	// an error in the generator is returned as the last value
+	Next func() (val Object, ok bool)
+	// Stop releases the generator before it is exhausted, it is nil if there is nothing to release
+	Stop func()
+	mu   sync.Mutex
+}
+
+// NewGenerator returns a generator that runs next and stop one call at a time so that
+// tasks can share it, stop is nil if there is nothing to release
+func NewGenerator(name string, next func() (Object, bool), stop func()) *Generator {
+	g := &Generator{Name: name}
+	g.Next = func() (Object, bool) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return next()
+	}
+	if stop != nil {
+		g.Stop = func() {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			stop()
+		}
+	}
+	return g
+}
+
+// Type returns GENERATOR_OBJ
+func (g *Generator) Type() Type { return GENERATOR_OBJ }
+
+// Inspect returns the generator's name ie. <generator count>
+func (g *Generator) Inspect() string { return "<generator " + g.Name + ">" }
+
+// Quote is a piece of code returned by `quote { ... }`, Node is an ast.Expression
+// or an *ast.BlockStatement when more than one statement was quoted
+type Quote struct {
+	Node ast.Node
+}
+
+// Type returns QUOTE_OBJ
+func (q *Quote) Type() Type { return QUOTE_OBJ }
+
+// Inspect returns the quoted code ie. quote((1 + 2))
+func (q *Quote) Inspect() string { return "quote(" + q.Node.String() + ")" }
+
+// Macro is a macro definition, its body is evaluated in Env with the quoted arguments
+// of a call bound to Parameters and the quote it returns replaces the call
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+// Type returns MACRO_OBJ
+func (m *Macro) Type() Type { return MACRO_OBJ }
+
+// Inspect returns the macro's parameters ie. macro(cond, body)
+func (m *Macro) Inspect() string {
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+	return "macro(" + strings.Join(params, ", ") + ")"
+}
+
+// hashObjects hashes the name and the objects, objects that are not Hashable
+// are hashed by their Inspect
+func hashObjects(name string, objs []Object) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	for _, obj := range objs {
+		if hashable, ok := obj.(Hashable); ok {
+			hk := hashable.HashKey()
+			h.Write([]byte(string(hk.Type) + strconv.FormatUint(hk.Value, 10)))
+		} else {
+			h.Write([]byte(obj.Inspect()))
+		}
+	}
+	return h.Sum64()
+}
+
+// inspectNested returns the Inspect of an object that is inside of a collection
+// strings are quoted so they can be told apart from other values
+func inspectNested(obj Object) string {
+	if s, ok := obj.(*String); ok {
+		return strconv.Quote(s.Value)
+	}
+	return obj.Inspect()
+}
@@ -0,0 +1,304 @@
+package object
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Locker is a lock that `with` holds while its block runs
+type Locker interface {
+	Object
+	Lock()
+	// Unlock releases the lock, it returns false if the lock was not held
+	Unlock() bool
+}
+
+// Mutex is a lock that is held by one task at a time
+type Mutex struct {
+	mu     sync.Mutex
+	locked int32
+}
+
+// Lock blocks until the mutex is held
+func (m *Mutex) Lock() {
+	m.mu.Lock()
+	atomic.StoreInt32(&m.locked, 1)
+}
+
+// Unlock releases the mutex, it returns false if it was not locked
+func (m *Mutex) Unlock() bool {
+	if !atomic.CompareAndSwapInt32(&m.locked, 1, 0) {
+		return false
+	}
+	m.mu.Unlock()
+	return true
+}
+
+// Type returns MUTEX_OBJ
+func (m *Mutex) Type() Type { return MUTEX_OBJ }
+
+// Inspect returns whether the mutex is held ie. <mutex locked>
+func (m *Mutex) Inspect() string {
+	if atomic.LoadInt32(&m.locked) == 1 {
+		return "<mutex locked>"
+	}
+	return "<mutex unlocked>"
+}
+
+// RWMutex is a lock that is held by one writer or by any number of readers, locking it
+// locks it for writing and its ReadLock locks it for reading
+type RWMutex struct {
+	mu      sync.RWMutex
+	writer  int32
+	readers int32
+}
+
+// Lock blocks until the rwmutex is held for writing
+func (rw *RWMutex) Lock() {
+	rw.mu.Lock()
+	atomic.StoreInt32(&rw.writer, 1)
+}
+
+// Unlock releases the write lock, it returns false if it was not locked for writing
+func (rw *RWMutex) Unlock() bool {
+	if !atomic.CompareAndSwapInt32(&rw.writer, 1, 0) {
+		return false
+	}
+	rw.mu.Unlock()
+	return true
+}
+
+// Type returns RWMUTEX_OBJ
+func (rw *RWMutex) Type() Type { return RWMUTEX_OBJ }
+
+// Inspect returns who holds the rwmutex ie. <rwmutex 2 readers>
+func (rw *RWMutex) Inspect() string {
+	if atomic.LoadInt32(&rw.writer) == 1 {
+		return "<rwmutex locked>"
+	}
+	return "<rwmutex " + strconv.Itoa(int(atomic.LoadInt32(&rw.readers))) + " readers>"
+}
+
+// ReadLock is the read side of a rwmutex
+type ReadLock struct {
+	RW *RWMutex
+}
+
+// Lock blocks until the rwmutex is held for reading
+func (r *ReadLock) Lock() {
+	r.RW.mu.RLock()
+	atomic.AddInt32(&r.RW.readers, 1)
+}
+
+// Unlock releases one read lock, it returns false if there were no readers
+func (r *ReadLock) Unlock() bool {
+	for {
+		n := atomic.LoadInt32(&r.RW.readers)
+		if n == 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&r.RW.readers, n, n-1) {
+			r.RW.mu.RUnlock()
+			return true
+		}
+	}
+}
+
+// Type returns READ_LOCK_OBJ
+func (r *ReadLock) Type() Type { return READ_LOCK_OBJ }
+
+// Inspect returns the rwmutex the read lock belongs to
+func (r *ReadLock) Inspect() string { return "read_lock(" + r.RW.Inspect() + ")" }
+
+// AtomicInt is an integer that can be updated by many tasks without a lock
+type AtomicInt struct {
+	value int64
+}
+
+// NewAtomicInt returns an atomic integer holding n
+func NewAtomicInt(n int64) *AtomicInt { return &AtomicInt{value: n} }
+
+// Load returns the current value
+func (a *AtomicInt) Load() int64 { return atomic.LoadInt64(&a.value) }
+
+// Store sets the value
+func (a *AtomicInt) Store(n int64) { atomic.StoreInt64(&a.value, n) }
+
+// Swap sets the value and returns the old one
+func (a *AtomicInt) Swap(n int64) int64 { return atomic.SwapInt64(&a.value, n) }
+
+// Add adds n and returns the new value
+func (a *AtomicInt) Add(n int64) int64 { return atomic.AddInt64(&a.value, n) }
+
+// CompareAndSwap sets the value to new if it is old and returns true if it did
+func (a *AtomicInt) CompareAndSwap(old, new int64) bool {
+	return atomic.CompareAndSwapInt64(&a.value, old, new)
+}
+
+// Type returns ATOMIC_INT_OBJ
+func (a *AtomicInt) Type() Type { return ATOMIC_INT_OBJ }
+
+// Inspect returns the current value ie. <atomic_int 3>
+func (a *AtomicInt) Inspect() string {
+	return "<atomic_int " + strconv.FormatInt(a.Load(), 10) + ">"
+}
+
+// AtomicRef is a cell holding any value that can be updated by many tasks
+type AtomicRef struct {
+	mu    sync.Mutex
+	value Object
+}
+
+// NewAtomicRef returns an atomic reference holding val
+func NewAtomicRef(val Object) *AtomicRef { return &AtomicRef{value: val} }
+
+// Load returns the current value
+func (a *AtomicRef) Load() Object {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.value
+}
+
+// Store sets the value
+func (a *AtomicRef) Store(val Object) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.value = val
+}
+
+// Swap sets the value and returns the old one
+func (a *AtomicRef) Swap(val Object) Object {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	old := a.value
+	a.value = val
+	return old
+}
+
+// CompareAndSwap sets the value to new if equal reports that it is old and returns
+// true if it did
+func (a *AtomicRef) CompareAndSwap(old, new Object, equal func(a, b Object) bool) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !equal(a.value, old) {
+		return false
+	}
+	a.value = new
+	return true
+}
+
+// Type returns ATOMIC_REF_OBJ
+func (a *AtomicRef) Type() Type { return ATOMIC_REF_OBJ }
+
+// Inspect returns the current value ie. <atomic_ref [1, 2]>
+func (a *AtomicRef) Inspect() string { return "<atomic_ref " + a.Load().Inspect() + ">" }
+
+// ConcurrentMap is a map that can be read and written by many tasks
+type ConcurrentMap struct {
+	mu sync.RWMutex
+	m  *Map
+}
+
+// NewConcurrentMap returns an empty concurrent map
+func NewConcurrentMap() *ConcurrentMap { return &ConcurrentMap{m: NewMap()} }
+
+// Get returns the value for key and if it existed
+func (cm *ConcurrentMap) Get(key Hashable) (Object, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.m.Get(key)
+}
+
+// Set inserts or replaces the value for key
+func (cm *ConcurrentMap) Set(key Hashable, val Object) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.m.Set(key, val)
+}
+
+// Delete removes the key and returns true if it existed
+func (cm *ConcurrentMap) Delete(key Hashable) bool {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.m.Delete(key)
+}
+
+// Update replaces the value for key with the result of fn while no other task can
+// use the map, fn is given the current value or nil if there is none. The value is
+// not changed if fn returns ok false
+func (cm *ConcurrentMap) Update(key Hashable, fn func(cur Object) (val Object, ok bool)) Object {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cur, _ := cm.m.Get(key)
+	val, ok := fn(cur)
+	if ok {
+		cm.m.Set(key, val)
+	}
+	return val
+}
+
+// Len returns the number of keys
+func (cm *ConcurrentMap) Len() int {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.m.Len()
+}
+
+// Snapshot returns a copy of the map as a plain map
+func (cm *ConcurrentMap) Snapshot() *Map {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	m := NewMap()
+	for _, pair := range cm.m.Pairs() {
+		m.Set(pair.Key.(Hashable), pair.Value)
+	}
+	return m
+}
+
+// Type returns CONCURRENT_MAP_OBJ
+func (cm *ConcurrentMap) Type() Type { return CONCURRENT_MAP_OBJ }
+
+// Inspect returns the pairs of the map ie. concurrent_map({"a": 1})
+func (cm *ConcurrentMap) Inspect() string { return "concurrent_map(" + cm.Snapshot().Inspect() + ")" }
+
+// ConcurrentQueue is a first in first out queue that many tasks can push to and pop from
+type ConcurrentQueue struct {
+	mu    sync.Mutex
+	elems []Object
+}
+
+// Push adds the value to the back of the queue
+func (q *ConcurrentQueue) Push(val Object) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.elems = append(q.elems, val)
+}
+
+// Pop removes the value at the front of the queue, ok is false if the queue is empty
+func (q *ConcurrentQueue) Pop() (val Object, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.elems) == 0 {
+		return nil, false
+	}
+	val = q.elems[0]
+	q.elems[0] = nil
+	q.elems = q.elems[1:]
+	return val, true
+}
+
+// Len returns the number of values in the queue
+func (q *ConcurrentQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.elems)
+}
+
+// Type returns CONCURRENT_QUEUE_OBJ
+func (q *ConcurrentQueue) Type() Type { return CONCURRENT_QUEUE_OBJ }
+
+// Inspect returns the number of values in the queue ie. <concurrent_queue 3>
+func (q *ConcurrentQueue) Inspect() string {
+	return "<concurrent_queue " + strconv.Itoa(q.Len()) + ">"
+}
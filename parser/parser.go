@@ -26,10 +26,14 @@ const (
 	BITWISE_XOR
 	// BITWISE_ADD is the bitwise and precedence
 	BITWISE_ADD
+	// PIPELINE_P is the |> precedence
+	PIPELINE_P
 	// EQUALS is the == and != precedence
 	EQUALS
 	// LESSGREATER < > <= >=
 	LESSGREATER
+	// COALESCE_P is the ?? precedence
+	COALESCE_P
 	// BITWISE_SHIFTS is the << and >> precedence
 	BITWISE_SHIFTS
 	// SUM is the plus and minus precedence
@@ -93,6 +97,10 @@ var precedences = map[token.Type]int{
 	token.LPAREN:      CALL,
 	token.LBRACKET:    INDEX,
 	token.DOT:         INDEX,
+	token.OPTDOT:      INDEX,
+	token.OPTLBRACKET: INDEX,
+	token.COALESCE:    COALESCE_P,
+	token.PIPELINE:    PIPELINE_P,
 }
 
 // Parser is the struct containing information relevant to parsing
@@ -103,6 +111,8 @@ type Parser struct {
 	peekToken token.Token
 
 	errors []string
+	// yields tracks whether each function body being parsed contains a yield
+	yields []bool
 
 	prefixParseFuns map[token.Type]prefixParseFun
 	infixParseFuns  map[token.Type]infixParseFun
@@ -126,6 +136,11 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.HEX, p.parseHexLiteral)
 	p.registerPrefix(token.OCTAL, p.parseOctalLiteral)
 	p.registerPrefix(token.BINARY, p.parseBinaryLiteral)
+	p.registerPrefix(token.RATIONAL, p.parseRationalLiteral)
+	p.registerPrefix(token.DECIMAL, p.parseDecimalLiteral)
+	p.registerPrefix(token.IMAGINARY, p.parseImaginaryLiteral)
+	p.registerPrefix(token.DURATION, p.parseDurationLiteral)
+	p.registerPrefix(token.SYMBOL, p.parseSymbolLiteral)
 	p.registerPrefix(token.NOT, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
@@ -135,6 +150,14 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.PIPE, p.parseLambdaLiteral)
+	p.registerPrefix(token.YIELD, p.parseYieldExpression)
+	p.registerPrefix(token.SPAWN, p.parseSpawnExpression)
+	p.registerPrefix(token.AWAIT, p.parseAwaitExpression)
+	p.registerPrefix(token.SELECT, p.parseSelectExpression)
+	p.registerPrefix(token.RECEIVE, p.parseReceiveExpression)
+	p.registerPrefix(token.SCOPE, p.parseScopeExpression)
+	p.registerPrefix(token.WITH, p.parseWithExpression)
+	p.registerPrefix(token.QUOTE, p.parseQuoteExpression)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.RAW_STRING, p.parseRawStringLiteral)
 	p.registerPrefix(token.BACKTICK, p.parseExecStringLiteral)
@@ -168,6 +191,10 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 	p.registerInfix(token.DOT, p.parseMemberAccessExpression)
+	p.registerInfix(token.OPTDOT, p.parseOptionalChainExpression)
+	p.registerInfix(token.OPTLBRACKET, p.parseOptionalIndexExpression)
+	p.registerInfix(token.COALESCE, p.parseInfixExpression)
+	p.registerInfix(token.PIPELINE, p.parsePipelineExpression)
 	p.registerInfix(token.POW, p.parseInfixExpression)
 	p.registerInfix(token.LSHIFT, p.parseInfixExpression)
 	p.registerInfix(token.RSHIFT, p.parseInfixExpression)
@@ -232,6 +259,9 @@ func (p *Parser) ParseProgram() *ast.Program {
 		tok = lcpy.NextToken()
 		if tok.Type == token.ILLEGAL {
 			msg := fmt.Sprintf("%s token encountered. got=%s", tok.Type, tok.Literal)
+			if printable := lcpy.GetSpanPrintable(tok.Span, msg); printable != "" {
+				msg = strings.TrimSuffix(printable, "\n")
+			}
 			p.errors = append(p.errors, msg)
 			return nil
 		}
@@ -270,6 +300,18 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseReturnStatement()
 	case token.IMPORT:
 		return p.parseImportStatement()
+	case token.ENUM:
+		return p.parseEnumStatement()
+	case token.STRUCT:
+		return p.parseStructStatement()
+	case token.TRAIT:
+		return p.parseTraitStatement()
+	case token.IMPL:
+		return p.parseImplStatement()
+	case token.MACRO:
+		return p.parseMacroStatement()
+	case token.AT:
+		return p.parseDecoratedFunctionStatement()
 	default:
 		// This is how im handling a function statement becuase otherwise all function literals
 		// will get confused and not be able to parse (due to the "fun" prefixed token)
@@ -295,14 +337,17 @@ func (p *Parser) parseVarStatement() *ast.VarStatement {
 	// token.IDENT and the value being the actual string of the identifier
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
+	var ok bool
+	if stmt.Type, ok = p.parseVariableType(); !ok {
+		return nil
+	}
+
+	// peekTokenIsAssignmentToken advances to the assignment token when it matches
 	if !p.peekTokenIsAssignmentToken() {
 		p.peekError(p.curToken.Type)
 		return nil
 	}
-	if p.peekTokenIsAssignmentToken() {
-		// p.nextToken()
-		stmt.AssignmentToken = p.curToken
-	}
+	stmt.AssignmentToken = p.curToken
 
 	p.nextToken()
 
@@ -330,6 +375,11 @@ func (p *Parser) parseValStatement() *ast.ValStatement {
 	// token.IDENT and the value being the actual string of the identifier
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
+	var ok bool
+	if stmt.Type, ok = p.parseVariableType(); !ok {
+		return nil
+	}
+
 	if !p.expectPeekIs(token.ASSIGN) {
 		return nil
 	}
@@ -374,17 +424,48 @@ func (p *Parser) parseFunctionLiteralStatement() *ast.FunctionStatement {
 		return nil
 	}
 
-	lit.Parameters, lit.ParameterExpressions = p.parseFunctionParameters()
+	lit.Parameters, lit.ParameterExpressions, lit.ParameterTypes = p.parseFunctionParameters()
+
+	var ok bool
+	if lit.ReturnType, ok = p.parseReturnType(); !ok {
+		return nil
+	}
 
 	if !p.expectPeekIs(token.LBRACE) {
 		return nil
 	}
 
-	lit.Body = p.parseBlockStatement()
+	lit.Body = p.parseFunctionBody()
 
 	return lit
 }
 
+// parseDecoratedFunctionStatement parses one or more `@decorator` lines followed by
+// the function statement they decorate ie. `@retry(times=3) fun fetch(url) { ... }`
+func (p *Parser) parseDecoratedFunctionStatement() ast.Statement {
+	decorators := []ast.Expression{}
+	for p.curTokenIs(token.AT) {
+		p.nextToken()
+		decorator := p.parseExpression(LOWEST)
+		if decorator == nil {
+			return nil
+		}
+		decorators = append(decorators, decorator)
+		p.nextToken()
+	}
+	if !p.curTokenIs(token.FUNCTION) || !p.peekTokenIs(token.IDENT) {
+		msg := fmt.Sprintf("expected a function statement after decorator, got %s instead", p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	stmt := p.parseFunctionLiteralStatement()
+	if stmt == nil {
+		return nil
+	}
+	stmt.Decorators = decorators
+	return stmt
+}
+
 // Expressions
 
 // parseExpression will see if their is an associated parsing function
@@ -433,17 +514,116 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	tokenLiteral := strings.Replace(p.curToken.Literal, "_", "", -1)
 	value, err := strconv.ParseInt(tokenLiteral, 0, 64)
 	if err != nil {
-		bigInt := new(big.Int)
-		bigValue, ok := bigInt.SetString(tokenLiteral, 10)
-		if ok {
-			bigLit := &ast.BigIntegerLiteral{Token: p.curToken}
-			bigLit.Value = bigValue
-			return bigLit
-		}
+		return p.parseBigIntegerLiteral(tokenLiteral, 10)
+	}
+	lit.Value = value
+	return lit
+}
+
+// parseBigIntegerLiteral will return the big int literal ast node for a value that
+// does not fit in 64 bits, tokenLiteral must already have its prefix and `_` removed
+func (p *Parser) parseBigIntegerLiteral(tokenLiteral string, base int) ast.Expression {
+	bigValue, ok := new(big.Int).SetString(tokenLiteral, base)
+	if !ok {
 		msg := fmt.Sprintf("could not parse %q as an integer", p.curToken.Literal)
 		p.errors = append(p.errors, msg)
 		return nil
 	}
+	return &ast.BigIntegerLiteral{Token: p.curToken, Value: bigValue}
+}
+
+// parseRationalLiteral will return the rational literal ast node ie. `1.5r` or `3r`
+func (p *Parser) parseRationalLiteral() ast.Expression {
+	tokenLiteral := strings.Replace(p.curToken.Literal, "_", "", -1)
+	tokenLiteral = strings.TrimSuffix(tokenLiteral, "r")
+	value, ok := new(big.Rat).SetString(tokenLiteral)
+	if !ok {
+		msg := fmt.Sprintf("could not parse %q as a rational", p.curToken.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	return &ast.RationalLiteral{Token: p.curToken, Value: value}
+}
+
+// parseDecimalLiteral will return the decimal literal ast node ie. `19.99d`
+func (p *Parser) parseDecimalLiteral() ast.Expression {
+	tokenLiteral := strings.Replace(p.curToken.Literal, "_", "", -1)
+	tokenLiteral = strings.TrimSuffix(tokenLiteral, "d")
+	exp := int64(0)
+	if e := strings.IndexAny(tokenLiteral, "eE"); e != -1 {
+		var err error
+		exp, err = strconv.ParseInt(tokenLiteral[e+1:], 10, 32)
+		if err != nil {
+			msg := fmt.Sprintf("could not parse %q as a decimal", p.curToken.Literal)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		tokenLiteral = tokenLiteral[:e]
+	}
+	scale := 0
+	if dot := strings.Index(tokenLiteral, "."); dot != -1 {
+		scale = len(tokenLiteral) - dot - 1
+		tokenLiteral = tokenLiteral[:dot] + tokenLiteral[dot+1:]
+	}
+	unscaled, ok := new(big.Int).SetString(tokenLiteral, 10)
+	if !ok {
+		msg := fmt.Sprintf("could not parse %q as a decimal", p.curToken.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	// an exponent moves the decimal point ie. `1.5e3d` is 1500
+	scale -= int(exp)
+	if scale < 0 {
+		unscaled.Mul(unscaled, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-scale)), nil))
+		scale = 0
+	}
+	return &ast.DecimalLiteral{Token: p.curToken, Unscaled: unscaled, Scale: scale}
+}
+
+// durationUnits are the milliseconds in each unit a duration literal may end with
+var durationUnits = map[string]*big.Rat{
+	"ms": big.NewRat(1, 1),
+	"s":  big.NewRat(1000, 1),
+	"m":  big.NewRat(60*1000, 1),
+	"h":  big.NewRat(60*60*1000, 1),
+}
+
+// parseDurationLiteral will return the duration literal ast node ie. `200ms` or `1.5s`
+// the value is kept in milliseconds so it can be passed to `sleep`, `after`, and `every`
+func (p *Parser) parseDurationLiteral() ast.Expression {
+	tokenLiteral := strings.Replace(p.curToken.Literal, "_", "", -1)
+	number := strings.TrimRight(tokenLiteral, "msh")
+	unit := durationUnits[tokenLiteral[len(number):]]
+	value, ok := new(big.Rat).SetString(number)
+	if !ok || unit == nil {
+		msg := fmt.Sprintf("could not parse %q as a duration", p.curToken.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	value.Mul(value, unit)
+	if !value.IsInt() || !value.Num().IsInt64() {
+		msg := fmt.Sprintf("duration %q is not a whole number of milliseconds", p.curToken.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	return &ast.DurationLiteral{Token: p.curToken, Value: value.Num().Int64()}
+}
+
+// parseSymbolLiteral will return the symbol literal ast node ie. `:name`
+func (p *Parser) parseSymbolLiteral() ast.Expression {
+	return &ast.SymbolLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+// parseImaginaryLiteral will return the imaginary literal ast node ie. `3i`
+func (p *Parser) parseImaginaryLiteral() ast.Expression {
+	lit := &ast.ImaginaryLiteral{Token: p.curToken}
+	tokenLiteral := strings.Replace(p.curToken.Literal, "_", "", -1)
+	value, err := strconv.ParseFloat(strings.TrimSuffix(tokenLiteral, "i"), 64)
+	if err != nil {
+		msg := fmt.Sprintf("could not parse %q as an imaginary number", p.curToken.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
 	lit.Value = value
 	return lit
 }
@@ -469,9 +649,7 @@ func (p *Parser) parseHexLiteral() ast.Expression {
 	tokenLiteral = strings.Replace(tokenLiteral, "0x", "", -1)
 	value, err := strconv.ParseUint(tokenLiteral, 16, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as an unsigned integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
-		return nil
+		return p.parseBigIntegerLiteral(tokenLiteral, 16)
 	}
 	lit.Value = value
 	return lit
@@ -484,9 +662,7 @@ func (p *Parser) parseOctalLiteral() ast.Expression {
 	tokenLiteral = strings.Replace(tokenLiteral, "0o", "", -1)
 	value, err := strconv.ParseUint(tokenLiteral, 8, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as an unsigned integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
-		return nil
+		return p.parseBigIntegerLiteral(tokenLiteral, 8)
 	}
 	lit.Value = value
 	return lit
@@ -499,9 +675,7 @@ func (p *Parser) parseBinaryLiteral() ast.Expression {
 	tokenLiteral = strings.Replace(tokenLiteral, "0b", "", -1)
 	value, err := strconv.ParseUint(tokenLiteral, 2, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as an unsigned integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
-		return nil
+		return p.parseBigIntegerLiteral(tokenLiteral, 2)
 	}
 	lit.Value = value
 	return lit
@@ -543,18 +717,272 @@ func (p *Parser) parseNullKeyword() ast.Expression {
 	return &ast.Null{}
 }
 
+// parseImportStatement parses an import of a module ie. `import strings` or `import path/filepath`
 func (p *Parser) parseImportStatement() ast.Statement {
 	stmt := &ast.ImportStatement{
 		Token: p.curToken,
 	}
+	if !p.expectPeekImportPathWord() {
+		return nil
+	}
+	pathTok := p.curToken
+	path := p.curToken.Literal
+	for {
+		// an element of the path is the tokens written without spaces between them
+		// ie. `github.com` is IDENT DOT IDENT and `yaml.v3` or `go-cmp` are read whole
+		for isImportPathToken(p.peekToken) && p.peekToken.Span.Start == p.curToken.Span.Start+len(p.curToken.Literal) {
+			p.nextToken()
+			path += p.curToken.Literal
+		}
+		if !p.peekTokenIs(token.FSLASH) {
+			break
+		}
+		p.nextToken()
+		if !p.expectPeekImportPathWord() {
+			return nil
+		}
+		path += "/" + p.curToken.Literal
+	}
+	stmt.Path = &ast.Identifier{Token: pathTok, Value: path}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
 
-	// set the statement name to the identifier with the current token being
-	// token.IDENT and the value being the actual string of the identifier
-	stmt.Path = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	// p.nextToken()
+// isImportPathToken returns true for the tokens that make up an element of an import
+// path, keywords are words of the path too ie. the `in` of `gopkg.in`
+func isImportPathToken(tok token.Token) bool {
+	switch tok.Type {
+	case token.IDENT, token.INT, token.FLOAT, token.DOT, token.MINUS:
+		return true
+	}
+	return token.LookupIdent(tok.Literal) == tok.Type
+}
+
+// expectPeekImportPathWord advances to the first word of an element of an import path
+func (p *Parser) expectPeekImportPathWord() bool {
+	if p.peekTokenIs(token.IDENT) || token.LookupIdent(p.peekToken.Literal) == p.peekToken.Type {
+		p.nextToken()
+		return true
+	}
+	p.peekError(token.IDENT)
+	return false
+}
+
+// parseEnumStatement parses an enum declaration ie. `enum Shape { Circle(r), Rect(w, h), Empty }`
+func (p *Parser) parseEnumStatement() ast.Statement {
+	stmt := &ast.EnumStatement{Token: p.curToken}
+	if !p.expectPeekIs(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeekIs(token.LBRACE) {
+		return nil
+	}
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeekIs(token.IDENT) {
+			return nil
+		}
+		variant := &ast.EnumVariant{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			variant.Fields = p.parseIdentifierList(token.RPAREN)
+			if variant.Fields == nil {
+				return nil
+			}
+		}
+		stmt.Variants = append(stmt.Variants, variant)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeekIs(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	if len(stmt.Variants) == 0 {
+		msg := fmt.Sprintf("enum %s must have at least one variant", stmt.Name.Value)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	return stmt
+}
+
+// parseStructStatement parses a struct declaration, fields may have defaults and
+// methods are function statements ie. `struct Point { x, y = 0, fun norm(self) { ... } }`
+func (p *Parser) parseStructStatement() ast.Statement {
+	stmt := &ast.StructStatement{Token: p.curToken}
+	if !p.expectPeekIs(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeekIs(token.LBRACE) {
+		return nil
+	}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		switch {
+		case p.curTokenIs(token.FUNCTION) && p.peekTokenIs(token.IDENT):
+			method := p.parseFunctionLiteralStatement()
+			if method == nil {
+				return nil
+			}
+			stmt.Methods = append(stmt.Methods, method)
+		case p.curTokenIs(token.IDENT):
+			stmt.Fields = append(stmt.Fields, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+			var def ast.Expression
+			if p.peekTokenIs(token.ASSIGN) {
+				p.nextToken()
+				p.nextToken()
+				def = p.parseExpression(LOWEST)
+			}
+			stmt.Defaults = append(stmt.Defaults, def)
+		default:
+			msg := fmt.Sprintf("expected field or method in struct %s, got %s instead", stmt.Name.Value, p.curToken.Type)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		// members can be separated by commas
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		}
+	}
+	p.nextToken()
 	return stmt
 }
 
+// parseTraitStatement parses a trait declaration, methods without a body are required
+func (p *Parser) parseTraitStatement() ast.Statement {
+	stmt := &ast.TraitStatement{Token: p.curToken}
+	if !p.expectPeekIs(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	stmt.Methods = p.parseMethods(stmt.Name.Value, true)
+	if stmt.Methods == nil {
+		return nil
+	}
+	return stmt
+}
+
+// parseImplStatement parses `impl Trait for Type { ... }` or `impl Type { ... }`
+func (p *Parser) parseImplStatement() ast.Statement {
+	stmt := &ast.ImplStatement{Token: p.curToken}
+	if !p.expectPeekIs(token.IDENT) {
+		return nil
+	}
+	stmt.Target = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if p.peekTokenIs(token.FOR) {
+		p.nextToken()
+		if !p.expectPeekIs(token.IDENT) {
+			return nil
+		}
+		stmt.Trait = stmt.Target
+		stmt.Target = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	stmt.Methods = p.parseMethods(stmt.Target.Value, false)
+	if stmt.Methods == nil {
+		return nil
+	}
+	return stmt
+}
+
+// parseMacroStatement parses `macro name(params) { ... }`, macro parameters cannot have defaults
+func (p *Parser) parseMacroStatement() ast.Statement {
+	stmt := &ast.MacroStatement{Token: p.curToken}
+	if !p.expectPeekIs(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeekIs(token.LPAREN) {
+		return nil
+	}
+	params, defaults, types := p.parseFunctionParameters()
+	for _, d := range defaults {
+		if d != nil {
+			msg := fmt.Sprintf("parameters of macro %s cannot have default values", stmt.Name.Value)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+	}
+	if types != nil {
+		msg := fmt.Sprintf("parameters of macro %s cannot have type annotations", stmt.Name.Value)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	stmt.Parameters = params
+	if !p.expectPeekIs(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+	return stmt
+}
+
+// parseQuoteExpression parses `quote { ... }`
+func (p *Parser) parseQuoteExpression() ast.Expression {
+	exp := &ast.QuoteExpression{Token: p.curToken}
+	if !p.expectPeekIs(token.LBRACE) {
+		return nil
+	}
+	exp.Body = p.parseBlockStatement()
+	return exp
+}
+
+// parseMethods parses a block of function statements for a trait or impl, when
+// bodyOptional is true a method may be only a signature ie. `fun area(self)`
+func (p *Parser) parseMethods(name string, bodyOptional bool) []*ast.FunctionStatement {
+	if !p.expectPeekIs(token.LBRACE) {
+		return nil
+	}
+	methods := []*ast.FunctionStatement{}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		if !p.curTokenIs(token.FUNCTION) || !p.peekTokenIs(token.IDENT) {
+			msg := fmt.Sprintf("expected method in %s, got %s instead", name, p.curToken.Type)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		method := &ast.FunctionStatement{Token: p.curToken}
+		p.nextToken()
+		method.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.expectPeekIs(token.LPAREN) {
+			return nil
+		}
+		method.Parameters, method.ParameterExpressions, method.ParameterTypes = p.parseFunctionParameters()
+		var ok bool
+		if method.ReturnType, ok = p.parseReturnType(); !ok {
+			return nil
+		}
+		if p.peekTokenIs(token.LBRACE) || !bodyOptional {
+			if !p.expectPeekIs(token.LBRACE) {
+				return nil
+			}
+			method.Body = p.parseFunctionBody()
+		}
+		methods = append(methods, method)
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		}
+	}
+	p.nextToken()
+	return methods
+}
+
+// parseIdentifierList parses comma separated identifiers up to the end token
+// it returns nil if anything other than an identifier is found
+func (p *Parser) parseIdentifierList(end token.Type) []*ast.Identifier {
+	identifiers := []*ast.Identifier{}
+	for !p.peekTokenIs(end) {
+		if !p.expectPeekIs(token.IDENT) {
+			return nil
+		}
+		identifiers = append(identifiers, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+		if !p.peekTokenIs(end) && !p.expectPeekIs(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	return identifiers
+}
+
 // parseParenGroupExpression parses a parenthesis grouped expression
 func (p *Parser) parseParenGroupExpresion() ast.Expression {
 	// skip the paren
@@ -607,6 +1035,32 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+// parseFunctionBody parses the block of a function and marks it if it contains a
+// yield so the evaluator knows calling the function returns a generator
+func (p *Parser) parseFunctionBody() *ast.BlockStatement {
+	p.yields = append(p.yields, false)
+	block := p.parseBlockStatement()
+	block.Yields = p.yields[len(p.yields)-1]
+	p.yields = p.yields[:len(p.yields)-1]
+	return block
+}
+
+// parseYieldExpression parses `yield x` or a bare `yield`
+func (p *Parser) parseYieldExpression() ast.Expression {
+	exp := &ast.YieldExpression{Token: p.curToken}
+	if len(p.yields) == 0 {
+		p.errors = append(p.errors, "yield is only allowed inside of a function")
+		return nil
+	}
+	p.yields[len(p.yields)-1] = true
+	if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) {
+		return exp
+	}
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+	return exp
+}
+
 // parseBlockStatement parses a block statement and returns a block statement ast node
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
@@ -633,72 +1087,159 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Parameters, lit.ParameterExpressions = p.parseFunctionParameters()
+	lit.Parameters, lit.ParameterExpressions, lit.ParameterTypes = p.parseFunctionParameters()
+
+	var ok bool
+	if lit.ReturnType, ok = p.parseReturnType(); !ok {
+		return nil
+	}
 
 	if !p.expectPeekIs(token.LBRACE) {
 		return nil
 	}
 
-	lit.Body = p.parseBlockStatement()
+	lit.Body = p.parseFunctionBody()
 
 	return lit
 }
 
-// parseFunctionParameters parses function parameters
-// TODO: Update this function to include default parameters may need new ast node for default parameters instead of just idents
-func (p *Parser) parseFunctionParameters() ([]*ast.Identifier, []ast.Expression) {
+// parseFunctionParameters parses function parameters, each parameter may have a type
+// annotation and a default value ie. `(a, b: int = 2)`. types is nil if no parameter is annotated
+func (p *Parser) parseFunctionParameters() ([]*ast.Identifier, []ast.Expression, []*ast.TypeAnnotation) {
 	identifiers := []*ast.Identifier{}
 	defaultParameters := []ast.Expression{}
+	types := []*ast.TypeAnnotation{}
+	annotated := false
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers, defaultParameters
+		return identifiers, defaultParameters, nil
 	}
 
-	p.nextToken()
-	val := p.parseExpression(LOWEST)
-	switch val.(type) {
-	case *ast.AssignmentExpression:
-		assignedExpression := val.(*ast.AssignmentExpression)
-		ident := assignedExpression.Left.(*ast.Identifier)
-		identifiers = append(identifiers, ident)
-		defaultParameters = append(defaultParameters, assignedExpression.Value)
-	case *ast.Identifier:
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	for {
+		p.nextToken()
+		ident, def, typ, ok := p.parseFunctionParameter()
+		if !ok {
+			return nil, nil, nil
+		}
 		identifiers = append(identifiers, ident)
-		defaultParameters = append(defaultParameters, nil)
-	default:
-		msg := fmt.Sprintf("expected assignment expression or identifier. got=%T", val)
-		p.errors = append(p.errors, msg)
-		return nil, nil
+		defaultParameters = append(defaultParameters, def)
+		types = append(types, typ)
+		annotated = annotated || typ != nil
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
 	}
 
-	for p.peekTokenIs(token.COMMA) {
+	if !p.expectPeekIs(token.RPAREN) {
+		return nil, nil, nil
+	}
+	if !annotated {
+		types = nil
+	}
+	return identifiers, defaultParameters, types
+}
+
+// parseFunctionParameter parses a single parameter ie. `b`, `b = 2`, or `b: int = 2`
+func (p *Parser) parseFunctionParameter() (*ast.Identifier, ast.Expression, *ast.TypeAnnotation, bool) {
+	if !p.curTokenIs(token.IDENT) {
+		msg := fmt.Sprintf("expected parameter name, got %s instead", p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil, nil, nil, false
+	}
+	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	var typ *ast.TypeAnnotation
+	if p.peekTokenIs(token.COLON) {
 		p.nextToken()
 		p.nextToken()
-		val := p.parseExpression(LOWEST)
-		switch val.(type) {
-		case *ast.AssignmentExpression:
-			assignedExpression := val.(*ast.AssignmentExpression)
-			ident := assignedExpression.Left.(*ast.Identifier)
-			identifiers = append(identifiers, ident)
-			defaultParameters = append(defaultParameters, assignedExpression.Value)
-		case *ast.Identifier:
-			ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-			identifiers = append(identifiers, ident)
-			defaultParameters = append(defaultParameters, nil)
-		default:
-			msg := fmt.Sprintf("expected assignment expression or identifier. got=%T", val)
-			p.errors = append(p.errors, msg)
-			return nil, nil
+		if typ = p.parseTypeAnnotation(); typ == nil {
+			return nil, nil, nil, false
 		}
 	}
+	var def ast.Expression
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+		def = p.parseExpression(LOWEST)
+	}
+	return ident, def, typ, true
+}
 
-	if !p.expectPeekIs(token.RPAREN) {
-		return nil, nil
+// parseVariableType parses the optional `: type` after the name of a var or val
+// ok is false if there is a colon but the type could not be parsed
+func (p *Parser) parseVariableType() (*ast.TypeAnnotation, bool) {
+	if !p.peekTokenIs(token.COLON) {
+		return nil, true
+	}
+	p.nextToken()
+	p.nextToken()
+	typ := p.parseTypeAnnotation()
+	return typ, typ != nil
+}
+
+// parseReturnType parses the optional `-> type` after the parameters of a function
+// ok is false if there is an arrow but the type could not be parsed
+func (p *Parser) parseReturnType() (*ast.TypeAnnotation, bool) {
+	if !p.peekTokenIs(token.ARROW) {
+		return nil, true
 	}
+	p.nextToken()
+	p.nextToken()
+	typ := p.parseTypeAnnotation()
+	return typ, typ != nil
+}
 
-	return identifiers, defaultParameters
+// parseTypeAnnotation parses a type starting at the current token ie. `int`, `list[str]`,
+// `map[str, int]`, or a union of types separated by `|` such as `int | none`
+func (p *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+	first := p.parseSingleType()
+	if first == nil || !p.peekTokenIs(token.PIPE) {
+		return first
+	}
+	union := &ast.TypeAnnotation{Token: first.Token, Span: first.Span, Union: []*ast.TypeAnnotation{first}}
+	for p.peekTokenIs(token.PIPE) {
+		p.nextToken()
+		p.nextToken()
+		alt := p.parseSingleType()
+		if alt == nil {
+			return nil
+		}
+		union.Union = append(union.Union, alt)
+		union.Span.End = alt.Span.End
+	}
+	return union
+}
+
+// parseSingleType parses a type name with its optional element types ie. `map[str, int]`
+func (p *Parser) parseSingleType() *ast.TypeAnnotation {
+	if !p.curTokenIs(token.IDENT) && !p.curTokenIs(token.FUNCTION) && !p.curTokenIs(token.NULL_KW) {
+		msg := fmt.Sprintf("expected a type, got %s instead", p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	typ := &ast.TypeAnnotation{Token: p.curToken, Span: p.curToken.Span, Name: p.curToken.Literal}
+	if !p.peekTokenIs(token.LBRACKET) {
+		return typ
+	}
+	p.nextToken()
+	for {
+		p.nextToken()
+		param := p.parseTypeAnnotation()
+		if param == nil {
+			return nil
+		}
+		typ.Params = append(typ.Params, param)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeekIs(token.RBRACKET) {
+		return nil
+	}
+	typ.Span.End = p.curToken.Span.Start + len(p.curToken.Literal)
+	return typ
 }
 
 // parseLambdaLiteral will parse a lambda expression and return the ast node
@@ -711,7 +1252,7 @@ func (p *Parser) parseLambdaLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Body = p.parseBlockStatement()
+	lit.Body = p.parseFunctionBody()
 
 	return lit
 }
@@ -755,11 +1296,15 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseExecStringLiteral will parse the exec string and its interpolation values
 func (p *Parser) parseExecStringLiteral() ast.Expression {
-	return &ast.ExecStringLiteral{
+	exp := &ast.ExecStringLiteral{
 		Token: p.curToken,
 		Value: p.curToken.Literal,
 	}
+
+	exp.InterpolationValues, exp.OriginalInterpolationString = p.parseStringInterpolationValues(p.curToken.Literal)
+	return exp
 }
 
 // parseRawStringLiteral is just like parse string however it doesnt allow
@@ -864,7 +1409,14 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	indxExp := &ast.IndexExpression{Token: p.curToken, Left: left}
 	// skip over the [
 	p.nextToken()
+	if p.curTokenIs(token.COLON) || (p.curTokenIs(token.SYMBOL) && !p.peekTokenIs(token.RBRACKET)) {
+		return p.parseSliceExpression(indxExp.Token, left, nil)
+	}
 	indxExp.Index = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.COLON) || p.peekTokenIs(token.SYMBOL) {
+		p.nextToken()
+		return p.parseSliceExpression(indxExp.Token, left, indxExp.Index)
+	}
 
 	if !p.expectPeekIs(token.RBRACKET) {
 		return nil
@@ -873,6 +1425,49 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return indxExp
 }
 
+// parseSliceExpression parses the rest of a slice after its first `:` ie. `xs[1:3]`
+// `:name` lexes as a symbol so a symbol where a `:` is expected is split back into the
+// colon and the identifier that starts the bound ie. `xs[:n+1]` or `xs[::n]`. `xs[:n]`
+// is left as an index with a symbol because it is also how a map is indexed with a
+// symbol, the evaluator slices it when xs is a list or string
+func (p *Parser) parseSliceExpression(tok token.Token, left, start ast.Expression) ast.Expression {
+	slice := &ast.SliceExpression{Token: tok, Left: left, Start: start}
+	if p.curTokenIs(token.SYMBOL) {
+		p.splitSymbol()
+		slice.End = p.parseExpression(LOWEST)
+	} else if !p.peekTokenIs(token.COLON) && !p.peekTokenIs(token.SYMBOL) && !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		slice.End = p.parseExpression(LOWEST)
+	}
+
+	if p.peekTokenIs(token.SYMBOL) {
+		p.nextToken()
+		p.splitSymbol()
+		slice.Step = p.parseExpression(LOWEST)
+	} else if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		if !p.peekTokenIs(token.RBRACKET) {
+			p.nextToken()
+			slice.Step = p.parseExpression(LOWEST)
+		}
+	}
+
+	if !p.expectPeekIs(token.RBRACKET) {
+		return nil
+	}
+	return slice
+}
+
+// splitSymbol replaces the current symbol token, that is really a colon followed by
+// a name, with the token of the name so the expression it starts can be parsed
+func (p *Parser) splitSymbol() {
+	p.curToken = token.Token{
+		Type:    token.LookupIdent(p.curToken.Literal),
+		Literal: p.curToken.Literal,
+		Span:    token.Span{Start: p.curToken.Span.Start + 1, End: p.curToken.Span.End},
+	}
+}
+
 // parseMemberAccessExpression parses a dot token to use as an index expression
 func (p *Parser) parseMemberAccessExpression(left ast.Expression) ast.Expression {
 	// first item needs to be a identifier
@@ -884,6 +1479,60 @@ func (p *Parser) parseMemberAccessExpression(left ast.Expression) ast.Expression
 	return indxExp
 }
 
+// parsePipelineExpression parses `xs |> filter(is_even)` into the call `filter(xs, is_even)`
+// the left value is inserted as the first argument, if the right side is not a call it is
+// called with the left value as the only argument ie. `xs |> sum` is `sum(xs)`
+func (p *Parser) parsePipelineExpression(left ast.Expression) ast.Expression {
+	tok := p.curToken
+	p.nextToken()
+	// the right side binds below comparison like the left side so `xs |> f() == 3` is
+	// `xs |> (f() == 3)`, the result is compared with `(xs |> len()) == 3`
+	right := p.parseExpression(PIPELINE_P)
+	if call, ok := right.(*ast.CallExpression); ok {
+		return &ast.CallExpression{
+			Token:            call.Token,
+			Function:         call.Function,
+			Arguments:        append([]ast.Expression{left}, call.Arguments...),
+			DefaultArguments: call.DefaultArguments,
+			Optional:         call.Optional,
+		}
+	}
+	if right == nil {
+		return nil
+	}
+	return &ast.CallExpression{Token: tok, Function: right, Arguments: []ast.Expression{left}, DefaultArguments: map[string]ast.Expression{}}
+}
+
+// parseOptionalChainExpression parses `?.` for optional member access `user?.name`
+// or an optional call `f?.()`, both are null when the left side is null
+func (p *Parser) parseOptionalChainExpression(left ast.Expression) ast.Expression {
+	tok := p.curToken
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		call := p.parseCallExpression(left).(*ast.CallExpression)
+		call.Optional = true
+		return call
+	}
+	if !p.expectPeekIs(token.IDENT) {
+		return nil
+	}
+	indx := &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	return &ast.IndexExpression{Token: tok, Left: left, Index: indx, Optional: true}
+}
+
+// parseOptionalIndexExpression parses `m?["k"]` and `xs?[1:]` which are null when the left side is null
+func (p *Parser) parseOptionalIndexExpression(left ast.Expression) ast.Expression {
+	switch exp := p.parseIndexExpression(left).(type) {
+	case *ast.IndexExpression:
+		exp.Optional = true
+		return exp
+	case *ast.SliceExpression:
+		exp.Optional = true
+		return exp
+	}
+	return nil
+}
+
 // parseForExpression parses a for expression and returns the for expressions
 // ast node
 // TODO: this function will need more work to cover the cases like ranges
@@ -917,7 +1566,7 @@ func (p *Parser) parseForExpression() ast.Expression {
 // parseAssignmentExpression will return a parsed assignment as an Expression ast node
 func (p *Parser) parseAssignmentExpression(exp ast.Expression) ast.Expression {
 	switch node := exp.(type) {
-	case *ast.Identifier, *ast.IndexExpression:
+	case *ast.Identifier, *ast.IndexExpression, *ast.SliceExpression:
 	default:
 		msg := fmt.Sprintf("expected identifier or index expression on left but got %T %#v", node, exp)
 		p.errors = append(p.errors, msg)
@@ -964,10 +1613,97 @@ func (p *Parser) parseMatchExpression() ast.Expression {
 		}
 	}
 
-	// Skip over the } on the way out of parsing the
+	return me
+}
+
+// parseSpawnExpression parses `spawn f(x)`, the call binds tighter than spawn
+func (p *Parser) parseSpawnExpression() ast.Expression {
+	exp := &ast.SpawnExpression{Token: p.curToken}
 	p.nextToken()
+	exp.Value = p.parseExpression(PREFIX)
+	if exp.Value == nil {
+		return nil
+	}
+	return exp
+}
 
-	return me
+// parseAwaitExpression parses `await task`
+func (p *Parser) parseAwaitExpression() ast.Expression {
+	exp := &ast.AwaitExpression{Token: p.curToken}
+	p.nextToken()
+	exp.Value = p.parseExpression(PREFIX)
+	if exp.Value == nil {
+		return nil
+	}
+	return exp
+}
+
+// parseSelectExpression parses the cases of a select which are written like the arms of a match
+func (p *Parser) parseSelectExpression() ast.Expression {
+	se := &ast.SelectExpression{Token: p.curToken}
+	if !p.expectPeekIs(token.LBRACE) {
+		return nil
+	}
+	p.nextToken()
+	for !p.curTokenIs(token.RBRACE) {
+		se.Cases = append(se.Cases, p.parseExpression(LOWEST))
+		if !p.expectPeekIs(token.RARROW) {
+			return nil
+		}
+		p.nextToken()
+
+		se.Consequence = append(se.Consequence, p.parseBlockStatement())
+		if !p.expectPeekIs(token.COMMA) {
+			return nil
+		}
+		p.nextToken()
+	}
+	return se
+}
+
+// parseScopeExpression parses `scope { ... }`
+func (p *Parser) parseScopeExpression() ast.Expression {
+	exp := &ast.ScopeExpression{Token: p.curToken}
+	if !p.expectPeekIs(token.LBRACE) {
+		return nil
+	}
+	exp.Body = p.parseBlockStatement()
+	return exp
+}
+
+// parseWithExpression parses `with lock { ... }`
+func (p *Parser) parseWithExpression() ast.Expression {
+	exp := &ast.WithExpression{Token: p.curToken}
+	p.nextToken()
+	exp.Lock = p.parseExpression(LOWEST)
+	if exp.Lock == nil || !p.expectPeekIs(token.LBRACE) {
+		return nil
+	}
+	exp.Body = p.parseBlockStatement()
+	return exp
+}
+
+// parseReceiveExpression parses the patterns of a receive which are written like the arms of a match
+func (p *Parser) parseReceiveExpression() ast.Expression {
+	re := &ast.ReceiveExpression{Token: p.curToken}
+	if !p.expectPeekIs(token.LBRACE) {
+		return nil
+	}
+	p.nextToken()
+	for !p.curTokenIs(token.RBRACE) {
+		re.Patterns = append(re.Patterns, p.parseExpression(LOWEST))
+		if !p.expectPeekIs(token.RARROW) {
+			return nil
+		}
+		p.nextToken()
+
+		re.Consequence = append(re.Consequence, p.parseBlockStatement())
+		if !p.expectPeekIs(token.COMMA) {
+			return nil
+		}
+		p.nextToken()
+	}
+	return re
 }
 
 // Helper functions
@@ -1043,7 +1779,7 @@ func (p *Parser) parseListComprehension(valueToBind ast.Expression) []ast.Expres
 
 	var program string
 	if ifCond != nil {
-		program = fmt.Sprintf("var __internal__ = []; for %s { if %s { __internal__ = append(__internal__, %s); } };:", expCond, ifCond, valueToBind.String())
+		program = fmt.Sprintf("var __internal__ = []; for %s { if %s { __internal__ = append(__internal__, %s); } };", expCond, ifCond, valueToBind.String())
 	} else {
 		program = fmt.Sprintf("var __internal__ = []; for %s { __internal__ = append(__internal__, %s);  };", expCond, valueToBind.String())
 	}
@@ -465,6 +465,368 @@ func TestBinaryLiteralExpression(t *testing.T) {
 
 }
 
+func TestBigUnsignedLiteralExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0x1_0000_0000_0000_0000;", "18446744073709551616"},
+		{"0o2_000_000_000_000_000_000_000;", "18446744073709551616"},
+		{"0b1_0000000000000000000000000000000000000000000000000000000000000000;", "18446744073709551616"},
+		{"18446744073709551616;", "18446744073709551616"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "<string>")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.BigIntegerLiteral)
+		if !ok {
+			t.Fatalf("exp is not an *ast.BigIntegerLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value.String() != tt.expected {
+			t.Fatalf("literal.Value not %s. got %s", tt.expected, literal.Value.String())
+		}
+	}
+}
+
+func TestRationalLiteralExpression(t *testing.T) {
+	input := "0.7_5r;"
+	l := lexer.New(input, "<string>")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.RationalLiteral)
+	if !ok {
+		t.Fatalf("exp is not an *ast.RationalLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value.RatString() != "3/4" {
+		t.Fatalf("literal.Value not 3/4. got %s", literal.Value.RatString())
+	}
+}
+
+func TestDecimalLiteralExpression(t *testing.T) {
+	input := "1_019.90d;"
+	l := lexer.New(input, "<string>")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.DecimalLiteral)
+	if !ok {
+		t.Fatalf("exp is not an *ast.DecimalLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Unscaled.String() != "101990" || literal.Scale != 2 {
+		t.Fatalf("literal not 101990e-2. got %se-%d", literal.Unscaled.String(), literal.Scale)
+	}
+}
+
+func TestDurationLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"200ms", 200},
+		{"5s", 5000},
+		{"1.5s", 1500},
+		{"2m", 120000},
+		{"1h", 3600000},
+		{"1_000ms", 1000},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "<string>")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.DurationLiteral)
+		if !ok {
+			t.Fatalf("exp is not an *ast.DurationLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected || literal.String() != tt.input {
+			t.Errorf("literal %s wrong. want %dms, got %dms (%s)", tt.input, tt.expected, literal.Value, literal.String())
+		}
+	}
+
+	p := New(lexer.New("0.5ms", "<string>"))
+	p.ParseProgram()
+	expected := `duration "0.5ms" is not a whole number of milliseconds`
+	if len(p.Errors()) != 1 || p.Errors()[0] != expected {
+		t.Fatalf("wrong parser errors. want %q, got=%q", expected, p.Errors())
+	}
+}
+
+func TestScientificAndHexFloatLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1e9", 1e9},
+		{"2.5e-3", 2.5e-3},
+		{".5", 0.5},
+		{"0x1.8p1", 3},
+		{"1_000e-3", 1},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "<string>")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp is not an *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Fatalf("literal.Value not %f. got %f", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestImaginaryLiteralExpression(t *testing.T) {
+	input := "2.5i;"
+	l := lexer.New(input, "<string>")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.ImaginaryLiteral)
+	if !ok {
+		t.Fatalf("exp is not an *ast.ImaginaryLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value != 2.5 {
+		t.Fatalf("literal.Value not 2.5. got %f", literal.Value)
+	}
+}
+
+func TestSymbolLiteralExpression(t *testing.T) {
+	input := ":running;"
+	l := lexer.New(input, "<string>")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.SymbolLiteral)
+	if !ok {
+		t.Fatalf("exp is not an *ast.SymbolLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value != "running" || literal.String() != ":running" {
+		t.Fatalf("literal not :running. got %s", literal.String())
+	}
+}
+
+func TestEnumStatementParsing(t *testing.T) {
+	input := `enum Shape {
+	Circle(r),
+	Rect(w, h),
+	Empty,
+}`
+	l := lexer.New(input, "<string>")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program does not have 1 statement. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.EnumStatement)
+	if !ok {
+		t.Fatalf("stmt is not an *ast.EnumStatement. got=%T", program.Statements[0])
+	}
+	if stmt.String() != "enum Shape { Circle(r), Rect(w, h), Empty }" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+	expectedDisplay := "EnumStatement{Name: 'Shape', Variants: ['Circle(r)', 'Rect(w, h)', 'Empty']}"
+	if stmt.Display() != expectedDisplay {
+		t.Errorf("stmt.Display() wrong. got=%q", stmt.Display())
+	}
+}
+
+func TestStructStatementParsing(t *testing.T) {
+	input := `struct Point {
+	x,
+	y = 0,
+	fun norm_sq(self) { self.x * self.x + self.y * self.y }
+}`
+	l := lexer.New(input, "<string>")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program does not have 1 statement. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.StructStatement)
+	if !ok {
+		t.Fatalf("stmt is not an *ast.StructStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Name.Value != "Point" || len(stmt.Fields) != 2 || len(stmt.Methods) != 1 {
+		t.Fatalf("wrong struct. got=%s", stmt.Display())
+	}
+	if stmt.Defaults[0] != nil || stmt.Defaults[1].String() != "0" {
+		t.Errorf("wrong defaults. got=%v", stmt.Defaults)
+	}
+	if stmt.Methods[0].Name.Value != "norm_sq" {
+		t.Errorf("wrong method name. got=%s", stmt.Methods[0].Name.Value)
+	}
+	if !strings.HasPrefix(stmt.Display(), "StructStatement{Name: 'Point', Fields: ['x', 'y = 0'], Methods: [") {
+		t.Errorf("stmt.Display() wrong. got=%q", stmt.Display())
+	}
+
+	// the struct parsed from its string is the same struct
+	p = New(lexer.New(stmt.String(), "<string>"))
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 1 || program.Statements[0].String() != stmt.String() || program.Statements[0].Display() != stmt.Display() {
+		t.Errorf("the struct did not round trip. want=%s, got=%s", stmt.Display(), program.Statements[0].Display())
+	}
+}
+
+func TestTraitAndImplParsing(t *testing.T) {
+	input := `trait Shape {
+	fun area(self)
+	fun describe(self) { "area #{self.area()}" }
+}
+impl Shape for Circle {
+	fun area(self) { 3 * self.r * self.r }
+}
+impl Circle { fun scale(self, k) { Circle(self.r * k) } }`
+	l := lexer.New(input, "<string>")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program does not have 3 statements. got=%d", len(program.Statements))
+	}
+	trait, ok := program.Statements[0].(*ast.TraitStatement)
+	if !ok {
+		t.Fatalf("stmt is not an *ast.TraitStatement. got=%T", program.Statements[0])
+	}
+	if trait.String() != "trait Shape { fun area(self), fun describe(self) }" {
+		t.Errorf("wrong trait. got=%s", trait.String())
+	}
+	if trait.Methods[0].Body != nil || trait.Methods[1].Body == nil {
+		t.Errorf("wrong method bodies. got=%s", trait.Display())
+	}
+	impl, ok := program.Statements[1].(*ast.ImplStatement)
+	if !ok {
+		t.Fatalf("stmt is not an *ast.ImplStatement. got=%T", program.Statements[1])
+	}
+	if impl.String() != "impl Shape for Circle { fun area(self) }" {
+		t.Errorf("wrong impl. got=%s", impl.String())
+	}
+	impl, ok = program.Statements[2].(*ast.ImplStatement)
+	if !ok {
+		t.Fatalf("stmt is not an *ast.ImplStatement. got=%T", program.Statements[2])
+	}
+	if impl.Trait != nil || impl.String() != "impl Circle { fun scale(self, k) }" {
+		t.Errorf("wrong impl. got=%s", impl.String())
+	}
+}
+
+func TestSliceExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"xs[1:3]", "(xs[1:3])"},
+		{"xs[:-1]", "(xs[:(-1)])"},
+		{"xs[::2]", "(xs[::2])"},
+		{"xs[1:]", "(xs[1:])"},
+		{"xs[:]", "(xs[:])"},
+		{"xs[:n]", "(xs[:n])"},
+		{"xs[::n]", "(xs[::n])"},
+		{"xs[i:j:k]", "(xs[i:j:k])"},
+		{"xs[:n:2]", "(xs[:n:2])"},
+		{"xs[(:key)]", "(xs[:key])"},
+		{"xs[:n+1]", "(xs[:(n + 1)])"},
+		{"xs[:f(x)]", "(xs[:f(x)])"},
+		{"xs[a:n*2]", "(xs[a:(n * 2)])"},
+		{"xs[:len(xs)-1]", "(xs[:(len(xs) - 1)])"},
+		{"xs[a :n]", "(xs[a:n])"},
+		{"xs[::n-1]", "(xs[::(n - 1)])"},
+		{"xs[:n:k*2]", "(xs[:n:(k * 2)])"},
+		{"xs[1:3] = [9]", "(xs[1:3]) = [9]"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "<string>")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestYieldExpressionParsing(t *testing.T) {
+	input := `fun count(n) {
+	var i = 0
+	for (i < n) { yield i; i += 1 }
+}
+val f = fun() { val g = fun() { yield }; 1 }`
+	l := lexer.New(input, "<string>")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program does not have 2 statements. got=%d", len(program.Statements))
+	}
+	count, ok := program.Statements[0].(*ast.FunctionStatement)
+	if !ok {
+		t.Fatalf("stmt is not an *ast.FunctionStatement. got=%T", program.Statements[0])
+	}
+	if !count.Body.Yields {
+		t.Errorf("count should yield")
+	}
+	if !strings.Contains(count.Body.String(), "yield i") {
+		t.Errorf("body does not contain the yield. got=%s", count.Body.String())
+	}
+	f := program.Statements[1].(*ast.ValStatement).Value.(*ast.FunctionLiteral)
+	if f.Body.Yields {
+		t.Errorf("only the inner function should yield")
+	}
+	g := f.Body.Statements[0].(*ast.ValStatement).Value.(*ast.FunctionLiteral)
+	if !g.Body.Yields {
+		t.Errorf("the inner function should yield")
+	}
+
+	l = lexer.New("yield 1", "<string>")
+	p = New(l)
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "yield is only allowed inside of a function" {
+		t.Errorf("wrong parser errors. got=%q", p.Errors())
+	}
+}
+
+func TestIllegalNumberError(t *testing.T) {
+	input := "val x = 1__0;"
+	l := lexer.New(input, "<string>")
+	p := New(l)
+	p.ParseProgram()
+
+	expected := "<string>:1:9 ILLEGAL token encountered. got=1__0"
+	if len(p.Errors()) != 1 || !strings.HasPrefix(p.Errors()[0], expected) {
+		t.Fatalf("wrong parser errors. want prefix %q, got=%q", expected, p.Errors())
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -550,6 +912,7 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5..5;", 5, "..", 5},
 		{"5..<5;", 5, "..<", 5},
 		{"5 in 5", 5, "in", 5},
+		{"5 not in 5", 5, "not in", 5},
 		// These dont count as infix because they are assignment
 		// as well
 		// TODO: Figure out where these precedences come into play
@@ -709,6 +1072,62 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a + b + c * d / f + g)",
 			"add((((a + b) + ((c * d) / f)) + g))",
 		},
+		{
+			"a ?? b + 1 == c",
+			"((a ?? (b + 1)) == c)",
+		},
+		{
+			"a ?? b ?? c and d",
+			"(((a ?? b) ?? c) and d)",
+		},
+		{
+			"user?.address?.city",
+			"((user?[\"address\"])?[\"city\"])",
+		},
+		{
+			"m?[\"k\"].v",
+			"((m?[\"k\"])[\"v\"])",
+		},
+		{
+			"f?.(x) ?? empty?(xs)",
+			"(f?.(x) ?? empty?(xs))",
+		},
+		{
+			"xs |> filter(is_even) |> map(double) |> sum()",
+			"sum(map(filter(xs, is_even), double))",
+		},
+		{
+			"xs |> len() == 3",
+			"(len() == 3)(xs)",
+		},
+		{
+			"(xs |> len()) == 3",
+			"(len(xs) == 3)",
+		},
+		{
+			"a == b |> f()",
+			"f((a == b))",
+		},
+		{
+			"xs |> f() |> g(1) or ok",
+			"(g(f(xs), 1) or ok)",
+		},
+		{
+			"xs |> f(1) + 2",
+			"(f(1) + 2)(xs)",
+		},
+		{
+			"a + b |> f",
+			"f((a + b))",
+		},
+		{
+			"a == b |> f(c) and d",
+			"(f((a == b), c) and d)",
+		},
+		{
+			"xs |> obj.run(1)",
+			"(obj[\"run\"])(xs, 1)",
+		},
 	}
 	for _, tt := range tests {
 		l := lexer.New(tt.input, "<string>")
@@ -1575,3 +1994,203 @@ func TestForExpression(t *testing.T) {
 	}
 
 }
+
+func TestMacroAndQuoteParsing(t *testing.T) {
+	input := `macro unless(cond, body) {
+	quote { if (not unquote(cond)) { unquote(body) } }
+}
+val q = quote { x + 1 }`
+	l := lexer.New(input, "<string>")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program does not have 2 statements. got=%d", len(program.Statements))
+	}
+	macro, ok := program.Statements[0].(*ast.MacroStatement)
+	if !ok {
+		t.Fatalf("stmt is not an *ast.MacroStatement. got=%T", program.Statements[0])
+	}
+	if macro.Name.Value != "unless" || len(macro.Parameters) != 2 || macro.Parameters[1].Value != "body" {
+		t.Errorf("wrong macro. got=%s", macro.String())
+	}
+	if _, ok := macro.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.QuoteExpression); !ok {
+		t.Errorf("macro body is not a quote. got=%s", macro.Body.String())
+	}
+	quote, ok := program.Statements[1].(*ast.ValStatement).Value.(*ast.QuoteExpression)
+	if !ok {
+		t.Fatalf("value is not an *ast.QuoteExpression. got=%T", program.Statements[1].(*ast.ValStatement).Value)
+	}
+	if quote.String() != "quote { (x + 1) }" {
+		t.Errorf("wrong quote. got=%q", quote.String())
+	}
+
+	l = lexer.New("macro m(a = 1) { quote { a } }", "<string>")
+	p = New(l)
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "parameters of macro m cannot have default values" {
+		t.Errorf("wrong parser errors. got=%q", p.Errors())
+	}
+}
+
+func TestDecoratorParsing(t *testing.T) {
+	input := `@memo
+@retry(times=3)
+fun fetch(url) { url }`
+	l := lexer.New(input, "<string>")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program does not have 1 statement. got=%d", len(program.Statements))
+	}
+	fetch, ok := program.Statements[0].(*ast.FunctionStatement)
+	if !ok {
+		t.Fatalf("stmt is not an *ast.FunctionStatement. got=%T", program.Statements[0])
+	}
+	if len(fetch.Decorators) != 2 {
+		t.Fatalf("wrong number of decorators. got=%d", len(fetch.Decorators))
+	}
+	if fetch.Decorators[0].String() != "memo" {
+		t.Errorf("wrong first decorator. got=%q", fetch.Decorators[0].String())
+	}
+	call, ok := fetch.Decorators[1].(*ast.CallExpression)
+	if !ok || call.Function.String() != "retry" || call.DefaultArguments["times"] == nil {
+		t.Errorf("wrong second decorator. got=%q", fetch.Decorators[1].String())
+	}
+	if !strings.HasPrefix(fetch.String(), "@memo\n@retry") {
+		t.Errorf("decorators are not in the string. got=%q", fetch.String())
+	}
+
+	l = lexer.New("@memo\nval x = 1", "<string>")
+	p = New(l)
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected a function statement after decorator, got VAL instead" {
+		t.Errorf("wrong parser errors. got=%q", p.Errors())
+	}
+}
+
+func TestImportStatementParsing(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedPath string
+		expectedName string
+	}{
+		{"import math", "math", "math"},
+		{"import path/filepath", "path/filepath", "filepath"},
+		{"import github.com/x/y", "github.com/x/y", "y"},
+		{"import github.com/google/go-cmp/cmp", "github.com/google/go-cmp/cmp", "cmp"},
+		{"import gopkg.in/yaml.v3", "gopkg.in/yaml.v3", "yaml"},
+		{"import github.com/mattn/go-sqlite3", "github.com/mattn/go-sqlite3", "sqlite3"},
+		{"import gopkg.in/check.v1", "gopkg.in/check.v1", "check"},
+		{"import k8s.io/api/v1", "k8s.io/api/v1", "v1"},
+		{"import github.com/", "", ""},
+		{"import /x", "", ""},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "<string>")
+		p := New(l)
+		program := p.ParseProgram()
+		if tt.expectedPath == "" {
+			if len(p.Errors()) == 0 {
+				t.Errorf("%q: expected parser errors", tt.input)
+			}
+			continue
+		}
+		checkParserErrors(t, p)
+		stmt, ok := program.Statements[0].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("stmt is not an *ast.ImportStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Path.Value != tt.expectedPath || stmt.Name() != tt.expectedName {
+			t.Errorf("wrong import. want path=%q name=%q, got path=%q name=%q", tt.expectedPath, tt.expectedName, stmt.Path.Value, stmt.Name())
+		}
+		if stmt.String() != tt.input {
+			t.Errorf("wrong string. want=%q, got=%q", tt.input, stmt.String())
+		}
+	}
+}
+
+func TestTypeAnnotationParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"val name: str = \"blue\"", "val name: str = \"blue\";"},
+		{"var n: int | none = null", "var n: int | none = null;\n"},
+		{"var xs: list[map[str, int]] = []", "var xs: list[map[str, int]] = [];\n"},
+		{"fun add(a: int, b: int = 2) -> int { a + b }", "fun add(a: int, b: int) -> int {\n\t(a + b)\n}\n"},
+		{"fun add(a : int, b :int) -> int { a + b }", "fun add(a: int, b: int) -> int {\n\t(a + b)\n}\n"},
+		{"val name : str = \"blue\"", "val name: str = \"blue\";"},
+		{"fun first(xs: list[str], n) -> str | none { xs[0] }", "fun first(xs: list[str], n) -> str | none {\n\t(xs[0])\n}\n"},
+		{"val f = fun(x: num) -> fun { x }", "val f = fun(x: num ) -> fun {\n\tx\n}\n;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "<string>")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("wrong program for %q. got=%q, want=%q", tt.input, program.String(), tt.expected)
+		}
+	}
+
+	l := lexer.New("fun add(a: int, b) -> int | none { a }", "<string>")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	fs := program.Statements[0].(*ast.FunctionStatement)
+	if len(fs.ParameterTypes) != 2 || fs.ParameterTypes[0].Name != "int" || fs.ParameterTypes[1] != nil {
+		t.Errorf("wrong parameter types. got=%v", fs.ParameterTypes)
+	}
+	if len(fs.ReturnType.Union) != 2 || fs.ReturnType.Union[1].Name != "none" {
+		t.Errorf("wrong return type. got=%s", fs.ReturnType.Display())
+	}
+	if fs.ReturnType.Span.Start != 22 || fs.ReturnType.Span.End != 32 {
+		t.Errorf("wrong return type span. got=%s", fs.ReturnType.Span)
+	}
+
+	l = lexer.New("val x: = 1", "<string>")
+	p = New(l)
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected a type, got = instead" {
+		t.Errorf("wrong parser errors. got=%q", p.Errors())
+	}
+}
+
+func TestConcurrencyParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"spawn f(1, 2)", "spawn f(1, 2)"},
+		{"await spawn f(x)", "await spawn f(x)"},
+		{"await tasks[0]", "await (tasks[0])"},
+		{"select { msg = recv(ch) => { msg }, _ => { 0 }, }", "select {\n\tmsg = recv(ch) => {msg},\n\t_ => {0},\n}"},
+		{"with m { n += 1 }", "with m { n += 1 }"},
+		{"with read_lock(rw) { cache[k] }", "with read_lock(rw) { (cache[k]) }"},
+		{"scope { spawn f(); spawn g() }", "scope { spawn f()spawn g() }"},
+		{"receive { [:add, n] => { n }, timeout(10) => { 0 }, }", "receive {\n\t[:add, n] => {n},\n\ttimeout(10) => {0},\n}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "<string>")
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if program.String() != tt.expected {
+			t.Errorf("wrong program for %q. got=%q, want=%q", tt.input, program.String(), tt.expected)
+		}
+	}
+
+	l := lexer.New("select { recv(ch) { 1 }, }", "<string>")
+	p := New(l)
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected next token to be =>, got { instead" {
+		t.Errorf("wrong parser errors. got=%q", p.Errors())
+	}
+}
@@ -0,0 +1,5 @@
+// stdlib registers Go standard library packages as blue modules, importing it makes
+// `import strings`, `import path/filepath`, and `import math` work in blue programs
+package stdlib
+
+//go:generate go run blue bindgen -o . strings path/filepath math
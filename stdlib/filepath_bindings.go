@@ -0,0 +1,38 @@
+// Code generated by blue bindgen; DO NOT EDIT.
+
+package stdlib
+
+import (
+	"blue/interp"
+	"path/filepath"
+)
+
+func init() {
+	interp.RegisterModule("path/filepath", interp.Module{
+		Funcs: map[string]interface{}{
+			"Abs":          filepath.Abs,
+			"Base":         filepath.Base,
+			"Clean":        filepath.Clean,
+			"Dir":          filepath.Dir,
+			"EvalSymlinks": filepath.EvalSymlinks,
+			"Ext":          filepath.Ext,
+			"FromSlash":    filepath.FromSlash,
+			"Glob":         filepath.Glob,
+			"HasPrefix":    filepath.HasPrefix,
+			"IsAbs":        filepath.IsAbs,
+			"Join":         filepath.Join,
+			"Match":        filepath.Match,
+			"Rel":          filepath.Rel,
+			"Split":        filepath.Split,
+			"SplitList":    filepath.SplitList,
+			"ToSlash":      filepath.ToSlash,
+			"VolumeName":   filepath.VolumeName,
+			"Walk":         filepath.Walk,
+			"WalkDir":      filepath.WalkDir,
+		},
+		Values: map[string]interface{}{
+			"ListSeparator": rune(filepath.ListSeparator),
+			"Separator":     rune(filepath.Separator),
+		},
+	})
+}
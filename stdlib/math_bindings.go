@@ -0,0 +1,114 @@
+// Code generated by blue bindgen; DO NOT EDIT.
+
+package stdlib
+
+import (
+	"blue/interp"
+	"math"
+)
+
+func init() {
+	interp.RegisterModule("math", interp.Module{
+		Funcs: map[string]interface{}{
+			"Abs":             math.Abs,
+			"Acos":            math.Acos,
+			"Acosh":           math.Acosh,
+			"Asin":            math.Asin,
+			"Asinh":           math.Asinh,
+			"Atan":            math.Atan,
+			"Atan2":           math.Atan2,
+			"Atanh":           math.Atanh,
+			"Cbrt":            math.Cbrt,
+			"Ceil":            math.Ceil,
+			"Copysign":        math.Copysign,
+			"Cos":             math.Cos,
+			"Cosh":            math.Cosh,
+			"Dim":             math.Dim,
+			"Erf":             math.Erf,
+			"Erfc":            math.Erfc,
+			"Erfcinv":         math.Erfcinv,
+			"Erfinv":          math.Erfinv,
+			"Exp":             math.Exp,
+			"Exp2":            math.Exp2,
+			"Expm1":           math.Expm1,
+			"FMA":             math.FMA,
+			"Float32bits":     math.Float32bits,
+			"Float32frombits": math.Float32frombits,
+			"Float64bits":     math.Float64bits,
+			"Float64frombits": math.Float64frombits,
+			"Floor":           math.Floor,
+			"Frexp":           math.Frexp,
+			"Gamma":           math.Gamma,
+			"Hypot":           math.Hypot,
+			"Ilogb":           math.Ilogb,
+			"Inf":             math.Inf,
+			"IsInf":           math.IsInf,
+			"IsNaN":           math.IsNaN,
+			"J0":              math.J0,
+			"J1":              math.J1,
+			"Jn":              math.Jn,
+			"Ldexp":           math.Ldexp,
+			"Lgamma":          math.Lgamma,
+			"Log":             math.Log,
+			"Log10":           math.Log10,
+			"Log1p":           math.Log1p,
+			"Log2":            math.Log2,
+			"Logb":            math.Logb,
+			"Max":             math.Max,
+			"Min":             math.Min,
+			"Mod":             math.Mod,
+			"Modf":            math.Modf,
+			"NaN":             math.NaN,
+			"Nextafter":       math.Nextafter,
+			"Nextafter32":     math.Nextafter32,
+			"Pow":             math.Pow,
+			"Pow10":           math.Pow10,
+			"Remainder":       math.Remainder,
+			"Round":           math.Round,
+			"RoundToEven":     math.RoundToEven,
+			"Signbit":         math.Signbit,
+			"Sin":             math.Sin,
+			"Sincos":          math.Sincos,
+			"Sinh":            math.Sinh,
+			"Sqrt":            math.Sqrt,
+			"Tan":             math.Tan,
+			"Tanh":            math.Tanh,
+			"Trunc":           math.Trunc,
+			"Y0":              math.Y0,
+			"Y1":              math.Y1,
+			"Yn":              math.Yn,
+		},
+		Values: map[string]interface{}{
+			"E":                      float64(math.E),
+			"Ln10":                   float64(math.Ln10),
+			"Ln2":                    float64(math.Ln2),
+			"Log10E":                 float64(math.Log10E),
+			"Log2E":                  float64(math.Log2E),
+			"MaxFloat32":             float64(math.MaxFloat32),
+			"MaxFloat64":             float64(math.MaxFloat64),
+			"MaxInt":                 int64(math.MaxInt),
+			"MaxInt16":               int64(math.MaxInt16),
+			"MaxInt32":               int64(math.MaxInt32),
+			"MaxInt64":               int64(math.MaxInt64),
+			"MaxInt8":                int64(math.MaxInt8),
+			"MaxUint":                uint64(math.MaxUint),
+			"MaxUint16":              int64(math.MaxUint16),
+			"MaxUint32":              int64(math.MaxUint32),
+			"MaxUint64":              uint64(math.MaxUint64),
+			"MaxUint8":               int64(math.MaxUint8),
+			"MinInt":                 int64(math.MinInt),
+			"MinInt16":               int64(math.MinInt16),
+			"MinInt32":               int64(math.MinInt32),
+			"MinInt64":               int64(math.MinInt64),
+			"MinInt8":                int64(math.MinInt8),
+			"Phi":                    float64(math.Phi),
+			"Pi":                     float64(math.Pi),
+			"SmallestNonzeroFloat32": float64(math.SmallestNonzeroFloat32),
+			"SmallestNonzeroFloat64": float64(math.SmallestNonzeroFloat64),
+			"Sqrt2":                  float64(math.Sqrt2),
+			"SqrtE":                  float64(math.SqrtE),
+			"SqrtPhi":                float64(math.SqrtPhi),
+			"SqrtPi":                 float64(math.SqrtPi),
+		},
+	})
+}
@@ -0,0 +1,46 @@
+package stdlib
+
+import (
+	"blue/interp"
+	"errors"
+	"testing"
+)
+
+func TestModules(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"import strings; strings.ToUpper(\"blue\")", "BLUE"},
+		{"import strings; strings.Split(\"a,b,c\", \",\")", "[\"a\", \"b\", \"c\"]"},
+		{"import strings; len(strings.Fields(\" a  b \"))", "2"},
+		{"import strings; val r = strings.NewReplacer(\"a\", \"o\"); r.Replace(\"banana\")", "bonono"},
+		{"import strings; val b = strings.Builder(); b.WriteString(\"x\"); b.WriteString(\"y\"); b.String()", "xy"},
+		{"import path/filepath; filepath.Join(\"a\", \"b\", \"../c.b\")", "a/c.b"},
+		{"import path/filepath; filepath.Ext(\"main.b\")", ".b"},
+		{"import path/filepath; filepath.Match(\"[\", \"a\")", "syntax error in pattern"},
+		{"import math; math.Sqrt(16.0)", "4"},
+		{"import math; math.Floor(math.Pi)", "3"},
+		{"import math; math.MaxInt64", "9223372036854775807"},
+		{"import math; math.Log10(1000.0)", "3"},
+		{"import math\nmath.Sqrt(4.0)", "2"},
+	}
+	for _, tt := range tests {
+		in := interp.New(interp.Options{})
+		got, err := in.Eval(tt.input)
+		if err != nil {
+			var blueErr *interp.Error
+			if !errors.As(err, &blueErr) || blueErr.Message != tt.expected {
+				t.Errorf("%s: wrong error. want=%s, got=%s", tt.input, tt.expected, err)
+			}
+			continue
+		}
+		obj, err := in.ToObject(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if obj.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. want=%s, got=%s", tt.input, tt.expected, obj.Inspect())
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Code generated by blue bindgen; DO NOT EDIT.
+
+package stdlib
+
+import (
+	"blue/interp"
+	"strings"
+)
+
+func init() {
+	interp.RegisterModule("strings", interp.Module{
+		Funcs: map[string]interface{}{
+			"Compare":        strings.Compare,
+			"Contains":       strings.Contains,
+			"ContainsAny":    strings.ContainsAny,
+			"ContainsRune":   strings.ContainsRune,
+			"Count":          strings.Count,
+			"EqualFold":      strings.EqualFold,
+			"Fields":         strings.Fields,
+			"FieldsFunc":     strings.FieldsFunc,
+			"HasPrefix":      strings.HasPrefix,
+			"HasSuffix":      strings.HasSuffix,
+			"Index":          strings.Index,
+			"IndexAny":       strings.IndexAny,
+			"IndexByte":      strings.IndexByte,
+			"IndexFunc":      strings.IndexFunc,
+			"IndexRune":      strings.IndexRune,
+			"Join":           strings.Join,
+			"LastIndex":      strings.LastIndex,
+			"LastIndexAny":   strings.LastIndexAny,
+			"LastIndexByte":  strings.LastIndexByte,
+			"LastIndexFunc":  strings.LastIndexFunc,
+			"Map":            strings.Map,
+			"NewReader":      strings.NewReader,
+			"NewReplacer":    strings.NewReplacer,
+			"Repeat":         strings.Repeat,
+			"Replace":        strings.Replace,
+			"ReplaceAll":     strings.ReplaceAll,
+			"Split":          strings.Split,
+			"SplitAfter":     strings.SplitAfter,
+			"SplitAfterN":    strings.SplitAfterN,
+			"SplitN":         strings.SplitN,
+			"Title":          strings.Title,
+			"ToLower":        strings.ToLower,
+			"ToLowerSpecial": strings.ToLowerSpecial,
+			"ToTitle":        strings.ToTitle,
+			"ToTitleSpecial": strings.ToTitleSpecial,
+			"ToUpper":        strings.ToUpper,
+			"ToUpperSpecial": strings.ToUpperSpecial,
+			"ToValidUTF8":    strings.ToValidUTF8,
+			"Trim":           strings.Trim,
+			"TrimFunc":       strings.TrimFunc,
+			"TrimLeft":       strings.TrimLeft,
+			"TrimLeftFunc":   strings.TrimLeftFunc,
+			"TrimPrefix":     strings.TrimPrefix,
+			"TrimRight":      strings.TrimRight,
+			"TrimRightFunc":  strings.TrimRightFunc,
+			"TrimSpace":      strings.TrimSpace,
+			"TrimSuffix":     strings.TrimSuffix,
+		},
+		Types: map[string]interface{}{
+			"Builder":  (*strings.Builder)(nil),
+			"Reader":   (*strings.Reader)(nil),
+			"Replacer": (*strings.Replacer)(nil),
+		},
+	})
+}
@@ -46,6 +46,8 @@ const (
 	LTEQ = "<="
 	// GTEQ is the string rep. of the greater than equal tok.
 	GTEQ = ">="
+	// ARROW is the string rep. of the return type tok. ie. fun f() -> int
+	ARROW = "->"
 	// RARROW is the string rep. of the right arrow tok.
 	RARROW = "=>"
 	// ANDEQ is the string rep. of the binary and equal tok.
@@ -113,12 +115,22 @@ const (
 	NONINCRANGE = "..<"
 	// PIPE is the string rep. of the pipe tok.
 	PIPE = "|"
+	// PIPELINE is the string rep. of the pipeline tok. ie. xs |> sum()
+	PIPELINE = "|>"
+	// OPTDOT is the string rep. of the optional chaining tok. ie. user?.name
+	OPTDOT = "?."
+	// OPTLBRACKET is the string rep. of the optional index tok. ie. m?["k"]
+	OPTLBRACKET = "?["
+	// COALESCE is the string rep. of the null coalescing tok. ie. x ?? 0
+	COALESCE = "??"
 )
 
 // Delimeter Token Literals
 const (
 	// HASH is the string rep. of a number sign tok.
 	HASH = "#"
+	// AT is the string rep. of the decorator tok. ie. @memo
+	AT = "@"
 	// DOT is the string rep. of a period tok.
 	DOT = "."
 	// COMMA is the string rep. of a comma tok.
@@ -157,6 +169,16 @@ const (
 	OCTAL = "OCTAL"
 	// BINARY is the string rep. of a binary tok.
 	BINARY = "BINARY"
+	// RATIONAL is the string rep. of a rational tok. ie. `1.5r`
+	RATIONAL = "RATIONAL"
+	// DECIMAL is the string rep. of a decimal tok. ie. `19.99d`
+	DECIMAL = "DECIMAL"
+	// IMAGINARY is the string rep. of an imaginary tok. ie. `3i`
+	IMAGINARY = "IMAGINARY"
+	// DURATION is the string rep. of a duration tok. ie. `200ms` or `5s`
+	DURATION = "DURATION"
+	// SYMBOL is the string rep. of a symbol tok. ie. `:name`
+	SYMBOL = "SYMBOL"
 	// STRING is the string rep. of a string literal tok.
 	STRING = "STRING"
 	// RAW_STRING is the string rep. of the raw string token
@@ -187,7 +209,7 @@ const (
 	FOR = "FOR"
 	// IN is the string rep. of the `in` tok.
 	IN = "IN"
-	// NOTIN is the string rep. of the `notin` tok.
+	// NOTIN is the string rep. of the `not in` tok.
 	NOTIN = "NOTIN"
 	// AND is the string rep. of the `and` tok.
 	AND = "AND"
@@ -203,27 +225,66 @@ const (
 	NULL_KW = "NULL_KW"
 	// IMPORT is the string rep. of the import tok
 	IMPORT = "IMPORT"
+	// ENUM is the string rep. of the enum tok
+	ENUM = "ENUM"
+	// STRUCT is the string rep. of the struct tok
+	STRUCT = "STRUCT"
+	// YIELD is the string rep. of the yield tok
+	YIELD = "YIELD"
+	// TRAIT is the string rep. of the trait tok
+	TRAIT = "TRAIT"
+	// IMPL is the string rep. of the impl tok
+	IMPL = "IMPL"
+	// MACRO is the string rep. of the macro tok
+	MACRO = "MACRO"
+	// QUOTE is the string rep. of the quote tok
+	QUOTE = "QUOTE"
+	// SPAWN is the string rep. of the spawn tok
+	SPAWN = "SPAWN"
+	// AWAIT is the string rep. of the await tok
+	AWAIT = "AWAIT"
+	// SELECT is the string rep. of the select tok
+	SELECT = "SELECT"
+	// RECEIVE is the string rep. of the receive tok
+	RECEIVE = "RECEIVE"
+	// SCOPE is the string rep. of the scope tok
+	SCOPE = "SCOPE"
+	// WITH is the string rep. of the with tok
+	WITH = "WITH"
 )
 
 // keywords map for the string to token type literal
 var keywords = map[string]Type{
-	"fun":    FUNCTION,
-	"var":    VAR,
-	"val":    VAL,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
-	"for":    FOR,
-	"in":     IN,
-	"and":    AND,
-	"or":     OR,
-	"not":    NOT,
-	"const":  CONST,
-	"match":  MATCH,
-	"null":   NULL_KW,
-	"import": IMPORT,
+	"fun":     FUNCTION,
+	"var":     VAR,
+	"val":     VAL,
+	"true":    TRUE,
+	"false":   FALSE,
+	"if":      IF,
+	"else":    ELSE,
+	"return":  RETURN,
+	"for":     FOR,
+	"in":      IN,
+	"and":     AND,
+	"or":      OR,
+	"not":     NOT,
+	"const":   CONST,
+	"match":   MATCH,
+	"null":    NULL_KW,
+	"import":  IMPORT,
+	"enum":    ENUM,
+	"struct":  STRUCT,
+	"yield":   YIELD,
+	"trait":   TRAIT,
+	"impl":    IMPL,
+	"macro":   MACRO,
+	"quote":   QUOTE,
+	"spawn":   SPAWN,
+	"await":   AWAIT,
+	"select":  SELECT,
+	"receive": RECEIVE,
+	"scope":   SCOPE,
+	"with":    WITH,
 }
 
 // LookupIdent will check if the identifer passed in matches one of the
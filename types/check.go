@@ -0,0 +1,1133 @@
+package types
+
+import (
+	"blue/ast"
+	"blue/token"
+	"sort"
+	"strings"
+)
+
+// Check infers the types of a program without running it and returns the type errors
+// it finds. Locals take the type of their initializer and calls are checked against
+// the signature of the function being called. Checking is gradual: anything that is
+// not annotated and cannot be inferred is unknown and never an error, so only code
+// that would certainly fail is reported
+func Check(program *ast.Program) []*Error {
+	c := &checker{scope: newScope(nil), macros: map[string]bool{}, traits: map[string]*ast.TraitStatement{}}
+	for _, stmt := range program.Statements {
+		if ms, ok := stmt.(*ast.MacroStatement); ok {
+			c.macros[ms.Name.Value] = true
+		}
+	}
+	c.statements(program.Statements)
+	sort.SliceStable(c.errors, func(i, j int) bool { return c.errors[i].Span.Start < c.errors[j].Span.Start })
+	return c.errors
+}
+
+// binding is the type of a name in scope
+type binding struct {
+	typ      Type
+	declared bool // declared is true when the type comes from an annotation
+}
+
+// scope maps names to their types, blocks that may not run get their own scope
+type scope struct {
+	names map[string]*binding
+	outer *scope
+}
+
+// newScope returns an empty scope inside of outer
+func newScope(outer *scope) *scope {
+	return &scope{names: map[string]*binding{}, outer: outer}
+}
+
+// lookup returns the binding of the name in this scope or an outer one
+func (s *scope) lookup(name string) (*binding, bool) {
+	for ; s != nil; s = s.outer {
+		if b, ok := s.names[name]; ok {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// checker holds the state of a single Check
+type checker struct {
+	scope  *scope
+	fn     *Func // fn is the function whose body is being checked, nil at the top level
+	macros map[string]bool
+	traits map[string]*ast.TraitStatement
+	errors []*Error
+}
+
+// errorf records a type error at the span
+func (c *checker) errorf(span token.Span, format string, a ...interface{}) {
+	c.errors = append(c.errors, newError(span, format, a...))
+}
+
+// bind binds the name in the current scope
+func (c *checker) bind(name string, typ Type, declared bool) {
+	c.scope.names[name] = &binding{typ: typ, declared: declared}
+}
+
+// inScope checks the statements in a new scope and returns the type of the last one
+func (c *checker) inScope(block *ast.BlockStatement, bind func()) Type {
+	if block == nil {
+		return None
+	}
+	outer := c.scope
+	c.scope = newScope(outer)
+	defer func() { c.scope = outer }()
+	if bind != nil {
+		bind()
+	}
+	return c.statements(block.Statements)
+}
+
+// statements checks each statement and returns the type of the last one, only an
+// expression statement has a value
+func (c *checker) statements(stmts []ast.Statement) Type {
+	var last Type = None
+	for _, stmt := range stmts {
+		last = c.statement(stmt)
+	}
+	return last
+}
+
+// statement checks the statement and returns its type
+func (c *checker) statement(stmt ast.Statement) Type {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		return c.expression(stmt.Expression)
+	case *ast.ValStatement:
+		c.binding(stmt.Name, stmt.Type, stmt.Value)
+	case *ast.VarStatement:
+		c.binding(stmt.Name, stmt.Type, stmt.Value)
+	case *ast.ReturnStatement:
+		got := c.expression(stmt.ReturnValue)
+		if c.fn != nil && !assignable(c.fn.Return, got) {
+			c.errorf(spanOf(stmt.ReturnValue), "return value of %s must be %s, got %s", c.fn.Name, c.fn.Return, got)
+		}
+		return Unknown
+	case *ast.FunctionStatement:
+		c.functionStatement(stmt)
+	case *ast.StructStatement:
+		c.structStatement(stmt)
+	case *ast.TraitStatement:
+		c.traits[stmt.Name.Value] = stmt
+		c.bind(stmt.Name.Value, Unknown, false)
+		for _, m := range stmt.Methods {
+			if m.Body != nil {
+				c.method(nil, m)
+			}
+		}
+	case *ast.ImplStatement:
+		c.implStatement(stmt)
+	case *ast.EnumStatement:
+		c.bind(stmt.Name.Value, Unknown, false)
+	case *ast.ImportStatement:
+		c.bind(stmt.Name(), Unknown, false)
+	}
+	return None
+}
+
+// binding checks the initializer of a val or var against its annotation and binds the
+// name to the annotated type or the type of the initializer
+func (c *checker) binding(name *ast.Identifier, annotation *ast.TypeAnnotation, value ast.Expression) {
+	got := c.expression(value)
+	if annotation == nil {
+		c.bind(name.Value, got, false)
+		return
+	}
+	want := c.resolve(annotation)
+	if !assignable(want, got) {
+		c.errorf(spanOf(value), "%q must be %s, got %s", name.Value, want, got)
+	}
+	c.bind(name.Value, want, true)
+}
+
+// resolve returns the type of an annotation, names that are not builtin types or
+// structs in scope are unknown
+func (c *checker) resolve(ta *ast.TypeAnnotation) Type {
+	if ta == nil {
+		return Unknown
+	}
+	if len(ta.Union) > 0 {
+		u := &Union{}
+		for _, alt := range ta.Union {
+			u.Types = append(u.Types, c.resolve(alt))
+		}
+		return u
+	}
+	param := func(i int) Type {
+		if i < len(ta.Params) {
+			return c.resolve(ta.Params[i])
+		}
+		return Unknown
+	}
+	switch ta.Name {
+	case "list":
+		return &List{Elem: param(0)}
+	case "set":
+		return &Set{Elem: param(0)}
+	case "map":
+		return &Map{Key: param(0), Value: param(1)}
+	case "fun":
+		return &Func{Unchecked: true, Return: Unknown}
+	}
+	if t, ok := basicTypes[ta.Name]; ok {
+		return t
+	}
+	if b, ok := c.scope.lookup(ta.Name); ok {
+		if st, ok := b.typ.(*Struct); ok {
+			return &Instance{Struct: st}
+		}
+	}
+	return Unknown
+}
+
+// signature returns the type of a function from its declaration
+func (c *checker) signature(name string, params []*ast.Identifier, defaults []ast.Expression, types []*ast.TypeAnnotation, ret *ast.TypeAnnotation, body *ast.BlockStatement) *Func {
+	fn := &Func{Name: name, Return: c.resolve(ret)}
+	for i, p := range params {
+		param := Param{Name: p.Value, Type: Unknown}
+		if i < len(defaults) && defaults[i] != nil {
+			param.Optional = true
+		}
+		if i < len(types) {
+			param.Type = c.resolve(types[i])
+		}
+		fn.Params = append(fn.Params, param)
+	}
+	if ret == nil && body != nil && body.Yields {
+		fn.Return = Generator
+	}
+	return fn
+}
+
+// function checks the body of the function with its parameters in scope, the value
+// of the last expression in the body is also returned
+func (c *checker) function(fn *Func, params []*ast.Identifier, defaults []ast.Expression, body *ast.BlockStatement) {
+	outer := c.fn
+	c.fn = fn
+	defer func() { c.fn = outer }()
+	if body.Yields {
+		// a return in a generator only stops it so its value is not checked
+		c.fn = &Func{Name: fn.Name, Return: Unknown}
+	}
+	c.forgetAssigned(body)
+	last := c.inScope(body, func() {
+		for i, p := range params {
+			if i < len(defaults) && defaults[i] != nil {
+				got := c.expression(defaults[i])
+				if !assignable(fn.Params[i].Type, got) {
+					c.errorf(spanOf(defaults[i]), "default of parameter %q must be %s, got %s", p.Value, fn.Params[i].Type, got)
+				}
+			}
+			c.bind(p.Value, fn.Params[i].Type, !isUnknown(fn.Params[i].Type))
+		}
+	})
+	if body.Yields || len(body.Statements) == 0 {
+		return
+	}
+	if es, ok := body.Statements[len(body.Statements)-1].(*ast.ExpressionStatement); ok && !assignable(c.fn.Return, last) {
+		c.errorf(spanOf(es.Expression), "return value of %s must be %s, got %s", fn.Name, c.fn.Return, last)
+	}
+}
+
+// functionStatement binds the function before checking its body so it can call itself
+// a decorated function is bound to whatever its decorators return so it is unknown
+func (c *checker) functionStatement(node *ast.FunctionStatement) {
+	for _, d := range node.Decorators {
+		c.expression(d)
+	}
+	fn := c.signature(node.Name.Value, node.Parameters, node.ParameterExpressions, node.ParameterTypes, node.ReturnType, node.Body)
+	if len(node.Decorators) > 0 {
+		c.bind(node.Name.Value, Unknown, false)
+	} else {
+		c.bind(node.Name.Value, fn, false)
+	}
+	c.function(fn, node.Parameters, node.ParameterExpressions, node.Body)
+}
+
+// structStatement binds the struct and then checks its methods so they can call each other
+func (c *checker) structStatement(node *ast.StructStatement) {
+	st := &Struct{Name: node.Name.Value, Methods: map[string]*Func{}}
+	for i, f := range node.Fields {
+		st.Fields = append(st.Fields, f.Value)
+		st.Optional = append(st.Optional, i < len(node.Defaults) && node.Defaults[i] != nil)
+	}
+	c.bind(st.Name, st, false)
+	c.addMethods(st, node.Methods)
+	for _, def := range node.Defaults {
+		if def != nil {
+			c.expression(def)
+		}
+	}
+	for _, m := range node.Methods {
+		c.method(st, m)
+	}
+}
+
+// implStatement adds the methods and the default methods of the trait to the struct
+func (c *checker) implStatement(node *ast.ImplStatement) {
+	var st *Struct
+	if b, ok := c.scope.lookup(node.Target.Value); ok {
+		st, _ = b.typ.(*Struct)
+	}
+	if st != nil {
+		c.addMethods(st, node.Methods)
+		if node.Trait != nil {
+			if trait, ok := c.traits[node.Trait.Value]; ok {
+				for _, m := range trait.Methods {
+					if _, ok := st.Methods[m.Name.Value]; !ok && m.Body != nil {
+						st.Methods[m.Name.Value] = c.signature(m.Name.Value, m.Parameters, m.ParameterExpressions, m.ParameterTypes, m.ReturnType, m.Body)
+					}
+				}
+			} else {
+				// the trait's default methods are not known so any member may exist
+				st.Dynamic = true
+			}
+		}
+	}
+	for _, m := range node.Methods {
+		c.method(st, m)
+	}
+}
+
+// addMethods adds the signatures of the methods to the struct
+func (c *checker) addMethods(st *Struct, methods []*ast.FunctionStatement) {
+	for _, m := range methods {
+		st.Methods[m.Name.Value] = c.signature(st.Name+"."+m.Name.Value, m.Parameters, m.ParameterExpressions, m.ParameterTypes, m.ReturnType, m.Body)
+		if m.Name.Value == "index" || m.Name.Value == "set_index" {
+			st.Dynamic = true
+		}
+	}
+}
+
+// method checks the body of a method with its receiver bound to an instance of the
+// struct, st is nil for the default methods of a trait
+func (c *checker) method(st *Struct, m *ast.FunctionStatement) {
+	var fn *Func
+	if st != nil {
+		fn = st.Methods[m.Name.Value]
+	} else {
+		fn = c.signature(m.Name.Value, m.Parameters, m.ParameterExpressions, m.ParameterTypes, m.ReturnType, m.Body)
+	}
+	if st != nil && len(fn.Params) > 0 && isUnknown(fn.Params[0].Type) {
+		sig := *fn
+		sig.Params = append([]Param{{Name: fn.Params[0].Name, Type: &Instance{Struct: st}}}, fn.Params[1:]...)
+		fn = &sig
+	}
+	c.function(fn, m.Parameters, m.ParameterExpressions, m.Body)
+}
+
+// expression checks the expression and returns its type
+func (c *checker) expression(exp ast.Expression) Type {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.BigIntegerLiteral, *ast.HexLiteral, *ast.OctalLiteral, *ast.BinaryLiteral, *ast.DurationLiteral:
+		return Int
+	case *ast.FloatLiteral:
+		return Float
+	case *ast.RationalLiteral:
+		return Rational
+	case *ast.DecimalLiteral:
+		return Decimal
+	case *ast.ImaginaryLiteral:
+		return Complex
+	case *ast.SymbolLiteral:
+		return Symbol
+	case *ast.Boolean:
+		return Bool
+	case *ast.Null:
+		return None
+	case *ast.StringLiteral:
+		c.expressions(exp.InterpolationValues)
+		return Str
+	case *ast.ExecStringLiteral:
+		c.expressions(exp.InterpolationValues)
+		return Str
+	case *ast.Identifier:
+		if b, ok := c.scope.lookup(exp.Value); ok {
+			return b.typ
+		}
+		if ret, ok := builtinReturns[exp.Value]; ok {
+			return &Func{Name: exp.Value, Unchecked: true, Return: ret}
+		}
+		return Unknown
+	case *ast.PrefixExpression:
+		return c.prefix(exp)
+	case *ast.InfixExpression:
+		return c.infix(exp)
+	case *ast.IfExpression:
+		c.expression(exp.Condition)
+		cons := c.inScope(exp.Consequence, nil)
+		if exp.Alternative == nil {
+			return Unknown
+		}
+		if alt := c.inScope(exp.Alternative, nil); sameType(cons, alt) {
+			return cons
+		}
+		return Unknown
+	case *ast.MatchExpression:
+		if exp.OptionalValue != nil {
+			c.expression(exp.OptionalValue)
+		}
+		c.expressions(exp.Condition)
+		for _, block := range exp.Consequence {
+			c.inScope(block, nil)
+		}
+		return Unknown
+	case *ast.ForExpression:
+		c.forExpression(exp)
+		return Unknown
+	case *ast.FunctionLiteral:
+		fn := c.signature("function", exp.Parameters, exp.ParameterExpressions, exp.ParameterTypes, exp.ReturnType, exp.Body)
+		c.function(fn, exp.Parameters, exp.ParameterExpressions, exp.Body)
+		return fn
+	case *ast.CallExpression:
+		return c.call(exp)
+	case *ast.ListLiteral:
+		return &List{Elem: commonType(c.expressions(exp.Elements))}
+	case *ast.ListCompLiteral:
+		return &List{Elem: Unknown}
+	case *ast.SetLiteral:
+		return &Set{Elem: commonType(c.expressions(exp.Elements))}
+	case *ast.MapLiteral:
+		return c.mapLiteral(exp)
+	case *ast.IndexExpression:
+		return c.index(exp, false)
+	case *ast.SliceExpression:
+		left := c.expression(exp.Left)
+		for _, e := range []ast.Expression{exp.Start, exp.End, exp.Step} {
+			if e != nil {
+				c.expression(e)
+			}
+		}
+		switch left.(type) {
+		case *List:
+			return left
+		}
+		if left == Str {
+			return Str
+		}
+		return Unknown
+	case *ast.YieldExpression:
+		if exp.Value != nil {
+			c.expression(exp.Value)
+		}
+		return Unknown
+	case *ast.QuoteExpression:
+		return Quote
+	case *ast.BlockExpression:
+		c.inScope(exp.Block, nil)
+		return Unknown
+	case *ast.AssignmentExpression:
+		c.assignment(exp)
+		return None
+	case *ast.SpawnExpression:
+		c.expression(exp.Value)
+		return Task
+	case *ast.AwaitExpression:
+		c.expression(exp.Value)
+		return Unknown
+	case *ast.SelectExpression:
+		for i, sc := range exp.Cases {
+			bind := ""
+			if assign, ok := sc.(*ast.AssignmentExpression); ok {
+				if ident, ok := assign.Left.(*ast.Identifier); ok {
+					bind, sc = ident.Value, assign.Value
+				}
+			}
+			if ident, ok := sc.(*ast.Identifier); !ok || ident.Value != "_" {
+				c.expression(sc)
+			}
+			c.inScope(exp.Consequence[i], func() {
+				if bind != "" {
+					c.bind(bind, Unknown, false)
+				}
+			})
+		}
+		return Unknown
+	case *ast.ScopeExpression:
+		return c.inScope(exp.Body, nil)
+	case *ast.WithExpression:
+		c.expression(exp.Lock)
+		return c.inScope(exp.Body, nil)
+	case *ast.ReceiveExpression:
+		for i, pattern := range exp.Patterns {
+			names := c.pattern(pattern)
+			c.inScope(exp.Consequence[i], func() {
+				for _, name := range names {
+					c.bind(name, Unknown, false)
+				}
+			})
+		}
+		return Unknown
+	}
+	return Unknown
+}
+
+// pattern checks a pattern of a receive and returns the names it binds
+func (c *checker) pattern(exp ast.Expression) []string {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		if exp.Value == "_" {
+			return nil
+		}
+		return []string{exp.Value}
+	case *ast.ListLiteral:
+		names := []string{}
+		for _, elem := range exp.Elements {
+			names = append(names, c.pattern(elem)...)
+		}
+		return names
+	case *ast.CallExpression:
+		if ident, ok := exp.Function.(*ast.Identifier); ok && ident.Value == "timeout" {
+			c.expression(exp)
+			return nil
+		}
+		c.expression(exp.Function)
+		names := []string{}
+		for _, arg := range exp.Arguments {
+			names = append(names, c.pattern(arg)...)
+		}
+		return names
+	}
+	c.expression(exp)
+	return nil
+}
+
+// expressions checks each expression and returns their types
+func (c *checker) expressions(exps []ast.Expression) []Type {
+	types := make([]Type, len(exps))
+	for i, exp := range exps {
+		types[i] = c.expression(exp)
+	}
+	return types
+}
+
+// builtinReturns are the return types of the builtin functions, their arguments are not checked
+var builtinReturns = map[string]Type{
+	"len":         Int,
+	"append":      &List{Elem: Unknown},
+	"type":        Str,
+	"decimal":     Decimal,
+	"int":         Int,
+	"float":       Float,
+	"complex":     Complex,
+	"real":        Float,
+	"imag":        Float,
+	"conj":        Complex,
+	"symbol":      Symbol,
+	"str":         Str,
+	"round":       Unknown,
+	"print":       None,
+	"println":     None,
+	"list":        &List{Elem: Unknown},
+	"next":        Unknown,
+	"take":        Generator,
+	"skip":        Generator,
+	"zip":         Generator,
+	"enumerate":   Generator,
+	"chunk":       Generator,
+	"window":      Generator,
+	"chan":        Channel,
+	"send":        None,
+	"recv":        Unknown,
+	"close":       None,
+	"sleep":       None,
+	"spawn_actor": Pid,
+	"self_pid":    Pid,
+	"supervisor":  Pid,
+	"whereis":     Unknown,
+	"alive":       Bool,
+	"children":    &List{Elem: Pid},
+	"link":        None,
+	"monitor":     None,
+	"trap_exits":  None,
+	"kill":        None,
+	"register":    None,
+
+	"mutex":            Mutex,
+	"rwmutex":          RWMutex,
+	"read_lock":        Unknown,
+	"lock":             None,
+	"unlock":           None,
+	"atomic_int":       AtomicInt,
+	"atomic_ref":       AtomicRef,
+	"load":             Unknown,
+	"store":            None,
+	"swap":             Unknown,
+	"cas":              Bool,
+	"add":              Int,
+	"update":           Unknown,
+	"concurrent_map":   ConcurrentMap,
+	"delete":           Bool,
+	"concurrent_queue": ConcurrentQueue,
+	"push":             None,
+	"pop":              Unknown,
+
+	"duration":     Int,
+	"after":        Timer,
+	"every":        Timer,
+	"cancel":       Bool,
+	"with_timeout": Unknown,
+
+	"implements": Bool,
+}
+
+// checkedOperand returns true for the types whose operators are all known, the operators
+// of other types may be overloaded or depend on their values
+func checkedOperand(t Type) bool {
+	if _, ok := t.(*List); ok {
+		return true
+	}
+	return isNumber(t) || t == Str || t == Bool || t == None
+}
+
+// prefix checks the operand of a prefix operator
+func (c *checker) prefix(exp *ast.PrefixExpression) Type {
+	right := c.expression(exp.Right)
+	switch exp.Operator {
+	case "not":
+		return Bool
+	case "-":
+		if isNumber(right) {
+			return right
+		}
+		if checkedOperand(right) {
+			c.errorf(spanOf(exp), "unknown operator: -%s", right)
+		}
+	}
+	return Unknown
+}
+
+// infix checks the operands of an infix operator and returns the type of the result
+func (c *checker) infix(exp *ast.InfixExpression) Type {
+	var left Type
+	if exp.Operator == "??" {
+		left = c.nullable(exp.Left)
+	} else {
+		left = c.expression(exp.Left)
+	}
+	right := c.expression(exp.Right)
+	return c.operator(exp.Operator, left, right, operatorSpan(exp))
+}
+
+// operator returns the type of applying the operator to the operands, an error is
+// reported at span when the operator cannot apply to them
+func (c *checker) operator(op string, left, right Type, span token.Span) Type {
+	switch op {
+	case "==", "!=", "in", "not in":
+		return Bool
+	case "and", "or":
+		if left == Bool && right == Bool {
+			return Bool
+		}
+		return Unknown
+	case "??":
+		if left == None {
+			return right
+		}
+		return Unknown
+	}
+	if !checkedOperand(left) || !checkedOperand(right) {
+		switch op {
+		case "<", ">", "<=", ">=":
+			return Bool
+		}
+		return Unknown
+	}
+
+	switch {
+	case isNumber(left) && isNumber(right):
+		switch op {
+		case "<", ">", "<=", ">=":
+			return Bool
+		}
+		// decimals are exact so they are not mixed with floats or complex numbers
+		if (left == Decimal && (right == Float || right == Complex)) || (right == Decimal && (left == Float || left == Complex)) {
+			c.errorf(span, "cannot mix %s and %s in arithmetic, convert with decimal() or float()", left, right)
+			return Unknown
+		}
+		switch op {
+		case "..", "..<":
+			if left == Int && right == Int {
+				return &List{Elem: Int}
+			}
+			return &List{Elem: Unknown}
+		}
+		switch {
+		case left == Decimal || right == Decimal:
+			return Decimal
+		case left == Complex || right == Complex:
+			return Complex
+		case left == Int && right == Int && op != "/" && op != "**":
+			return Int
+		case (left == Float || right == Float) && (left == Int || right == Int || left == right):
+			return Float
+		}
+		return Num
+	case left == Str && right == Str:
+		switch op {
+		case "+":
+			return Str
+		case "<", ">", "<=", ">=":
+			return Bool
+		}
+	case left == Str && right == Int && op == "*":
+		return Str
+	case op == "+":
+		if l, ok := left.(*List); ok {
+			if r, ok := right.(*List); ok {
+				if sameType(l.Elem, r.Elem) {
+					return l
+				}
+				return &List{Elem: Unknown}
+			}
+		}
+	}
+	if !sameType(left, right) {
+		c.errorf(span, "type mismatch: %s %s %s", left, op, right)
+	} else {
+		c.errorf(span, "unknown operator: %s %s %s", left, op, right)
+	}
+	return Unknown
+}
+
+// forExpression checks the loop, the variable of a for-in loop is bound to the
+// element type of the iterable
+func (c *checker) forExpression(exp *ast.ForExpression) {
+	if infix, ok := exp.Condition.(*ast.InfixExpression); ok && infix.Operator == "in" {
+		if ident, ok := infix.Left.(*ast.Identifier); ok {
+			iterable := c.expression(infix.Right)
+			var elem Type = Unknown
+			switch t := iterable.(type) {
+			case *List:
+				elem = t.Elem
+			case *Set:
+				elem = t.Elem
+			}
+			if iterable == Str {
+				elem = Str
+			}
+			c.forgetAssigned(exp.Consequence)
+			c.inScope(exp.Consequence, func() { c.bind(ident.Value, elem, false) })
+			return
+		}
+	}
+	c.forgetAssigned(exp.Consequence)
+	c.expression(exp.Condition)
+	c.inScope(exp.Consequence, nil)
+}
+
+// forgetAssigned makes the un-annotated vars that are reassigned in a body that can
+// run more than once unknown, as a value from a previous run may already be assigned
+// ie. `last` is not always null in `var last = null; for (x in xs) { f(last); last = x }`
+func (c *checker) forgetAssigned(body *ast.BlockStatement) {
+	ast.Modify(body, func(n ast.Node) ast.Node {
+		if a, ok := n.(*ast.AssignmentExpression); ok {
+			if ident, ok := a.Left.(*ast.Identifier); ok {
+				if b, ok := c.scope.lookup(ident.Value); ok && !b.declared {
+					b.typ = Unknown
+				}
+			}
+		}
+		return n
+	})
+}
+
+// mapLiteral returns the type of the map, a map whose keys are all names or plain
+// strings is a record that knows the type of each field
+func (c *checker) mapLiteral(exp *ast.MapLiteral) Type {
+	fields := map[string]Type{}
+	keys, values := []Type{}, []Type{}
+	record := len(exp.Pairs) > 0
+	for k, v := range exp.Pairs {
+		val := c.expression(v)
+		values = append(values, val)
+		if name, ok := fieldName(k); ok {
+			fields[name] = val
+			keys = append(keys, Str)
+			continue
+		}
+		record = false
+		keys = append(keys, c.expression(k))
+	}
+	if record {
+		return &Map{Key: Str, Value: commonType(values), Fields: fields}
+	}
+	return &Map{Key: commonType(keys), Value: commonType(values)}
+}
+
+// fieldName returns the name of a constant map key ie. `name` in `{"name": 1}`
+func fieldName(k ast.Expression) (string, bool) {
+	if k, ok := k.(*ast.StringLiteral); ok && len(k.InterpolationValues) == 0 && !strings.Contains(k.Value, "#{") {
+		return k.Value, true
+	}
+	return "", false
+}
+
+// indexName returns the name of a constant index ie. `name` in `user.name` or `user["name"]`
+func indexName(exp *ast.IndexExpression) (string, bool) {
+	if _, ok := exp.Index.(*ast.StringLiteral); !ok {
+		return "", false
+	}
+	return fieldName(exp.Index)
+}
+
+// memberName returns the name of a member access ie. `name` in `user.name`
+func memberName(exp *ast.IndexExpression) (*ast.StringLiteral, bool) {
+	lit, ok := exp.Index.(*ast.StringLiteral)
+	return lit, ok && lit.Token.Type == token.IDENT
+}
+
+// nullable checks an expression whose value may be null ie. the left side of `?.` or `??`
+// so looking up a field that is missing from a record is not an error
+func (c *checker) nullable(exp ast.Expression) Type {
+	if ie, ok := exp.(*ast.IndexExpression); ok {
+		return c.index(ie, true)
+	}
+	return c.expression(exp)
+}
+
+// index returns the type of an index or member access, a member that does not exist
+// on a record or a struct instance is an error unless missing fields are allowed
+func (c *checker) index(exp *ast.IndexExpression, allowMissing bool) Type {
+	var left Type
+	if exp.Optional {
+		left = c.nullable(exp.Left)
+	} else {
+		left = c.expression(exp.Left)
+	}
+	if member, ok := memberName(exp); ok {
+		typ := c.member(left, member.Value, member.Token.Span, allowMissing)
+		if exp.Optional {
+			return Unknown
+		}
+		return typ
+	}
+	index := c.expression(exp.Index)
+	switch left := left.(type) {
+	case *List:
+		if index == Int {
+			return left.Elem
+		}
+	case *Map:
+		if left.Fields != nil {
+			if name, ok := indexName(exp); ok {
+				if t, ok := left.Fields[name]; ok {
+					return t
+				}
+				return None
+			}
+		}
+		return left.Value
+	}
+	if left == Str && index == Int {
+		return Str
+	}
+	return Unknown
+}
+
+// member returns the type of the named member of the value
+func (c *checker) member(left Type, name string, span token.Span, allowMissing bool) Type {
+	switch left := left.(type) {
+	case *Map:
+		if left.Fields == nil {
+			return left.Value
+		}
+		if t, ok := left.Fields[name]; ok {
+			return t
+		}
+		if allowMissing {
+			return None
+		}
+		c.errorf(span, "%s has no field %q", left, name)
+	case *Instance:
+		st := left.Struct
+		if st.fieldIndex(name) != -1 {
+			return Unknown
+		}
+		if m, ok := st.Methods[name]; ok {
+			return boundMethod(m)
+		}
+		if !st.Dynamic {
+			c.errorf(span, "%s has no field %q", st.Name, name)
+		}
+	case *Struct:
+		if m, ok := left.Methods[name]; ok {
+			return m
+		}
+		if !left.Dynamic {
+			c.errorf(span, "struct %s has no method %q", left.Name, name)
+		}
+	}
+	return Unknown
+}
+
+// boundMethod returns the signature of the method without its receiver
+func boundMethod(m *Func) *Func {
+	bound := *m
+	if len(m.Params) > 0 {
+		bound.Params = m.Params[1:]
+	}
+	return &bound
+}
+
+// call checks the arguments of the call against what is being called and returns the
+// type of the result
+func (c *checker) call(exp *ast.CallExpression) Type {
+	if ident, ok := exp.Function.(*ast.Identifier); ok && c.macros[ident.Value] {
+		// the arguments of a macro are code, not values
+		return Unknown
+	}
+	var callee Type
+	if exp.Optional {
+		callee = c.nullable(exp.Function)
+	} else {
+		callee = c.expression(exp.Function)
+	}
+	args := c.expressions(exp.Arguments)
+	names := make([]string, 0, len(exp.DefaultArguments))
+	named := make(map[string]Type, len(exp.DefaultArguments))
+	for name, arg := range exp.DefaultArguments {
+		names = append(names, name)
+		named[name] = c.expression(arg)
+	}
+	sort.Strings(names)
+	span := spanOf(exp.Function)
+
+	if !c.isBuiltin(exp.Function) {
+		// the callee may add fields to a record that is passed to it
+		for _, arg := range append(args, typesOf(named)...) {
+			if m, ok := arg.(*Map); ok {
+				m.open()
+			}
+		}
+	}
+
+	switch callee := callee.(type) {
+	case *Func:
+		if callee.Unchecked {
+			return callee.Return
+		}
+		c.arguments(callee, args, names, named, exp, span)
+		return callee.Return
+	case *Struct:
+		c.constructor(callee, args, names, span)
+		return &Instance{Struct: callee}
+	case *List, *Map, *Set, *Instance:
+		c.errorf(span, "cannot call non-function %s", callee)
+	case *Basic:
+		if callee == None && exp.Optional {
+			return None
+		}
+		if !isUnknown(callee) {
+			c.errorf(span, "cannot call non-function %s", callee)
+		}
+	}
+	return Unknown
+}
+
+// isBuiltin returns true if the expression names a builtin function that is not shadowed
+func (c *checker) isBuiltin(exp ast.Expression) bool {
+	ident, ok := exp.(*ast.Identifier)
+	if !ok {
+		return false
+	}
+	if _, ok := c.scope.lookup(ident.Value); ok {
+		return false
+	}
+	_, ok = builtinReturns[ident.Value]
+	return ok
+}
+
+// typesOf returns the types of the named arguments
+func typesOf(named map[string]Type) []Type {
+	types := make([]Type, 0, len(named))
+	for _, t := range named {
+		types = append(types, t)
+	}
+	return types
+}
+
+// arguments checks the number, names, and types of the arguments passed to the function
+func (c *checker) arguments(fn *Func, args []Type, names []string, named map[string]Type, exp *ast.CallExpression, span token.Span) {
+	if len(args) > len(fn.Params) {
+		c.errorf(span, "wrong number of arguments to %s. want at most %d, got=%d", fn.Name, len(fn.Params), len(args))
+		return
+	}
+	passed := make([]bool, len(fn.Params))
+	for i, arg := range args {
+		passed[i] = true
+		c.argument(fn, i, arg, exp.Arguments[i])
+	}
+	for _, name := range names {
+		idx := fn.paramIndex(name)
+		if idx == -1 {
+			c.errorf(span, "unexpected named argument %q to %s", name, fn.Name)
+			continue
+		}
+		if passed[idx] {
+			c.errorf(span, "argument %q to %s was already passed positionally", name, fn.Name)
+			continue
+		}
+		passed[idx] = true
+		c.argument(fn, idx, named[name], exp.DefaultArguments[name])
+	}
+	for i, p := range fn.Params {
+		if !passed[i] && !p.Optional {
+			c.errorf(span, "missing argument for parameter %q of %s", p.Name, fn.Name)
+		}
+	}
+}
+
+// argument checks the type of the argument passed to the i-th parameter
+func (c *checker) argument(fn *Func, i int, got Type, arg ast.Expression) {
+	p := fn.Params[i]
+	if !assignable(p.Type, got) {
+		c.errorf(spanOf(arg), "argument %q of %s must be %s, got %s", p.Name, fn.Name, p.Type, got)
+	}
+}
+
+// constructor checks the fields passed to the struct
+func (c *checker) constructor(st *Struct, args []Type, names []string, span token.Span) {
+	if len(args) > len(st.Fields) {
+		c.errorf(span, "wrong number of arguments to %s. want at most %d, got=%d", st.Name, len(st.Fields), len(args))
+		return
+	}
+	passed := make([]bool, len(st.Fields))
+	for i := range args {
+		passed[i] = true
+	}
+	for _, name := range names {
+		idx := st.fieldIndex(name)
+		if idx == -1 {
+			c.errorf(span, "%s has no field %q", st.Name, name)
+			continue
+		}
+		passed[idx] = true
+	}
+	for i, f := range st.Fields {
+		if !passed[i] && !st.Optional[i] {
+			c.errorf(span, "missing field %q for %s", f, st.Name)
+		}
+	}
+}
+
+// assignment checks the assigned value, reassigning a var with a different type makes
+// it unknown unless it is annotated in which case the value must match the annotation
+func (c *checker) assignment(exp *ast.AssignmentExpression) {
+	val := c.expression(exp.Value)
+	op := strings.TrimSuffix(exp.Token.Literal, "=")
+
+	switch left := exp.Left.(type) {
+	case *ast.Identifier:
+		b, ok := c.scope.lookup(left.Value)
+		if !ok {
+			return
+		}
+		if op != "" {
+			val = c.operator(op, b.typ, val, exp.Token.Span)
+		}
+		if b.declared {
+			if !assignable(b.typ, val) {
+				c.errorf(left.Token.Span, "%q must be %s, got %s", left.Value, b.typ, val)
+			}
+			return
+		}
+		if !sameType(b.typ, val) {
+			b.typ = Unknown
+		}
+	case *ast.IndexExpression:
+		obj := c.expression(left.Left)
+		name, isMember := memberName(left)
+		if !isMember {
+			c.expression(left.Index)
+		}
+		switch obj := obj.(type) {
+		case *Map:
+			if obj.Fields == nil {
+				return
+			}
+			field, ok := indexName(left)
+			if !ok {
+				obj.open()
+				return
+			}
+			if op != "" {
+				val = c.operator(op, obj.Fields[field], val, exp.Token.Span)
+			}
+			if cur, ok := obj.Fields[field]; ok && !sameType(cur, val) {
+				val = Unknown
+			}
+			obj.Fields[field] = val
+			obj.Value = commonType(typesOf(obj.Fields))
+		case *Instance:
+			if isMember && obj.Struct.fieldIndex(name.Value) == -1 && !obj.Struct.Dynamic {
+				c.errorf(name.Token.Span, "%s has no field %q", obj.Struct.Name, name.Value)
+			}
+		}
+	default:
+		c.expression(exp.Left)
+	}
+}
+
+// spanOf returns the location of the expression that errors are reported at
+func spanOf(exp ast.Expression) token.Span {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		return exp.Token.Span
+	case *ast.InfixExpression:
+		return spanOf(exp.Left)
+	case *ast.CallExpression:
+		return spanOf(exp.Function)
+	case *ast.IndexExpression:
+		if member, ok := memberName(exp); ok {
+			return member.Token.Span
+		}
+		return spanOf(exp.Left)
+	case *ast.SliceExpression:
+		return spanOf(exp.Left)
+	case *ast.AssignmentExpression:
+		return spanOf(exp.Left)
+	case *ast.PrefixExpression:
+		return exp.Token.Span
+	case *ast.IntegerLiteral:
+		return exp.Token.Span
+	case *ast.FloatLiteral:
+		return exp.Token.Span
+	case *ast.StringLiteral:
+		return exp.Token.Span
+	case *ast.Boolean:
+		return exp.Token.Span
+	case *ast.Null:
+		return exp.Token.Span
+	case *ast.SymbolLiteral:
+		return exp.Token.Span
+	case *ast.ListLiteral:
+		return exp.Token.Span
+	case *ast.MapLiteral:
+		return exp.Token.Span
+	case *ast.SetLiteral:
+		return exp.Token.Span
+	case *ast.FunctionLiteral:
+		return exp.Token.Span
+	case *ast.IfExpression:
+		return exp.Token.Span
+	case *ast.MatchExpression:
+		return exp.Token.Span
+	}
+	return token.Span{}
+}
+
+// operatorSpan returns the location of the operator or the left operand if the
+// operator has no location
+func operatorSpan(exp *ast.InfixExpression) token.Span {
+	if exp.Token.Span != (token.Span{}) {
+		return exp.Token.Span
+	}
+	return spanOf(exp.Left)
+}
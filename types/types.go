@@ -0,0 +1,341 @@
+package types
+
+import (
+	"blue/token"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Type is the static type of an expression as inferred by the checker
+type Type interface {
+	String() string
+}
+
+// Basic is a type without element types ie. int or str
+type Basic struct {
+	Name string
+}
+
+// String returns the name of the type
+func (b *Basic) String() string { return b.Name }
+
+var (
+	Unknown   = &Basic{Name: "unknown"} // Unknown is the type of un-annotated code, it is compatible with every type
+	Any       = &Basic{Name: "any"}     // Any is the `any` annotation, it is compatible with every type
+	Int       = &Basic{Name: "int"}
+	Float     = &Basic{Name: "float"}
+	Num       = &Basic{Name: "num"} // Num is a number whose exact type is not known
+	Rational  = &Basic{Name: "rational"}
+	Decimal   = &Basic{Name: "decimal"}
+	Complex   = &Basic{Name: "complex"}
+	Str       = &Basic{Name: "str"}
+	Bool      = &Basic{Name: "bool"}
+	None      = &Basic{Name: "none"}
+	Symbol    = &Basic{Name: "symbol"}
+	Generator = &Basic{Name: "generator"}
+	Quote     = &Basic{Name: "quote"}
+	Task      = &Basic{Name: "task"}
+	Channel   = &Basic{Name: "channel"}
+	Pid       = &Basic{Name: "pid"}
+	Timer     = &Basic{Name: "timer"}
+
+	Mutex           = &Basic{Name: "mutex"}
+	RWMutex         = &Basic{Name: "rwmutex"}
+	AtomicInt       = &Basic{Name: "atomic_int"}
+	AtomicRef       = &Basic{Name: "atomic_ref"}
+	ConcurrentMap   = &Basic{Name: "concurrent_map"}
+	ConcurrentQueue = &Basic{Name: "concurrent_queue"}
+)
+
+// basicTypes are the builtin type names that are not generic
+var basicTypes = map[string]Type{
+	"any":       Any,
+	"int":       Int,
+	"float":     Float,
+	"num":       Num,
+	"rational":  Rational,
+	"decimal":   Decimal,
+	"complex":   Complex,
+	"str":       Str,
+	"bool":      Bool,
+	"none":      None,
+	"null":      None,
+	"symbol":    Symbol,
+	"generator": Generator,
+	"quote":     Quote,
+	"task":      Task,
+	"channel":   Channel,
+	"pid":       Pid,
+	"timer":     Timer,
+
+	"mutex":            Mutex,
+	"rwmutex":          RWMutex,
+	"atomic_int":       AtomicInt,
+	"atomic_ref":       AtomicRef,
+	"concurrent_map":   ConcurrentMap,
+	"concurrent_queue": ConcurrentQueue,
+}
+
+// List is a list whose elements are of type Elem
+type List struct {
+	Elem Type
+}
+
+// String returns the list type ie. `list[int]` or `list` when the elements are unknown
+func (l *List) String() string {
+	if isUnknown(l.Elem) {
+		return "list"
+	}
+	return "list[" + l.Elem.String() + "]"
+}
+
+// Set is a set whose elements are of type Elem
+type Set struct {
+	Elem Type
+}
+
+// String returns the set type ie. `set[str]`
+func (s *Set) String() string {
+	if isUnknown(s.Elem) {
+		return "set"
+	}
+	return "set[" + s.Elem.String() + "]"
+}
+
+// Map is a map from Key to Value, a map literal whose keys are all names is a record
+// and also knows the type of each field, Fields is nil for every other map
+type Map struct {
+	Key    Type
+	Value  Type
+	Fields map[string]Type
+}
+
+// String returns the map type ie. `map[str, int]` or the record `{name: str, age: int}`
+func (m *Map) String() string {
+	if m.Fields != nil {
+		names := make([]string, 0, len(m.Fields))
+		for name := range m.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fields := make([]string, len(names))
+		for i, name := range names {
+			fields[i] = name + ": " + m.Fields[name].String()
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+	if isUnknown(m.Key) && isUnknown(m.Value) {
+		return "map"
+	}
+	return "map[" + m.Key.String() + ", " + m.Value.String() + "]"
+}
+
+// open turns a record into a plain map once its fields can no longer be tracked
+func (m *Map) open() {
+	if m.Fields == nil {
+		return
+	}
+	m.Fields = nil
+	m.Key, m.Value = Str, Unknown
+}
+
+// Param is a parameter of a function, Optional is true when it has a default value
+type Param struct {
+	Name     string
+	Type     Type
+	Optional bool
+}
+
+// Func is the signature of a function, an Unchecked function accepts any arguments
+// ie. builtins and values annotated as `fun`
+type Func struct {
+	Name      string
+	Params    []Param
+	Return    Type
+	Unchecked bool
+}
+
+// String returns the signature ie. `fun(a: int, b) -> str`
+func (f *Func) String() string {
+	if f.Unchecked {
+		return "fun"
+	}
+	params := make([]string, len(f.Params))
+	for i, p := range f.Params {
+		if isUnknown(p.Type) {
+			params[i] = p.Name
+		} else {
+			params[i] = p.Name + ": " + p.Type.String()
+		}
+	}
+	out := "fun(" + strings.Join(params, ", ") + ")"
+	if !isUnknown(f.Return) {
+		out += " -> " + f.Return.String()
+	}
+	return out
+}
+
+// paramIndex returns the position of the named parameter or -1 if it does not exist
+func (f *Func) paramIndex(name string) int {
+	for i, p := range f.Params {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Struct is a struct declaration, calling it constructs an Instance
+type Struct struct {
+	Name     string
+	Fields   []string
+	Optional []bool           // Optional is true for each field that has a default
+	Methods  map[string]*Func // Methods include the receiver as their first parameter
+	Dynamic  bool             // Dynamic is true when the struct defines `index` or `set_index`
+}
+
+// String returns the struct type ie. `struct Point`
+func (s *Struct) String() string { return "struct " + s.Name }
+
+// fieldIndex returns the position of the field or -1 if it does not exist
+func (s *Struct) fieldIndex(name string) int {
+	for i, f := range s.Fields {
+		if f == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Instance is a value constructed from a struct
+type Instance struct {
+	Struct *Struct
+}
+
+// String returns the name of the struct
+func (i *Instance) String() string { return i.Struct.Name }
+
+// Union is a value that is one of the types
+type Union struct {
+	Types []Type
+}
+
+// String returns the union as it would be annotated ie. `int | none`
+func (u *Union) String() string {
+	alts := make([]string, len(u.Types))
+	for i, t := range u.Types {
+		alts[i] = t.String()
+	}
+	return strings.Join(alts, " | ")
+}
+
+// isUnknown returns true if nothing is known about the type
+func isUnknown(t Type) bool {
+	return t == nil || t == Unknown || t == Any
+}
+
+// isNumber returns true for the numeric types
+func isNumber(t Type) bool {
+	switch t {
+	case Int, Float, Num, Rational, Decimal, Complex:
+		return true
+	}
+	return false
+}
+
+// sameType returns true if both types are known and identical
+func sameType(a, b Type) bool {
+	if isUnknown(a) || isUnknown(b) {
+		return false
+	}
+	return a == b || a.String() == b.String()
+}
+
+// commonType returns the type shared by all of the types or Unknown if they differ
+func commonType(ts []Type) Type {
+	if len(ts) == 0 {
+		return Unknown
+	}
+	for _, t := range ts[1:] {
+		if !sameType(ts[0], t) {
+			return Unknown
+		}
+	}
+	return ts[0]
+}
+
+// assignable returns true if a value of type got can be used where want is expected
+// unknown types are always assignable so un-annotated code is never an error
+func assignable(want, got Type) bool {
+	if isUnknown(want) || isUnknown(got) {
+		return true
+	}
+	if u, ok := got.(*Union); ok {
+		for _, t := range u.Types {
+			if !assignable(want, t) {
+				return false
+			}
+		}
+		return true
+	}
+	switch want := want.(type) {
+	case *Union:
+		for _, t := range want.Types {
+			if assignable(t, got) {
+				return true
+			}
+		}
+		return false
+	case *Basic:
+		if want == Num || got == Num {
+			// the exact type of a num is not known so it may be any number
+			return isNumber(want) && isNumber(got)
+		}
+		return want == got
+	case *List:
+		got, ok := got.(*List)
+		return ok && assignable(want.Elem, got.Elem)
+	case *Set:
+		got, ok := got.(*Set)
+		return ok && assignable(want.Elem, got.Elem)
+	case *Map:
+		got, ok := got.(*Map)
+		if !ok {
+			return false
+		}
+		if got.Fields != nil {
+			for _, t := range got.Fields {
+				if !assignable(want.Value, t) {
+					return false
+				}
+			}
+			return assignable(want.Key, Str)
+		}
+		return assignable(want.Key, got.Key) && assignable(want.Value, got.Value)
+	case *Func:
+		switch got.(type) {
+		case *Func, *Struct:
+			return true
+		}
+		return false
+	case *Instance:
+		got, ok := got.(*Instance)
+		return ok && got.Struct == want.Struct
+	}
+	return false
+}
+
+// Error is a type error found by the checker, Span is where it is reported
+type Error struct {
+	Span    token.Span
+	Message string
+}
+
+// Error returns the message of the error
+func (e *Error) Error() string { return e.Message }
+
+// newError returns an error for the span
+func newError(span token.Span, format string, a ...interface{}) *Error {
+	return &Error{Span: span, Message: fmt.Sprintf(format, a...)}
+}
@@ -0,0 +1,193 @@
+package types
+
+import (
+	"blue/lexer"
+	"blue/parser"
+	"blue/token"
+	"testing"
+)
+
+// testCheck parses and checks the input and returns the messages of the type errors
+func testCheck(t *testing.T, input string) []string {
+	l := lexer.New(input, "<string>")
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser had errors for %q: %v", input, p.Errors())
+	}
+	msgs := []string{}
+	for _, err := range Check(program) {
+		msgs = append(msgs, err.Message)
+	}
+	return msgs
+}
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`"a" + 1`, []string{"type mismatch: str + int"}},
+		{`val x = 1; val y = x - "b"`, []string{"type mismatch: int - str"}},
+		{`val s = "a"; s * "b"`, []string{"unknown operator: str * str"}},
+		{`-"a"`, []string{"unknown operator: -str"}},
+		{`var n = 1; n += "a"`, []string{"type mismatch: int + str"}},
+		{`val xs = [1, 2]; xs + 1`, []string{"type mismatch: list[int] + int"}},
+		{`val n = 5; n(1)`, []string{"cannot call non-function int"}},
+		{`val m = {"a": 1}; m()`, []string{"cannot call non-function {a: int}"}},
+		{"fun f(a, b) { a }\nf(1)", []string{`missing argument for parameter "b" of f`}},
+		{"fun f(a, b=2) { a }\nf(1, 2, 3)", []string{"wrong number of arguments to f. want at most 2, got=3"}},
+		{"fun f(a) { a }\nf(1, c=2)", []string{`unexpected named argument "c" to f`}},
+		{"fun f(a, b) { a }\nf(1, a=2)", []string{`argument "a" to f was already passed positionally`, `missing argument for parameter "b" of f`}},
+		{"fun f(a: int) { a }\nf(\"x\")", []string{`argument "a" of f must be int, got str`}},
+		{"fun f(a: list[int]) { a }\nf([\"x\"])", []string{`argument "a" of f must be list[int], got list[str]`}},
+		{"fun f(a: int | none) { a }\nf(:x)", []string{`argument "a" of f must be int | none, got symbol`}},
+		{"val f = fun(a: str) { a }\nf(b=1, a=2)", []string{`unexpected named argument "b" to function`, `argument "a" of function must be str, got int`}},
+		{"fun f() -> int { \"a\" }", []string{"return value of f must be int, got str"}},
+		{"fun f(x) -> str { if (x) { return 1 }\n\"a\" }", []string{"return value of f must be str, got int"}},
+		{"fun f(x: int) -> int { x }\nval s: str = f(1)", []string{`"s" must be str, got int`}},
+		{`var x: int = 1; x = "a"`, []string{`"x" must be int, got str`}},
+		{"fun f(a: int = \"x\") { a }", []string{`default of parameter "a" must be int, got str`}},
+		{`val user = {"name": "a", "age": 1}; user.nmae`, []string{`{age: int, name: str} has no field "nmae"`}},
+		{`val user = {"name": "a"}; user.name + 1`, []string{"type mismatch: str + int"}},
+		{`val user = {"name": "a"}; user.age = 1; user.age + "a"`, []string{"type mismatch: int + str"}},
+		{"struct P { x, y }\nP(1)", []string{`missing field "y" for P`}},
+		{"struct P { x, y = 0 }\nP(1, 2, 3)", []string{"wrong number of arguments to P. want at most 2, got=3"}},
+		{"struct P { x }\nP(z=1)", []string{`P has no field "z"`, `missing field "x" for P`}},
+		{"struct P { x }\nval p = P(1); p.z", []string{`P has no field "z"`}},
+		{"struct P { x }\nval p = P(1); p.z = 2", []string{`P has no field "z"`}},
+		{"struct P { x }\nP.norm", []string{`struct P has no method "norm"`}},
+		{"struct P { x, fun add(self, n: int) { self.x + n } }\nval p = P(1); p.add(\"a\")", []string{`argument "n" of P.add must be int, got str`}},
+		{"struct P { x }\nfun f(p: P) { p }\nf(1)", []string{`argument "p" of f must be P, got int`}},
+		{"fun f(a) { a }\nspawn f(1, 2)", []string{"wrong number of arguments to f. want at most 1, got=2"}},
+		{"val ch = chan()\nselect { m = recv(ch) => { m }, timeout(10) => { 1 + \"a\" }, }", []string{"type mismatch: int + str"}},
+		{"val x = scope { spawn f(); 1 }; x + \"a\"", []string{"type mismatch: int + str"}},
+		{"receive { [:add, n] => { n }, timeout(10) => { 1 + \"a\" }, }", []string{"type mismatch: int + str"}},
+		{"val m = mutex()\nwith m { 1 + \"a\" }", []string{"type mismatch: int + str"}},
+		{"fun f(m: mutex) { m }\nf(atomic_int())", []string{`argument "m" of f must be mutex, got atomic_int`}},
+		{"fun f(t: timer) { cancel(t) }\nf(5s)", []string{`argument "t" of f must be timer, got int`}},
+		{"5s + \"a\"", []string{"type mismatch: int + str"}},
+		{"1.5d + 1.0", []string{"cannot mix decimal and float in arithmetic, convert with decimal() or float()"}},
+		{"2.0 ** 1.5d", []string{"cannot mix float and decimal in arithmetic, convert with decimal() or float()"}},
+		{"val d = 1.5d; d * 1i", []string{"cannot mix decimal and complex in arithmetic, convert with decimal() or float()"}},
+		{"for (x in [1, 2]) { x + \"a\" }", []string{"type mismatch: int + str"}},
+		{`val x = 1; if (true) { x + "a" } else { x - "b" }`, []string{"type mismatch: int + str", "type mismatch: int - str"}},
+	}
+
+	for _, tt := range tests {
+		got := testCheck(t, tt.input)
+		if len(got) != len(tt.expected) {
+			t.Errorf("wrong errors for %q. want=%q, got=%q", tt.input, tt.expected, got)
+			continue
+		}
+		for i, msg := range tt.expected {
+			if got[i] != msg {
+				t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, msg, got[i])
+			}
+		}
+	}
+}
+
+func TestCheckGradual(t *testing.T) {
+	tests := []string{
+		`fun f(a, b) { a + b }
+f(1, "a")`,
+		`val x = y + 1; x + "a"`,
+		`fun f(a) { a }
+val x = f(1); x + "a"`,
+		`var x = 1; x = "a"; x + 1`,
+		`var x = null; if (true) { x = 1 }; x + 1`,
+		`var last = null; var total = 0
+for (x in [1, 2, 3]) { if (last != null) { total += x - last }; last = x }`,
+		`var last = null
+fun f(x) { if (last != null) { x - last }; last = x }`,
+		`val m = {"a": 1}; m.b ?? 2`,
+		`val m = {"a": {"b": 1}}; m.c?.b`,
+		`val m = {}; m["a"] = 1; m.a`,
+		`val m = {"a": 1}; val k = "b"; m[k] = 2; m.b`,
+		`val m = {"a": 1}
+fun set(x) { x.b = 2 }
+set(m); m.b`,
+		`val m = {"a": 1}; len(m); m.a + 1`,
+		`1 + 2.5; 1 / 2; 2 ** 3; "a" * 3; [1] + ["a"]; 1 == "a"; 1 in [1]; "a" < "b"`,
+		`1.5d < 1.0; 1.5d == 1.5; 1.5d * 2; 1.5d + 1 / 3`,
+		`val f: fun = |x| => { x }
+f(1, 2, 3)`,
+		`fun f(a, b=2) { a }
+f(1); f(1, 2); f(a=1); f(1, b=3)`,
+		`@memo
+fun f(n) { n }
+f(1, 2)`,
+		`fun g(a: num) -> int { 1 }
+g(1 / 2); g(1.5)`,
+		`fun g() { yield 1; return "a" }
+g()`,
+		`struct P { x, fun index(self, k) { 1 } }
+val p = P(1); p.anything`,
+		`trait Show { fun show(self) { "x" } }
+struct P { x }
+impl Show for P {}
+P(1).show()`,
+		`struct P { x }
+val p = P(x=1); p.x + "a"`,
+		`macro unless(c, body) { quote { if (not unquote(c)) { unquote(body) } } }
+unless(false, "a" + 1)`,
+		`quote { "a" + 1 }`,
+		`receive { [:add, n] => { n + 1 }, Msg.Set(v) => { v + "a" }, }`,
+	}
+
+	for _, input := range tests {
+		if got := testCheck(t, input); len(got) != 0 {
+			t.Errorf("expected no errors for %q, got=%q", input, got)
+		}
+	}
+}
+
+func TestCheckErrorSpans(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected token.Span
+	}{
+		{`val x = "a" + 1`, token.Span{Start: 12, End: 12}},
+		{`val x = 1; x == 1 and x ?? 2; x .. "a"`, token.Span{Start: 32, End: 33}},
+		{`val n = 1; n(2)`, token.Span{Start: 11, End: 12}},
+		{`val m = {"a": 1}; m.bc`, token.Span{Start: 20, End: 22}},
+		{"fun f(a: int) { a }\nf(\"x\")", token.Span{Start: 22, End: 24}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input, "<string>")
+		p := parser.New(l)
+		errs := Check(p.ParseProgram())
+		if len(errs) != 1 {
+			t.Errorf("expected 1 error for %q, got=%d", tt.input, len(errs))
+			continue
+		}
+		if errs[0].Span != tt.expected {
+			t.Errorf("wrong span for %q. want=%+v, got=%+v", tt.input, tt.expected, errs[0].Span)
+		}
+	}
+}
+
+func TestTypeString(t *testing.T) {
+	tests := []struct {
+		typ      Type
+		expected string
+	}{
+		{Int, "int"},
+		{&List{Elem: Unknown}, "list"},
+		{&List{Elem: &List{Elem: Str}}, "list[list[str]]"},
+		{&Map{Key: Str, Value: Int}, "map[str, int]"},
+		{&Map{Key: Str, Value: Unknown, Fields: map[string]Type{"b": Int, "a": Str}}, "{a: str, b: int}"},
+		{&Union{Types: []Type{Int, None}}, "int | none"},
+		{&Func{Params: []Param{{Name: "a", Type: Int}, {Name: "b", Type: Unknown}}, Return: Str}, "fun(a: int, b) -> str"},
+		{&Func{Unchecked: true}, "fun"},
+		{&Instance{Struct: &Struct{Name: "Point"}}, "Point"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.typ.String(); got != tt.expected {
+			t.Errorf("wrong string. want=%q, got=%q", tt.expected, got)
+		}
+	}
+}